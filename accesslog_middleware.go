@@ -84,3 +84,8 @@ func (w *statusWriter) Write(b []byte) (int, error) {
 	w.written += n
 	return n, err
 }
+
+// Unwrap lets http.ResponseController reach Flush, Hijack and deadlines of the underlying writer.
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
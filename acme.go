@@ -0,0 +1,116 @@
+/*
+ * Copyright (c) 2026 Karagatan LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package servion
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.arpabet.com/glue"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+	"golang.org/x/xerrors"
+)
+
+type implAcmeManager struct {
+	Properties glue.Properties `inject:""`
+	Runtime    Runtime         `inject:"optional"`
+
+	manager *autocert.Manager
+}
+
+/*
+AcmeManager creates the AcmeProvider of the servers with the "acme" option. It
+belongs in the application context next to the RunCommand, so that every server
+uses the one certificate manager: autocert only offers the HTTP-01 challenge of
+a certificate a TLS server requests when the plain server answering it runs on
+the same manager.
+
+	beans := []interface{}{
+		properties,
+		servion.AcmeManager(),
+		servion.RunCommand(
+			servion.HttpServerScanner("https-server", servion.HealthHandler()),
+			servion.HttpServerScanner("http-server"),
+		),
+	}
+
+Configuration properties:
+
+	acme.domains        – semicolon or comma separated host names to obtain certificates for
+	acme.directory      – ACME directory URL (default Let's Encrypt production)
+	acme.email          – contact address of the ACME account
+	acme.cache-dir      – account key and certificate cache, relative to the home directory (default "acme")
+	acme.renew-before   – how long before expiry certificates renew (default 720h)
+	acme.ca-file        – PEM CA bundle trusting the directory server, e.g. of Pebble
+*/
+func AcmeManager() AcmeProvider {
+	return &implAcmeManager{}
+}
+
+func (t *implAcmeManager) PostConstruct() (err error) {
+	t.manager, err = newAcmeManager(t.Properties, t.Runtime)
+	return err
+}
+
+func (t *implAcmeManager) TLSConfig() *tls.Config {
+	return t.manager.TLSConfig()
+}
+
+func (t *implAcmeManager) HTTPHandler(fallback http.Handler) http.Handler {
+	return t.manager.HTTPHandler(fallback)
+}
+
+// newAcmeManager creates the ACME certificate manager from the acme.* properties.
+func newAcmeManager(properties glue.Properties, runtime Runtime) (*autocert.Manager, error) {
+	var domains []string
+	for _, d := range strings.FieldsFunc(properties.GetString("acme.domains", ""), func(r rune) bool { return r == ';' || r == ',' }) {
+		if d = strings.TrimSpace(d); d != "" {
+			domains = append(domains, d)
+		}
+	}
+	if len(domains) == 0 {
+		return nil, xerrors.New("acme: property 'acme.domains' is required")
+	}
+
+	cacheDir := properties.GetString("acme.cache-dir", "acme")
+	if !filepath.IsAbs(cacheDir) && runtime != nil {
+		cacheDir = filepath.Join(runtime.HomeDir(), cacheDir)
+	}
+
+	client := &acme.Client{DirectoryURL: properties.GetString("acme.directory", acme.LetsEncryptURL)}
+	if caFile := properties.GetString("acme.ca-file", ""); caFile != "" {
+		if !filepath.IsAbs(caFile) && runtime != nil {
+			caFile = filepath.Join(runtime.HomeDir(), caFile)
+		}
+		data, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, xerrors.Errorf("acme: read CA bundle: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, xerrors.Errorf("acme: no certificate in CA bundle %s", caFile)
+		}
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+		client.HTTPClient = &http.Client{Transport: transport}
+	}
+
+	return &autocert.Manager{
+		// enabling the option accepts the terms of service of the directory
+		Prompt:      autocert.AcceptTOS,
+		Cache:       autocert.DirCache(cacheDir),
+		HostPolicy:  autocert.HostWhitelist(domains...),
+		RenewBefore: properties.GetDuration("acme.renew-before", 30*24*time.Hour),
+		Client:      client,
+		Email:       properties.GetString("acme.email", ""),
+	}, nil
+}
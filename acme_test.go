@@ -0,0 +1,413 @@
+package servion
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.arpabet.com/glue"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// newTestAcmeManager returns the AcmeManager bean of props as the container would.
+func newTestAcmeManager(t *testing.T, props glue.Properties) *implAcmeManager {
+	t.Helper()
+	m := &implAcmeManager{Properties: props}
+	if err := m.PostConstruct(); err != nil {
+		t.Fatalf("AcmeManager: %v", err)
+	}
+	return m
+}
+
+func TestNewAcmeManager(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	writeTestCA(t, ca, filepath.Join(dir, "pebble.crt"))
+
+	props := glue.NewProperties()
+	if _, err := newAcmeManager(props, nil); err == nil {
+		t.Fatal("expected error without acme.domains")
+	}
+
+	props.Set("acme.domains", "example.com; www.example.com")
+	props.Set("acme.directory", "https://localhost:14000/dir")
+	props.Set("acme.email", "ops@example.com")
+	props.Set("acme.cache-dir", filepath.Join(dir, "acme"))
+	props.Set("acme.renew-before", "240h")
+	props.Set("acme.ca-file", filepath.Join(dir, "pebble.crt"))
+	m, err := newAcmeManager(props, nil)
+	if err != nil {
+		t.Fatalf("newAcmeManager: %v", err)
+	}
+	if m.Client.DirectoryURL != "https://localhost:14000/dir" || m.Email != "ops@example.com" || m.RenewBefore != 240*time.Hour {
+		t.Errorf("manager = %+v", m)
+	}
+	if m.Client.HTTPClient == nil {
+		t.Error("acme.ca-file did not configure the HTTP client")
+	}
+	if m.Cache.(autocert.DirCache) != autocert.DirCache(filepath.Join(dir, "acme")) {
+		t.Errorf("cache = %v", m.Cache)
+	}
+	ctx := context.Background()
+	if err := m.HostPolicy(ctx, "www.example.com"); err != nil {
+		t.Errorf("listed host refused: %v", err)
+	}
+	if err := m.HostPolicy(ctx, "other.example.com"); err == nil {
+		t.Error("unlisted host accepted")
+	}
+
+	props.Set("acme.ca-file", filepath.Join(dir, "missing.crt"))
+	if _, err := newAcmeManager(props, nil); err == nil {
+		t.Error("expected error for a missing CA bundle")
+	}
+}
+
+func TestNewAcmeManager_Defaults(t *testing.T) {
+	props := glue.NewProperties()
+	props.Set("acme.domains", "example.com")
+	m, err := newAcmeManager(props, newMockRuntime(true))
+	if err != nil {
+		t.Fatalf("newAcmeManager: %v", err)
+	}
+	if m.Client.DirectoryURL != acme.LetsEncryptURL || m.RenewBefore != 30*24*time.Hour {
+		t.Errorf("manager = %+v", m)
+	}
+	// relative to the home directory of the runtime
+	if m.Cache.(autocert.DirCache) != autocert.DirCache("/tmp/acme") {
+		t.Errorf("cache = %v", m.Cache)
+	}
+}
+
+func TestHttpServerFactory_AcmeTLS(t *testing.T) {
+	props := glue.NewProperties()
+	props.Set("test-server.bind-address", "127.0.0.1:0")
+	props.Set("test-server.options", "tls;acme")
+	props.Set("acme.domains", "example.com")
+	props.Set("acme.cache-dir", t.TempDir())
+
+	f := &implHttpServerFactory{
+		Log:        zap.NewNop(),
+		Properties: props,
+		TlsConfig:  &tls.Config{InsecureSkipVerify: true},
+		Acme:       newTestAcmeManager(t, props),
+		beanName:   "test-server",
+	}
+	obj, err := f.Object()
+	if err != nil {
+		t.Fatalf("Object: %v", err)
+	}
+	cfg := obj.(*http.Server).TLSConfig
+	if cfg == nil || cfg.GetCertificate == nil || cfg.InsecureSkipVerify {
+		t.Fatalf("TLSConfig = %+v, want the ACME manager's", cfg)
+	}
+	if !containsString(cfg.NextProtos, acme.ALPNProto) {
+		t.Errorf("NextProtos = %v, want %s for TLS-ALPN-01", cfg.NextProtos, acme.ALPNProto)
+	}
+	// hosts outside acme.domains get no certificate
+	if _, err := cfg.GetCertificate(&tls.ClientHelloInfo{ServerName: "other.example.com"}); err == nil {
+		t.Error("certificate for an unlisted host")
+	}
+
+	props.Set("test-server.tls-profile", "public")
+	if _, err := f.Object(); err == nil {
+		t.Error("expected error for a TLS profile together with acme")
+	}
+
+	props.Set("test-server.tls-profile", "")
+	f.Acme = nil
+	if _, err := f.Object(); err == nil {
+		t.Error("expected error for the acme option without an AcmeManager")
+	}
+}
+
+func TestHttpServerFactory_AcmeHTTPChallenge(t *testing.T) {
+	cacheDir := t.TempDir()
+	props := glue.NewProperties()
+	props.Set("test-server.bind-address", "127.0.0.1:0")
+	props.Set("test-server.options", "handlers;acme")
+	props.Set("acme.domains", "example.com")
+	props.Set("acme.cache-dir", cacheDir)
+
+	f := &implHttpServerFactory{
+		Log:        zap.NewNop(),
+		Properties: props,
+		Handlers:   []HttpHandler{&testHandler{pattern: "/hello"}},
+		Acme:       newTestAcmeManager(t, props),
+		beanName:   "test-server",
+	}
+	obj, err := f.Object()
+	if err != nil {
+		t.Fatalf("Object: %v", err)
+	}
+	srv := obj.(*http.Server)
+	if srv.TLSConfig != nil {
+		t.Fatal("plain server got a TLS config")
+	}
+
+	// the manager of the TLS server leaves the token in the shared cache
+	if err := os.WriteFile(filepath.Join(cacheDir, "tok+http-01"), []byte("tok.thumbprint"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	srv.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://example.com/.well-known/acme-challenge/tok", nil))
+	if body, _ := io.ReadAll(w.Body); w.Code != http.StatusOK || string(body) != "tok.thumbprint" {
+		t.Errorf("challenge = %d %q", w.Code, body)
+	}
+
+	w = httptest.NewRecorder()
+	srv.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://example.com/hello", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "ok" {
+		t.Errorf("handler behind the challenge = %d %q", w.Code, w.Body.String())
+	}
+}
+
+// acmeStandIn is a minimal RFC 8555 directory offering only HTTP-01 challenges,
+// which it validates against the plain server at challengeAddr. Request
+// signatures are not checked.
+type acmeStandIn struct {
+	t             *testing.T
+	ca            *testCA
+	srv           *httptest.Server
+	challengeAddr string
+
+	mu       sync.Mutex
+	nonce    int
+	jwk      json.RawMessage
+	domain   string
+	token    string
+	status   string // of the authorization
+	leaf     []byte
+	validate error
+}
+
+func newAcmeStandIn(t *testing.T) *acmeStandIn {
+	s := &acmeStandIn{t: t, ca: newTestCA(t), status: acme.StatusPending}
+	s.srv = httptest.NewServer(http.HandlerFunc(s.handle))
+	t.Cleanup(s.srv.Close)
+	return s
+}
+
+func (s *acmeStandIn) url(path string) string {
+	return s.srv.URL + path
+}
+
+func (s *acmeStandIn) handle(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.nonce++
+	w.Header().Set("Replay-Nonce", fmt.Sprintf("nonce-%d", s.nonce))
+	s.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+
+	var jws struct{ Protected, Payload string }
+	var protected struct{ Jwk json.RawMessage }
+	var payload []byte
+	if r.Method == http.MethodPost {
+		json.NewDecoder(r.Body).Decode(&jws)
+		header, _ := base64.RawURLEncoding.DecodeString(jws.Protected)
+		json.Unmarshal(header, &protected)
+		payload, _ = base64.RawURLEncoding.DecodeString(jws.Payload)
+	}
+
+	switch r.URL.Path {
+	case "/dir":
+		json.NewEncoder(w).Encode(map[string]string{
+			"newNonce":   s.url("/nonce"),
+			"newAccount": s.url("/account"),
+			"newOrder":   s.url("/order"),
+		})
+	case "/nonce":
+	case "/account":
+		s.mu.Lock()
+		s.jwk = protected.Jwk
+		s.mu.Unlock()
+		w.Header().Set("Location", s.url("/account/1"))
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"status":"valid"}`))
+	case "/order":
+		var req struct{ Identifiers []struct{ Value string } }
+		json.Unmarshal(payload, &req)
+		s.mu.Lock()
+		s.domain = req.Identifiers[0].Value
+		s.token = "tok-" + strings.ReplaceAll(s.domain, ".", "-")
+		s.mu.Unlock()
+		w.Header().Set("Location", s.url("/order/1"))
+		w.WriteHeader(http.StatusCreated)
+		s.writeOrder(w)
+	case "/order/1":
+		s.writeOrder(w)
+	case "/authz/1":
+		s.mu.Lock()
+		z := map[string]interface{}{
+			"status":     s.status,
+			"identifier": map[string]string{"type": "dns", "value": s.domain},
+			"challenges": []map[string]string{{"type": "http-01", "url": s.url("/chal/1"), "token": s.token, "status": s.status}},
+		}
+		s.mu.Unlock()
+		json.NewEncoder(w).Encode(z)
+	case "/chal/1":
+		err := s.validateHTTP01()
+		s.mu.Lock()
+		s.status, s.validate = acme.StatusValid, err
+		if err != nil {
+			s.status = acme.StatusInvalid
+		}
+		json.NewEncoder(w).Encode(map[string]string{"type": "http-01", "url": s.url("/chal/1"), "token": s.token, "status": s.status})
+		s.mu.Unlock()
+	case "/finalize":
+		var req struct{ CSR string }
+		json.Unmarshal(payload, &req)
+		der, _ := base64.RawURLEncoding.DecodeString(req.CSR)
+		csr, err := x509.ParseCertificateRequest(der)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		leaf := &x509.Certificate{
+			SerialNumber: big.NewInt(2),
+			DNSNames:     csr.DNSNames,
+			NotBefore:    time.Now().Add(-time.Minute),
+			NotAfter:     time.Now().Add(90 * 24 * time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		}
+		if der, err = x509.CreateCertificate(rand.Reader, leaf, s.ca.cert, csr.PublicKey, s.ca.key); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		s.mu.Lock()
+		s.leaf = der
+		s.mu.Unlock()
+		s.writeOrder(w)
+	case "/cert/1":
+		w.Header().Set("Content-Type", "application/pem-certificate-chain")
+		s.mu.Lock()
+		pem.Encode(w, &pem.Block{Type: "CERTIFICATE", Bytes: s.leaf})
+		s.mu.Unlock()
+		pem.Encode(w, &pem.Block{Type: "CERTIFICATE", Bytes: s.ca.cert.Raw})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *acmeStandIn) writeOrder(w http.ResponseWriter) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	o := map[string]interface{}{
+		"status":         acme.StatusPending,
+		"identifiers":    []map[string]string{{"type": "dns", "value": s.domain}},
+		"authorizations": []string{s.url("/authz/1")},
+		"finalize":       s.url("/finalize"),
+	}
+	switch {
+	case s.leaf != nil:
+		o["status"], o["certificate"] = acme.StatusValid, s.url("/cert/1")
+	case s.status == acme.StatusValid:
+		o["status"] = acme.StatusReady
+	case s.status == acme.StatusInvalid:
+		o["status"] = acme.StatusInvalid
+	}
+	json.NewEncoder(w).Encode(o)
+}
+
+// validateHTTP01 fetches the key authorization of the token from the plain server, as a CA does on port 80.
+func (s *acmeStandIn) validateHTTP01() error {
+	s.mu.Lock()
+	domain, token, jwk := s.domain, s.token, s.jwk
+	s.mu.Unlock()
+
+	var key struct{ X, Y string }
+	json.Unmarshal(jwk, &key)
+	x, _ := base64.RawURLEncoding.DecodeString(key.X)
+	y, _ := base64.RawURLEncoding.DecodeString(key.Y)
+	thumbprint, err := acme.JWKThumbprint(&ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)})
+	if err != nil {
+		return err
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "http://"+s.challengeAddr+"/.well-known/acme-challenge/"+token, nil)
+	req.Host = domain
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || string(body) != token+"."+thumbprint {
+		return fmt.Errorf("http-01: %d %q", resp.StatusCode, body)
+	}
+	return nil
+}
+
+func TestAcmeManager_IssueOverHTTP01(t *testing.T) {
+	ca := newAcmeStandIn(t)
+	props := glue.NewProperties()
+	props.Set("https-server.bind-address", "127.0.0.1:0")
+	props.Set("https-server.options", "handlers;tls;acme")
+	props.Set("http-server.bind-address", "127.0.0.1:0")
+	props.Set("http-server.options", "acme")
+	props.Set("acme.domains", "example.test")
+	props.Set("acme.directory", ca.url("/dir"))
+	props.Set("acme.cache-dir", t.TempDir())
+
+	// the one bean of the application context, injected into both servers
+	manager := newTestAcmeManager(t, props)
+	server := func(name string) *http.Server {
+		f := &implHttpServerFactory{
+			Log:        zap.NewNop(),
+			Properties: props,
+			Handlers:   []HttpHandler{&testHandler{pattern: "/hello"}},
+			Acme:       manager,
+			beanName:   name,
+		}
+		obj, err := f.Object()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		return obj.(*http.Server)
+	}
+	httpsServer, httpServer := server("https-server"), server("http-server")
+
+	port80 := httptest.NewServer(httpServer.Handler)
+	defer port80.Close()
+	ca.challengeAddr = strings.TrimPrefix(port80.URL, "http://")
+
+	// the first handshake naming the host obtains its certificate
+	cert, err := httpsServer.TLSConfig.GetCertificate(&tls.ClientHelloInfo{
+		ServerName:       "example.test",
+		SignatureSchemes: []tls.SignatureScheme{tls.ECDSAWithP256AndSHA256},
+		SupportedCurves:  []tls.CurveID{tls.CurveP256},
+		CipherSuites:     []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256},
+	})
+	if err != nil {
+		t.Fatalf("GetCertificate: %v (challenge: %v)", err, ca.validate)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := leaf.VerifyHostname("example.test"); err != nil {
+		t.Errorf("certificate: %v", err)
+	}
+	if _, err := leaf.Verify(x509.VerifyOptions{Roots: ca.ca.pool}); err != nil {
+		t.Errorf("certificate not issued by the CA: %v", err)
+	}
+}
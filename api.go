@@ -11,6 +11,7 @@ import (
 	"net"
 	"net/http"
 	"reflect"
+	"time"
 
 	"go.arpabet.com/glue"
 	"go.uber.org/zap"
@@ -244,6 +245,248 @@ type Authenticator interface {
 	Authenticate(token string) (AuthInfo, error)
 }
 
+var SessionAuthenticatorClass = reflect.TypeOf((*SessionAuthenticator)(nil)).Elem()
+
+/*
+SessionAuthenticator resolves the identity of a browser session, e.g. the cookie
+set by OidcHandler after login. AuthMiddleware consults the bean found in its
+context for requests without an Authorization header.
+*/
+type SessionAuthenticator interface {
+
+	// AuthenticateSession returns the identity of the session of r, ok=false when
+	// r carries no valid session.
+	AuthenticateSession(r *http.Request) (info AuthInfo, ok bool, err error)
+}
+
+var CertificateAuthenticatorClass = reflect.TypeOf((*CertificateAuthenticator)(nil)).Elem()
+
+/*
+CertificateAuthenticator resolves the identity of a client that presented a
+verified TLS certificate (mutual TLS). AuthMiddleware and the gRPC
+AuthInterceptor consult it for requests without an Authorization header whose
+connection carries a verified chain; requesting and verifying client
+certificates is up to the injected *tls.Config (ClientAuth and ClientCAs).
+*/
+type CertificateAuthenticator interface {
+
+	// AuthenticateCertificate maps the verified client certificate of state to
+	// auth info. ErrUnauthorized means the certificate is not accepted.
+	AuthenticateCertificate(state *tls.ConnectionState) (AuthInfo, error)
+}
+
+var RevocationStoreClass = reflect.TypeOf((*RevocationStore)(nil)).Elem()
+
+/*
+RevocationStore keeps revoked tokens, by their "jti" claim, and revoked
+subjects, whose tokens issued before a point in time are refused. JwtAuthProvider
+consults the bean found in its context after a token verifies.
+MemoryRevocationStore and FileRevocationStore are the built-in implementations;
+a store shared by the replicas of a service revokes a token on all of them.
+*/
+type RevocationStore interface {
+
+	// RevokeToken revokes the token with jti. The entry is kept until expires,
+	// when the token would be refused as expired anyway.
+	RevokeToken(jti string, expires time.Time) error
+
+	// RevokeSubject revokes the tokens of subject issued before, keeping the
+	// entry until expires.
+	RevokeSubject(subject string, before, expires time.Time) error
+
+	// IsRevoked reports whether the token with jti of subject issued at issuedAt
+	// is revoked. A zero issuedAt counts as issued before any subject revocation.
+	IsRevoked(jti, subject string, issuedAt time.Time) (bool, error)
+}
+
+// UserAccount is a local user of a UserStore, known by its password hash only.
+type UserAccount struct {
+	Username        string    `json:"username"`
+	PasswordHash    string    `json:"passwordHash"`
+	Roles           []string  `json:"roles,omitempty"`
+	Disabled        bool      `json:"disabled,omitempty"`
+	FailedLogins    int       `json:"failedLogins,omitempty"`
+	LockedUntil     time.Time `json:"lockedUntil,omitempty"`
+	PasswordChanged time.Time `json:"passwordChanged"`
+}
+
+var UserStoreClass = reflect.TypeOf((*UserStore)(nil)).Elem()
+
+/*
+UserStore keeps the local user accounts of LoginHandler and UserAuthProvider.
+FileUserStore is the built-in implementation.
+*/
+type UserStore interface {
+
+	// Load returns the account of username, nil when it is unknown.
+	Load(username string) (*UserAccount, error)
+
+	// Store saves u, replacing the account with the same user name.
+	Store(u *UserAccount) error
+
+	// Delete removes the account of username; unknown users are not an error.
+	Delete(username string) error
+
+	// List returns all accounts ordered by user name.
+	List() ([]*UserAccount, error)
+}
+
+// Session is the server-side state of a browser session.
+type Session struct {
+	ID        string            `json:"id"`
+	Auth      AuthInfo          `json:"auth"`
+	Values    map[string]string `json:"values,omitempty"`
+	CSRFToken string            `json:"csrf"`
+	Created   time.Time         `json:"created"`
+	LastSeen  time.Time         `json:"lastSeen"`
+}
+
+var SessionStoreClass = reflect.TypeOf((*SessionStore)(nil)).Elem()
+
+/*
+SessionStore keeps sessions by ID. MemorySessionStore and FileSessionStore are
+the built-in implementations; a store shared by the replicas of a service lets
+any of them serve a session.
+*/
+type SessionStore interface {
+
+	// Load returns the session with id, nil when it is unknown or expired.
+	Load(id string) (*Session, error)
+
+	// Store saves s, replacing the session with the same ID, until expires.
+	Store(s *Session, expires time.Time) error
+
+	// Delete removes the session with id; unknown ids are not an error.
+	Delete(id string) error
+}
+
+var SessionManagerClass = reflect.TypeOf((*SessionManager)(nil)).Elem()
+
+/*
+SessionManager ties sessions to requests through a cookie holding only the
+session ID. It enforces idle and absolute expiry, and is a SessionAuthenticator,
+so AuthMiddleware accepts the session of logged in users.
+*/
+type SessionManager interface {
+	SessionAuthenticator
+
+	// Session returns the live session of r, nil when there is none.
+	Session(r *http.Request) (*Session, error)
+
+	// Start begins a session for info after login. The current session of r, if
+	// any, is deleted, so the ID always changes with the privilege level.
+	Start(w http.ResponseWriter, r *http.Request, info AuthInfo) (*Session, error)
+
+	// Renew moves the session of r to a new ID and CSRF token, keeping its data,
+	// e.g. after a role change or re-authentication.
+	Renew(w http.ResponseWriter, r *http.Request) (*Session, error)
+
+	// Save stores changes of s.Auth and s.Values.
+	Save(s *Session) error
+
+	// End deletes the session of r and clears its cookie.
+	End(w http.ResponseWriter, r *http.Request) error
+}
+
+const (
+	RateLimitFixedWindow   = "fixed-window"
+	RateLimitSlidingWindow = "sliding-window"
+	RateLimitTokenBucket   = "token-bucket"
+	RateLimitGCRA          = "gcra"
+)
+
+// RateLimitRule is a limit of requests per interval applied to every path under Prefix.
+// The default rule (built from ratelimit.limit / ratelimit.interval) has an empty Prefix.
+type RateLimitRule struct {
+	Prefix   string
+	Limit    int
+	Interval time.Duration
+}
+
+// RateLimitResult is the outcome of one request against a bucket.
+type RateLimitResult struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration // until the quota is fully restored
+	RetryAfter time.Duration // until the next request would be allowed, only when denied
+}
+
+var RateLimitStoreClass = reflect.TypeOf((*RateLimitStore)(nil)).Elem()
+
+/*
+RateLimitStore keeps rate limit state outside the middleware, so replicas of the
+same application share one budget per client. When a bean implementing it is
+present in the server context, RateLimiterMiddleware consults it for every
+request; any error degrades that request to the middleware's local buckets, so an
+unreachable store never turns into an outage.
+*/
+type RateLimitStore interface {
+
+	// Take consumes one request from the bucket of key under rule, using the
+	// named algorithm (one of the RateLimit* constants).
+	Take(ctx context.Context, key string, algorithm string, rule RateLimitRule) (RateLimitResult, error)
+}
+
+var ErrForbidden = xerrors.New("forbidden")
+
+const (
+	TransportHTTP = "http"
+	TransportGRPC = "grpc"
+	TransportVRPC = "vrpc"
+)
+
+// AccessRequest describes one operation to authorize.
+type AccessRequest struct {
+	Transport string // one of the Transport* constants
+	Method    string // HTTP method, empty for gRPC and vRPC
+	Resource  string // HTTP path, gRPC full method or vRPC function name
+}
+
+var AuthorizerClass = reflect.TypeOf((*Authorizer)(nil)).Elem()
+
+/*
+Authorizer decides whether an identity may perform an operation. AuthzMiddleware,
+the grpc AuthzInterceptor and the vrpc Authorize wrapper consult the bean found in
+their context, after authentication has stored the identity with ContextWithAuth.
+*/
+type Authorizer interface {
+
+	// Authorize returns nil to allow the request. A denial wraps ErrForbidden, or
+	// ErrUnauthorized when info is nil (anonymous) and the rule needs an identity.
+	Authorize(ctx context.Context, req AccessRequest, info *AuthInfo) error
+}
+
+// Priority is the class of a request under a ConcurrencyLimiter; lower classes are shed first.
+type Priority int
+
+const (
+	PriorityLow Priority = iota
+	PriorityNormal
+	PriorityHigh
+	PriorityCritical // never queued nor shed: health checks, admin calls
+)
+
+var ErrOverloaded = xerrors.New("server overloaded")
+
+var ConcurrencyLimiterClass = reflect.TypeOf((*ConcurrencyLimiter)(nil)).Elem()
+
+/*
+ConcurrencyLimiter caps the number of requests in flight in the whole process,
+adapting the cap to the observed latency. ConcurrencyLimitMiddleware and the grpc
+ConcurrencyInterceptor share the bean found in their context, so HTTP and gRPC
+traffic draw on one budget.
+*/
+type ConcurrencyLimiter interface {
+
+	// Acquire admits a request, waiting in the bounded queue while the limit is
+	// reached. It returns ErrOverloaded when the request is shed, or the context
+	// error. On success release must be called exactly once when the request
+	// completes; ok=false reports a failure caused by overload (a timeout, a 503),
+	// which shrinks the limit.
+	Acquire(ctx context.Context, priority Priority) (release func(ok bool), err error)
+}
+
 // ComponentClass Generic component class that has a name and ability to GetStats
 var ComponentClass = reflect.TypeOf((*Component)(nil)).Elem()
 
@@ -257,3 +500,64 @@ type Component interface {
 
 	GetStats(cb func(name, value string) bool) error
 }
+
+var HealthWarnerClass = reflect.TypeOf((*HealthWarner)(nil)).Elem()
+
+/*
+HealthWarner is a Component that reports conditions needing attention before
+they fail, such as a certificate close to expiry. HealthHandler lists the
+warnings of all such components while the status stays UP.
+*/
+type HealthWarner interface {
+	Component
+
+	HealthWarnings() []string
+}
+
+var TlsConfigProviderClass = reflect.TypeOf((*TlsConfigProvider)(nil)).Elem()
+
+/*
+TlsConfigProvider holds the named TLS profiles created by TlsProfiles. Servers
+and clients pick one with the <bean>.tls-profile property in place of a
+*tls.Config bean. Certificates of a profile reload from disk when they change,
+and every configuration returned reads them at each handshake.
+*/
+type TlsConfigProvider interface {
+
+	// ServerConfig returns a new server configuration of the named profile.
+	ServerConfig(name string) (*tls.Config, error)
+
+	// ClientConfig returns a new client configuration of the named profile.
+	ClientConfig(name string) (*tls.Config, error)
+}
+
+var AcmeProviderClass = reflect.TypeOf((*AcmeProvider)(nil)).Elem()
+
+/*
+AcmeProvider obtains and renews certificates from an ACME CA for the servers with
+the "acme" option, created by AcmeManager. A single bean serves all of them, so
+the HTTP-01 challenges of the certificates a TLS server requests are answered by
+the plain server on port 80.
+*/
+type AcmeProvider interface {
+
+	// TLSConfig returns a server configuration getting its certificates from the CA and answering TLS-ALPN-01 challenges.
+	TLSConfig() *tls.Config
+
+	// HTTPHandler answers HTTP-01 challenges and passes every other request to fallback.
+	HTTPHandler(fallback http.Handler) http.Handler
+}
+
+var HttpDispatcherClass = reflect.TypeOf((*HttpDispatcher)(nil)).Elem()
+
+/*
+HttpDispatcher takes the requests of another protocol off an HTTP server before
+its router, handlers, middleware and body limit, for example gRPC calls sharing
+the port. HttpServerFactory applies the dispatchers of its own context under the
+h2c and ACME handlers, so they see every HTTP/2 stream of the server.
+*/
+type HttpDispatcher interface {
+
+	// Dispatch returns a handler serving the requests of the dispatcher and passing the others to next.
+	Dispatch(next http.Handler) http.Handler
+}
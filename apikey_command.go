@@ -0,0 +1,102 @@
+/*
+ * Copyright (c) 2026 Karagatan LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package servion
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.arpabet.com/cligo"
+	"golang.org/x/xerrors"
+)
+
+type implApiKeyCommand struct {
+	Parent  cligo.CliGroup `cli:"group=cli"`
+	Subject string         `cli:"option=subject,default=,help=identity the key stands for"`
+	Roles   string         `cli:"option=roles,default=,help=comma separated roles"`
+	Scopes  string         `cli:"option=scopes,default=,help=comma separated scopes"`
+	Expires string         `cli:"option=expires,default=,help=key lifetime such as 720h or 90d; empty never expires"`
+
+	out io.Writer
+}
+
+/*
+ApiKeyCommand creates the "apikey" command generating a random API key. It
+prints the key, shown this once, and the line to append to the file of
+ApiKeyProvider, which holds only the SHA-256 of the key:
+
+	app apikey --subject billing-job --roles service --scopes invoices.rw --expires 90d
+*/
+func ApiKeyCommand() cligo.CliCommand {
+	return &implApiKeyCommand{out: os.Stdout}
+}
+
+func (t *implApiKeyCommand) Command() string {
+	return "apikey"
+}
+
+func (t *implApiKeyCommand) Help() (string, string) {
+	return "Generates an API key.",
+		`This command generates a random API key and prints it together with the line to add to the API key file (apikey.file).
+The file keeps only the SHA-256 of the key, so the key is shown once and can not be recovered later.`
+}
+
+func (t *implApiKeyCommand) Run(ctx context.Context) error {
+	subject := strings.TrimSpace(t.Subject)
+	if subject == "" || strings.ContainsAny(subject, " \t#") {
+		return xerrors.New("--subject is required and must not contain spaces or '#'")
+	}
+	token := randomToken()
+	key := &apiKey{
+		hash:    hashToken(token),
+		subject: subject,
+		roles:   apiKeyList(t.Roles),
+		scopes:  apiKeyList(t.Scopes),
+	}
+	for _, v := range append(append([]string{}, key.roles...), key.scopes...) {
+		if strings.ContainsAny(v, " \t#") {
+			return xerrors.Errorf("role or scope '%s' must not contain spaces or '#'", v)
+		}
+	}
+	if t.Expires != "" {
+		lifetime, err := parseKeyLifetime(t.Expires)
+		if err != nil {
+			return err
+		}
+		key.expires = time.Now().Add(lifetime).Truncate(time.Second)
+	}
+
+	fmt.Fprintf(t.out, "# API key of %s, shown only this once\n", subject)
+	fmt.Fprintln(t.out, token)
+	fmt.Fprintln(t.out)
+	fmt.Fprintln(t.out, "# line for the API key file")
+	fmt.Fprintln(t.out, key.String())
+	return nil
+}
+
+// parseKeyLifetime parses a Go duration, or a number of days such as "90d".
+func parseKeyLifetime(s string) (time.Duration, error) {
+	var (
+		d   time.Duration
+		err error
+	)
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		var n int
+		n, err = strconv.Atoi(days)
+		d = time.Duration(n) * 24 * time.Hour
+	} else {
+		d, err = time.ParseDuration(s)
+	}
+	if err != nil || d <= 0 {
+		return 0, xerrors.Errorf("invalid --expires '%s', expected a positive duration such as 720h or 90d", s)
+	}
+	return d, nil
+}
@@ -0,0 +1,63 @@
+package servion
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestApiKeyCommand_GeneratesUsableLine(t *testing.T) {
+	var out bytes.Buffer
+	cmd := &implApiKeyCommand{Subject: "billing-job", Roles: "service", Scopes: "invoices.rw,invoices.read", Expires: "90d", out: &out}
+	if err := cmd.Run(context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	var lines []string
+	for _, line := range strings.Split(out.String(), "\n") {
+		if line != "" && !strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) != 2 {
+		t.Fatalf("expected key and file line, got %q", out.String())
+	}
+	token, line := lines[0], lines[1]
+	if strings.Contains(line, token) {
+		t.Fatal("file line must not contain the plaintext key")
+	}
+
+	path := filepath.Join(t.TempDir(), "apikeys")
+	writeApiKeys(t, path, line)
+	p := newApiKeyProvider(t, path)
+	info, err := p.Authenticate(token)
+	if err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	if info.Subject != "billing-job" || len(info.Scopes) != 2 {
+		t.Fatalf("unexpected identity %+v", info)
+	}
+	expires, _ := time.Parse(time.RFC3339, info.Attributes["expires"])
+	if d := time.Until(expires); d < 89*24*time.Hour || d > 90*24*time.Hour {
+		t.Fatalf("expires in %v, want 90 days", d)
+	}
+}
+
+func TestApiKeyCommand_InvalidOptions(t *testing.T) {
+	for _, cmd := range []*implApiKeyCommand{
+		{},
+		{Subject: "two words"},
+		{Subject: "job", Roles: "a b"},
+		{Subject: "job", Expires: "soon"},
+		{Subject: "job", Expires: "-1h"},
+	} {
+		cmd.out = io.Discard
+		if err := cmd.Run(context.Background()); err == nil {
+			t.Errorf("%+v: expected error", cmd)
+		}
+	}
+}
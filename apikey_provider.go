@@ -0,0 +1,260 @@
+/*
+ * Copyright (c) 2026 Karagatan LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package servion
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/xerrors"
+)
+
+// maxApiKeyFileSize caps the API key file read on every change.
+const maxApiKeyFileSize = 8 << 20
+
+type implApiKeyProvider struct {
+	Log     *zap.Logger `inject:"optional"`
+	Runtime Runtime     `inject:"optional"`
+
+	// Key file, relative paths resolve against the home directory
+	File string `value:"apikey.file,default=apikeys"`
+
+	// How often the file is checked for changes
+	ReloadInterval time.Duration `value:"apikey.reload-interval,default=10s"`
+
+	path    string
+	mu      sync.RWMutex
+	keys    map[string]*apiKey
+	fileMod time.Time
+	stopCh  chan struct{}
+	wg      sync.WaitGroup
+}
+
+// apiKey is one line of the API key file; the key itself is only known by its hash.
+type apiKey struct {
+	hash     string
+	subject  string
+	roles    []string
+	scopes   []string
+	expires  time.Time
+	disabled bool
+}
+
+/*
+ApiKeyProvider creates an Authenticator of API keys listed in a file. Each line
+holds the SHA-256 of a key and the identity it stands for, fields separated by
+spaces, "-" for an empty list or no expiry:
+
+	# sha256(key)                                                      subject    roles        scopes      expires               flags
+	9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08 billing-job service      invoices.rw 2027-01-01T00:00:00Z
+	60303ae22b998861bce3b28f33eec1be758a213c86c93c076dbe9f558c11c752 partner-acme partner      -           -                     disabled
+
+Plaintext keys never appear in configuration; "servion apikey" generates a key
+and prints its line. Expired and disabled keys are refused. The file is checked
+every apikey.reload-interval and reloaded when it changed, so keys are added,
+disabled or removed without a restart; a file that fails to parse is logged and
+the keys loaded before stay in use.
+
+Configuration properties:
+
+	apikey.file             – key file, relative to the home directory (default "apikeys")
+	apikey.reload-interval  – how often the file is checked for changes (default 10s)
+*/
+func ApiKeyProvider() Authenticator {
+	return &implApiKeyProvider{}
+}
+
+func (t *implApiKeyProvider) PostConstruct() error {
+	if t.Log == nil {
+		t.Log = zap.NewNop()
+	}
+	t.path = t.File
+	if !filepath.IsAbs(t.path) && t.Runtime != nil {
+		t.path = filepath.Join(t.Runtime.HomeDir(), t.path)
+	}
+	if err := t.reload(); err != nil {
+		return xerrors.Errorf("apikey: %w", err)
+	}
+	if t.ReloadInterval > 0 {
+		t.stopCh = make(chan struct{})
+		t.wg.Add(1)
+		go t.reloadLoop(t.stopCh)
+	}
+	return nil
+}
+
+func (t *implApiKeyProvider) Destroy() error {
+	if t.stopCh != nil {
+		close(t.stopCh)
+		t.stopCh = nil
+	}
+	t.wg.Wait()
+	return nil
+}
+
+func (t *implApiKeyProvider) reloadLoop(stopCh <-chan struct{}) {
+	defer t.wg.Done()
+	ticker := time.NewTicker(t.ReloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := t.reload(); err != nil {
+				t.Log.Warn("ApiKeysReloadFailed", zap.String("file", t.path), zap.Error(err))
+			}
+		case <-stopCh:
+			return
+		}
+	}
+}
+
+// reload parses the key file again when its modification time changed.
+func (t *implApiKeyProvider) reload() error {
+	fi, err := os.Stat(t.path)
+	if err != nil {
+		return xerrors.Errorf("stat API key file: %w", err)
+	}
+	t.mu.RLock()
+	unchanged := t.keys != nil && fi.ModTime().Equal(t.fileMod)
+	t.mu.RUnlock()
+	if unchanged {
+		return nil
+	}
+	if fi.Size() > maxApiKeyFileSize {
+		return xerrors.Errorf("API key file %s exceeds %d bytes", t.path, maxApiKeyFileSize)
+	}
+	data, err := os.ReadFile(t.path)
+	if err != nil {
+		return xerrors.Errorf("read API key file: %w", err)
+	}
+	keys, err := parseApiKeys(data)
+	if err != nil {
+		return xerrors.Errorf("%s:%w", t.path, err)
+	}
+	t.mu.Lock()
+	t.keys = keys
+	t.fileMod = fi.ModTime()
+	t.mu.Unlock()
+	t.Log.Info("ApiKeysLoaded", zap.String("file", t.path), zap.Int("keys", len(keys)))
+	return nil
+}
+
+func (t *implApiKeyProvider) Authenticate(token string) (AuthInfo, error) {
+	h := hashToken(token)
+	t.mu.RLock()
+	key, ok := t.keys[h]
+	t.mu.RUnlock()
+	if !ok {
+		return AuthInfo{}, ErrUnauthorized
+	}
+	if key.disabled {
+		return AuthInfo{}, xerrors.Errorf("API key of '%s' is disabled: %w", key.subject, ErrUnauthorized)
+	}
+	if !key.expires.IsZero() && !time.Now().Before(key.expires) {
+		return AuthInfo{}, xerrors.Errorf("API key of '%s' expired: %w", key.subject, ErrUnauthorized)
+	}
+	info := AuthInfo{
+		HashedToken: h,
+		Subject:     key.subject,
+		Roles:       key.roles,
+		Scopes:      key.scopes,
+		Attributes:  map[string]string{"auth": "apikey"},
+	}
+	if !key.expires.IsZero() {
+		info.Attributes["expires"] = key.expires.Format(time.RFC3339)
+	}
+	return info, nil
+}
+
+func parseApiKeys(data []byte) (map[string]*apiKey, error) {
+	keys := make(map[string]*apiKey)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		key, err := parseApiKey(line)
+		if err != nil {
+			return nil, xerrors.Errorf("%d: %w", n, err)
+		}
+		if _, dup := keys[key.hash]; dup {
+			return nil, xerrors.Errorf("%d: duplicate key hash of '%s'", n, key.subject)
+		}
+		keys[key.hash] = key
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func parseApiKey(line string) (*apiKey, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 5 || len(fields) > 6 {
+		return nil, xerrors.New("expected hash subject roles scopes expires [disabled]")
+	}
+	hash := strings.ToLower(fields[0])
+	if b, err := hex.DecodeString(hash); err != nil || len(b) != 32 {
+		return nil, xerrors.Errorf("'%s' is not a hex SHA-256 hash", fields[0])
+	}
+	key := &apiKey{
+		hash:    hash,
+		subject: fields[1],
+		roles:   apiKeyList(fields[2]),
+		scopes:  apiKeyList(fields[3]),
+	}
+	if fields[4] != "-" {
+		expires, err := time.Parse(time.RFC3339, fields[4])
+		if err != nil {
+			return nil, xerrors.Errorf("invalid expiry '%s', expected RFC 3339 time or -", fields[4])
+		}
+		key.expires = expires
+	}
+	if len(fields) == 6 {
+		if fields[5] != "disabled" {
+			return nil, xerrors.Errorf("unknown flag '%s', expected disabled", fields[5])
+		}
+		key.disabled = true
+	}
+	return key, nil
+}
+
+func apiKeyList(field string) []string {
+	if field == "-" {
+		return nil
+	}
+	return commaList(field)
+}
+
+// String formats k as a line of the API key file.
+func (k *apiKey) String() string {
+	field := func(list []string) string {
+		if len(list) == 0 {
+			return "-"
+		}
+		return strings.Join(list, ",")
+	}
+	expires := "-"
+	if !k.expires.IsZero() {
+		expires = k.expires.UTC().Format(time.RFC3339)
+	}
+	line := strings.Join([]string{k.hash, k.subject, field(k.roles), field(k.scopes), expires}, " ")
+	if k.disabled {
+		line += " disabled"
+	}
+	return line
+}
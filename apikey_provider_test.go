@@ -0,0 +1,138 @@
+package servion
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeApiKeys(t *testing.T, path string, lines ...string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newApiKeyProvider(t *testing.T, path string) *implApiKeyProvider {
+	t.Helper()
+	p := &implApiKeyProvider{File: path}
+	if err := p.PostConstruct(); err != nil {
+		t.Fatalf("PostConstruct: %v", err)
+	}
+	t.Cleanup(func() { p.Destroy() })
+	return p
+}
+
+func TestApiKeyProvider_Authenticate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "apikeys")
+	future := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	past := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	writeApiKeys(t, path,
+		"# hash subject roles scopes expires flags",
+		hashToken("key-1")+" billing-job service,reader invoices.rw "+future,
+		hashToken("key-2")+" partner - - -  # never expires",
+		hashToken("key-3")+" old-job - - "+past,
+		hashToken("key-4")+" blocked - - - disabled",
+	)
+	p := newApiKeyProvider(t, path)
+
+	info, err := p.Authenticate("key-1")
+	if err != nil {
+		t.Fatalf("key-1: %v", err)
+	}
+	if info.Subject != "billing-job" || info.HashedToken != hashToken("key-1") {
+		t.Errorf("unexpected identity %+v", info)
+	}
+	if strings.Join(info.Roles, ",") != "service,reader" || strings.Join(info.Scopes, ",") != "invoices.rw" {
+		t.Errorf("Roles = %v, Scopes = %v", info.Roles, info.Scopes)
+	}
+	if info.Attributes["auth"] != "apikey" || info.Attributes["expires"] != future {
+		t.Errorf("Attributes = %v", info.Attributes)
+	}
+
+	info, err = p.Authenticate("key-2")
+	if err != nil || info.Subject != "partner" || len(info.Roles) != 0 {
+		t.Fatalf("key-2: %+v, %v", info, err)
+	}
+
+	for _, key := range []string{"key-3", "key-4", "unknown"} {
+		if _, err := p.Authenticate(key); !errors.Is(err, ErrUnauthorized) {
+			t.Errorf("%s: expected ErrUnauthorized, got %v", key, err)
+		}
+	}
+}
+
+func TestApiKeyProvider_Reload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "apikeys")
+	writeApiKeys(t, path, hashToken("key-1")+" job-1 - - -")
+	p := newApiKeyProvider(t, path)
+
+	// disable key-1 and add key-2
+	writeApiKeys(t, path,
+		hashToken("key-1")+" job-1 - - - disabled",
+		hashToken("key-2")+" job-2 - - -",
+	)
+	os.Chtimes(path, time.Now().Add(time.Second), time.Now().Add(time.Second))
+	if err := p.reload(); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if _, err := p.Authenticate("key-1"); !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("disabled key-1: expected ErrUnauthorized, got %v", err)
+	}
+	if info, err := p.Authenticate("key-2"); err != nil || info.Subject != "job-2" {
+		t.Fatalf("key-2: %+v, %v", info, err)
+	}
+
+	// a broken file keeps the keys loaded before
+	writeApiKeys(t, path, "not-a-hash job-3 - - -")
+	os.Chtimes(path, time.Now().Add(2*time.Second), time.Now().Add(2*time.Second))
+	if err := p.reload(); err == nil {
+		t.Fatal("expected reload error for a broken file")
+	}
+	if _, err := p.Authenticate("key-2"); err != nil {
+		t.Fatalf("key-2 after broken reload: %v", err)
+	}
+}
+
+func TestApiKeyProvider_InvalidFile(t *testing.T) {
+	dir := t.TempDir()
+	for _, line := range []string{
+		"abc job - - -",
+		hashToken("k") + " job - -",
+		hashToken("k") + " job - - tomorrow",
+		hashToken("k") + " job - - - revoked",
+	} {
+		path := filepath.Join(dir, "apikeys")
+		writeApiKeys(t, path, line)
+		p := &implApiKeyProvider{File: path}
+		if err := p.PostConstruct(); err == nil {
+			p.Destroy()
+			t.Errorf("line %q: expected error", line)
+		}
+	}
+
+	path := filepath.Join(dir, "dup")
+	writeApiKeys(t, path, hashToken("k")+" a - - -", hashToken("k")+" b - - -")
+	if err := (&implApiKeyProvider{File: path}).PostConstruct(); err == nil {
+		t.Error("duplicate hash: expected error")
+	}
+	if err := (&implApiKeyProvider{File: filepath.Join(dir, "missing")}).PostConstruct(); err == nil {
+		t.Error("missing file: expected error")
+	}
+}
+
+func TestApiKeyProvider_RelativeToHome(t *testing.T) {
+	home := t.TempDir()
+	writeApiKeys(t, filepath.Join(home, "apikeys"), hashToken("key-1")+" job-1 - - -")
+	p := &implApiKeyProvider{File: "apikeys", Runtime: NewRuntime(home)}
+	if err := p.PostConstruct(); err != nil {
+		t.Fatalf("PostConstruct: %v", err)
+	}
+	defer p.Destroy()
+	if _, err := p.Authenticate("key-1"); err != nil {
+		t.Fatalf("key-1: %v", err)
+	}
+}
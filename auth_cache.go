@@ -0,0 +1,98 @@
+/*
+ * Copyright (c) 2026 Karagatan LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package servion
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+type implCachingAuthenticator struct {
+	next        Authenticator
+	ttl         time.Duration
+	negativeTTL time.Duration
+	size        int
+
+	mu      sync.Mutex
+	entries map[string]authCacheEntry
+}
+
+type authCacheEntry struct {
+	info    AuthInfo
+	err     error
+	expires time.Time
+}
+
+/*
+CachingAuthenticator returns an Authenticator remembering the results of next,
+for providers that are slow or remote such as token introspection. Accepted
+tokens are cached for ttl, but never past the RFC 3339 "expires" attribute of
+their AuthInfo; tokens refused with ErrUnauthorized are cached for negativeTTL,
+so a flood of bad tokens does not reach the provider, 0 disables it. Other
+errors, ErrServiceUnavailable included, are not cached. Entries are keyed by
+the token hash and at most size of them are kept.
+*/
+func CachingAuthenticator(next Authenticator, ttl, negativeTTL time.Duration, size int) Authenticator {
+	if size <= 0 {
+		size = 10000
+	}
+	return &implCachingAuthenticator{
+		next:        next,
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+		size:        size,
+		entries:     make(map[string]authCacheEntry),
+	}
+}
+
+func (t *implCachingAuthenticator) Authenticate(token string) (AuthInfo, error) {
+	key := hashToken(token)
+	now := time.Now()
+
+	t.mu.Lock()
+	e, ok := t.entries[key]
+	t.mu.Unlock()
+	if ok && now.Before(e.expires) {
+		return e.info, e.err
+	}
+
+	info, err := t.next.Authenticate(token)
+	switch {
+	case err == nil:
+		expires := now.Add(t.ttl)
+		if exp, perr := time.Parse(time.RFC3339, info.Attributes["expires"]); perr == nil && exp.Before(expires) {
+			expires = exp
+		}
+		t.put(key, authCacheEntry{info: info, expires: expires}, now)
+	case errors.Is(err, ErrUnauthorized) && t.negativeTTL > 0:
+		t.put(key, authCacheEntry{err: err, expires: now.Add(t.negativeTTL)}, now)
+	}
+	return info, err
+}
+
+func (t *implCachingAuthenticator) put(key string, e authCacheEntry, now time.Time) {
+	if !now.Before(e.expires) {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.entries[key]; !ok && len(t.entries) >= t.size {
+		for k, old := range t.entries {
+			if !now.Before(old.expires) {
+				delete(t.entries, k)
+			}
+		}
+		// still full of live entries, make room by dropping any one
+		for k := range t.entries {
+			if len(t.entries) < t.size {
+				break
+			}
+			delete(t.entries, k)
+		}
+	}
+	t.entries[key] = e
+}
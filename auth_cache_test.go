@@ -0,0 +1,85 @@
+package servion
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCachingAuthenticator(t *testing.T) {
+	var calls int32
+	next := &mockAuthenticator{authFunc: func(token string) (AuthInfo, error) {
+		atomic.AddInt32(&calls, 1)
+		switch token {
+		case "good":
+			return AuthInfo{Subject: "alice"}, nil
+		case "down":
+			return AuthInfo{}, ErrServiceUnavailable
+		}
+		return AuthInfo{}, ErrUnauthorized
+	}}
+	a := CachingAuthenticator(next, time.Minute, time.Minute, 10)
+
+	for i := 0; i < 3; i++ {
+		info, err := a.Authenticate("good")
+		if err != nil || info.Subject != "alice" {
+			t.Fatalf("good: %+v, %v", info, err)
+		}
+		if _, err := a.Authenticate("bad"); !errors.Is(err, ErrUnauthorized) {
+			t.Fatalf("bad: %v", err)
+		}
+	}
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Errorf("provider called %d times, want 2", n)
+	}
+
+	// unavailability is not cached
+	atomic.StoreInt32(&calls, 0)
+	a.Authenticate("down")
+	a.Authenticate("down")
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Errorf("unavailable provider called %d times, want 2", n)
+	}
+}
+
+func TestCachingAuthenticator_Expiry(t *testing.T) {
+	var calls int32
+	expires := time.Now().Add(-time.Second).UTC().Format(time.RFC3339)
+	next := &mockAuthenticator{authFunc: func(token string) (AuthInfo, error) {
+		atomic.AddInt32(&calls, 1)
+		if token == "expiring" {
+			return AuthInfo{Subject: "bob", Attributes: map[string]string{"expires": expires}}, nil
+		}
+		return AuthInfo{}, ErrUnauthorized
+	}}
+
+	// an info expiring before the TTL is not kept past its expiry
+	a := CachingAuthenticator(next, time.Hour, 0, 10)
+	a.Authenticate("expiring")
+	a.Authenticate("expiring")
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Errorf("expired info served from cache, %d calls", n)
+	}
+
+	// negativeTTL 0 disables negative caching
+	atomic.StoreInt32(&calls, 0)
+	a.Authenticate("bad")
+	a.Authenticate("bad")
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Errorf("refused token cached, %d calls", n)
+	}
+}
+
+func TestCachingAuthenticator_Size(t *testing.T) {
+	next := &mockAuthenticator{authFunc: func(token string) (AuthInfo, error) {
+		return AuthInfo{Subject: token}, nil
+	}}
+	a := CachingAuthenticator(next, time.Minute, time.Minute, 2).(*implCachingAuthenticator)
+	for _, token := range []string{"a", "b", "c", "d"} {
+		a.Authenticate(token)
+	}
+	if n := len(a.entries); n != 2 {
+		t.Errorf("cache holds %d entries, want 2", n)
+	}
+}
@@ -0,0 +1,71 @@
+/*
+ * Copyright (c) 2026 Karagatan LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package servion
+
+import (
+	"errors"
+	"sort"
+
+	"go.arpabet.com/glue"
+)
+
+type implAuthenticatorChain struct {
+	list []Authenticator
+}
+
+/*
+ChainAuthenticators returns an Authenticator trying list in order, e.g. an API
+key provider before a JWT provider; the first to accept the token wins. A
+provider answering ErrUnauthorized passes the token on, any other error ends
+the chain. When every provider refuses and one of them was unavailable, the
+result is its ErrServiceUnavailable rather than ErrUnauthorized, so a token is
+not reported invalid while the provider that could accept it is down.
+*/
+func ChainAuthenticators(list ...Authenticator) Authenticator {
+	if len(list) == 1 {
+		return list[0]
+	}
+	return &implAuthenticatorChain{list: list}
+}
+
+func (t *implAuthenticatorChain) Authenticate(token string) (AuthInfo, error) {
+	var unavailable error
+	for _, a := range t.list {
+		info, err := a.Authenticate(token)
+		switch {
+		case err == nil:
+			return info, nil
+		case errors.Is(err, ErrServiceUnavailable):
+			unavailable = err
+		case !errors.Is(err, ErrUnauthorized):
+			return AuthInfo{}, err
+		}
+	}
+	if unavailable != nil {
+		return AuthInfo{}, unavailable
+	}
+	return AuthInfo{}, ErrUnauthorized
+}
+
+/*
+OrderAuthenticators returns a copy of list sorted by BeanOrder, keeping the
+context order for beans that are not glue.OrderedBean. Transports chaining the
+injected Authenticator beans use it so that every server tries them alike.
+*/
+func OrderAuthenticators(list []Authenticator) []Authenticator {
+	sorted := make([]Authenticator, len(list))
+	copy(sorted, list)
+	order := func(a Authenticator) int {
+		if o, ok := a.(glue.OrderedBean); ok {
+			return o.BeanOrder()
+		}
+		return 0
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return order(sorted[i]) < order(sorted[j])
+	})
+	return sorted
+}
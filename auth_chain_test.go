@@ -0,0 +1,66 @@
+package servion
+
+import (
+	"errors"
+	"testing"
+
+	"golang.org/x/xerrors"
+)
+
+type orderedAuthenticator struct {
+	mockAuthenticator
+	order int
+}
+
+func (o *orderedAuthenticator) BeanOrder() int { return o.order }
+
+func TestChainAuthenticators(t *testing.T) {
+	var calls []string
+	provider := func(name string, err error) Authenticator {
+		return &mockAuthenticator{authFunc: func(token string) (AuthInfo, error) {
+			calls = append(calls, name)
+			if token == name {
+				return AuthInfo{Subject: name}, nil
+			}
+			return AuthInfo{}, err
+		}}
+	}
+
+	chain := ChainAuthenticators(provider("a", ErrUnauthorized), provider("b", ErrUnauthorized))
+	if info, err := chain.Authenticate("b"); err != nil || info.Subject != "b" {
+		t.Fatalf("b: %+v, %v", info, err)
+	}
+	if len(calls) != 2 || calls[0] != "a" {
+		t.Fatalf("calls = %v, want a then b", calls)
+	}
+	if _, err := chain.Authenticate("c"); !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("c: expected ErrUnauthorized, got %v", err)
+	}
+
+	// an unavailable provider is not hidden behind a refusal of the others
+	down := xerrors.Errorf("idp down: %w", ErrServiceUnavailable)
+	chain = ChainAuthenticators(provider("a", down), provider("b", ErrUnauthorized))
+	if _, err := chain.Authenticate("c"); !errors.Is(err, ErrServiceUnavailable) {
+		t.Fatalf("expected ErrServiceUnavailable, got %v", err)
+	}
+	if info, err := chain.Authenticate("b"); err != nil || info.Subject != "b" {
+		t.Fatalf("b with a down: %+v, %v", info, err)
+	}
+
+	// other errors end the chain
+	calls = nil
+	chain = ChainAuthenticators(provider("a", xerrors.New("boom")), provider("b", ErrUnauthorized))
+	if _, err := chain.Authenticate("b"); err == nil || errors.Is(err, ErrUnauthorized) || len(calls) != 1 {
+		t.Fatalf("expected internal error after one call, got %v, calls %v", err, calls)
+	}
+}
+
+func TestOrderAuthenticators(t *testing.T) {
+	first := &orderedAuthenticator{order: -1}
+	plain := &mockAuthenticator{}
+	last := &orderedAuthenticator{order: 5}
+	got := OrderAuthenticators([]Authenticator{last, plain, first})
+	if got[0] != first || got[1] != plain || got[2] != last {
+		t.Fatalf("unexpected order %v", got)
+	}
+}
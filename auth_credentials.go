@@ -0,0 +1,121 @@
+/*
+ * Copyright (c) 2026 Karagatan LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package servion
+
+import (
+	"encoding/base64"
+	"net/http"
+	"strings"
+
+	"golang.org/x/xerrors"
+)
+
+const (
+	CredentialBearer = "bearer"
+	CredentialBasic  = "basic"
+	CredentialHeader = "header"
+	CredentialCookie = "cookie"
+	CredentialQuery  = "query"
+)
+
+/*
+CredentialSource is a place a request carries its credential:
+
+	bearer          Authorization: Bearer <credential>
+	basic           Authorization: Basic, the password or else the user name
+	header:<Name>   a header such as X-API-Key
+	cookie:<name>   a cookie, e.g. for WebSocket upgrades from browsers
+	query:<name>    a query parameter; it ends up in access logs, prefer the others
+*/
+type CredentialSource struct {
+	Kind string
+	Name string
+}
+
+// DefaultCredentialSources reads the bearer token only.
+var DefaultCredentialSources = []CredentialSource{{Kind: CredentialBearer}}
+
+// ParseCredentialSources parses entries such as "bearer" or "header:X-API-Key".
+func ParseCredentialSources(list []string) ([]CredentialSource, error) {
+	var sources []CredentialSource
+	for _, entry := range list {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		kind, name, _ := strings.Cut(entry, ":")
+		src := CredentialSource{Kind: strings.ToLower(strings.TrimSpace(kind)), Name: strings.TrimSpace(name)}
+		switch src.Kind {
+		case CredentialBearer, CredentialBasic:
+			if src.Name != "" {
+				return nil, xerrors.Errorf("credential source '%s' takes no name", entry)
+			}
+		case CredentialHeader, CredentialCookie, CredentialQuery:
+			if src.Name == "" {
+				return nil, xerrors.Errorf("credential source '%s' needs a name, e.g. %s:X-API-Key", entry, src.Kind)
+			}
+		default:
+			return nil, xerrors.Errorf("unknown credential source '%s', expected bearer, basic, header:<name>, cookie:<name> or query:<name>", entry)
+		}
+		sources = append(sources, src)
+	}
+	if len(sources) == 0 {
+		return DefaultCredentialSources, nil
+	}
+	return sources, nil
+}
+
+// FromRequest returns the credential r carries in this source.
+func (s CredentialSource) FromRequest(r *http.Request) (string, bool) {
+	switch s.Kind {
+	case CredentialBearer, CredentialBasic:
+		return s.FromAuthorization(r.Header.Get("Authorization"))
+	case CredentialHeader:
+		v := strings.TrimSpace(r.Header.Get(s.Name))
+		return v, v != ""
+	case CredentialCookie:
+		c, err := r.Cookie(s.Name)
+		if err != nil || c.Value == "" {
+			return "", false
+		}
+		return c.Value, true
+	case CredentialQuery:
+		v := r.URL.Query().Get(s.Name)
+		return v, v != ""
+	}
+	return "", false
+}
+
+// FromAuthorization returns the credential of an Authorization header value for a bearer or basic source.
+func (s CredentialSource) FromAuthorization(value string) (string, bool) {
+	parts := strings.Fields(value)
+	if len(parts) != 2 {
+		return "", false
+	}
+	switch {
+	case s.Kind == CredentialBearer && strings.EqualFold(parts[0], "Bearer"):
+		return parts[1], true
+	case s.Kind == CredentialBasic && strings.EqualFold(parts[0], "Basic"):
+		decoded, err := base64.StdEncoding.DecodeString(parts[1])
+		if err != nil {
+			return "", false
+		}
+		user, password, _ := strings.Cut(string(decoded), ":")
+		if password != "" {
+			return password, true
+		}
+		return user, user != ""
+	}
+	return "", false
+}
+
+// String formats s as parsed by ParseCredentialSources.
+func (s CredentialSource) String() string {
+	if s.Name == "" {
+		return s.Kind
+	}
+	return s.Kind + ":" + s.Name
+}
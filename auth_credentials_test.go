@@ -0,0 +1,52 @@
+package servion
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseCredentialSources(t *testing.T) {
+	sources, err := ParseCredentialSources([]string{" bearer ", "Header:X-API-Key", "", "cookie:token"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sources) != 3 || sources[0].String() != "bearer" || sources[1].String() != "header:X-API-Key" || sources[2].String() != "cookie:token" {
+		t.Fatalf("unexpected sources %v", sources)
+	}
+
+	sources, err = ParseCredentialSources(nil)
+	if err != nil || len(sources) != 1 || sources[0].Kind != CredentialBearer {
+		t.Fatalf("default: %v, %v", sources, err)
+	}
+
+	for _, entry := range []string{"basic:user", "query", "token"} {
+		if _, err := ParseCredentialSources([]string{entry}); err == nil {
+			t.Errorf("%q: expected error", entry)
+		}
+	}
+}
+
+func TestCredentialSource_FromRequest(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/ws?token=q", nil)
+	r.Header.Set("X-API-Key", " k ")
+	r.AddCookie(&http.Cookie{Name: "token", Value: "c"})
+	r.SetBasicAuth("user", "p")
+
+	for src, want := range map[CredentialSource]string{
+		{Kind: CredentialHeader, Name: "X-API-Key"}: "k",
+		{Kind: CredentialCookie, Name: "token"}:     "c",
+		{Kind: CredentialQuery, Name: "token"}:      "q",
+		{Kind: CredentialBasic}:                     "p",
+	} {
+		if got, ok := src.FromRequest(r); !ok || got != want {
+			t.Errorf("%s: %q, %v, want %q", src, got, ok, want)
+		}
+	}
+	if _, ok := (CredentialSource{Kind: CredentialBearer}).FromRequest(r); ok {
+		t.Error("bearer must not match a Basic header")
+	}
+	if _, ok := (CredentialSource{Kind: CredentialCookie, Name: "missing"}).FromRequest(r); ok {
+		t.Error("missing cookie must not match")
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"errors"
 	"net/http"
 	"strings"
+
+	"golang.org/x/xerrors"
 )
 
 type implAuthMiddleware struct {
@@ -11,14 +13,71 @@ type implAuthMiddleware struct {
 
 	Prefixes []string `value:"auth.prefixes,default=/api"`
 
+	// Where requests carry credentials, tried in order: bearer, basic, header:<Name>, cookie:<name>, query:<name>
+	Credentials []string `value:"auth.credentials,default=bearer"`
+
+	// Let requests without credentials through anonymously
+	Optional bool `value:"auth.optional,default=false"`
+
 	Authenticator Authenticator `inject:"-"`
+
+	// Token authenticators of the context, chained when Authenticator is not set
+	Authenticators []Authenticator `inject:"optional"`
+
+	// Client certificates of mutual TLS, used for requests without an Authorization header
+	Certificates CertificateAuthenticator `inject:"optional"`
+
+	// Browser sessions, used for requests without an Authorization header
+	Sessions []SessionAuthenticator `inject:"optional"`
+
+	sources []CredentialSource
 }
 
+/*
+AuthMiddleware creates an HttpMiddleware authenticating requests under
+auth.prefixes. The credential is read from the first of the auth.credentials
+sources the request carries and validated by the Authenticator beans of the
+context, tried in BeanOrder (see ChainAuthenticators), so an API key provider
+and a JWT provider can serve the same routes. Requests without a credential
+fall back to a verified client certificate and then to browser sessions.
+
+With auth.optional requests without any credential pass anonymously, handlers
+telling them apart with AuthFromContext; a credential that is present but
+invalid is still refused.
+
+Configuration properties:
+
+	auth.prefixes     – URL prefixes to protect (default "/api")
+	auth.credentials  – credential sources tried in order (default "bearer")
+	auth.optional     – let anonymous requests through (default false)
+*/
 func AuthMiddleware(beanOrder int) HttpMiddleware {
 	return &implAuthMiddleware{beanOrder: beanOrder}
 }
 
+func (t *implAuthMiddleware) PostConstruct() (err error) {
+	t.sources, err = ParseCredentialSources(t.Credentials)
+	if err != nil {
+		return xerrors.Errorf("auth.credentials: %w", err)
+	}
+	return nil
+}
+
 func (t *implAuthMiddleware) Middleware(next http.Handler) http.Handler {
+
+	authenticator := t.Authenticator
+	if authenticator == nil {
+		authenticator = ChainAuthenticators(OrderAuthenticators(t.Authenticators)...)
+	}
+	sources := t.sources
+	if sources == nil {
+		sources = DefaultCredentialSources
+	}
+	challenge := "Bearer"
+	if sources[0].Kind == CredentialBasic {
+		challenge = `Basic realm="api"`
+	}
+
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
 		if r.Method == http.MethodOptions {
@@ -26,23 +85,60 @@ func (t *implAuthMiddleware) Middleware(next http.Handler) http.Handler {
 			return
 		}
 
+		var (
+			token string
+			found bool
+		)
+		for _, src := range sources {
+			if token, found = src.FromRequest(r); found {
+				break
+			}
+		}
+
 		h := r.Header.Get("Authorization")
-		if h == "" {
-			w.Header().Set("WWW-Authenticate", "Bearer")
-			http.Error(w, "Authorization header is missing", http.StatusUnauthorized)
+		if !found && h == "" && t.Certificates != nil && r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+			auth, err := t.Certificates.AuthenticateCertificate(r.TLS)
+			if errors.Is(err, ErrUnauthorized) {
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(ContextWithAuth(r.Context(), auth)))
 			return
 		}
-
-		parts := strings.Fields(h)
-		if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") {
-			w.Header().Set("WWW-Authenticate", "Bearer")
-			http.Error(w, "invalid Authorization header", http.StatusUnauthorized)
+		if !found && h == "" {
+			for _, sessions := range t.Sessions {
+				auth, ok, err := sessions.AuthenticateSession(r)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+				if ok {
+					next.ServeHTTP(w, r.WithContext(ContextWithAuth(r.Context(), auth)))
+					return
+				}
+			}
+		}
+		if !found {
+			if h == "" && t.Optional {
+				next.ServeHTTP(w, r)
+				return
+			}
+			w.Header().Set("WWW-Authenticate", challenge)
+			if h == "" {
+				http.Error(w, "Authorization header is missing", http.StatusUnauthorized)
+			} else {
+				http.Error(w, "invalid Authorization header", http.StatusUnauthorized)
+			}
 			return
 		}
 
-		auth, err := t.Authenticator.Authenticate(parts[1])
+		auth, err := authenticator.Authenticate(token)
 		if errors.Is(err, ErrUnauthorized) {
-			w.Header().Set("WWW-Authenticate", "Bearer")
+			w.Header().Set("WWW-Authenticate", challenge)
 			http.Error(w, err.Error(), http.StatusUnauthorized)
 			return
 		}
@@ -291,3 +291,146 @@ func TestAuthMiddleware_BeanOrder(t *testing.T) {
 		t.Errorf("BeanOrder() = %d, want 5", got)
 	}
 }
+
+func TestAuthMiddleware_CredentialSources(t *testing.T) {
+	mw := &implAuthMiddleware{
+		Prefixes:    []string{"/api"},
+		Credentials: []string{"header:X-API-Key", "bearer", "basic", "cookie:access_token", "query:access_token"},
+		Authenticator: &mockAuthenticator{
+			authFunc: func(token string) (AuthInfo, error) {
+				if token == "secret" {
+					return AuthInfo{Subject: "svc"}, nil
+				}
+				return AuthInfo{}, ErrUnauthorized
+			},
+		},
+	}
+	if err := mw.PostConstruct(); err != nil {
+		t.Fatalf("PostConstruct: %v", err)
+	}
+	handler := mw.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth, _ := AuthFromContext(r.Context())
+		w.Write([]byte(auth.Subject))
+	}))
+
+	tests := []struct {
+		name  string
+		setup func(r *http.Request)
+		code  int
+	}{
+		{"api key header", func(r *http.Request) { r.Header.Set("X-API-Key", "secret") }, http.StatusOK},
+		{"bearer", func(r *http.Request) { r.Header.Set("Authorization", "Bearer secret") }, http.StatusOK},
+		{"basic password", func(r *http.Request) { r.SetBasicAuth("client", "secret") }, http.StatusOK},
+		{"basic user", func(r *http.Request) { r.SetBasicAuth("secret", "") }, http.StatusOK},
+		{"cookie", func(r *http.Request) { r.AddCookie(&http.Cookie{Name: "access_token", Value: "secret"}) }, http.StatusOK},
+		{"query", func(r *http.Request) { r.URL.RawQuery = "access_token=secret" }, http.StatusOK},
+		{"header wins", func(r *http.Request) {
+			r.Header.Set("X-API-Key", "wrong")
+			r.Header.Set("Authorization", "Bearer secret")
+		}, http.StatusUnauthorized},
+		{"none", func(r *http.Request) {}, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/data", nil)
+			tt.setup(r)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			if w.Code != tt.code {
+				t.Fatalf("status = %d, want %d", w.Code, tt.code)
+			}
+			if tt.code == http.StatusOK && w.Body.String() != "svc" {
+				t.Fatalf("subject = %q, want svc", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestAuthMiddleware_ChainsAuthenticators(t *testing.T) {
+	apiKeys := &mockAuthenticator{authFunc: func(token string) (AuthInfo, error) {
+		if token == "key" {
+			return AuthInfo{Subject: "key-holder"}, nil
+		}
+		return AuthInfo{}, ErrUnauthorized
+	}}
+	jwts := &mockAuthenticator{authFunc: func(token string) (AuthInfo, error) {
+		if token == "jwt" {
+			return AuthInfo{Subject: "jwt-holder"}, nil
+		}
+		return AuthInfo{}, ErrUnauthorized
+	}}
+	mw := &implAuthMiddleware{Prefixes: []string{"/api"}, Authenticators: []Authenticator{apiKeys, jwts}}
+	handler := mw.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth, _ := AuthFromContext(r.Context())
+		w.Write([]byte(auth.Subject))
+	}))
+
+	for token, want := range map[string]string{"key": "key-holder", "jwt": "jwt-holder"} {
+		r := httptest.NewRequest(http.MethodGet, "/api/data", nil)
+		r.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != http.StatusOK || w.Body.String() != want {
+			t.Fatalf("%s: %d %q, want %q", token, w.Code, w.Body.String(), want)
+		}
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/api/data", nil)
+	r.Header.Set("Authorization", "Bearer other")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("unknown token: status = %d, want 401", w.Code)
+	}
+}
+
+func TestAuthMiddleware_Optional(t *testing.T) {
+	mw := &implAuthMiddleware{
+		Prefixes: []string{"/api"},
+		Optional: true,
+		Authenticator: &mockAuthenticator{authFunc: func(token string) (AuthInfo, error) {
+			if token == "good" {
+				return AuthInfo{Subject: "user1"}, nil
+			}
+			return AuthInfo{}, ErrUnauthorized
+		}},
+	}
+	handler := mw.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if auth, ok := AuthFromContext(r.Context()); ok {
+			w.Write([]byte(auth.Subject))
+		} else {
+			w.Write([]byte("anonymous"))
+		}
+	}))
+
+	tests := []struct {
+		header string
+		code   int
+		body   string
+	}{
+		{"", http.StatusOK, "anonymous"},
+		{"Bearer good", http.StatusOK, "user1"},
+		{"Bearer bad", http.StatusUnauthorized, ""},
+		{"Basic dXNlcjpwYXNz", http.StatusUnauthorized, ""},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/api/data", nil)
+		if tt.header != "" {
+			r.Header.Set("Authorization", tt.header)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != tt.code || (tt.body != "" && w.Body.String() != tt.body) {
+			t.Errorf("%q: %d %q, want %d %q", tt.header, w.Code, w.Body.String(), tt.code, tt.body)
+		}
+	}
+}
+
+func TestAuthMiddleware_InvalidCredentials(t *testing.T) {
+	for _, list := range [][]string{{"header"}, {"bearer:x"}, {"form:token"}} {
+		mw := &implAuthMiddleware{Credentials: list}
+		if err := mw.PostConstruct(); err == nil {
+			t.Errorf("%v: expected error", list)
+		}
+	}
+}
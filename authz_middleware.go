@@ -0,0 +1,84 @@
+/*
+ * Copyright (c) 2026 Karagatan LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package servion
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+type implAuthzMiddleware struct {
+	beanOrder int
+
+	Prefixes []string `value:"authz.prefixes,default=/api"`
+
+	Authorizer Authorizer `inject:""`
+}
+
+/*
+AuthzMiddleware creates an HttpMiddleware enforcing the Authorizer of the context
+(see PolicyAuthorizer) on every request. Place it after AuthMiddleware, with a
+higher bean order, so the identity is already in the request context. Denied
+requests get 403, anonymous requests to rules needing an identity 401.
+
+Configuration properties:
+
+	authz.prefixes  – URL prefixes to authorize (default "/api")
+*/
+func AuthzMiddleware(beanOrder int) HttpMiddleware {
+	return &implAuthzMiddleware{beanOrder: beanOrder}
+}
+
+func (t *implAuthzMiddleware) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		// CORS preflight carries no credentials
+		if r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		var info *AuthInfo
+		if auth, ok := AuthFromContext(r.Context()); ok {
+			info = &auth
+		}
+
+		err := t.Authorizer.Authorize(r.Context(), AccessRequest{
+			Transport: TransportHTTP,
+			Method:    r.Method,
+			Resource:  r.URL.Path,
+		}, info)
+		if errors.Is(err, ErrUnauthorized) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "authentication required", http.StatusUnauthorized)
+			return
+		}
+		if errors.Is(err, ErrForbidden) {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (t *implAuthzMiddleware) BeanOrder() int {
+	return t.beanOrder
+}
+
+func (t *implAuthzMiddleware) Match(prefix string) bool {
+	for _, p := range t.Prefixes {
+		if strings.HasPrefix(prefix, p) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,57 @@
+package servion
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestAuthzMiddleware(t *testing.T) {
+	authorizer := &implPolicyAuthorizer{
+		Log:     zap.NewNop(),
+		Rules:   "/api/admin/** = role:admin; /api/** = authenticated",
+		Default: "deny",
+	}
+	if err := authorizer.PostConstruct(); err != nil {
+		t.Fatal(err)
+	}
+	m := &implAuthzMiddleware{Prefixes: []string{"/api"}, Authorizer: authorizer}
+	handler := m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	serve := func(method, path string, info *AuthInfo) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, nil)
+		if info != nil {
+			r = r.WithContext(ContextWithAuth(r.Context(), *info))
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	admin := &AuthInfo{Subject: "root", Roles: []string{"admin"}}
+	user := &AuthInfo{Subject: "bob"}
+
+	if w := serve("GET", "/api/admin/users", admin); w.Code != http.StatusNoContent {
+		t.Errorf("admin: status = %d, want 204", w.Code)
+	}
+	if w := serve("GET", "/api/admin/users", user); w.Code != http.StatusForbidden {
+		t.Errorf("user: status = %d, want 403", w.Code)
+	}
+	w := serve("GET", "/api/orders", nil)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("anonymous: status = %d, want 401", w.Code)
+	}
+	if got := w.Header().Get("WWW-Authenticate"); got != "Bearer" {
+		t.Errorf("WWW-Authenticate = %q, want Bearer", got)
+	}
+	if w := serve("OPTIONS", "/api/admin/users", nil); w.Code != http.StatusNoContent {
+		t.Errorf("preflight: status = %d, want 204", w.Code)
+	}
+	if !m.Match("/api/x") || m.Match("/static") {
+		t.Error("unexpected Match result")
+	}
+}
@@ -0,0 +1,387 @@
+/*
+ * Copyright (c) 2026 Karagatan LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package servion
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"go.uber.org/zap"
+	"golang.org/x/xerrors"
+)
+
+type implPolicyAuthorizer struct {
+	Log     *zap.Logger `inject:""`
+	Runtime Runtime     `inject:""`
+
+	// Semicolon separated rules, evaluated before the policy file
+	Rules string `value:"authz.rules,default="`
+
+	// File with one rule per line, relative paths resolve against the home directory
+	PolicyFile string `value:"authz.policy-file,default="`
+
+	// Decision when no rule matches: deny or permit
+	Default string `value:"authz.default,default=deny"`
+
+	LogAllowed bool `value:"authz.log-allowed,default=true"`
+
+	rules         []*authzRule
+	defaultPermit bool
+}
+
+/*
+PolicyAuthorizer creates an Authorizer evaluating declarative rules. Each rule is
+"<target> = <requirement>", the first rule matching a request decides it:
+
+	GET,HEAD /api/reports/**   = any-role:analyst,admin
+	/api/admin/**              = role:admin & scope:admin.write
+	/api/users/{id}            = authenticated
+	grpc:/billing.Billing/*    = all-scopes:billing.read,billing.write
+	vrpc:greet                 = permit
+
+HTTP targets are an optional comma separated method list and a path pattern where
+"*" or "{name}" match one segment and a trailing "**" any number of them. gRPC
+targets match full method names and vRPC targets function names, "*" matching
+within one path element.
+
+Requirements are permit, deny, authenticated, any-role (alias role), all-roles,
+any-scope (alias scope) and all-scopes, joined with "&" when all must hold.
+
+Every decision is logged as AuthzAllow or AuthzDeny with the subject and the rule.
+
+Configuration properties:
+
+	authz.rules        – semicolon separated rules, evaluated first
+	authz.policy-file  – file with one rule per line, "#" starts a comment
+	authz.default      – deny (default) or permit when no rule matches
+	authz.log-allowed  – log allowed requests too (default true)
+*/
+func PolicyAuthorizer() Authorizer {
+	return &implPolicyAuthorizer{}
+}
+
+func (t *implPolicyAuthorizer) PostConstruct() error {
+	switch strings.ToLower(strings.TrimSpace(t.Default)) {
+	case "deny", "":
+	case "permit":
+		t.defaultPermit = true
+	default:
+		return xerrors.Errorf("authz.default must be deny or permit, got '%s'", t.Default)
+	}
+
+	for _, line := range ParsePrefixList(t.Rules) {
+		rule, err := parseAuthzRule(line)
+		if err != nil {
+			return xerrors.Errorf("authz.rules: %w", err)
+		}
+		t.rules = append(t.rules, rule)
+	}
+
+	if t.PolicyFile != "" {
+		fileRules, err := t.loadPolicyFile()
+		if err != nil {
+			return err
+		}
+		t.rules = append(t.rules, fileRules...)
+	}
+	return nil
+}
+
+func (t *implPolicyAuthorizer) loadPolicyFile() ([]*authzRule, error) {
+	name := t.PolicyFile
+	if !filepath.IsAbs(name) && t.Runtime != nil {
+		name = filepath.Join(t.Runtime.HomeDir(), name)
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, xerrors.Errorf("open authz policy file: %w", err)
+	}
+	defer f.Close()
+
+	var rules []*authzRule
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		if line = strings.TrimSpace(line); line == "" {
+			continue
+		}
+		rule, err := parseAuthzRule(line)
+		if err != nil {
+			return nil, xerrors.Errorf("%s:%d: %w", name, n, err)
+		}
+		rules = append(rules, rule)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, xerrors.Errorf("read authz policy file: %w", err)
+	}
+	return rules, nil
+}
+
+func (t *implPolicyAuthorizer) Authorize(ctx context.Context, req AccessRequest, info *AuthInfo) error {
+	for _, rule := range t.rules {
+		if rule.matches(req) {
+			if rule.permits(info) {
+				t.logAllow(req, info, rule.text)
+				return nil
+			}
+			t.logDeny(req, info, rule.text)
+			if info == nil && rule.needsIdentity() {
+				return xerrors.Errorf("%s requires authentication: %w", req.Resource, ErrUnauthorized)
+			}
+			return xerrors.Errorf("%s %s: %w", req.Method, req.Resource, ErrForbidden)
+		}
+	}
+	if t.defaultPermit {
+		t.logAllow(req, info, "default")
+		return nil
+	}
+	t.logDeny(req, info, "default")
+	return xerrors.Errorf("%s %s: %w", req.Method, req.Resource, ErrForbidden)
+}
+
+func (t *implPolicyAuthorizer) logAllow(req AccessRequest, info *AuthInfo, rule string) {
+	if t.LogAllowed {
+		t.Log.Info("AuthzAllow", authzFields(req, info, rule)...)
+	}
+}
+
+func (t *implPolicyAuthorizer) logDeny(req AccessRequest, info *AuthInfo, rule string) {
+	t.Log.Warn("AuthzDeny", authzFields(req, info, rule)...)
+}
+
+func authzFields(req AccessRequest, info *AuthInfo, rule string) []zap.Field {
+	subject := ""
+	if info != nil {
+		subject = info.Subject
+	}
+	return []zap.Field{
+		zap.String("transport", req.Transport),
+		zap.String("method", req.Method),
+		zap.String("resource", req.Resource),
+		zap.String("subject", subject),
+		zap.String("rule", rule),
+	}
+}
+
+type authzRule struct {
+	text      string
+	transport string
+	methods   map[string]bool // nil matches any method
+	pattern   string
+	segments  []string // HTTP path pattern split by "/"
+	require   []authzRequirement
+}
+
+type authzRequirement struct {
+	kind   string
+	values []string
+}
+
+func parseAuthzRule(line string) (*authzRule, error) {
+	target, requirement, ok := strings.Cut(line, "=")
+	target, requirement = strings.TrimSpace(target), strings.TrimSpace(requirement)
+	if !ok || target == "" || requirement == "" {
+		return nil, xerrors.Errorf("invalid authz rule '%s', expected target = requirement", line)
+	}
+	rule := &authzRule{text: strings.Join(strings.Fields(line), " ")}
+
+	switch {
+	case strings.HasPrefix(target, "grpc:"):
+		rule.transport = TransportGRPC
+		rule.pattern = strings.TrimSpace(target[len("grpc:"):])
+	case strings.HasPrefix(target, "vrpc:"):
+		rule.transport = TransportVRPC
+		rule.pattern = strings.TrimSpace(target[len("vrpc:"):])
+	default:
+		rule.transport = TransportHTTP
+		fields := strings.Fields(target)
+		switch len(fields) {
+		case 1:
+			rule.pattern = fields[0]
+		case 2:
+			rule.pattern = fields[1]
+			methods := make(map[string]bool)
+			for _, m := range strings.Split(fields[0], ",") {
+				if m = strings.ToUpper(strings.TrimSpace(m)); m != "" {
+					methods[m] = true
+				}
+			}
+			if !methods["*"] {
+				rule.methods = methods
+			}
+		default:
+			return nil, xerrors.Errorf("invalid authz target '%s', expected [METHODS] /path", target)
+		}
+		if !strings.HasPrefix(rule.pattern, "/") {
+			return nil, xerrors.Errorf("invalid authz path '%s', must start with /", rule.pattern)
+		}
+		rule.segments = splitPath(rule.pattern)
+	}
+	if rule.pattern == "" {
+		return nil, xerrors.Errorf("invalid authz rule '%s', empty target", line)
+	}
+	if rule.transport != TransportHTTP {
+		if _, err := path.Match(rule.pattern, ""); err != nil {
+			return nil, xerrors.Errorf("invalid authz pattern '%s': %w", rule.pattern, err)
+		}
+	}
+
+	for _, part := range strings.Split(requirement, "&") {
+		req, err := parseAuthzRequirement(strings.TrimSpace(part))
+		if err != nil {
+			return nil, err
+		}
+		rule.require = append(rule.require, req)
+	}
+	return rule, nil
+}
+
+func parseAuthzRequirement(s string) (authzRequirement, error) {
+	kind, list, hasValues := strings.Cut(s, ":")
+	kind = strings.ToLower(strings.TrimSpace(kind))
+	var values []string
+	for _, v := range strings.Split(list, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+
+	switch kind {
+	case "permit", "deny", "authenticated":
+		if hasValues {
+			return authzRequirement{}, xerrors.Errorf("authz requirement '%s' takes no values", kind)
+		}
+	case "role", "any-role", "all-roles", "scope", "any-scope", "all-scopes":
+		if len(values) == 0 {
+			return authzRequirement{}, xerrors.Errorf("authz requirement '%s' needs values", kind)
+		}
+		switch kind {
+		case "role":
+			kind = "any-role"
+		case "scope":
+			kind = "any-scope"
+		}
+	default:
+		return authzRequirement{}, xerrors.Errorf("unknown authz requirement '%s'", s)
+	}
+	return authzRequirement{kind: kind, values: values}, nil
+}
+
+func (r *authzRule) matches(req AccessRequest) bool {
+	if r.transport != req.Transport {
+		return false
+	}
+	if r.transport != TransportHTTP {
+		ok, _ := path.Match(r.pattern, req.Resource)
+		return ok
+	}
+	if r.methods != nil && !r.methods[strings.ToUpper(req.Method)] {
+		return false
+	}
+	return matchPathSegments(r.segments, splitPath(req.Resource))
+}
+
+func (r *authzRule) permits(info *AuthInfo) bool {
+	for _, req := range r.require {
+		if !req.satisfied(info) {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *authzRule) needsIdentity() bool {
+	for _, req := range r.require {
+		if req.kind == "deny" {
+			return false
+		}
+	}
+	for _, req := range r.require {
+		if req.kind != "permit" {
+			return true
+		}
+	}
+	return false
+}
+
+func (r authzRequirement) satisfied(info *AuthInfo) bool {
+	switch r.kind {
+	case "permit":
+		return true
+	case "deny":
+		return false
+	}
+	if info == nil {
+		return false
+	}
+	switch r.kind {
+	case "authenticated":
+		return true
+	case "any-role":
+		return containsAny(info.Roles, r.values)
+	case "all-roles":
+		return containsAll(info.Roles, r.values)
+	case "any-scope":
+		return containsAny(info.Scopes, r.values)
+	case "all-scopes":
+		return containsAll(info.Scopes, r.values)
+	}
+	return false
+}
+
+func containsAny(have, want []string) bool {
+	for _, w := range want {
+		for _, h := range have {
+			if h == w {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func containsAll(have, want []string) bool {
+	for _, w := range want {
+		if !containsAny(have, []string{w}) {
+			return false
+		}
+	}
+	return true
+}
+
+func splitPath(p string) []string {
+	p = strings.Trim(p, "/")
+	if p == "" {
+		return nil
+	}
+	return strings.Split(p, "/")
+}
+
+// matchPathSegments matches "*" and "{name}" to one segment and a trailing "**" to the rest.
+func matchPathSegments(pattern, segments []string) bool {
+	for i, p := range pattern {
+		if p == "**" && i == len(pattern)-1 {
+			return true
+		}
+		if i >= len(segments) {
+			return false
+		}
+		if p == "*" || (strings.HasPrefix(p, "{") && strings.HasSuffix(p, "}")) {
+			continue
+		}
+		if p != segments[i] {
+			return false
+		}
+	}
+	return len(pattern) == len(segments)
+}
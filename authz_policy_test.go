@@ -0,0 +1,173 @@
+package servion
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func newTestPolicyAuthorizer(t *testing.T, rules string, configure func(a *implPolicyAuthorizer)) *implPolicyAuthorizer {
+	t.Helper()
+	a := &implPolicyAuthorizer{
+		Log:        zap.NewNop(),
+		Runtime:    newMockRuntime(true),
+		Rules:      rules,
+		Default:    "deny",
+		LogAllowed: true,
+	}
+	if configure != nil {
+		configure(a)
+	}
+	if err := a.PostConstruct(); err != nil {
+		t.Fatalf("PostConstruct: %v", err)
+	}
+	return a
+}
+
+func TestPolicyAuthorizer_Decisions(t *testing.T) {
+	a := newTestPolicyAuthorizer(t, `
+		GET,HEAD /api/reports/** = any-role:analyst,admin;
+		/api/admin/** = role:admin & scope:admin.write;
+		/api/users/{id} = authenticated;
+		DELETE /api/items/* = all-roles:editor,owner;
+		/api/public/** = permit;
+		/api/legacy/** = deny;
+		grpc:/billing.Billing/* = all-scopes:billing.read,billing.write;
+		vrpc:greet = permit;
+		vrpc:admin.* = role:admin`, nil)
+
+	admin := &AuthInfo{Subject: "root", Roles: []string{"admin"}, Scopes: []string{"admin.write"}}
+	analyst := &AuthInfo{Subject: "ann", Roles: []string{"analyst"}}
+	editor := &AuthInfo{Subject: "ed", Roles: []string{"editor"}}
+	owner := &AuthInfo{Subject: "ow", Roles: []string{"editor", "owner"}}
+	biller := &AuthInfo{Subject: "svc", Scopes: []string{"billing.read", "billing.write"}}
+
+	http := func(method, path string) AccessRequest {
+		return AccessRequest{Transport: TransportHTTP, Method: method, Resource: path}
+	}
+	grpc := func(method string) AccessRequest { return AccessRequest{Transport: TransportGRPC, Resource: method} }
+	vrpc := func(name string) AccessRequest { return AccessRequest{Transport: TransportVRPC, Resource: name} }
+
+	tests := []struct {
+		name string
+		req  AccessRequest
+		info *AuthInfo
+		want error
+	}{
+		{"role any", http("GET", "/api/reports/2026/q1"), analyst, nil},
+		{"role any, method not covered falls to default", http("POST", "/api/reports/2026"), analyst, ErrForbidden},
+		{"role missing", http("GET", "/api/reports"), editor, ErrForbidden},
+		{"role and scope", http("POST", "/api/admin/users"), admin, nil},
+		{"role without scope", http("POST", "/api/admin/users"), &AuthInfo{Subject: "x", Roles: []string{"admin"}}, ErrForbidden},
+		{"anonymous needs identity", http("GET", "/api/users/42"), nil, ErrUnauthorized},
+		{"authenticated", http("GET", "/api/users/42"), editor, nil},
+		{"segment count differs", http("GET", "/api/users/42/orders"), editor, ErrForbidden},
+		{"all roles", http("DELETE", "/api/items/7"), owner, nil},
+		{"not all roles", http("DELETE", "/api/items/7"), editor, ErrForbidden},
+		{"permit anonymous", http("GET", "/api/public/doc"), nil, nil},
+		{"deny anonymous is forbidden", http("GET", "/api/legacy/x"), nil, ErrForbidden},
+		{"deny", http("GET", "/api/legacy/x"), admin, ErrForbidden},
+		{"no rule", http("GET", "/api/other"), admin, ErrForbidden},
+		{"grpc all scopes", grpc("/billing.Billing/Charge"), biller, nil},
+		{"grpc missing scope", grpc("/billing.Billing/Charge"), analyst, ErrForbidden},
+		{"grpc other service", grpc("/billing.Other/Charge"), biller, ErrForbidden},
+		{"vrpc permit", vrpc("greet"), nil, nil},
+		{"vrpc glob", vrpc("admin.reset"), admin, nil},
+		{"vrpc glob denied", vrpc("admin.reset"), editor, ErrForbidden},
+		{"transports do not mix", http("GET", "greet"), nil, ErrForbidden},
+	}
+	for _, tt := range tests {
+		err := a.Authorize(context.Background(), tt.req, tt.info)
+		if tt.want == nil && err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+		}
+		if tt.want != nil && !errors.Is(err, tt.want) {
+			t.Errorf("%s: error = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestPolicyAuthorizer_DefaultPermit(t *testing.T) {
+	a := newTestPolicyAuthorizer(t, "/api/admin/** = role:admin", func(a *implPolicyAuthorizer) {
+		a.Default = "permit"
+	})
+	if err := a.Authorize(context.Background(), AccessRequest{Transport: TransportHTTP, Method: "GET", Resource: "/api/x"}, nil); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	if err := a.Authorize(context.Background(), AccessRequest{Transport: TransportHTTP, Method: "GET", Resource: "/api/admin"}, &AuthInfo{Subject: "u"}); !errors.Is(err, ErrForbidden) {
+		t.Errorf("error = %v, want ErrForbidden", err)
+	}
+}
+
+func TestPolicyAuthorizer_PolicyFile(t *testing.T) {
+	dir := t.TempDir()
+	rt := newMockRuntime(true)
+	policy := "# reports\nGET /api/reports/** = role:analyst  # read only\n\n/api/** = authenticated\n"
+	if err := os.WriteFile(filepath.Join(dir, "policy.txt"), []byte(policy), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	a := newTestPolicyAuthorizer(t, "/api/reports/export = role:admin", func(a *implPolicyAuthorizer) {
+		a.PolicyFile = filepath.Join(dir, "policy.txt")
+		a.Runtime = rt
+	})
+	if len(a.rules) != 3 {
+		t.Fatalf("rules = %d, want 3", len(a.rules))
+	}
+	analyst := &AuthInfo{Subject: "ann", Roles: []string{"analyst"}}
+	// property rules come first
+	if err := a.Authorize(context.Background(), AccessRequest{Transport: TransportHTTP, Method: "GET", Resource: "/api/reports/export"}, analyst); !errors.Is(err, ErrForbidden) {
+		t.Errorf("error = %v, want ErrForbidden", err)
+	}
+	if err := a.Authorize(context.Background(), AccessRequest{Transport: TransportHTTP, Method: "GET", Resource: "/api/reports/daily"}, analyst); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestPolicyAuthorizer_LogsDecisions(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	a := newTestPolicyAuthorizer(t, "/api/** = role:admin", func(a *implPolicyAuthorizer) {
+		a.Log = zap.New(core)
+	})
+
+	a.Authorize(context.Background(), AccessRequest{Transport: TransportHTTP, Method: "GET", Resource: "/api/x"}, &AuthInfo{Subject: "root", Roles: []string{"admin"}})
+	a.Authorize(context.Background(), AccessRequest{Transport: TransportHTTP, Method: "GET", Resource: "/api/x"}, &AuthInfo{Subject: "bob"})
+
+	entries := logs.All()
+	if len(entries) != 2 || entries[0].Message != "AuthzAllow" || entries[1].Message != "AuthzDeny" {
+		t.Fatalf("log entries = %v", entries)
+	}
+	if got := entries[1].ContextMap()["subject"]; got != "bob" {
+		t.Errorf("subject = %v, want bob", got)
+	}
+	if got := entries[1].ContextMap()["rule"]; got != "/api/** = role:admin" {
+		t.Errorf("rule = %v", got)
+	}
+}
+
+func TestParseAuthzRule_Invalid(t *testing.T) {
+	for _, bad := range []string{
+		"/api",
+		"/api = ",
+		"api/x = permit",
+		"GET POST /api = permit",
+		"/api = role",
+		"/api = permit:x",
+		"/api = superuser",
+		"grpc:[ = permit",
+	} {
+		if _, err := parseAuthzRule(bad); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+	a := &implPolicyAuthorizer{Default: "maybe"}
+	if err := a.PostConstruct(); err == nil {
+		t.Error("expected error for authz.default=maybe")
+	}
+}
@@ -0,0 +1,179 @@
+/*
+ * Copyright (c) 2026 Karagatan LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package servion
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"sort"
+	"strings"
+
+	"golang.org/x/xerrors"
+)
+
+type bodyLimitRule struct {
+	key   string // path prefix or media type
+	limit int64  // 0 means unlimited
+}
+
+type implBodyLimitMiddleware struct {
+	beanOrder int
+
+	Prefixes []string `value:"bodylimit.prefixes,default=/"`
+
+	// Limit of paths not covered by Rules, bytes
+	Max int `value:"bodylimit.max,default=1048576"`
+
+	// Per-route limits, e.g. "/api/upload=100MB;/api=1MB"
+	Rules string `value:"bodylimit.rules,default="`
+
+	// Per media type limits, e.g. "application/json=256KB;multipart/form-data=50MB"
+	ContentTypes string `value:"bodylimit.content-types,default="`
+
+	rules        []bodyLimitRule
+	contentTypes []bodyLimitRule
+}
+
+/*
+BodyLimitMiddleware creates an HttpMiddleware that caps request bodies. Requests
+declaring a larger Content-Length are rejected with 413 before the handler runs;
+other bodies are wrapped with http.MaxBytesReader, so a handler reading past the
+limit gets an *http.MaxBytesError.
+
+The limit of a request is the rule of its longest matching prefix, or
+bodylimit.max. A matching content type rule can only tighten it. A limit of 0
+lifts the cap, e.g. "/api/stream=0".
+
+The middleware runs behind the <server>.max-body-bytes ceiling of each server
+(default 10MB), so it can only tighten that: a rule above the ceiling, or of 0,
+needs the server property raised or set to 0 as well. HttpServerFactory warns of
+such rules at startup.
+
+Configuration properties:
+
+	bodylimit.prefixes       – URL prefixes the middleware applies to (default "/")
+	bodylimit.max            – limit of other paths in bytes (default 1048576)
+	bodylimit.rules          – per-route limits, e.g. "/api/upload=100MB;/api=1MB"
+	bodylimit.content-types  – per media type limits, e.g. "application/json=256KB"
+
+Sizes in rules are bytes, or carry a KB, MB or GB (1024-based) suffix.
+*/
+func BodyLimitMiddleware(beanOrder int) HttpMiddleware {
+	return &implBodyLimitMiddleware{
+		beanOrder: beanOrder,
+	}
+}
+
+func (t *implBodyLimitMiddleware) PostConstruct() (err error) {
+	if t.Max < 0 {
+		return xerrors.Errorf("bodylimit.max must not be negative, got %d", t.Max)
+	}
+	t.rules, err = parseBodyLimitRules(t.Rules)
+	if err != nil {
+		return err
+	}
+	t.contentTypes, err = parseBodyLimitRules(t.ContentTypes)
+	if err != nil {
+		return err
+	}
+	for i := range t.contentTypes {
+		t.contentTypes[i].key = strings.ToLower(t.contentTypes[i].key)
+	}
+	return nil
+}
+
+func (t *implBodyLimitMiddleware) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limitRequestBody(w, r, next, t.limitFor(r))
+	})
+}
+
+func (t *implBodyLimitMiddleware) limitFor(r *http.Request) int64 {
+	limit := int64(t.Max)
+	for _, rule := range t.rules {
+		if strings.HasPrefix(r.URL.Path, rule.key) {
+			limit = rule.limit
+			break
+		}
+	}
+	if len(t.contentTypes) > 0 {
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		for _, rule := range t.contentTypes {
+			if rule.key == mediaType && rule.limit > 0 && (limit == 0 || rule.limit < limit) {
+				limit = rule.limit
+				break
+			}
+		}
+	}
+	return limit
+}
+
+// limitsAbove lists the rules lifting the cap or allowing more than ceiling bytes.
+func (t *implBodyLimitMiddleware) limitsAbove(ceiling int64) []string {
+	var list []string
+	if t.Max == 0 || int64(t.Max) > ceiling {
+		list = append(list, fmt.Sprintf("bodylimit.max=%d", t.Max))
+	}
+	for _, rule := range append(t.rules, t.contentTypes...) {
+		if rule.limit == 0 || rule.limit > ceiling {
+			list = append(list, fmt.Sprintf("%s=%d", rule.key, rule.limit))
+		}
+	}
+	return list
+}
+
+func (t *implBodyLimitMiddleware) BeanOrder() int {
+	return t.beanOrder
+}
+
+func (t *implBodyLimitMiddleware) Match(prefix string) bool {
+	for _, p := range t.Prefixes {
+		if strings.HasPrefix(prefix, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// limitRequestBody serves the request with its body capped at limit bytes, 0 meaning no cap.
+func limitRequestBody(w http.ResponseWriter, r *http.Request, next http.Handler, limit int64) {
+	if limit > 0 {
+		if r.ContentLength > limit {
+			w.Header().Set("Connection", "close")
+			http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		if r.Body != nil && r.Body != http.NoBody {
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
+		}
+	}
+	next.ServeHTTP(w, r)
+}
+
+/*
+parseBodyLimitRules parses "key=size" entries separated by semicolons. Rules are
+returned longest key first, so the most specific prefix wins.
+*/
+func parseBodyLimitRules(str string) ([]bodyLimitRule, error) {
+	var rules []bodyLimitRule
+	for _, entry := range ParsePrefixList(str) {
+		key, value, ok := strings.Cut(entry, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, xerrors.Errorf("invalid body limit rule '%s', expected key=size", entry)
+		}
+		limit, err := ParseByteSize(value)
+		if err != nil {
+			return nil, xerrors.Errorf("invalid size in body limit rule '%s': %w", entry, err)
+		}
+		rules = append(rules, bodyLimitRule{key: key, limit: limit})
+	}
+	sort.SliceStable(rules, func(i, j int) bool {
+		return len(rules[i].key) > len(rules[j].key)
+	})
+	return rules, nil
+}
@@ -0,0 +1,91 @@
+package servion
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newTestBodyLimitMiddleware(t *testing.T, max int, rules, contentTypes string) *implBodyLimitMiddleware {
+	t.Helper()
+	m := &implBodyLimitMiddleware{
+		beanOrder:    1,
+		Prefixes:     []string{"/"},
+		Max:          max,
+		Rules:        rules,
+		ContentTypes: contentTypes,
+	}
+	if err := m.PostConstruct(); err != nil {
+		t.Fatalf("PostConstruct: %v", err)
+	}
+	return m
+}
+
+func TestBodyLimitMiddleware_RejectsDeclaredLength(t *testing.T) {
+	m := newTestBodyLimitMiddleware(t, 10, "", "")
+	handler := m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("handler must not run")
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/items", strings.NewReader(strings.Repeat("x", 11))))
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("status = %d, want 413", w.Code)
+	}
+}
+
+func TestBodyLimitMiddleware_CapsUndeclaredLength(t *testing.T) {
+	m := newTestBodyLimitMiddleware(t, 10, "", "")
+
+	var readErr error
+	handler := m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, readErr = io.ReadAll(r.Body)
+	}))
+
+	r := httptest.NewRequest(http.MethodPost, "/api/items", io.NopCloser(strings.NewReader(strings.Repeat("x", 100))))
+	r.ContentLength = -1 // chunked
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	var maxErr *http.MaxBytesError
+	if !errors.As(readErr, &maxErr) || maxErr.Limit != 10 {
+		t.Errorf("read error = %v, want MaxBytesError(10)", readErr)
+	}
+}
+
+func TestBodyLimitMiddleware_LimitFor(t *testing.T) {
+	m := newTestBodyLimitMiddleware(t, 1<<20, "/api/upload=100MB;/api=64KB;/api/stream=0", "application/json=16KB;multipart/form-data=1GB")
+
+	tests := []struct {
+		path        string
+		contentType string
+		want        int64
+	}{
+		{"/index.html", "", 1 << 20},
+		{"/api/items", "text/plain", 64 << 10},
+		{"/api/items", "application/json; charset=utf-8", 16 << 10},
+		{"/api/upload/photo", "multipart/form-data; boundary=x", 100 << 20}, // content types only tighten
+		{"/api/upload/photo", "application/json", 16 << 10},
+		{"/api/stream/logs", "", 0},
+		{"/api/stream/logs", "application/json", 16 << 10},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodPost, tt.path, nil)
+		if tt.contentType != "" {
+			r.Header.Set("Content-Type", tt.contentType)
+		}
+		if got := m.limitFor(r); got != tt.want {
+			t.Errorf("limitFor(%s, %q) = %d, want %d", tt.path, tt.contentType, got, tt.want)
+		}
+	}
+}
+
+func TestParseBodyLimitRules_Invalid(t *testing.T) {
+	for _, bad := range []string{"/api", "/api=big", "/api=-1", "=1MB"} {
+		if _, err := parseBodyLimitRules(bad); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
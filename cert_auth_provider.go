@@ -0,0 +1,308 @@
+/*
+ * Copyright (c) 2026 Karagatan LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package servion
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"path"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/xerrors"
+)
+
+type implCertAuthProvider struct {
+	Log *zap.Logger `inject:"optional"`
+
+	// Semicolon separated mapping rules "<selector>:<pattern> = <grants>"
+	Rules string `value:"mtls.rules,default="`
+
+	// Identities tried in order for AuthInfo.Subject: spiffe, uri, dns, email, cn
+	SubjectFrom []string `value:"mtls.subject-from,default=spiffe;dns;cn"`
+
+	// Accepted SPIFFE trust domains, empty accepts any
+	TrustDomains []string `value:"mtls.trust-domains,default="`
+
+	// Reject certificates matching no rule
+	RequireRule bool `value:"mtls.require-rule,default=false"`
+
+	rules []*certRule
+}
+
+// certRule grants roles and scopes to certificates whose selected identity matches pattern.
+type certRule struct {
+	selector string
+	pattern  string
+	roles    []string
+	scopes   []string
+}
+
+// certSelectors are the certificate identities rules and mtls.subject-from may name.
+var certSelectors = map[string]bool{
+	"spiffe": true,
+	"uri":    true,
+	"dns":    true,
+	"email":  true,
+	"cn":     true,
+	"ou":     true,
+	"o":      true,
+}
+
+/*
+CertAuthProvider creates a CertificateAuthenticator mapping the verified client
+certificate of a mutual TLS connection to AuthInfo. Only certificates verified
+by the *tls.Config of the server count; a certificate the handshake merely
+received is never trusted.
+
+The subject is the first identity of mtls.subject-from the certificate has:
+the SPIFFE ID (a spiffe:// URI SAN), another URI SAN, a DNS SAN, an email SAN
+or the common name. Roles and scopes come from mapping rules, all matching
+rules contribute:
+
+	spiffe:spiffe://prod.example.com/billing/* = roles:billing-service
+	dns:*.internal.example.com                 = roles:service & scopes:metrics.read
+	cn:ops-*                                   = roles:admin
+	ou:Platform                                = scopes:deploy
+
+Patterns use path.Match syntax, so "*" does not cross a "/" but does cross the
+dots of a DNS name. The selectors ou and o match the organizational unit and
+organization of the subject name.
+
+Attributes carry auth=mtls, cn, spiffe_id, uri, dns, email, ou, o, serial and
+the SHA-256 fingerprint of the certificate, which is also AuthInfo.HashedToken.
+AuthInfo.Issuer is the distinguished name of the issuing CA.
+
+Configuration properties:
+
+	mtls.rules          – semicolon separated mapping rules
+	mtls.subject-from   – identities tried for the subject (default "spiffe;dns;cn")
+	mtls.trust-domains  – accepted SPIFFE trust domains, empty accepts any
+	mtls.require-rule   – reject certificates matching no rule (default false)
+*/
+func CertAuthProvider() CertificateAuthenticator {
+	return &implCertAuthProvider{}
+}
+
+func (t *implCertAuthProvider) PostConstruct() error {
+	if t.Log == nil {
+		t.Log = zap.NewNop()
+	}
+	var subjectFrom []string
+	for _, s := range t.SubjectFrom {
+		if s = strings.ToLower(strings.TrimSpace(s)); s == "" {
+			continue
+		}
+		subjectFrom = append(subjectFrom, s)
+		if s == "ou" || s == "o" || !certSelectors[s] {
+			return xerrors.Errorf("mtls.subject-from: unknown identity '%s', expected spiffe, uri, dns, email or cn", s)
+		}
+	}
+	if len(subjectFrom) == 0 {
+		return xerrors.New("mtls.subject-from is empty")
+	}
+	t.SubjectFrom = subjectFrom
+
+	var domains []string
+	for _, domain := range t.TrustDomains {
+		if domain = strings.ToLower(strings.TrimSpace(domain)); domain != "" {
+			domains = append(domains, domain)
+		}
+	}
+	t.TrustDomains = domains
+	for _, line := range ParsePrefixList(t.Rules) {
+		rule, err := parseCertRule(line)
+		if err != nil {
+			return xerrors.Errorf("mtls.rules: %w", err)
+		}
+		t.rules = append(t.rules, rule)
+	}
+	return nil
+}
+
+func (t *implCertAuthProvider) AuthenticateCertificate(state *tls.ConnectionState) (AuthInfo, error) {
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return AuthInfo{}, xerrors.Errorf("no verified client certificate: %w", ErrUnauthorized)
+	}
+	leaf := state.VerifiedChains[0][0]
+
+	// connections outlive certificates, the handshake checked the validity only once
+	now := time.Now()
+	if now.Before(leaf.NotBefore) || now.After(leaf.NotAfter) {
+		return AuthInfo{}, xerrors.Errorf("client certificate expired: %w", ErrUnauthorized)
+	}
+
+	ids := certIdentities(leaf)
+	if spiffeID := firstOf(ids["spiffe"]); spiffeID != "" && len(t.TrustDomains) > 0 {
+		if !containsString(t.TrustDomains, spiffeTrustDomain(spiffeID)) {
+			t.Log.Warn("CertRejected", zap.String("spiffe_id", spiffeID), zap.String("reason", "trust domain"))
+			return AuthInfo{}, xerrors.Errorf("SPIFFE trust domain of '%s' is not accepted: %w", spiffeID, ErrUnauthorized)
+		}
+	}
+
+	info := AuthInfo{
+		Issuer:     leaf.Issuer.String(),
+		Attributes: map[string]string{"auth": "mtls"},
+	}
+	for _, s := range t.SubjectFrom {
+		if v := firstOf(ids[s]); v != "" {
+			info.Subject = v
+			break
+		}
+	}
+	if info.Subject == "" {
+		return AuthInfo{}, xerrors.Errorf("client certificate has none of the identities %s: %w", strings.Join(t.SubjectFrom, ", "), ErrUnauthorized)
+	}
+
+	matched := false
+	for _, rule := range t.rules {
+		if rule.matches(ids) {
+			matched = true
+			info.Roles = appendUnique(info.Roles, rule.roles...)
+			info.Scopes = appendUnique(info.Scopes, rule.scopes...)
+		}
+	}
+	if !matched && t.RequireRule {
+		t.Log.Warn("CertRejected", zap.String("subject", info.Subject), zap.String("reason", "no rule"))
+		return AuthInfo{}, xerrors.Errorf("no mtls rule matches '%s': %w", info.Subject, ErrUnauthorized)
+	}
+
+	attrs := map[string]string{
+		"cn":        firstOf(ids["cn"]),
+		"spiffe_id": firstOf(ids["spiffe"]),
+		"uri":       strings.Join(ids["uri"], ","),
+		"dns":       strings.Join(ids["dns"], ","),
+		"email":     strings.Join(ids["email"], ","),
+		"ou":        strings.Join(ids["ou"], ","),
+		"o":         strings.Join(ids["o"], ","),
+		"serial":    leaf.SerialNumber.Text(16),
+	}
+	for k, v := range attrs {
+		if v != "" {
+			info.Attributes[k] = v
+		}
+	}
+	sum := sha256.Sum256(leaf.Raw)
+	info.HashedToken = hex.EncodeToString(sum[:])
+	info.Attributes["fingerprint"] = info.HashedToken
+	return info, nil
+}
+
+func parseCertRule(line string) (*certRule, error) {
+	i := strings.LastIndexByte(line, '=')
+	if i < 0 {
+		return nil, xerrors.Errorf("invalid mtls rule '%s', expected selector:pattern = grants", line)
+	}
+	target, grants := strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:])
+	selector, pattern, ok := strings.Cut(target, ":")
+	selector, pattern = strings.ToLower(strings.TrimSpace(selector)), strings.TrimSpace(pattern)
+	if !ok || !certSelectors[selector] || pattern == "" {
+		return nil, xerrors.Errorf("invalid mtls target '%s', expected spiffe, uri, dns, email, cn, ou or o followed by :pattern", target)
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, xerrors.Errorf("invalid mtls pattern '%s': %w", pattern, err)
+	}
+	rule := &certRule{
+		selector: selector,
+		pattern:  pattern,
+	}
+	for _, part := range strings.Split(grants, "&") {
+		kind, list, _ := strings.Cut(strings.TrimSpace(part), ":")
+		var values []string
+		for _, v := range strings.Split(list, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+		if len(values) == 0 {
+			return nil, xerrors.Errorf("invalid mtls grant '%s', expected roles:a,b or scopes:x,y", part)
+		}
+		switch strings.ToLower(strings.TrimSpace(kind)) {
+		case "roles", "role":
+			rule.roles = append(rule.roles, values...)
+		case "scopes", "scope":
+			rule.scopes = append(rule.scopes, values...)
+		default:
+			return nil, xerrors.Errorf("invalid mtls grant '%s', expected roles:a,b or scopes:x,y", part)
+		}
+	}
+	return rule, nil
+}
+
+func (r *certRule) matches(ids map[string][]string) bool {
+	for _, v := range ids[r.selector] {
+		if ok, _ := path.Match(r.pattern, v); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// certIdentities lists the identities of a certificate by selector.
+func certIdentities(cert *x509.Certificate) map[string][]string {
+	ids := map[string][]string{
+		"dns":   cert.DNSNames,
+		"email": cert.EmailAddresses,
+		"ou":    cert.Subject.OrganizationalUnit,
+		"o":     cert.Subject.Organization,
+	}
+	if cert.Subject.CommonName != "" {
+		ids["cn"] = []string{cert.Subject.CommonName}
+	}
+	for _, u := range cert.URIs {
+		if u.Scheme == "spiffe" {
+			ids["spiffe"] = append(ids["spiffe"], u.String())
+		} else {
+			ids["uri"] = append(ids["uri"], u.String())
+		}
+	}
+	return ids
+}
+
+// spiffeTrustDomain returns the trust domain of a SPIFFE ID, spiffe://<trust domain>/<path>.
+func spiffeTrustDomain(id string) string {
+	domain, _, _ := strings.Cut(strings.TrimPrefix(id, "spiffe://"), "/")
+	return strings.ToLower(domain)
+}
+
+func firstOf(list []string) string {
+	if len(list) == 0 {
+		return ""
+	}
+	return list[0]
+}
+
+// commaList splits a comma separated list, dropping blank entries.
+func commaList(s string) []string {
+	var list []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func appendUnique(list []string, values ...string) []string {
+	for _, v := range values {
+		if !containsString(list, v) {
+			list = append(list, v)
+		}
+	}
+	return list
+}
@@ -0,0 +1,286 @@
+package servion
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pool *x509.CertPool
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return &testCA{cert: cert, key: key, pool: pool}
+}
+
+// issue signs a client certificate for tmpl and returns it with its key.
+func (ca *testCA) issue(t *testing.T, tmpl *x509.Certificate) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tmpl.SerialNumber == nil {
+		tmpl.SerialNumber = big.NewInt(time.Now().UnixNano())
+	}
+	if tmpl.NotBefore.IsZero() {
+		tmpl.NotBefore = time.Now().Add(-time.Minute)
+		tmpl.NotAfter = time.Now().Add(time.Hour)
+	}
+	tmpl.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, _ := x509.ParseCertificate(der)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+// verifiedState is the connection state of a handshake that verified cert.
+func (ca *testCA) verifiedState(cert tls.Certificate) *tls.ConnectionState {
+	return &tls.ConnectionState{
+		PeerCertificates: []*x509.Certificate{cert.Leaf},
+		VerifiedChains:   [][]*x509.Certificate{{cert.Leaf, ca.cert}},
+	}
+}
+
+func newCertProvider(t *testing.T, rules string) *implCertAuthProvider {
+	t.Helper()
+	p := &implCertAuthProvider{
+		Rules:       rules,
+		SubjectFrom: []string{"spiffe", "dns", "cn"},
+	}
+	if err := p.PostConstruct(); err != nil {
+		t.Fatalf("PostConstruct: %v", err)
+	}
+	return p
+}
+
+func mustURL(t *testing.T, s string) *url.URL {
+	t.Helper()
+	u, err := url.Parse(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+func TestCertAuthProvider_MapsIdentities(t *testing.T) {
+	ca := newTestCA(t)
+	p := newCertProvider(t,
+		"spiffe:spiffe://prod.example.com/billing/* = roles:billing-service & scopes:invoices.read;"+
+			"dns:*.internal.example.com = roles:service;"+
+			"ou:Platform = scopes:deploy;"+
+			"cn:ops-* = roles:admin")
+
+	cert := ca.issue(t, &x509.Certificate{
+		Subject:  pkix.Name{CommonName: "billing", OrganizationalUnit: []string{"Platform"}},
+		DNSNames: []string{"billing.internal.example.com"},
+		URIs:     []*url.URL{mustURL(t, "spiffe://prod.example.com/billing/api")},
+	})
+
+	info, err := p.AuthenticateCertificate(ca.verifiedState(cert))
+	if err != nil {
+		t.Fatalf("AuthenticateCertificate: %v", err)
+	}
+	if info.Subject != "spiffe://prod.example.com/billing/api" {
+		t.Errorf("Subject = %q", info.Subject)
+	}
+	if strings.Join(info.Roles, ",") != "billing-service,service" {
+		t.Errorf("Roles = %v", info.Roles)
+	}
+	if strings.Join(info.Scopes, ",") != "invoices.read,deploy" {
+		t.Errorf("Scopes = %v", info.Scopes)
+	}
+	if info.Issuer != "CN=Test CA" {
+		t.Errorf("Issuer = %q", info.Issuer)
+	}
+	if info.Attributes["auth"] != "mtls" || info.Attributes["cn"] != "billing" ||
+		info.Attributes["spiffe_id"] != "spiffe://prod.example.com/billing/api" ||
+		info.Attributes["dns"] != "billing.internal.example.com" || info.Attributes["ou"] != "Platform" {
+		t.Errorf("Attributes = %v", info.Attributes)
+	}
+	if info.HashedToken == "" || info.Attributes["fingerprint"] != info.HashedToken {
+		t.Errorf("fingerprint = %q, HashedToken = %q", info.Attributes["fingerprint"], info.HashedToken)
+	}
+}
+
+func TestCertAuthProvider_SubjectPreference(t *testing.T) {
+	ca := newTestCA(t)
+	p := newCertProvider(t, "")
+
+	dns := ca.issue(t, &x509.Certificate{
+		Subject:  pkix.Name{CommonName: "worker"},
+		DNSNames: []string{"worker.example.com"},
+	})
+	info, err := p.AuthenticateCertificate(ca.verifiedState(dns))
+	if err != nil || info.Subject != "worker.example.com" {
+		t.Fatalf("dns subject: %q, %v", info.Subject, err)
+	}
+
+	cn := ca.issue(t, &x509.Certificate{Subject: pkix.Name{CommonName: "worker"}})
+	info, err = p.AuthenticateCertificate(ca.verifiedState(cn))
+	if err != nil || info.Subject != "worker" {
+		t.Fatalf("cn subject: %q, %v", info.Subject, err)
+	}
+	if len(info.Roles) != 0 {
+		t.Errorf("Roles = %v, want none", info.Roles)
+	}
+
+	none := ca.issue(t, &x509.Certificate{EmailAddresses: []string{"ops@example.com"}})
+	if _, err := p.AuthenticateCertificate(ca.verifiedState(none)); !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("no identity: expected ErrUnauthorized, got %v", err)
+	}
+}
+
+func TestCertAuthProvider_Rejections(t *testing.T) {
+	ca := newTestCA(t)
+	p := newCertProvider(t, "cn:svc-* = roles:service")
+	p.RequireRule = true
+	p.TrustDomains = []string{"prod.example.com"}
+
+	if _, err := p.AuthenticateCertificate(&tls.ConnectionState{}); !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("unverified: expected ErrUnauthorized, got %v", err)
+	}
+
+	unmatched := ca.issue(t, &x509.Certificate{Subject: pkix.Name{CommonName: "guest"}})
+	if _, err := p.AuthenticateCertificate(ca.verifiedState(unmatched)); !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("no rule: expected ErrUnauthorized, got %v", err)
+	}
+
+	foreign := ca.issue(t, &x509.Certificate{
+		Subject: pkix.Name{CommonName: "svc-a"},
+		URIs:    []*url.URL{mustURL(t, "spiffe://dev.example.com/svc-a")},
+	})
+	if _, err := p.AuthenticateCertificate(ca.verifiedState(foreign)); !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("foreign trust domain: expected ErrUnauthorized, got %v", err)
+	}
+
+	expired := ca.issue(t, &x509.Certificate{
+		Subject:   pkix.Name{CommonName: "svc-a"},
+		NotBefore: time.Now().Add(-2 * time.Hour),
+		NotAfter:  time.Now().Add(-time.Hour),
+	})
+	if _, err := p.AuthenticateCertificate(ca.verifiedState(expired)); !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("expired: expected ErrUnauthorized, got %v", err)
+	}
+
+	ok := ca.issue(t, &x509.Certificate{Subject: pkix.Name{CommonName: "svc-a"}})
+	if info, err := p.AuthenticateCertificate(ca.verifiedState(ok)); err != nil || info.Subject != "svc-a" {
+		t.Fatalf("matching rule: %q, %v", info.Subject, err)
+	}
+}
+
+func TestCertAuthProvider_InvalidConfig(t *testing.T) {
+	for _, rules := range []string{
+		"cn:svc = admin",
+		"host:svc = roles:admin",
+		"cn: = roles:admin",
+		"cn:svc = roles:",
+		"cn:[ = roles:admin",
+		"cn:svc",
+	} {
+		p := &implCertAuthProvider{Rules: rules, SubjectFrom: []string{"cn"}}
+		if err := p.PostConstruct(); err == nil {
+			t.Errorf("rules %q: expected error", rules)
+		}
+	}
+	p := &implCertAuthProvider{SubjectFrom: []string{"ou"}}
+	if err := p.PostConstruct(); err == nil {
+		t.Error("subject-from ou: expected error")
+	}
+}
+
+func TestAuthMiddleware_ClientCertificate(t *testing.T) {
+	ca := newTestCA(t)
+	mw := &implAuthMiddleware{
+		Prefixes:     []string{"/api"},
+		Certificates: newCertProvider(t, "cn:svc-* = roles:service"),
+	}
+	srv := httptest.NewUnstartedServer(mw.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth, _ := AuthFromContext(r.Context())
+		io.WriteString(w, auth.Subject+" "+strings.Join(auth.Roles, ","))
+	})))
+	srv.TLS = &tls.Config{ClientAuth: tls.VerifyClientCertIfGiven, ClientCAs: ca.pool}
+	srv.StartTLS()
+	defer srv.Close()
+
+	call := func(certs ...tls.Certificate) (int, string) {
+		transport := srv.Client().Transport.(*http.Transport).Clone()
+		transport.TLSClientConfig.Certificates = certs
+		client := &http.Client{Transport: transport}
+		defer transport.CloseIdleConnections()
+		resp, err := client.Get(srv.URL + "/api/data")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	if code, body := call(ca.issue(t, &x509.Certificate{Subject: pkix.Name{CommonName: "svc-orders"}})); code != http.StatusOK || body != "svc-orders service" {
+		t.Fatalf("client certificate: %d %q", code, body)
+	}
+	if code, _ := call(); code != http.StatusUnauthorized {
+		t.Fatalf("no certificate: status %d, want 401", code)
+	}
+}
+
+func TestCommaList(t *testing.T) {
+	cases := map[string][]string{
+		"":                  nil,
+		" , ":               nil,
+		"-":                 {"-"},
+		"a, b,,c ":          {"a", "b", "c"},
+		"spiffe://dev/a,b ": {"spiffe://dev/a", "b"},
+	}
+	for in, want := range cases {
+		got := commaList(in)
+		if len(got) != len(want) {
+			t.Errorf("commaList(%q) = %q, want %q", in, got, want)
+			continue
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("commaList(%q) = %q, want %q", in, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,500 @@
+/*
+ * Copyright (c) 2026 Karagatan LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package servion
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.arpabet.com/cligo"
+	"golang.org/x/xerrors"
+)
+
+const (
+	caCertFile = "ca.crt"
+	caKeyFile  = "ca.key"
+)
+
+type implCertGroup struct {
+	Parent cligo.CliGroup `cli:"group=cli"`
+}
+
+/*
+CertCommands creates the "cert" command group of a development CA, whose files
+load as they are into TLS profiles:
+
+	app cert init-ca
+	app cert issue --name api --dns api.local,localhost --ip 127.0.0.1
+	app cert issue --name billing --usage client --spiffe spiffe://dev.local/billing
+	app cert inspect --file tls/api.crt
+
+	tls.public.cert-file=tls/api.crt
+	tls.public.key-file=tls/api.key
+	tls.public.ca-file=tls/ca.crt
+
+Keys are ECDSA P-256 unless --key asks for rsa or ed25519, written as PKCS#8 PEM
+readable by the owner only. The CA is meant for development and tests; it is not
+protected beyond its file mode.
+
+Add them to the beans of cligo.Main with append(beans, servion.CertCommands()...).
+*/
+func CertCommands() []interface{} {
+	return []interface{}{
+		&implCertGroup{},
+		&implCertInitCaCommand{out: os.Stdout},
+		&implCertIssueCommand{out: os.Stdout},
+		&implCertInspectCommand{out: os.Stdout},
+	}
+}
+
+func (t *implCertGroup) Group() string {
+	return "cert"
+}
+
+func (t *implCertGroup) Help() (string, string) {
+	return "Manages a development certificate authority.",
+		`These commands create a local CA under the home directory, issue server and client certificates signed by it, and print the details of certificate files.`
+}
+
+type implCertInitCaCommand struct {
+	Parent   cligo.CliGroup `cli:"group=cert"`
+	HomeDir  string         `cli:"option=home,default=.,help=home directory of application"`
+	Dir      string         `cli:"option=dir,default=tls,help=certificate directory, relative to the home directory"`
+	Name     string         `cli:"option=name,default=Servion Development CA,help=common name of the CA"`
+	Key      string         `cli:"option=key,default=ecdsa,help=key type, ecdsa, rsa or ed25519"`
+	Validity string         `cli:"option=validity,default=3650d,help=CA lifetime such as 3650d"`
+
+	out io.Writer
+}
+
+func (t *implCertInitCaCommand) Command() string {
+	return "init-ca"
+}
+
+func (t *implCertInitCaCommand) Help() (string, string) {
+	return "Creates a development CA.", `This command creates ca.crt and ca.key in the certificate directory. It refuses to replace an existing CA, remove its files first.`
+}
+
+func (t *implCertInitCaCommand) Run(ctx context.Context) error {
+	dir := certDir(t.HomeDir, t.Dir)
+	certPath, keyPath := filepath.Join(dir, caCertFile), filepath.Join(dir, caKeyFile)
+	for _, path := range []string{certPath, keyPath} {
+		if _, err := os.Stat(path); err == nil {
+			return xerrors.Errorf("%s exists, remove the CA files to create a new one", path)
+		}
+	}
+	validity, err := parseValidity(t.Validity)
+	if err != nil {
+		return err
+	}
+	key, err := generateCertKey(t.Key)
+	if err != nil {
+		return err
+	}
+	serial, err := randomSerial()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: t.Name},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(validity),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		MaxPathLenZero:        true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, key.Public(), key)
+	if err != nil {
+		return xerrors.Errorf("create CA certificate: %w", err)
+	}
+	if err := writeCertPair(certPath, keyPath, der, key); err != nil {
+		return err
+	}
+	fmt.Fprintf(t.out, "created CA %q valid until %s\n", t.Name, tmpl.NotAfter.UTC().Format(time.RFC3339))
+	fmt.Fprintf(t.out, "certificate %s\nkey         %s\n", certPath, keyPath)
+	return nil
+}
+
+type implCertIssueCommand struct {
+	Parent   cligo.CliGroup `cli:"group=cert"`
+	HomeDir  string         `cli:"option=home,default=.,help=home directory of application"`
+	Dir      string         `cli:"option=dir,default=tls,help=certificate directory, relative to the home directory"`
+	Name     string         `cli:"option=name,default=,help=common name and base name of the files"`
+	DNS      string         `cli:"option=dns,default=,help=comma separated DNS names"`
+	IP       string         `cli:"option=ip,default=,help=comma separated IP addresses"`
+	Spiffe   string         `cli:"option=spiffe,default=,help=SPIFFE ID such as spiffe://dev.local/billing"`
+	Email    string         `cli:"option=email,default=,help=comma separated email addresses"`
+	Usage    string         `cli:"option=usage,default=server,help=server, client or server,client"`
+	Key      string         `cli:"option=key,default=ecdsa,help=key type, ecdsa, rsa or ed25519"`
+	Validity string         `cli:"option=validity,default=90d,help=certificate lifetime such as 720h or 90d"`
+	Force    bool           `cli:"option=force,default=false,help=replace existing certificate files"`
+
+	out io.Writer
+}
+
+func (t *implCertIssueCommand) Command() string {
+	return "issue"
+}
+
+func (t *implCertIssueCommand) Help() (string, string) {
+	return "Issues a certificate signed by the development CA.",
+		`This command writes <name>.crt, the certificate followed by the CA, and <name>.key to the certificate directory.
+A server certificate without --dns or --ip names --name as its DNS name. Existing files are only replaced with --force.`
+}
+
+func (t *implCertIssueCommand) Run(ctx context.Context) error {
+	name := strings.TrimSpace(t.Name)
+	if name == "" || strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, ".") || name == "ca" {
+		return xerrors.New("--name is required, must not be 'ca' and must be usable as a file name")
+	}
+	dir := certDir(t.HomeDir, t.Dir)
+	certPath, keyPath := filepath.Join(dir, name+".crt"), filepath.Join(dir, name+".key")
+	if !t.Force {
+		for _, path := range []string{certPath, keyPath} {
+			if _, err := os.Stat(path); err == nil {
+				return xerrors.Errorf("%s exists, pass --force to replace it", path)
+			}
+		}
+	}
+	caCert, caKey, err := loadCA(dir)
+	if err != nil {
+		return err
+	}
+	validity, err := parseValidity(t.Validity)
+	if err != nil {
+		return err
+	}
+	serial, err := randomSerial()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(validity),
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		DNSNames:              commaList(t.DNS),
+		EmailAddresses:        commaList(t.Email),
+	}
+	if tmpl.NotAfter.After(caCert.NotAfter) {
+		tmpl.NotAfter = caCert.NotAfter
+	}
+	for _, s := range commaList(t.IP) {
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return xerrors.Errorf("invalid --ip '%s'", s)
+		}
+		tmpl.IPAddresses = append(tmpl.IPAddresses, ip)
+	}
+	if t.Spiffe != "" {
+		u, err := url.Parse(t.Spiffe)
+		if err != nil || u.Scheme != "spiffe" || u.Host == "" {
+			return xerrors.Errorf("invalid --spiffe '%s', expected spiffe://<trust domain>/<path>", t.Spiffe)
+		}
+		tmpl.URIs = []*url.URL{u}
+	}
+	for _, usage := range commaList(t.Usage) {
+		switch usage {
+		case "server":
+			tmpl.ExtKeyUsage = append(tmpl.ExtKeyUsage, x509.ExtKeyUsageServerAuth)
+		case "client":
+			tmpl.ExtKeyUsage = append(tmpl.ExtKeyUsage, x509.ExtKeyUsageClientAuth)
+		default:
+			return xerrors.Errorf("unknown --usage '%s', expected server or client", usage)
+		}
+	}
+	if len(tmpl.ExtKeyUsage) == 0 {
+		return xerrors.New("--usage is required, server, client or server,client")
+	}
+	server := containsString(commaList(t.Usage), "server")
+	if server && len(tmpl.DNSNames) == 0 && len(tmpl.IPAddresses) == 0 {
+		tmpl.DNSNames = []string{name}
+	}
+
+	key, err := generateCertKey(t.Key)
+	if err != nil {
+		return err
+	}
+	if _, isRSA := key.(*rsa.PrivateKey); isRSA {
+		tmpl.KeyUsage |= x509.KeyUsageKeyEncipherment
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, caCert, key.Public(), caKey)
+	if err != nil {
+		return xerrors.Errorf("create certificate: %w", err)
+	}
+	if err := writeCertPair(certPath, keyPath, der, key, caCert.Raw); err != nil {
+		return err
+	}
+	fmt.Fprintf(t.out, "issued %q valid until %s\n", name, tmpl.NotAfter.UTC().Format(time.RFC3339))
+	fmt.Fprintf(t.out, "certificate %s\nkey         %s\n", certPath, keyPath)
+	return nil
+}
+
+type implCertInspectCommand struct {
+	Parent  cligo.CliGroup `cli:"group=cert"`
+	HomeDir string         `cli:"option=home,default=.,help=home directory of application"`
+	File    string         `cli:"option=file,default=,help=PEM certificate file, relative to the home directory"`
+	CA      string         `cli:"option=ca,default=,help=PEM CA bundle to verify the chain against, relative to the home directory"`
+
+	out io.Writer
+}
+
+func (t *implCertInspectCommand) Command() string {
+	return "inspect"
+}
+
+func (t *implCertInspectCommand) Help() (string, string) {
+	return "Prints the details of a certificate file.",
+		`This command prints subject, issuer, validity, names and key usage of every certificate in a PEM file, and with --ca whether the chain verifies.`
+}
+
+func (t *implCertInspectCommand) Run(ctx context.Context) error {
+	if t.File == "" {
+		return xerrors.New("--file is required")
+	}
+	certs, err := readCertificates(certDir(t.HomeDir, t.File))
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for i, c := range certs {
+		if i > 0 {
+			fmt.Fprintln(t.out)
+		}
+		fingerprint := sha256.Sum256(c.Raw)
+		fmt.Fprintf(t.out, "certificate %d\n", i)
+		fmt.Fprintf(t.out, "  subject      %s\n", c.Subject)
+		fmt.Fprintf(t.out, "  issuer       %s\n", c.Issuer)
+		fmt.Fprintf(t.out, "  serial       %s\n", c.SerialNumber.Text(16))
+		fmt.Fprintf(t.out, "  not before   %s\n", c.NotBefore.UTC().Format(time.RFC3339))
+		fmt.Fprintf(t.out, "  not after    %s (%s)\n", c.NotAfter.UTC().Format(time.RFC3339), expiryText(c.NotAfter, now))
+		fmt.Fprintf(t.out, "  key          %s\n", describeCertKey(c.PublicKey))
+		if c.IsCA {
+			fmt.Fprintln(t.out, "  ca           yes")
+		}
+		if usage := extKeyUsageNames(c.ExtKeyUsage); usage != "" {
+			fmt.Fprintf(t.out, "  usage        %s\n", usage)
+		}
+		for _, d := range c.DNSNames {
+			fmt.Fprintf(t.out, "  dns          %s\n", d)
+		}
+		for _, ip := range c.IPAddresses {
+			fmt.Fprintf(t.out, "  ip           %s\n", ip)
+		}
+		for _, u := range c.URIs {
+			fmt.Fprintf(t.out, "  uri          %s\n", u)
+		}
+		for _, e := range c.EmailAddresses {
+			fmt.Fprintf(t.out, "  email        %s\n", e)
+		}
+		fmt.Fprintf(t.out, "  sha256       %s\n", hex.EncodeToString(fingerprint[:]))
+	}
+
+	if t.CA != "" {
+		roots, err := readCertificates(certDir(t.HomeDir, t.CA))
+		if err != nil {
+			return err
+		}
+		opts := x509.VerifyOptions{
+			Roots:         x509.NewCertPool(),
+			Intermediates: x509.NewCertPool(),
+			KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+		}
+		for _, r := range roots {
+			opts.Roots.AddCert(r)
+		}
+		for _, c := range certs[1:] {
+			opts.Intermediates.AddCert(c)
+		}
+		fmt.Fprintln(t.out)
+		if _, err := certs[0].Verify(opts); err != nil {
+			fmt.Fprintf(t.out, "verify: failed, %v\n", err)
+			return xerrors.Errorf("certificate does not verify: %w", err)
+		}
+		fmt.Fprintln(t.out, "verify: ok")
+	}
+	return nil
+}
+
+// certDir resolves path against the home directory.
+func certDir(home, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(home, path)
+}
+
+func parseValidity(s string) (time.Duration, error) {
+	d, err := parseKeyLifetime(s)
+	if err != nil {
+		return 0, xerrors.Errorf("invalid --validity '%s', expected a positive duration such as 720h or 90d", s)
+	}
+	return d, nil
+}
+
+func generateCertKey(kind string) (crypto.Signer, error) {
+	switch kind {
+	case "ecdsa":
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case "rsa":
+		return rsa.GenerateKey(rand.Reader, 3072)
+	case "ed25519":
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		return key, err
+	}
+	return nil, xerrors.Errorf("unknown --key '%s', expected ecdsa, rsa or ed25519", kind)
+}
+
+// randomSerial returns a positive 128-bit serial number.
+func randomSerial() (*big.Int, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 127))
+	if err != nil {
+		return nil, err
+	}
+	return serial.Add(serial, big.NewInt(1)), nil
+}
+
+// writeCertPair writes the certificate der followed by chain as PEM, and key as PKCS#8 PEM readable by the owner only.
+func writeCertPair(certPath, keyPath string, der []byte, key crypto.Signer, chain ...[]byte) error {
+	keyDer, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return xerrors.Errorf("encode key: %w", err)
+	}
+	var certPEM bytes.Buffer
+	for _, b := range append([][]byte{der}, chain...) {
+		pem.Encode(&certPEM, &pem.Block{Type: "CERTIFICATE", Bytes: b})
+	}
+	if err := os.MkdirAll(filepath.Dir(certPath), 0700); err != nil {
+		return xerrors.Errorf("create certificate directory: %w", err)
+	}
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDer}), 0600); err != nil {
+		return xerrors.Errorf("write key: %w", err)
+	}
+	if err := os.WriteFile(certPath, certPEM.Bytes(), 0644); err != nil {
+		return xerrors.Errorf("write certificate: %w", err)
+	}
+	return nil
+}
+
+func loadCA(dir string) (*x509.Certificate, crypto.Signer, error) {
+	certs, err := readCertificates(filepath.Join(dir, caCertFile))
+	if err != nil {
+		return nil, nil, xerrors.Errorf("run cert init-ca first: %w", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, caKeyFile))
+	if err != nil {
+		return nil, nil, xerrors.Errorf("read CA key: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PRIVATE KEY" {
+		return nil, nil, xerrors.Errorf("%s is not a PKCS#8 PEM private key", filepath.Join(dir, caKeyFile))
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("parse CA key: %w", err)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, nil, xerrors.Errorf("unsupported CA key %T", key)
+	}
+	if !certs[0].IsCA {
+		return nil, nil, xerrors.Errorf("%s is not a CA certificate", filepath.Join(dir, caCertFile))
+	}
+	return certs[0], signer, nil
+}
+
+func readCertificates(path string) ([]*x509.Certificate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, xerrors.Errorf("read certificate: %w", err)
+	}
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		c, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, xerrors.Errorf("parse certificate in %s: %w", path, err)
+		}
+		certs = append(certs, c)
+	}
+	if len(certs) == 0 {
+		return nil, xerrors.Errorf("no PEM certificate in %s", path)
+	}
+	return certs, nil
+}
+
+func expiryText(notAfter, now time.Time) string {
+	left := notAfter.Sub(now)
+	switch {
+	case left <= 0:
+		return "expired"
+	case left < 48*time.Hour:
+		return fmt.Sprintf("expires in %s", left.Truncate(time.Minute))
+	}
+	return fmt.Sprintf("expires in %d days", int(left/(24*time.Hour)))
+}
+
+func describeCertKey(pub interface{}) string {
+	switch k := pub.(type) {
+	case *ecdsa.PublicKey:
+		return "ECDSA " + k.Curve.Params().Name
+	case *rsa.PublicKey:
+		return fmt.Sprintf("RSA %d", k.N.BitLen())
+	case ed25519.PublicKey:
+		return "Ed25519"
+	}
+	return fmt.Sprintf("%T", pub)
+}
+
+func extKeyUsageNames(usages []x509.ExtKeyUsage) string {
+	var names []string
+	for _, u := range usages {
+		switch u {
+		case x509.ExtKeyUsageServerAuth:
+			names = append(names, "server")
+		case x509.ExtKeyUsageClientAuth:
+			names = append(names, "client")
+		case x509.ExtKeyUsageAny:
+			names = append(names, "any")
+		default:
+			names = append(names, fmt.Sprintf("%d", u))
+		}
+	}
+	return strings.Join(names, ",")
+}
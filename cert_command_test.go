@@ -0,0 +1,195 @@
+package servion
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.arpabet.com/glue"
+)
+
+func TestCertCommands(t *testing.T) {
+	home := t.TempDir()
+	var out bytes.Buffer
+	ctx := context.Background()
+
+	initCA := &implCertInitCaCommand{HomeDir: home, Dir: "tls", Name: "Test Dev CA", Key: "ecdsa", Validity: "365d", out: &out}
+	if err := initCA.Run(ctx); err != nil {
+		t.Fatalf("init-ca: %v", err)
+	}
+	if err := initCA.Run(ctx); err == nil {
+		t.Error("init-ca over an existing CA must fail")
+	}
+	if fi, err := os.Stat(filepath.Join(home, "tls", "ca.key")); err != nil || fi.Mode().Perm() != 0600 {
+		t.Errorf("CA key mode = %v, %v", fi, err)
+	}
+
+	issue := func(name, usage, dns, spiffe, key string) error {
+		cmd := &implCertIssueCommand{HomeDir: home, Dir: "tls", Name: name, DNS: dns, IP: "127.0.0.1",
+			Spiffe: spiffe, Usage: usage, Key: key, Validity: "90d", out: &out}
+		return cmd.Run(ctx)
+	}
+	if err := issue("api", "server", "localhost", "", "ecdsa"); err != nil {
+		t.Fatalf("issue server: %v", err)
+	}
+	if err := issue("billing", "client", "", "spiffe://dev.local/billing", "ed25519"); err != nil {
+		t.Fatalf("issue client: %v", err)
+	}
+
+	// the files load as they are into TLS profiles and complete a mutual handshake
+	props := glue.NewProperties()
+	props.Set("tls.server.cert-file", "tls/api.crt")
+	props.Set("tls.server.key-file", "tls/api.key")
+	props.Set("tls.server.ca-file", "tls/ca.crt")
+	props.Set("tls.server.client-auth", "require-and-verify")
+	props.Set("tls.client.cert-file", "tls/billing.crt")
+	props.Set("tls.client.key-file", "tls/billing.key")
+	props.Set("tls.client.ca-file", "tls/ca.crt")
+	props.Set("tls.client.server-name", "localhost")
+	profiles := &implTlsProfiles{Runtime: &homeRuntime{mockRuntime: newMockRuntime(true), home: home},
+		Properties: props, Names: []string{"server", "client"}}
+	if err := profiles.PostConstruct(); err != nil {
+		t.Fatalf("TLS profiles: %v", err)
+	}
+	defer profiles.Destroy()
+	serverCfg, _ := profiles.ServerConfig("server")
+	clientCfg, _ := profiles.ClientConfig("client")
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", serverCfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	peer := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			peer <- err.Error()
+			return
+		}
+		defer conn.Close()
+		tc := conn.(*tls.Conn)
+		if err := tc.Handshake(); err != nil {
+			peer <- err.Error()
+			return
+		}
+		peer <- tc.ConnectionState().VerifiedChains[0][0].URIs[0].String()
+	}()
+	conn, err := tls.Dial("tcp", ln.Addr().String(), clientCfg)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	conn.Close()
+	if got := <-peer; got != "spiffe://dev.local/billing" {
+		t.Errorf("server saw %q, want the SPIFFE ID", got)
+	}
+
+	out.Reset()
+	inspect := &implCertInspectCommand{HomeDir: home, File: "tls/api.crt", CA: "tls/ca.crt", out: &out}
+	if err := inspect.Run(ctx); err != nil {
+		t.Fatalf("inspect: %v", err)
+	}
+	for _, want := range []string{"subject      CN=api", "issuer       CN=Test Dev CA", "usage        server",
+		"dns          localhost", "ip           127.0.0.1", "expires in 89 days", "certificate 1", "ca           yes", "verify: ok"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("inspect output lacks %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestCertCommands_Rejections(t *testing.T) {
+	home := t.TempDir()
+	var out bytes.Buffer
+	ctx := context.Background()
+
+	issue := &implCertIssueCommand{HomeDir: home, Dir: "tls", Name: "api", Usage: "server", Key: "ecdsa", Validity: "90d", out: &out}
+	if err := issue.Run(ctx); err == nil || !strings.Contains(err.Error(), "init-ca") {
+		t.Errorf("issue without a CA: %v", err)
+	}
+
+	initCA := &implCertInitCaCommand{HomeDir: home, Dir: "tls", Name: "CA", Key: "dsa", Validity: "365d", out: &out}
+	if err := initCA.Run(ctx); err == nil {
+		t.Error("expected error for an unknown key type")
+	}
+	initCA.Key = "rsa"
+	if err := initCA.Run(ctx); err != nil {
+		t.Fatalf("init-ca: %v", err)
+	}
+
+	cases := map[string]func(c *implCertIssueCommand){
+		"no name":      func(c *implCertIssueCommand) { c.Name = "" },
+		"path name":    func(c *implCertIssueCommand) { c.Name = "../api" },
+		"ca name":      func(c *implCertIssueCommand) { c.Name = "ca" },
+		"bad ip":       func(c *implCertIssueCommand) { c.IP = "localhost" },
+		"bad spiffe":   func(c *implCertIssueCommand) { c.Spiffe = "https://dev.local/billing" },
+		"bad usage":    func(c *implCertIssueCommand) { c.Usage = "signing" },
+		"bad validity": func(c *implCertIssueCommand) { c.Validity = "-1d" },
+		"unknown key":  func(c *implCertIssueCommand) { c.Key = "dsa" },
+		"empty usage":  func(c *implCertIssueCommand) { c.Usage = "" },
+	}
+	for name, mutate := range cases {
+		t.Run(name, func(t *testing.T) {
+			c := &implCertIssueCommand{HomeDir: home, Dir: "tls", Name: "api", Usage: "server", Key: "ecdsa", Validity: "90d", out: &out}
+			mutate(c)
+			if err := c.Run(ctx); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+
+	// validity is capped by the CA
+	issue.Validity = "10000d"
+	if err := issue.Run(ctx); err != nil {
+		t.Fatalf("issue: %v", err)
+	}
+	certs, err := readCertificates(filepath.Join(home, "tls", "api.crt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if certs[0].NotAfter.After(certs[1].NotAfter) || certs[0].NotAfter.Before(time.Now().Add(300*24*time.Hour)) {
+		t.Errorf("not after %s, CA %s", certs[0].NotAfter, certs[1].NotAfter)
+	}
+	// a server certificate without names is issued for its name
+	if len(certs[0].DNSNames) != 1 || certs[0].DNSNames[0] != "api" {
+		t.Errorf("DNS names = %v", certs[0].DNSNames)
+	}
+
+	// existing files are only replaced with --force
+	issue.Validity = "90d"
+	if err := issue.Run(ctx); err == nil || !strings.Contains(err.Error(), "--force") {
+		t.Errorf("issue over an existing certificate: %v", err)
+	}
+	issue.Force = true
+	if err := issue.Run(ctx); err != nil {
+		t.Fatalf("issue --force: %v", err)
+	}
+	if certs, err = readCertificates(filepath.Join(home, "tls", "api.crt")); err != nil {
+		t.Fatal(err)
+	}
+	if certs[0].NotAfter.After(time.Now().Add(91 * 24 * time.Hour)) {
+		t.Errorf("certificate not replaced, not after %s", certs[0].NotAfter)
+	}
+
+	other := t.TempDir()
+	otherCA := &implCertInitCaCommand{HomeDir: other, Dir: ".", Name: "Other", Key: "ecdsa", Validity: "365d", out: &out}
+	if err := otherCA.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	inspect := &implCertInspectCommand{HomeDir: home, File: "tls/api.crt", CA: filepath.Join(other, "ca.crt"), out: &out}
+	if err := inspect.Run(ctx); err == nil {
+		t.Error("chain verified against a foreign CA")
+	}
+}
+
+// homeRuntime is a mockRuntime with its own home directory.
+type homeRuntime struct {
+	*mockRuntime
+	home string
+}
+
+func (r *homeRuntime) HomeDir() string { return r.home }
@@ -0,0 +1,215 @@
+/*
+ * Copyright (c) 2026 Karagatan LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	flag "github.com/spf13/pflag"
+	"golang.org/x/xerrors"
+)
+
+// jsonWebKey is a public key of a JSON Web Key Set, RFC 7517.
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+type jsonWebKeySet struct {
+	Keys []jsonWebKey `json:"keys"`
+}
+
+// toJWK describes the public half of key as a signature JWK; the kid defaults to its thumbprint.
+func toJWK(key interface{}, kid, alg string) (jsonWebKey, error) {
+	var jwk jsonWebKey
+	switch k := publicOf(key).(type) {
+	case *ecdsa.PublicKey:
+		size := (k.Curve.Params().BitSize + 7) / 8
+		jwk = jsonWebKey{
+			Kty: "EC",
+			Crv: k.Curve.Params().Name,
+			X:   base64.RawURLEncoding.EncodeToString(k.X.FillBytes(make([]byte, size))),
+			Y:   base64.RawURLEncoding.EncodeToString(k.Y.FillBytes(make([]byte, size))),
+		}
+	case *rsa.PublicKey:
+		jwk = jsonWebKey{
+			Kty: "RSA",
+			N:   base64.RawURLEncoding.EncodeToString(k.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.E)).Bytes()),
+		}
+	case ed25519.PublicKey:
+		jwk = jsonWebKey{Kty: "OKP", Crv: "Ed25519", X: base64.RawURLEncoding.EncodeToString(k)}
+	default:
+		return jwk, xerrors.Errorf("unsupported key %s", describeKey(key))
+	}
+	method, err := signingMethodFor(key, alg)
+	if err != nil {
+		return jwk, err
+	}
+	jwk.Use, jwk.Alg, jwk.Kid = "sig", method.Alg(), kid
+	if jwk.Kid == "" {
+		jwk.Kid = jwk.thumbprint()
+	}
+	return jwk, nil
+}
+
+// thumbprint is the JWK thumbprint of RFC 7638, base64url SHA-256 over the required members.
+func (k jsonWebKey) thumbprint() string {
+	var members string
+	switch k.Kty {
+	case "EC":
+		members = fmt.Sprintf(`{"crv":%q,"kty":"EC","x":%q,"y":%q}`, k.Crv, k.X, k.Y)
+	case "RSA":
+		members = fmt.Sprintf(`{"e":%q,"kty":"RSA","n":%q}`, k.E, k.N)
+	case "OKP":
+		members = fmt.Sprintf(`{"crv":%q,"kty":"OKP","x":%q}`, k.Crv, k.X)
+	}
+	sum := sha256.Sum256([]byte(members))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// keyThumbprint returns the RFC 7638 thumbprint of the public half of key, empty for unsupported keys.
+func keyThumbprint(key interface{}) string {
+	jwk, err := toJWK(key, "", "")
+	if err != nil {
+		return ""
+	}
+	return jwk.Kid
+}
+
+// publicKey parses the JWK, refusing what JwtAuthProvider would refuse.
+func (k jsonWebKey) publicKey() (interface{}, error) {
+	decode := func(s string) ([]byte, error) {
+		if s == "" {
+			return nil, xerrors.New("missing value")
+		}
+		return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	}
+	switch k.Kty {
+	case "RSA":
+		n, err := decode(k.N)
+		if err != nil {
+			return nil, xerrors.Errorf("key %q: modulus: %w", k.Kid, err)
+		}
+		e, err := decode(k.E)
+		if err != nil {
+			return nil, xerrors.Errorf("key %q: exponent: %w", k.Kid, err)
+		}
+		pub := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		if pub.N.BitLen() < minRSAKeyBits {
+			return nil, xerrors.Errorf("key %q: RSA key of %d bits is too short", k.Kid, pub.N.BitLen())
+		}
+		return pub, nil
+	case "EC":
+		curve, err := curveByName(k.Crv)
+		if err != nil {
+			return nil, xerrors.Errorf("key %q: %w", k.Kid, err)
+		}
+		x, errX := decode(k.X)
+		y, errY := decode(k.Y)
+		if errX != nil || errY != nil {
+			return nil, xerrors.Errorf("key %q: invalid coordinates", k.Kid)
+		}
+		pub := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(pub.X, pub.Y) {
+			return nil, xerrors.Errorf("key %q: point is not on curve %s", k.Kid, k.Crv)
+		}
+		return pub, nil
+	case "OKP":
+		x, err := decode(k.X)
+		if k.Crv != "Ed25519" || err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, xerrors.Errorf("key %q: invalid Ed25519 key", k.Kid)
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, xerrors.Errorf("key %q: unsupported key type %q", k.Kid, k.Kty)
+}
+
+// readJWKS reads a JSON Web Key Set file, keeping the signature keys.
+func readJWKS(path string) ([]jsonWebKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var set jsonWebKeySet
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, xerrors.Errorf("invalid JWKS %s: %w", path, err)
+	}
+	var keys []jsonWebKey
+	for _, k := range set.Keys {
+		if k.Use == "" || k.Use == "sig" {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == 0 {
+		return nil, xerrors.Errorf("JWKS %s has no signature key", path)
+	}
+	return keys, nil
+}
+
+// --- jwks ---
+
+func cmdJwks(args []string) {
+	fs := flag.NewFlagSet("jwks", flag.ExitOnError)
+	kid := fs.String("kid", "", "Key ID, only with a single key (default: RFC 7638 thumbprint)")
+	alg := fs.String("alg", "", "Algorithm of the keys, e.g. PS256 for RSA (default by key type)")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Output a JSON Web Key Set of public keys.
+
+Each key is a file, PEM text or base64 DER of a public key, certificate or
+private key; only public halves are written. Serve the output to
+JwtAuthProvider with jwt.jwks-file or jwt.jwks-url.
+
+Usage:
+  jwttool jwks [flags] <key>...
+
+Flags:
+`)
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, `
+Examples:
+  jwttool jwks current.pub next.pub > jwks.json
+`)
+	}
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(1)
+	}
+	if *kid != "" && fs.NArg() > 1 {
+		fatal("--kid applies to a single key")
+	}
+	set := jsonWebKeySet{Keys: []jsonWebKey{}}
+	for _, arg := range fs.Args() {
+		pub, err := parsePublicKey(arg)
+		if err != nil {
+			fatal("%s: %v", arg, err)
+		}
+		jwk, err := toJWK(pub, *kid, *alg)
+		if err != nil {
+			fatal("%s: %v", arg, err)
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	printJSON(set)
+}
@@ -0,0 +1,285 @@
+/*
+ * Copyright (c) 2026 Karagatan LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/xerrors"
+)
+
+// minRSAKeyBits matches the shortest RSA key JwtAuthProvider accepts from a JWKS.
+const minRSAKeyBits = 2048
+
+// generateKey creates a private key of type ec (with curve), rsa (with bits) or ed25519.
+func generateKey(keyType, curve string, bits int) (crypto.Signer, error) {
+	switch strings.ToLower(keyType) {
+	case "ec", "ecdsa":
+		c, err := curveByName(curve)
+		if err != nil {
+			return nil, err
+		}
+		return ecdsa.GenerateKey(c, rand.Reader)
+	case "rsa":
+		if bits < minRSAKeyBits {
+			return nil, xerrors.Errorf("RSA keys need at least %d bits, got %d", minRSAKeyBits, bits)
+		}
+		return rsa.GenerateKey(rand.Reader, bits)
+	case "ed25519", "eddsa":
+		_, priv, err := ed25519.GenerateKey(rand.Reader)
+		return priv, err
+	}
+	return nil, xerrors.Errorf("unknown key type %q, expected ec, rsa or ed25519", keyType)
+}
+
+func curveByName(name string) (elliptic.Curve, error) {
+	switch strings.ToUpper(name) {
+	case "P-256", "P256":
+		return elliptic.P256(), nil
+	case "P-384", "P384":
+		return elliptic.P384(), nil
+	case "P-521", "P521":
+		return elliptic.P521(), nil
+	}
+	return nil, xerrors.Errorf("unknown curve %q, expected P-256, P-384 or P-521", name)
+}
+
+// describeKey names the type of a public or private key, e.g. "ECDSA P-256" or "RSA 3072".
+func describeKey(key interface{}) string {
+	switch k := publicOf(key).(type) {
+	case *ecdsa.PublicKey:
+		return "ECDSA " + k.Curve.Params().Name
+	case *rsa.PublicKey:
+		return fmt.Sprintf("RSA %d", k.N.BitLen())
+	case ed25519.PublicKey:
+		return "Ed25519"
+	}
+	return fmt.Sprintf("%T", key)
+}
+
+// publicOf returns the public half of a private key, or key itself when it is public.
+func publicOf(key interface{}) interface{} {
+	switch k := key.(type) {
+	case *ecdsa.PrivateKey:
+		return &k.PublicKey
+	case *rsa.PrivateKey:
+		return &k.PublicKey
+	case ed25519.PrivateKey:
+		return k.Public()
+	}
+	return key
+}
+
+/*
+readKeyData returns the key material of a flag value: the content of the file it
+names, or the value itself, which is PEM text or base64 DER.
+*/
+func readKeyData(value string) ([]byte, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, xerrors.New("empty key")
+	}
+	if path, ok := strings.CutPrefix(value, "@"); ok {
+		return os.ReadFile(path)
+	}
+	if !strings.Contains(value, "-----BEGIN") {
+		if fi, err := os.Stat(value); err == nil && !fi.IsDir() {
+			return os.ReadFile(value)
+		}
+	}
+	return []byte(value), nil
+}
+
+// decodeKeyData returns the DER blocks of PEM data, or the decoded base64 DER.
+func decodeKeyData(data []byte) ([]*pem.Block, error) {
+	var blocks []*pem.Block
+	rest := data
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		blocks = append(blocks, block)
+	}
+	if len(blocks) > 0 {
+		return blocks, nil
+	}
+	der, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(string(data)), ""))
+	if err != nil {
+		return nil, xerrors.New("key is neither PEM nor base64 DER")
+	}
+	return []*pem.Block{{Type: "", Bytes: der}}, nil
+}
+
+// parsePrivateKey reads a PKCS#8, SEC 1 (EC PRIVATE KEY) or PKCS#1 (RSA PRIVATE KEY) private key.
+func parsePrivateKey(value string) (crypto.Signer, error) {
+	data, err := readKeyData(value)
+	if err != nil {
+		return nil, err
+	}
+	blocks, err := decodeKeyData(data)
+	if err != nil {
+		return nil, err
+	}
+	for _, block := range blocks {
+		var key interface{}
+		switch block.Type {
+		case "EC PRIVATE KEY":
+			key, err = x509.ParseECPrivateKey(block.Bytes)
+		case "RSA PRIVATE KEY":
+			key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+		case "PRIVATE KEY", "":
+			key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, xerrors.Errorf("parse private key: %w", err)
+		}
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, xerrors.Errorf("unsupported private key %T", key)
+		}
+		return signer, nil
+	}
+	return nil, xerrors.New("no private key found")
+}
+
+/*
+parsePublicKey reads a PKIX public key, the public key of a certificate, or the
+public half of a private key.
+*/
+func parsePublicKey(value string) (interface{}, error) {
+	data, err := readKeyData(value)
+	if err != nil {
+		return nil, err
+	}
+	blocks, err := decodeKeyData(data)
+	if err != nil {
+		return nil, err
+	}
+	for _, block := range blocks {
+		switch block.Type {
+		case "PUBLIC KEY", "":
+			if pub, err := x509.ParsePKIXPublicKey(block.Bytes); err == nil {
+				return pub, nil
+			} else if block.Type != "" {
+				return nil, xerrors.Errorf("parse public key: %w", err)
+			}
+			// base64 DER may be a private key as well
+			if priv, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+				return publicOf(priv), nil
+			}
+			return nil, xerrors.New("key is neither a PKIX public key nor a PKCS#8 private key")
+		case "RSA PUBLIC KEY":
+			return x509.ParsePKCS1PublicKey(block.Bytes)
+		case "CERTIFICATE":
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, xerrors.Errorf("parse certificate: %w", err)
+			}
+			return cert.PublicKey, nil
+		case "PRIVATE KEY", "EC PRIVATE KEY", "RSA PRIVATE KEY":
+			priv, err := parsePrivateKey(string(pem.EncodeToMemory(block)))
+			if err != nil {
+				return nil, err
+			}
+			return priv.Public(), nil
+		}
+	}
+	return nil, xerrors.New("no public key found")
+}
+
+// encodePrivateKey returns key as PKCS#8, in PEM or in base64 DER.
+func encodePrivateKey(key crypto.Signer, format string) (string, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return "", xerrors.Errorf("marshal private key: %w", err)
+	}
+	return encodeDER("PRIVATE KEY", der, format)
+}
+
+// encodePublicKey returns key as PKIX, in PEM or in base64 DER.
+func encodePublicKey(key interface{}, format string) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(publicOf(key))
+	if err != nil {
+		return "", xerrors.Errorf("marshal public key: %w", err)
+	}
+	return encodeDER("PUBLIC KEY", der, format)
+}
+
+func encodeDER(blockType string, der []byte, format string) (string, error) {
+	switch strings.ToLower(format) {
+	case "pem":
+		return string(pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})), nil
+	case "der", "base64", "":
+		return base64.StdEncoding.EncodeToString(der), nil
+	}
+	return "", xerrors.Errorf("unknown format %q, expected der or pem", format)
+}
+
+/*
+signingMethodFor returns the JWT algorithm for key: alg when given and fitting
+the key, else ES256/384/512 by curve, RS256 for RSA and EdDSA for Ed25519.
+*/
+func signingMethodFor(key interface{}, alg string) (jwt.SigningMethod, error) {
+	if alg != "" {
+		method := jwt.GetSigningMethod(alg)
+		if method == nil {
+			return nil, xerrors.Errorf("unknown algorithm %q", alg)
+		}
+		if !keyAccepts(publicOf(key), method) {
+			return nil, xerrors.Errorf("algorithm %s does not fit a %s key", alg, describeKey(key))
+		}
+		return method, nil
+	}
+	switch k := publicOf(key).(type) {
+	case *ecdsa.PublicKey:
+		switch k.Curve.Params().BitSize {
+		case 256:
+			return jwt.SigningMethodES256, nil
+		case 384:
+			return jwt.SigningMethodES384, nil
+		case 521:
+			return jwt.SigningMethodES512, nil
+		}
+	case *rsa.PublicKey:
+		return jwt.SigningMethodRS256, nil
+	case ed25519.PublicKey:
+		return jwt.SigningMethodEdDSA, nil
+	}
+	return nil, xerrors.Errorf("unsupported key %s", describeKey(key))
+}
+
+// keyAccepts reports whether the public key pub may verify tokens signed with method.
+func keyAccepts(pub interface{}, method jwt.SigningMethod) bool {
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		_, rs := method.(*jwt.SigningMethodRSA)
+		_, ps := method.(*jwt.SigningMethodRSAPSS)
+		return rs || ps
+	case *ecdsa.PublicKey:
+		m, ok := method.(*jwt.SigningMethodECDSA)
+		return ok && m.CurveBits == k.Curve.Params().BitSize
+	case ed25519.PublicKey:
+		_, ok := method.(*jwt.SigningMethodEd25519)
+		return ok
+	}
+	return false
+}
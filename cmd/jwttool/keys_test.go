@@ -0,0 +1,135 @@
+/*
+ * Copyright (c) 2026 Karagatan LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestKeyRoundTrip(t *testing.T) {
+	for _, c := range []struct {
+		keyType, curve string
+		bits           int
+		alg            string
+	}{
+		{"ec", "P-256", 0, "ES256"},
+		{"ec", "P-384", 0, "ES384"},
+		{"rsa", "", 2048, "RS256"},
+		{"ed25519", "", 0, "EdDSA"},
+	} {
+		priv, err := generateKey(c.keyType, c.curve, c.bits)
+		if err != nil {
+			t.Fatalf("%s: %v", c.keyType, err)
+		}
+		for _, format := range []string{"der", "pem"} {
+			privText, err := encodePrivateKey(priv, format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pubText, err := encodePublicKey(priv.Public(), format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			parsed, err := parsePrivateKey(privText)
+			if err != nil {
+				t.Fatalf("%s %s private: %v", c.keyType, format, err)
+			}
+			pub, err := parsePublicKey(pubText)
+			if err != nil {
+				t.Fatalf("%s %s public: %v", c.keyType, format, err)
+			}
+			if keyThumbprint(parsed) != keyThumbprint(priv) || keyThumbprint(pub) != keyThumbprint(priv) {
+				t.Errorf("%s %s: key changed in the round trip", c.keyType, format)
+			}
+			// the public key of a private key
+			if pub, err := parsePublicKey(privText); err != nil || keyThumbprint(pub) != keyThumbprint(priv) {
+				t.Errorf("%s %s: public key of private key: %v", c.keyType, format, err)
+			}
+		}
+		method, err := signingMethodFor(priv, "")
+		if err != nil || method.Alg() != c.alg {
+			t.Errorf("%s: algorithm %v, %v; want %s", c.keyType, method, err, c.alg)
+		}
+	}
+}
+
+func TestParseKeys_FilesAndLegacyFormats(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, blockType string, der []byte) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	ec, _ := generateKey("ec", "P-256", 0)
+	sec1, err := x509.MarshalECPrivateKey(ec.(*ecdsa.PrivateKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey, _ := generateKey("rsa", "", 2048)
+	pkcs1 := x509.MarshalPKCS1PrivateKey(rsaKey.(*rsa.PrivateKey))
+
+	for name, c := range map[string]struct {
+		value string
+		key   crypto.Signer
+	}{
+		"sec1 file":  {write("ec.pem", "EC PRIVATE KEY", sec1), ec},
+		"pkcs1 file": {"@" + write("rsa.pem", "RSA PRIVATE KEY", pkcs1), rsaKey},
+		"rsa public": {write("rsa.pub", "RSA PUBLIC KEY", x509.MarshalPKCS1PublicKey(rsaKey.Public().(*rsa.PublicKey))), rsaKey},
+	} {
+		pub, err := parsePublicKey(c.value)
+		if err != nil || keyThumbprint(pub) != keyThumbprint(c.key) {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+	if _, err := parsePrivateKey(filepath.Join(dir, "ec.pem")); err != nil {
+		t.Errorf("SEC 1 private key: %v", err)
+	}
+
+	if _, err := generateKey("rsa", "", 1024); err == nil {
+		t.Error("1024 bit RSA key accepted")
+	}
+	if _, err := parsePrivateKey("not a key"); err == nil {
+		t.Error("garbage accepted as a key")
+	}
+}
+
+func TestJWKRoundTrip(t *testing.T) {
+	for _, keyType := range []string{"ec", "rsa", "ed25519"} {
+		priv, _ := generateKey(keyType, "P-521", 2048)
+		jwk, err := toJWK(priv, "", "")
+		if err != nil {
+			t.Fatalf("%s: %v", keyType, err)
+		}
+		if jwk.Kid != keyThumbprint(priv) || jwk.Use != "sig" || jwk.Alg == "" {
+			t.Errorf("%s: %+v", keyType, jwk)
+		}
+		pub, err := jwk.publicKey()
+		if err != nil || keyThumbprint(pub) != jwk.Kid {
+			t.Errorf("%s: parse JWK: %v", keyType, err)
+		}
+	}
+
+	// the thumbprint example of RFC 7638 section 3.1
+	jwk := jsonWebKey{
+		Kty: "RSA",
+		E:   "AQAB",
+		N: "0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbI" +
+			"SD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw",
+	}
+	if tp := jwk.thumbprint(); tp != "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs" {
+		t.Errorf("thumbprint %s", tp)
+	}
+}
@@ -7,11 +7,6 @@ package main
 
 import (
 	"bufio"
-	"crypto/ecdsa"
-	"crypto/elliptic"
-	"crypto/rand"
-	"crypto/x509"
-	"encoding/base64"
 	"fmt"
 	"os"
 	"strings"
@@ -33,6 +28,16 @@ func main() {
 		cmdGenerateKeys(os.Args[2:])
 	case "generate-token":
 		cmdGenerateToken(os.Args[2:])
+	case "decode":
+		cmdDecode(os.Args[2:])
+	case "verify":
+		cmdVerify(os.Args[2:])
+	case "jwks":
+		cmdJwks(os.Args[2:])
+	case "convert":
+		cmdConvert(os.Args[2:])
+	case "serve":
+		cmdServe(os.Args[2:])
 	case "help", "--help", "-h":
 		printUsage()
 	default:
@@ -49,8 +54,13 @@ Usage:
   jwttool <command> [flags]
 
 Commands:
-  generate-keys    Generate an ECDSA P-256 key pair (base64 DER)
+  generate-keys    Generate an ECDSA, RSA or Ed25519 key pair (base64 DER or PEM)
   generate-token   Generate a signed JWT token
+  decode           Print the header and claims of a token without verifying it
+  verify           Verify a token with a secret, key or JWKS and report why it fails
+  jwks             Output a JSON Web Key Set of public keys
+  convert          Convert a key between PEM and base64 DER
+  serve            Run a local OpenID Connect identity provider for development
 
 Run "jwttool <command> --help" for details.
 `)
@@ -60,43 +70,124 @@ Run "jwttool <command> --help" for details.
 
 func cmdGenerateKeys(args []string) {
 	fs := flag.NewFlagSet("generate-keys", flag.ExitOnError)
+	keyType := fs.StringP("type", "t", "ec", "Key type: ec, rsa or ed25519")
+	curve := fs.String("curve", "P-256", "Curve of ec keys: P-256, P-384 or P-521")
+	bits := fs.Int("bits", 3072, "Size of rsa keys, at least 2048")
+	format := fs.StringP("format", "f", "der", "Output format: der (base64) or pem")
+	out := fs.StringP("out", "o", "", "Write <out>.key and <out>.pub instead of printing")
 	fs.Usage = func() {
-		fmt.Fprintf(os.Stderr, `Generate an ECDSA P-256 key pair.
+		fmt.Fprintf(os.Stderr, `Generate a key pair for signing JWT tokens.
 
-Outputs the private key and public key as base64-encoded DER strings,
-ready to use in Servion configuration (jwt.public-key property).
+Outputs the private key (PKCS#8) and public key (PKIX) as base64-encoded DER
+strings, ready to use in Servion configuration (jwt.public-key property), or
+as PEM. Use "jwttool jwks" to publish public keys as a JWK Set.
 
 Usage:
-  jwttool generate-keys
+  jwttool generate-keys [flags]
 
+Flags:
+`)
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, `
+Examples:
+  jwttool generate-keys
+  jwttool generate-keys -t rsa --bits 4096 -f pem -o signing
+  jwttool generate-keys -t ed25519
 `)
 	}
 	fs.Parse(args)
 
-	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	priv, err := generateKey(*keyType, *curve, *bits)
 	if err != nil {
 		fatal("generate key: %v", err)
 	}
-
-	privDER, err := x509.MarshalPKCS8PrivateKey(priv)
+	privText, err := encodePrivateKey(priv, *format)
 	if err != nil {
-		fatal("marshal private key: %v", err)
+		fatal("%v", err)
 	}
-
-	pubDER, err := x509.MarshalPKIXPublicKey(&priv.PublicKey)
+	pubText, err := encodePublicKey(priv.Public(), *format)
 	if err != nil {
-		fatal("marshal public key: %v", err)
+		fatal("%v", err)
 	}
 
-	privB64 := base64.StdEncoding.EncodeToString(privDER)
-	pubB64 := base64.StdEncoding.EncodeToString(pubDER)
+	if *out != "" {
+		if err := os.WriteFile(*out+".key", []byte(strings.TrimSpace(privText)+"\n"), 0600); err != nil {
+			fatal("write private key: %v", err)
+		}
+		if err := os.WriteFile(*out+".pub", []byte(strings.TrimSpace(pubText)+"\n"), 0644); err != nil {
+			fatal("write public key: %v", err)
+		}
+		fmt.Printf("# %s key pair, kid %s\n", describeKey(priv), keyThumbprint(priv))
+		fmt.Printf("private key: %s.key\npublic key:  %s.pub\n", *out, *out)
+		return
+	}
 
-	fmt.Println("# ECDSA P-256 key pair")
+	fmt.Printf("# %s key pair, kid %s\n", describeKey(priv), keyThumbprint(priv))
 	fmt.Println("# Keep the private key secret. Use the public key in jwt.public-key property.")
 	fmt.Println()
-	fmt.Printf("private-key=%s\n", privB64)
+	if strings.EqualFold(*format, "pem") {
+		fmt.Print(privText)
+		fmt.Println()
+		fmt.Print(pubText)
+		return
+	}
+	fmt.Printf("private-key=%s\n", privText)
 	fmt.Println()
-	fmt.Printf("public-key=%s\n", pubB64)
+	fmt.Printf("public-key=%s\n", pubText)
+}
+
+// --- convert ---
+
+func cmdConvert(args []string) {
+	fs := flag.NewFlagSet("convert", flag.ExitOnError)
+	format := fs.StringP("format", "f", "pem", "Output format: der (base64) or pem")
+	public := fs.Bool("public", false, "Output the public key of a private key")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Convert a key between PEM and base64 DER.
+
+Reads PKCS#8, SEC 1 and PKCS#1 private keys, PKIX public keys and
+certificates, from a file, PEM text or base64 DER. Private keys are written as
+PKCS#8, public keys as PKIX.
+
+Usage:
+  jwttool convert [flags] <key>
+
+Flags:
+`)
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, `
+Examples:
+  jwttool convert -f der signing.pem          # for jwt.public-key
+  jwttool convert --public idp-key.pem > idp.pub
+`)
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	var (
+		text string
+		err  error
+	)
+	if priv, perr := parsePrivateKey(fs.Arg(0)); perr == nil {
+		if *public {
+			text, err = encodePublicKey(priv.Public(), *format)
+		} else {
+			text, err = encodePrivateKey(priv, *format)
+		}
+	} else {
+		pub, perr := parsePublicKey(fs.Arg(0))
+		if perr != nil {
+			fatal("%v", perr)
+		}
+		text, err = encodePublicKey(pub, *format)
+	}
+	if err != nil {
+		fatal("%v", err)
+	}
+	fmt.Println(strings.TrimSpace(text))
 }
 
 // --- generate-token ---
@@ -104,8 +195,10 @@ Usage:
 func cmdGenerateToken(args []string) {
 	fs := flag.NewFlagSet("generate-token", flag.ExitOnError)
 
-	privateKey := fs.StringP("private-key", "k", "", "ECDSA private key (base64 DER)")
+	privateKey := fs.StringP("private-key", "k", "", "ECDSA, RSA or Ed25519 private key: file, PEM or base64 DER")
 	secret := fs.String("secret", "", "HMAC shared secret (alternative to --private-key)")
+	alg := fs.String("alg", "", "Signing algorithm, e.g. PS256 or RS512 (default by key type)")
+	kid := fs.String("kid", "", "Key ID header (default: RFC 7638 thumbprint of the key)")
 	subject := fs.StringP("subject", "s", "", "Subject claim (sub)")
 	issuer := fs.StringP("issuer", "i", "", "Issuer claim (iss)")
 	audience := fs.StringP("audience", "a", "", "Audience claim (aud)")
@@ -118,7 +211,8 @@ func cmdGenerateToken(args []string) {
 	fs.Usage = func() {
 		fmt.Fprintf(os.Stderr, `Generate a signed JWT token.
 
-Supports ECDSA (--private-key) or HMAC (--secret) signing.
+Supports ECDSA, RSA and Ed25519 (--private-key) or HMAC (--secret) signing.
+Tokens signed with a key carry its thumbprint as kid, matching "jwttool jwks".
 Use --interactive to be prompted for values.
 
 Usage:
@@ -132,6 +226,9 @@ Examples:
   # ECDSA token
   jwttool generate-token -k <base64-private-key> -s user@example.com -r admin,editor -e 24h
 
+  # RSA-PSS token with a key file
+  jwttool generate-token -k signing.key --alg PS256 -s user@example.com
+
   # HMAC token
   jwttool generate-token --secret my-secret -s service-account -e 8760h
 
@@ -145,10 +242,10 @@ Examples:
 
 	if *interactive {
 		if *privateKey == "" && *secret == "" {
-			fmt.Print("Signing method (ecdsa/hmac) [ecdsa]: ")
+			fmt.Print("Signing method (key/hmac) [key]: ")
 			method := readLine(reader)
-			if method == "" || method == "ecdsa" {
-				fmt.Print("Private key (base64): ")
+			if method == "" || method == "key" || method == "ecdsa" {
+				fmt.Print("Private key (file or base64): ")
 				*privateKey = readLine(reader)
 			} else {
 				fmt.Print("HMAC secret: ")
@@ -230,9 +327,9 @@ Examples:
 
 	var tokenStr string
 	if *privateKey != "" {
-		tokenStr, err = signECDSA(*privateKey, claims)
+		tokenStr, err = signWithKey(*privateKey, *alg, *kid, claims)
 	} else {
-		tokenStr, err = signHMAC(*secret, claims)
+		tokenStr, err = signHMAC(*secret, *alg, claims)
 	}
 	if err != nil {
 		fatal("sign token: %v", err)
@@ -241,26 +338,34 @@ Examples:
 	fmt.Println(tokenStr)
 }
 
-func signECDSA(privB64 string, claims jwt.MapClaims) (string, error) {
-	der, err := base64.StdEncoding.DecodeString(privB64)
+// signWithKey signs claims with a private key, by default with the algorithm of its type and its thumbprint as kid.
+func signWithKey(keyValue, alg, kid string, claims jwt.MapClaims) (string, error) {
+	key, err := parsePrivateKey(keyValue)
 	if err != nil {
-		return "", xerrors.Errorf("base64 decode private key: %w", err)
+		return "", err
 	}
-	key, err := x509.ParsePKCS8PrivateKey(der)
+	method, err := signingMethodFor(key, alg)
 	if err != nil {
-		return "", xerrors.Errorf("parse private key: %w", err)
+		return "", err
 	}
-	ecKey, ok := key.(*ecdsa.PrivateKey)
-	if !ok {
-		return "", xerrors.Errorf("key is not ECDSA, got %T", key)
+	token := jwt.NewWithClaims(method, claims)
+	if kid == "" {
+		kid = keyThumbprint(key)
 	}
-
-	token := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
-	return token.SignedString(ecKey)
+	token.Header["kid"] = kid
+	return token.SignedString(key)
 }
 
-func signHMAC(secret string, claims jwt.MapClaims) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+func signHMAC(secret, alg string, claims jwt.MapClaims) (string, error) {
+	var method jwt.SigningMethod = jwt.SigningMethodHS256
+	if alg != "" {
+		hs, ok := jwt.GetSigningMethod(alg).(*jwt.SigningMethodHMAC)
+		if !ok {
+			return "", xerrors.Errorf("algorithm %q does not fit a secret, expected HS256, HS384 or HS512", alg)
+		}
+		method = hs
+	}
+	token := jwt.NewWithClaims(method, claims)
 	return token.SignedString([]byte(secret))
 }
 
@@ -0,0 +1,512 @@
+/*
+ * Copyright (c) 2026 Karagatan LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	flag "github.com/spf13/pflag"
+	"golang.org/x/xerrors"
+)
+
+// authCodeTTL bounds the time between the login and the code exchange.
+const authCodeTTL = time.Minute
+
+// devClient is an OAuth client of the dev issuer; an empty secret makes it a public client, which must use PKCE.
+type devClient struct {
+	id     string
+	secret string
+	roles  []string
+}
+
+type authCode struct {
+	clientID      string
+	redirectURI   string
+	codeChallenge string
+	nonce         string
+	scope         string
+	subject       string
+	roles         []string
+	expires       time.Time
+}
+
+/*
+devIssuer is a local OpenID Connect provider for development and integration
+tests. It signs with one key and accepts any user name on its login page.
+*/
+type devIssuer struct {
+	issuer   string
+	audience string
+	ttl      time.Duration
+	key      crypto.Signer
+	method   jwt.SigningMethod
+	jwk      jsonWebKey
+	clients  map[string]*devClient
+
+	mu    sync.Mutex
+	codes map[string]*authCode
+}
+
+func newDevIssuer(issuer, audience string, ttl time.Duration, key crypto.Signer, alg string, clients []*devClient) (*devIssuer, error) {
+	method, err := signingMethodFor(key, alg)
+	if err != nil {
+		return nil, err
+	}
+	jwk, err := toJWK(key, "", method.Alg())
+	if err != nil {
+		return nil, err
+	}
+	t := &devIssuer{
+		issuer:   strings.TrimSuffix(issuer, "/"),
+		audience: audience,
+		ttl:      ttl,
+		key:      key,
+		method:   method,
+		jwk:      jwk,
+		clients:  make(map[string]*devClient),
+		codes:    make(map[string]*authCode),
+	}
+	for _, c := range clients {
+		t.clients[c.id] = c
+	}
+	return t, nil
+}
+
+// parseDevClient parses "id:secret[:role,role]"; "id:" is a public client.
+func parseDevClient(s string) (*devClient, error) {
+	parts := strings.SplitN(s, ":", 3)
+	if len(parts) < 2 || parts[0] == "" {
+		return nil, xerrors.Errorf("invalid client %q, expected id:secret[:roles]", s)
+	}
+	c := &devClient{id: parts[0], secret: parts[1]}
+	if len(parts) == 3 {
+		c.roles = splitTrim(parts[2])
+	}
+	return c, nil
+}
+
+func (t *devIssuer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/.well-known/openid-configuration":
+		t.discovery(w, r)
+	case "/jwks.json":
+		writeJSON(w, http.StatusOK, jsonWebKeySet{Keys: []jsonWebKey{t.jwk}})
+	case "/authorize":
+		t.authorize(w, r)
+	case "/token":
+		t.token(w, r)
+	case "/logout":
+		t.logout(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (t *devIssuer) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                t.issuer,
+		"authorization_endpoint":                t.issuer + "/authorize",
+		"token_endpoint":                        t.issuer + "/token",
+		"jwks_uri":                              t.issuer + "/jwks.json",
+		"end_session_endpoint":                  t.issuer + "/logout",
+		"response_types_supported":              []string{"code"},
+		"grant_types_supported":                 []string{"authorization_code", "client_credentials"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{t.method.Alg()},
+		"code_challenge_methods_supported":      []string{"S256"},
+		"token_endpoint_auth_methods_supported": []string{"client_secret_basic", "client_secret_post", "none"},
+		"scopes_supported":                      []string{"openid", "profile", "email"},
+	})
+}
+
+var loginPage = template.Must(template.New("login").Parse(`<!DOCTYPE html>
+<html><head><title>jwttool dev login</title></head>
+<body style="font-family: sans-serif; max-width: 28em; margin: 4em auto">
+<h2>Sign in to {{.ClientID}}</h2>
+<p>Development identity provider, any user name is accepted.</p>
+<form method="post">
+{{range $k, $v := .Params}}<input type="hidden" name="{{$k}}" value="{{$v}}">
+{{end}}<p><label>User name<br><input name="username" autofocus required></label></p>
+<p><label>Roles (comma separated)<br><input name="roles"></label></p>
+<p><button type="submit">Sign in</button></p>
+</form>
+</body></html>
+`))
+
+// authorize shows the login page and, when it is posted, redirects back with a code.
+func (t *devIssuer) authorize(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	client := t.clients[r.Form.Get("client_id")]
+	if client == nil {
+		http.Error(w, "unknown client_id", http.StatusBadRequest)
+		return
+	}
+	redirectURI := r.Form.Get("redirect_uri")
+	if u, err := url.Parse(redirectURI); err != nil || !u.IsAbs() {
+		http.Error(w, "redirect_uri must be an absolute URL", http.StatusBadRequest)
+		return
+	}
+	if r.Form.Get("response_type") != "code" {
+		redirectError(w, r, redirectURI, "unsupported_response_type")
+		return
+	}
+	challenge := r.Form.Get("code_challenge")
+	if challenge != "" && r.Form.Get("code_challenge_method") != "S256" {
+		redirectError(w, r, redirectURI, "invalid_request")
+		return
+	}
+	if challenge == "" && client.secret == "" {
+		// public clients have nothing but PKCE to bind the code to
+		redirectError(w, r, redirectURI, "invalid_request")
+		return
+	}
+
+	username := strings.TrimSpace(r.PostForm.Get("username"))
+	if r.Method != http.MethodPost || username == "" {
+		params := make(map[string]string)
+		for _, k := range []string{"client_id", "redirect_uri", "response_type", "scope", "state", "nonce", "code_challenge", "code_challenge_method"} {
+			if v := r.Form.Get(k); v != "" {
+				params[k] = v
+			}
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		loginPage.Execute(w, map[string]interface{}{"ClientID": client.id, "Params": params})
+		return
+	}
+
+	code := randomString()
+	t.mu.Lock()
+	now := time.Now()
+	for k, c := range t.codes {
+		if now.After(c.expires) {
+			delete(t.codes, k)
+		}
+	}
+	t.codes[code] = &authCode{
+		clientID:      client.id,
+		redirectURI:   redirectURI,
+		codeChallenge: challenge,
+		nonce:         r.Form.Get("nonce"),
+		scope:         r.Form.Get("scope"),
+		subject:       username,
+		roles:         splitTrim(r.PostForm.Get("roles")),
+		expires:       now.Add(authCodeTTL),
+	}
+	t.mu.Unlock()
+
+	q := url.Values{"code": {code}}
+	if state := r.Form.Get("state"); state != "" {
+		q.Set("state", state)
+	}
+	http.Redirect(w, r, appendQuery(redirectURI, q), http.StatusFound)
+}
+
+func (t *devIssuer) token(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		tokenError(w, http.StatusBadRequest, "invalid_request")
+		return
+	}
+	client, authenticated := t.clientOf(r)
+	if client == nil {
+		tokenError(w, http.StatusUnauthorized, "invalid_client")
+		return
+	}
+
+	switch r.PostForm.Get("grant_type") {
+	case "client_credentials":
+		if !authenticated || client.secret == "" {
+			tokenError(w, http.StatusUnauthorized, "invalid_client")
+			return
+		}
+		scope := r.PostForm.Get("scope")
+		access, err := t.sign(t.claims(client.id, t.audience, client.id, scope, client.roles))
+		if err != nil {
+			tokenError(w, http.StatusInternalServerError, "server_error")
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"access_token": access,
+			"token_type":   "Bearer",
+			"expires_in":   int(t.ttl.Seconds()),
+			"scope":        scope,
+		})
+
+	case "authorization_code":
+		t.mu.Lock()
+		code := t.codes[r.PostForm.Get("code")]
+		delete(t.codes, r.PostForm.Get("code"))
+		t.mu.Unlock()
+		if code == nil || time.Now().After(code.expires) || code.clientID != client.id || code.redirectURI != r.PostForm.Get("redirect_uri") {
+			tokenError(w, http.StatusBadRequest, "invalid_grant")
+			return
+		}
+		if client.secret != "" && !authenticated {
+			tokenError(w, http.StatusUnauthorized, "invalid_client")
+			return
+		}
+		if code.codeChallenge != "" {
+			sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+			if base64.RawURLEncoding.EncodeToString(sum[:]) != code.codeChallenge {
+				tokenError(w, http.StatusBadRequest, "invalid_grant")
+				return
+			}
+		}
+
+		access, err := t.sign(t.claims(code.subject, t.audience, client.id, code.scope, code.roles))
+		if err != nil {
+			tokenError(w, http.StatusInternalServerError, "server_error")
+			return
+		}
+		idClaims := t.claims(code.subject, client.id, client.id, "", code.roles)
+		delete(idClaims, "scope")
+		idClaims["preferred_username"] = code.subject
+		idClaims["name"] = code.subject
+		if strings.Contains(code.subject, "@") {
+			idClaims["email"] = code.subject
+		}
+		if code.nonce != "" {
+			idClaims["nonce"] = code.nonce
+		}
+		idToken, err := t.sign(idClaims)
+		if err != nil {
+			tokenError(w, http.StatusInternalServerError, "server_error")
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"access_token": access,
+			"id_token":     idToken,
+			"token_type":   "Bearer",
+			"expires_in":   int(t.ttl.Seconds()),
+			"scope":        code.scope,
+		})
+
+	default:
+		tokenError(w, http.StatusBadRequest, "unsupported_grant_type")
+	}
+}
+
+/*
+clientOf returns the client of a token request, from HTTP Basic or the
+client_id and client_secret form fields; authenticated tells whether the
+secret was presented and matched.
+*/
+func (t *devIssuer) clientOf(r *http.Request) (*devClient, bool) {
+	id, secret, basic := r.BasicAuth()
+	if basic {
+		// RFC 6749 section 2.3.1 form-encodes both before Basic
+		id, _ = url.QueryUnescape(id)
+		secret, _ = url.QueryUnescape(secret)
+	} else {
+		id, secret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	client := t.clients[id]
+	if client == nil {
+		return nil, false
+	}
+	if secret == "" {
+		return client, false
+	}
+	if subtle.ConstantTimeCompare([]byte(secret), []byte(client.secret)) != 1 {
+		return nil, false
+	}
+	return client, true
+}
+
+func (t *devIssuer) claims(subject, audience, clientID, scope string, roles []string) jwt.MapClaims {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":       t.issuer,
+		"sub":       subject,
+		"iat":       now.Unix(),
+		"exp":       now.Add(t.ttl).Unix(),
+		"jti":       randomString(),
+		"client_id": clientID,
+	}
+	if audience != "" {
+		claims["aud"] = audience
+	}
+	if scope != "" {
+		claims["scope"] = scope
+	}
+	if len(roles) > 0 {
+		claims["roles"] = roles
+	}
+	return claims
+}
+
+func (t *devIssuer) sign(claims jwt.MapClaims) (string, error) {
+	token := jwt.NewWithClaims(t.method, claims)
+	token.Header["kid"] = t.jwk.Kid
+	return token.SignedString(t.key)
+}
+
+func (t *devIssuer) logout(w http.ResponseWriter, r *http.Request) {
+	if target := r.URL.Query().Get("post_logout_redirect_uri"); target != "" {
+		http.Redirect(w, r, target, http.StatusFound)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintln(w, "signed out")
+}
+
+func redirectError(w http.ResponseWriter, r *http.Request, redirectURI, code string) {
+	q := url.Values{"error": {code}}
+	if state := r.Form.Get("state"); state != "" {
+		q.Set("state", state)
+	}
+	http.Redirect(w, r, appendQuery(redirectURI, q), http.StatusFound)
+}
+
+func tokenError(w http.ResponseWriter, status int, code string) {
+	if status == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", `Basic realm="token"`)
+	}
+	writeJSON(w, status, map[string]string{"error": code})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func appendQuery(endpoint string, q url.Values) string {
+	if strings.Contains(endpoint, "?") {
+		return endpoint + "&" + q.Encode()
+	}
+	return endpoint + "?" + q.Encode()
+}
+
+func randomString() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// --- serve ---
+
+func cmdServe(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", "127.0.0.1:9000", "Listen address")
+	issuer := fs.StringP("issuer", "i", "", "Issuer URL (default: http://<addr>)")
+	keyValue := fs.StringP("private-key", "k", "", "Signing key: file, PEM or base64 DER (default: a new ECDSA P-256 key)")
+	alg := fs.String("alg", "", "Signing algorithm (default by key type)")
+	audience := fs.StringP("audience", "a", "", "Audience (aud) of access tokens")
+	ttl := fs.StringP("expiry", "e", "1h", "Token lifetime")
+	clients := fs.StringSlice("client", []string{"dev-client:dev-secret"}, "Client id:secret[:roles], repeatable; id: is a public client")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Run a local OpenID Connect identity provider for development and tests.
+
+Serves discovery, JWKS, a client-credentials and authorization-code token
+endpoint and a login page accepting any user name. Never expose it.
+
+  /.well-known/openid-configuration   discovery document
+  /jwks.json                          public key of the signing key
+  /authorize                          login page of the authorization code flow (PKCE S256)
+  /token                              authorization_code and client_credentials grants
+  /logout                             end session, redirects to post_logout_redirect_uri
+
+Usage:
+  jwttool serve [flags]
+
+Flags:
+`)
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, `
+Examples:
+  jwttool serve -a my-api --client web-app:web-secret --client billing:billing-secret:service
+
+  # then in the service
+  jwt.jwks-url=http://127.0.0.1:9000/jwks.json
+  jwt.issuer=http://127.0.0.1:9000
+  jwt.audience=my-api
+`)
+	}
+	fs.Parse(args)
+
+	dur, err := parseDuration(*ttl)
+	if err != nil || dur <= 0 {
+		fatal("invalid expiry %q", *ttl)
+	}
+	var key crypto.Signer
+	if *keyValue != "" {
+		if key, err = parsePrivateKey(*keyValue); err != nil {
+			fatal("--private-key: %v", err)
+		}
+	} else if key, err = generateKey("ec", "P-256", 0); err != nil {
+		fatal("generate key: %v", err)
+	}
+	var list []*devClient
+	for _, s := range *clients {
+		c, err := parseDevClient(s)
+		if err != nil {
+			fatal("%v", err)
+		}
+		list = append(list, c)
+	}
+	if *issuer == "" {
+		*issuer = "http://" + *addr
+	}
+
+	idp, err := newDevIssuer(*issuer, *audience, dur, key, *alg, list)
+	if err != nil {
+		fatal("%v", err)
+	}
+	ln, err := net.Listen("tcp", *addr)
+	if err != nil {
+		fatal("listen: %v", err)
+	}
+	srv := &http.Server{Handler: idp, ReadHeaderTimeout: 10 * time.Second}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+
+	fmt.Printf("# dev identity provider, %s key %s, do not expose\n", describeKey(key), idp.jwk.Kid)
+	fmt.Printf("issuer:    %s\n", idp.issuer)
+	fmt.Printf("discovery: %s/.well-known/openid-configuration\n", idp.issuer)
+	fmt.Printf("jwks:      %s/jwks.json\n", idp.issuer)
+	for _, c := range list {
+		fmt.Printf("client:    %s\n", c.id)
+	}
+	if err := srv.Serve(ln); err != nil && err != http.ErrServerClosed {
+		fatal("serve: %v", err)
+	}
+}
@@ -0,0 +1,218 @@
+/*
+ * Copyright (c) 2026 Karagatan LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestIssuer(t *testing.T) (*devIssuer, *httptest.Server) {
+	key, err := generateKey("ec", "P-256", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(nil)
+	t.Cleanup(srv.Close)
+	confidential, _ := parseDevClient("web:web-secret:admin,ops")
+	public, _ := parseDevClient("spa:")
+	idp, err := newDevIssuer(srv.URL, "my-api", time.Hour, key, "", []*devClient{confidential, public})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.Config.Handler = idp
+	return idp, srv
+}
+
+func noRedirectClient() *http.Client {
+	return &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+}
+
+func postToken(t *testing.T, srv *httptest.Server, form url.Values, id, secret string) (int, map[string]interface{}) {
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+"/token", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if id != "" {
+		req.SetBasicAuth(url.QueryEscape(id), url.QueryEscape(secret))
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var body map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&body)
+	return resp.StatusCode, body
+}
+
+func verifyWithJWKS(t *testing.T, srv *httptest.Server, token, audience string) map[string]interface{} {
+	resp, err := http.Get(srv.URL + "/jwks.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var set jsonWebKeySet
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		t.Fatal(err)
+	}
+	claims, err := verifyToken(token, verifyOptions{jwks: set.Keys, issuer: srv.URL, audience: audience, now: time.Now()})
+	if err != nil {
+		t.Fatalf("token does not verify: %v", err)
+	}
+	return claims
+}
+
+func TestDevIssuer_Discovery(t *testing.T) {
+	_, srv := newTestIssuer(t)
+	resp, err := http.Get(srv.URL + "/.well-known/openid-configuration")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var doc map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&doc)
+	if doc["issuer"] != srv.URL || doc["jwks_uri"] != srv.URL+"/jwks.json" || doc["token_endpoint"] != srv.URL+"/token" {
+		t.Errorf("unexpected discovery document %v", doc)
+	}
+}
+
+func TestDevIssuer_ClientCredentials(t *testing.T) {
+	_, srv := newTestIssuer(t)
+	form := url.Values{"grant_type": {"client_credentials"}, "scope": {"read write"}}
+
+	status, body := postToken(t, srv, form, "web", "web-secret")
+	if status != http.StatusOK {
+		t.Fatalf("expected 200, got %d %v", status, body)
+	}
+	claims := verifyWithJWKS(t, srv, body["access_token"].(string), "my-api")
+	if claims["sub"] != "web" || claims["scope"] != "read write" {
+		t.Errorf("unexpected claims %v", claims)
+	}
+	if roles, _ := claims["roles"].([]interface{}); len(roles) != 2 {
+		t.Errorf("expected the client roles, got %v", claims["roles"])
+	}
+
+	if status, _ := postToken(t, srv, form, "web", "wrong"); status != http.StatusUnauthorized {
+		t.Errorf("wrong secret: expected 401, got %d", status)
+	}
+	if status, _ := postToken(t, srv, form, "spa", ""); status != http.StatusUnauthorized {
+		t.Errorf("public client: expected 401, got %d", status)
+	}
+}
+
+func TestDevIssuer_AuthorizationCode(t *testing.T) {
+	_, srv := newTestIssuer(t)
+	verifier := "a-sufficiently-long-code-verifier-for-the-test-0123456789"
+	sum := sha256.Sum256([]byte(verifier))
+	challenge := base64.RawURLEncoding.EncodeToString(sum[:])
+	params := url.Values{
+		"client_id":             {"spa"},
+		"redirect_uri":          {"http://localhost:3000/callback"},
+		"response_type":         {"code"},
+		"scope":                 {"openid profile"},
+		"state":                 {"xyz"},
+		"nonce":                 {"n-1"},
+		"code_challenge":        {challenge},
+		"code_challenge_method": {"S256"},
+	}
+
+	resp, err := http.Get(srv.URL + "/authorize?" + params.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") {
+		t.Fatalf("expected the login page, got %d", resp.StatusCode)
+	}
+
+	login := url.Values{}
+	for k, v := range params {
+		login[k] = v
+	}
+	login.Set("username", "alice@example.com")
+	login.Set("roles", "user")
+	resp, err = noRedirectClient().PostForm(srv.URL+"/authorize", login)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if resp.StatusCode != http.StatusFound || err != nil {
+		t.Fatalf("expected a redirect, got %d", resp.StatusCode)
+	}
+	if location.Query().Get("state") != "xyz" {
+		t.Errorf("state not returned: %s", location)
+	}
+	code := location.Query().Get("code")
+
+	exchange := url.Values{
+		"grant_type":    {"authorization_code"},
+		"client_id":     {"spa"},
+		"code":          {code},
+		"redirect_uri":  {"http://localhost:3000/callback"},
+		"code_verifier": {"wrong-verifier"},
+	}
+	if status, _ := postToken(t, srv, exchange, "", ""); status != http.StatusBadRequest {
+		t.Errorf("wrong verifier: expected 400, got %d", status)
+	}
+
+	// a failed exchange consumes the code
+	resp, _ = noRedirectClient().PostForm(srv.URL+"/authorize", login)
+	resp.Body.Close()
+	location, _ = url.Parse(resp.Header.Get("Location"))
+	exchange.Set("code", location.Query().Get("code"))
+	exchange.Set("code_verifier", verifier)
+	status, body := postToken(t, srv, exchange, "", "")
+	if status != http.StatusOK {
+		t.Fatalf("expected 200, got %d %v", status, body)
+	}
+	id := verifyWithJWKS(t, srv, body["id_token"].(string), "spa")
+	if id["sub"] != "alice@example.com" || id["nonce"] != "n-1" || id["email"] != "alice@example.com" {
+		t.Errorf("unexpected ID token claims %v", id)
+	}
+	access := verifyWithJWKS(t, srv, body["access_token"].(string), "my-api")
+	if access["scope"] != "openid profile" {
+		t.Errorf("unexpected access token claims %v", access)
+	}
+
+	if status, _ := postToken(t, srv, exchange, "", ""); status != http.StatusBadRequest {
+		t.Errorf("code reuse: expected 400, got %d", status)
+	}
+}
+
+func TestDevIssuer_PublicClientRequiresPKCE(t *testing.T) {
+	_, srv := newTestIssuer(t)
+	params := url.Values{
+		"client_id":     {"spa"},
+		"redirect_uri":  {"http://localhost:3000/callback"},
+		"response_type": {"code"},
+	}
+	resp, err := noRedirectClient().Get(srv.URL + "/authorize?" + params.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	location, _ := url.Parse(resp.Header.Get("Location"))
+	if location == nil || location.Query().Get("error") != "invalid_request" {
+		t.Errorf("expected invalid_request, got %d %s", resp.StatusCode, resp.Header.Get("Location"))
+	}
+}
+
+func TestParseDevClient(t *testing.T) {
+	c, err := parseDevClient("svc:s3cret:a, b")
+	if err != nil || c.id != "svc" || c.secret != "s3cret" || len(c.roles) != 2 {
+		t.Errorf("unexpected client %+v, %v", c, err)
+	}
+	if _, err := parseDevClient("svc"); err == nil {
+		t.Error("expected an error without a secret separator")
+	}
+}
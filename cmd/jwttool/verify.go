@@ -0,0 +1,333 @@
+/*
+ * Copyright (c) 2026 Karagatan LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package main
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	flag "github.com/spf13/pflag"
+	"golang.org/x/xerrors"
+)
+
+// verifyOptions mirror the checks of JwtAuthProvider.
+type verifyOptions struct {
+	secret   string
+	key      interface{}
+	jwks     []jsonWebKey
+	issuer   string
+	audience string
+	leeway   time.Duration
+	now      time.Time
+}
+
+/*
+verifyToken verifies tokenStr like JwtAuthProvider does, exp included, and
+explains a failure in terms of the token: which key was looked for, which
+algorithm was used, when it expired.
+*/
+func verifyToken(tokenStr string, opts verifyOptions) (jwt.MapClaims, error) {
+	header, claims, err := decodeToken(tokenStr)
+	if err != nil {
+		return nil, err
+	}
+	alg, _ := header["alg"].(string)
+	kid, _ := header["kid"].(string)
+
+	var used string
+	keyFunc := func(token *jwt.Token) (interface{}, error) {
+		switch {
+		case opts.secret != "":
+			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+				return nil, xerrors.Errorf("token is signed with %s, but a secret verifies HS256/384/512 only", alg)
+			}
+			used = "secret"
+			return []byte(opts.secret), nil
+		case opts.key != nil:
+			if !keyAccepts(opts.key, token.Method) {
+				return nil, xerrors.Errorf("token is signed with %s, which a %s key can not verify", alg, describeKey(opts.key))
+			}
+			used = describeKey(opts.key) + " key"
+			return opts.key, nil
+		}
+		var (
+			candidates []interface{}
+			kids       []string
+			mismatch   error
+		)
+		for _, jwk := range opts.jwks {
+			kids = append(kids, jwk.Kid)
+			if kid != "" && jwk.Kid != kid {
+				continue
+			}
+			if jwk.Alg != "" && jwk.Alg != alg {
+				mismatch = xerrors.Errorf("JWKS key %q declares alg %s, the token uses %s", jwk.Kid, jwk.Alg, alg)
+				continue
+			}
+			pub, err := jwk.publicKey()
+			if err != nil {
+				mismatch = err
+				continue
+			}
+			if !keyAccepts(pub, token.Method) {
+				mismatch = xerrors.Errorf("JWKS key %q is a %s key, which can not verify %s", jwk.Kid, describeKey(pub), alg)
+				continue
+			}
+			candidates = append(candidates, pub)
+			used = fmt.Sprintf("JWKS key %q", jwk.Kid)
+		}
+		switch len(candidates) {
+		case 0:
+			if mismatch != nil && kid != "" {
+				return nil, mismatch
+			}
+			if kid == "" {
+				return nil, xerrors.Errorf("token has no kid and no JWKS key fits %s", alg)
+			}
+			return nil, xerrors.Errorf("no JWKS key with kid %q for %s, the set has %s", kid, alg, strings.Join(kids, ", "))
+		case 1:
+			return candidates[0], nil
+		}
+		used = "JWKS"
+		keys := make([]jwt.VerificationKey, len(candidates))
+		for i, c := range candidates {
+			keys[i] = c
+		}
+		return jwt.VerificationKeySet{Keys: keys}, nil
+	}
+
+	parserOpts := []jwt.ParserOption{
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(opts.leeway),
+		jwt.WithTimeFunc(func() time.Time { return opts.now }),
+	}
+	if opts.issuer != "" {
+		parserOpts = append(parserOpts, jwt.WithIssuer(opts.issuer))
+	}
+	if opts.audience != "" {
+		parserOpts = append(parserOpts, jwt.WithAudience(opts.audience))
+	}
+
+	_, err = jwt.Parse(tokenStr, keyFunc, parserOpts...)
+	switch {
+	case err == nil:
+		return claims, nil
+	case errors.Is(err, jwt.ErrTokenUnverifiable):
+		return nil, unwrapJwtError(err)
+	case errors.Is(err, jwt.ErrTokenSignatureInvalid):
+		return nil, xerrors.Errorf("%s signature does not verify with the %s", alg, used)
+	case errors.Is(err, jwt.ErrTokenRequiredClaimMissing):
+		return nil, xerrors.New("token has no exp claim, JwtAuthProvider requires one")
+	case errors.Is(err, jwt.ErrTokenExpired):
+		exp, _ := claims.GetExpirationTime()
+		return nil, xerrors.Errorf("token expired at %s, %s ago", exp.UTC().Format(time.RFC3339), opts.now.Sub(exp.Time).Round(time.Second))
+	case errors.Is(err, jwt.ErrTokenNotValidYet):
+		nbf, _ := claims.GetNotBefore()
+		if nbf == nil {
+			return nil, xerrors.New("token is not valid yet, iat is in the future")
+		}
+		return nil, xerrors.Errorf("token is not valid before %s, in %s", nbf.UTC().Format(time.RFC3339), nbf.Sub(opts.now).Round(time.Second))
+	case errors.Is(err, jwt.ErrTokenUsedBeforeIssued):
+		return nil, xerrors.New("token iat is in the future")
+	case errors.Is(err, jwt.ErrTokenInvalidIssuer):
+		iss, _ := claims.GetIssuer()
+		return nil, xerrors.Errorf("issuer %q, expected %q", iss, opts.issuer)
+	case errors.Is(err, jwt.ErrTokenInvalidAudience):
+		aud, _ := claims.GetAudience()
+		return nil, xerrors.Errorf("audience %q, expected %q", strings.Join(aud, ","), opts.audience)
+	}
+	return nil, err
+}
+
+// unwrapJwtError returns the error of the key function that jwt.Parse joined with ErrTokenUnverifiable.
+func unwrapJwtError(err error) error {
+	if u, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, e := range u.Unwrap() {
+			if e != jwt.ErrTokenUnverifiable {
+				return e
+			}
+		}
+	}
+	return err
+}
+
+// decodeToken returns the header and claims of tokenStr without verifying it.
+func decodeToken(tokenStr string) (map[string]interface{}, jwt.MapClaims, error) {
+	parts := strings.Split(strings.TrimSpace(tokenStr), ".")
+	if len(parts) != 3 {
+		return nil, nil, xerrors.Errorf("malformed token: %d parts, a JWS has 3", len(parts))
+	}
+	var header map[string]interface{}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, nil, xerrors.Errorf("malformed token header: %w", err)
+	}
+	claims := jwt.MapClaims{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, nil, xerrors.Errorf("malformed token claims: %w", err)
+	}
+	return header, claims, nil
+}
+
+func decodeSegment(seg string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(seg, "="))
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(strings.NewReader(string(data)))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
+// readToken returns the token argument, or a line of stdin for "-" or no argument.
+func readToken(args []string) string {
+	if len(args) > 0 && args[0] != "-" {
+		return strings.TrimSpace(args[0])
+	}
+	return readLine(bufio.NewReader(os.Stdin))
+}
+
+// --- decode ---
+
+func cmdDecode(args []string) {
+	fs := flag.NewFlagSet("decode", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Print the header and claims of a JWT without verifying it.
+
+Usage:
+  jwttool decode <token>
+  echo "$TOKEN" | jwttool decode
+
+`)
+	}
+	fs.Parse(args)
+
+	header, claims, err := decodeToken(readToken(fs.Args()))
+	if err != nil {
+		fatal("%v", err)
+	}
+	fmt.Println("# header")
+	printJSON(header)
+	fmt.Println("# claims (not verified)")
+	printJSON(claims)
+	for _, line := range describeTimes(claims, time.Now()) {
+		fmt.Println("# " + line)
+	}
+}
+
+// describeTimes renders the time claims of claims relative to now, e.g. "exp 2026-01-02T15:04:05Z (in 55m0s)".
+func describeTimes(claims jwt.MapClaims, now time.Time) []string {
+	var lines []string
+	for _, name := range []string{"iat", "nbf", "exp"} {
+		if _, ok := claims[name]; !ok {
+			continue
+		}
+		var (
+			t   *jwt.NumericDate
+			err error
+		)
+		switch name {
+		case "iat":
+			t, err = claims.GetIssuedAt()
+		case "nbf":
+			t, err = claims.GetNotBefore()
+		default:
+			t, err = claims.GetExpirationTime()
+		}
+		if err != nil || t == nil {
+			lines = append(lines, name+" is not a number")
+			continue
+		}
+		rel := "in " + t.Sub(now).Round(time.Second).String()
+		if t.Before(now) {
+			rel = now.Sub(t.Time).Round(time.Second).String() + " ago"
+		}
+		lines = append(lines, fmt.Sprintf("%s %s (%s)", name, t.UTC().Format(time.RFC3339), rel))
+	}
+	return lines
+}
+
+// --- verify ---
+
+func cmdVerify(args []string) {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	secret := fs.String("secret", "", "HMAC shared secret")
+	key := fs.StringP("key", "k", "", "Public key, certificate or private key: file, PEM or base64 DER")
+	jwksFile := fs.String("jwks", "", "JWKS file selecting the key by kid")
+	issuer := fs.StringP("issuer", "i", "", "Expected issuer (iss)")
+	audience := fs.StringP("audience", "a", "", "Expected audience (aud)")
+	leeway := fs.Duration("leeway", 0, "Allowed clock skew for exp, nbf and iat")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Verify a JWT the way JwtAuthProvider does and report why it fails.
+
+Exactly one of --secret, --key or --jwks is required. Exits with 1 when the
+token is invalid.
+
+Usage:
+  jwttool verify [flags] <token>
+
+Flags:
+`)
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, `
+Examples:
+  jwttool verify --jwks jwks.json -i https://auth.example.com -a my-api "$TOKEN"
+  jwttool verify --key public.pem "$TOKEN"
+`)
+	}
+	fs.Parse(args)
+
+	opts := verifyOptions{secret: *secret, issuer: *issuer, audience: *audience, leeway: *leeway, now: time.Now()}
+	sources := 0
+	for _, s := range []string{*secret, *key, *jwksFile} {
+		if s != "" {
+			sources++
+		}
+	}
+	if sources != 1 {
+		fatal("exactly one of --secret, --key or --jwks is required")
+	}
+	var err error
+	if *key != "" {
+		if opts.key, err = parsePublicKey(*key); err != nil {
+			fatal("--key: %v", err)
+		}
+	}
+	if *jwksFile != "" {
+		if opts.jwks, err = readJWKS(*jwksFile); err != nil {
+			fatal("--jwks: %v", err)
+		}
+	}
+
+	claims, err := verifyToken(readToken(fs.Args()), opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("# valid")
+	printJSON(claims)
+	for _, line := range describeTimes(claims, opts.now) {
+		fmt.Println("# " + line)
+	}
+}
+
+// printJSON prints v indented, with the keys of maps sorted.
+func printJSON(v interface{}) {
+	if m, ok := v.(jwt.MapClaims); ok {
+		v = map[string]interface{}(m)
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		fatal("encode JSON: %v", err)
+	}
+	fmt.Println(string(data))
+}
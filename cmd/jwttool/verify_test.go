@@ -0,0 +1,79 @@
+/*
+ * Copyright (c) 2026 Karagatan LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestVerifyToken(t *testing.T) {
+	now := time.Now()
+	priv, _ := generateKey("ec", "P-256", 0)
+	other, _ := generateKey("ec", "P-256", 0)
+	privText, _ := encodePrivateKey(priv, "pem")
+	jwk, _ := toJWK(priv, "", "")
+
+	sign := func(claims jwt.MapClaims) string {
+		token, err := signWithKey(privText, "", "", claims)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	valid := sign(jwt.MapClaims{"sub": "alice", "iss": "idp", "aud": "api", "exp": now.Add(time.Hour).Unix()})
+
+	claims, err := verifyToken(valid, verifyOptions{jwks: []jsonWebKey{jwk}, issuer: "idp", audience: "api", now: now})
+	if err != nil || claims["sub"] != "alice" {
+		t.Fatalf("valid token: %v, %v", claims, err)
+	}
+	if _, err := verifyToken(valid, verifyOptions{key: priv.Public(), now: now}); err != nil {
+		t.Errorf("valid token with key: %v", err)
+	}
+
+	otherJwk, _ := toJWK(other, "", "")
+	hmacToken, _ := signHMAC("secret", "", jwt.MapClaims{"sub": "bob", "exp": now.Add(time.Hour).Unix()})
+	for _, c := range []struct {
+		name, token string
+		opts        verifyOptions
+		reason      string
+	}{
+		{"wrong key", valid, verifyOptions{key: other.Public()}, "does not verify"},
+		{"unknown kid", valid, verifyOptions{jwks: []jsonWebKey{otherJwk}}, "no JWKS key with kid"},
+		{"issuer", valid, verifyOptions{jwks: []jsonWebKey{jwk}, issuer: "other"}, `issuer "idp", expected "other"`},
+		{"audience", valid, verifyOptions{jwks: []jsonWebKey{jwk}, audience: "billing"}, `audience "api", expected "billing"`},
+		{"expired", sign(jwt.MapClaims{"sub": "a", "exp": now.Add(-time.Minute).Unix()}), verifyOptions{key: priv.Public()}, "expired at"},
+		{"no exp", sign(jwt.MapClaims{"sub": "a"}), verifyOptions{key: priv.Public()}, "no exp claim"},
+		{"not yet valid", sign(jwt.MapClaims{"sub": "a", "exp": now.Add(time.Hour).Unix(), "nbf": now.Add(time.Minute).Unix()}), verifyOptions{key: priv.Public()}, "not valid before"},
+		{"secret for key token", valid, verifyOptions{secret: "secret"}, "HS256/384/512 only"},
+		{"key for hmac token", hmacToken, verifyOptions{key: priv.Public()}, "can not verify"},
+		{"malformed", "abc", verifyOptions{secret: "secret"}, "malformed"},
+	} {
+		c.opts.now = now
+		_, err := verifyToken(c.token, c.opts)
+		if err == nil || !strings.Contains(err.Error(), c.reason) {
+			t.Errorf("%s: got %v, want %q", c.name, err, c.reason)
+		}
+	}
+
+	if _, err := verifyToken(hmacToken, verifyOptions{secret: "secret", now: now}); err != nil {
+		t.Errorf("hmac token: %v", err)
+	}
+	if _, err := verifyToken(valid, verifyOptions{key: priv.Public(), now: now.Add(2 * time.Hour), leeway: 2 * time.Hour}); err != nil {
+		t.Errorf("leeway: %v", err)
+	}
+}
+
+func TestDescribeTimes(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	lines := describeTimes(jwt.MapClaims{"iat": float64(now.Unix() - 60), "exp": float64(now.Unix() + 3600)}, now)
+	if len(lines) != 2 || !strings.HasSuffix(lines[0], "(1m0s ago)") || !strings.HasSuffix(lines[1], "(in 1h0m0s)") {
+		t.Errorf("lines %q", lines)
+	}
+}
@@ -0,0 +1,336 @@
+/*
+ * Copyright (c) 2026 Karagatan LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package servion
+
+import (
+	"container/list"
+	"context"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/atomic"
+	"golang.org/x/xerrors"
+)
+
+const (
+	ConcurrencyAIMD     = "aimd"
+	ConcurrencyGradient = "gradient"
+)
+
+var priorityNames = map[Priority]string{
+	PriorityLow:      "low",
+	PriorityNormal:   "normal",
+	PriorityHigh:     "high",
+	PriorityCritical: "critical",
+}
+
+func (p Priority) String() string {
+	if name, ok := priorityNames[p]; ok {
+		return name
+	}
+	return strconv.Itoa(int(p))
+}
+
+// ParsePriority parses a priority class name: low, normal, high or critical.
+func ParsePriority(s string) (Priority, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	for p, name := range priorityNames {
+		if name == s {
+			return p, true
+		}
+	}
+	return PriorityNormal, false
+}
+
+type implConcurrencyLimiter struct {
+	Algorithm        string        `value:"concurrency.algorithm,default=gradient"`
+	InitialLimit     int           `value:"concurrency.initial-limit,default=20"`
+	MinLimit         int           `value:"concurrency.min-limit,default=1"`
+	MaxLimit         int           `value:"concurrency.max-limit,default=1000"`
+	QueueSize        int           `value:"concurrency.queue-size,default=50"`
+	QueueTimeout     time.Duration `value:"concurrency.queue-timeout,default=1s"`
+	LatencyThreshold time.Duration `value:"concurrency.latency-threshold,default=1s"`
+	Backoff          int           `value:"concurrency.backoff,default=90"`
+	Tolerance        int           `value:"concurrency.tolerance,default=150"`
+
+	mu       sync.Mutex
+	alg      limitAlgorithm
+	limit    float64
+	inflight int
+	queued   int
+	queues   [PriorityCritical]*list.List // one FIFO per sheddable class
+	shed     atomic.Int64
+}
+
+type concurrencyWaiter struct {
+	priority Priority
+	el       *list.Element // nil once admitted or evicted
+	admitted bool
+	ready    chan struct{}
+}
+
+/*
+AdaptiveConcurrencyLimiter creates the process-wide ConcurrencyLimiter used by
+ConcurrencyLimitMiddleware and the grpc ConcurrencyInterceptor. The limit of
+requests in flight moves with the observed latency: it grows while latency stays
+flat and shrinks as soon as requests start to queue up inside the process.
+
+Requests over the limit wait in a bounded queue and are admitted highest priority
+first. When the queue is full, a new request evicts the oldest waiter of a lower
+class, or is shed itself; waiters are shed after concurrency.queue-timeout.
+Critical requests bypass the limiter entirely.
+
+Configuration properties:
+
+	concurrency.algorithm          – gradient (default) or aimd
+	concurrency.initial-limit      – limit before any latency is observed (default 20)
+	concurrency.min-limit          – lower bound of the limit (default 1)
+	concurrency.max-limit          – upper bound of the limit (default 1000)
+	concurrency.queue-size         – requests waiting for a slot, 0 disables queueing (default 50)
+	concurrency.queue-timeout      – longest wait for a slot (default 1s)
+	concurrency.latency-threshold  – aimd: slower requests shrink the limit (default 1s)
+	concurrency.backoff            – aimd: percent of the limit kept on overload (default 90)
+	concurrency.tolerance          – gradient: latency growth in percent of the baseline tolerated before shrinking (default 150)
+*/
+func AdaptiveConcurrencyLimiter() ConcurrencyLimiter {
+	return &implConcurrencyLimiter{}
+}
+
+func (t *implConcurrencyLimiter) PostConstruct() error {
+	if t.MinLimit < 1 {
+		t.MinLimit = 1
+	}
+	if t.MaxLimit < t.MinLimit {
+		return xerrors.Errorf("concurrency.max-limit %d is below concurrency.min-limit %d", t.MaxLimit, t.MinLimit)
+	}
+	switch strings.ToLower(strings.TrimSpace(t.Algorithm)) {
+	case ConcurrencyAIMD:
+		if t.Backoff <= 0 || t.Backoff >= 100 {
+			return xerrors.Errorf("concurrency.backoff must be between 1 and 99 percent, got %d", t.Backoff)
+		}
+		t.alg = &aimdLimit{threshold: t.LatencyThreshold, backoff: float64(t.Backoff) / 100}
+	case ConcurrencyGradient, "":
+		if t.Tolerance < 100 {
+			return xerrors.Errorf("concurrency.tolerance must be at least 100 percent, got %d", t.Tolerance)
+		}
+		t.alg = &gradientLimit{tolerance: float64(t.Tolerance) / 100}
+	default:
+		return xerrors.Errorf("unknown concurrency algorithm '%s'", t.Algorithm)
+	}
+	t.limit = t.clamp(float64(t.InitialLimit))
+	for i := range t.queues {
+		t.queues[i] = list.New()
+	}
+	return nil
+}
+
+func (t *implConcurrencyLimiter) BeanName() string {
+	return "concurrency-limiter"
+}
+
+func (t *implConcurrencyLimiter) GetStats(cb func(name, value string) bool) error {
+	t.mu.Lock()
+	limit, inflight, queued := int(t.limit), t.inflight, t.queued
+	t.mu.Unlock()
+
+	cb("limit", strconv.Itoa(limit))
+	cb("inflight", strconv.Itoa(inflight))
+	cb("queued", strconv.Itoa(queued))
+	cb("shed", strconv.FormatInt(t.shed.Load(), 10))
+	return nil
+}
+
+func (t *implConcurrencyLimiter) Acquire(ctx context.Context, priority Priority) (func(ok bool), error) {
+	if priority >= PriorityCritical {
+		return func(bool) {}, nil
+	}
+	if priority < PriorityLow {
+		priority = PriorityLow
+	}
+
+	t.mu.Lock()
+	// a free slot goes to the queue first, newcomers never overtake waiters
+	if t.queued == 0 && t.inflight < int(t.limit) {
+		t.inflight++
+		t.mu.Unlock()
+		return t.releaseFunc(), nil
+	}
+	if t.queued >= t.QueueSize {
+		victim := t.lowestWaiterBelow(priority)
+		if victim == nil {
+			t.mu.Unlock()
+			t.shed.Inc()
+			return nil, ErrOverloaded
+		}
+		t.dequeue(victim)
+		close(victim.ready)
+	}
+	w := &concurrencyWaiter{priority: priority, ready: make(chan struct{})}
+	w.el = t.queues[priority].PushBack(w)
+	t.queued++
+	t.mu.Unlock()
+
+	var timeout <-chan time.Time
+	if t.QueueTimeout > 0 {
+		timer := time.NewTimer(t.QueueTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case <-w.ready:
+	case <-ctx.Done():
+	case <-timeout:
+	}
+
+	t.mu.Lock()
+	if w.el != nil {
+		// gave up while still waiting
+		t.dequeue(w)
+		t.mu.Unlock()
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		t.shed.Inc()
+		return nil, ErrOverloaded
+	}
+	admitted := w.admitted
+	t.mu.Unlock()
+
+	if !admitted {
+		t.shed.Inc()
+		return nil, ErrOverloaded
+	}
+	return t.releaseFunc(), nil
+}
+
+func (t *implConcurrencyLimiter) releaseFunc() func(ok bool) {
+	start := time.Now()
+	var once sync.Once
+	return func(ok bool) {
+		once.Do(func() {
+			t.release(time.Since(start), ok)
+		})
+	}
+}
+
+func (t *implConcurrencyLimiter) release(rtt time.Duration, ok bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.limit = t.clamp(t.alg.update(t.limit, rtt, t.inflight, ok))
+	t.inflight--
+
+	for t.queued > 0 && t.inflight < int(t.limit) {
+		w := t.highestWaiter()
+		t.dequeue(w)
+		w.admitted = true
+		t.inflight++
+		close(w.ready)
+	}
+}
+
+func (t *implConcurrencyLimiter) clamp(limit float64) float64 {
+	return math.Max(float64(t.MinLimit), math.Min(float64(t.MaxLimit), limit))
+}
+
+// dequeue removes a waiting request from its queue, the caller holds the lock.
+func (t *implConcurrencyLimiter) dequeue(w *concurrencyWaiter) {
+	t.queues[w.priority].Remove(w.el)
+	w.el = nil
+	t.queued--
+}
+
+func (t *implConcurrencyLimiter) highestWaiter() *concurrencyWaiter {
+	for p := len(t.queues) - 1; p >= 0; p-- {
+		if el := t.queues[p].Front(); el != nil {
+			return el.Value.(*concurrencyWaiter)
+		}
+	}
+	return nil
+}
+
+// lowestWaiterBelow returns the oldest waiter of the lowest class under priority.
+func (t *implConcurrencyLimiter) lowestWaiterBelow(priority Priority) *concurrencyWaiter {
+	for p := PriorityLow; p < priority; p++ {
+		if el := t.queues[p].Front(); el != nil {
+			return el.Value.(*concurrencyWaiter)
+		}
+	}
+	return nil
+}
+
+// limitAlgorithm computes the next limit after a request completed in rtt
+// while inflight requests (itself included) were running.
+type limitAlgorithm interface {
+	update(limit float64, rtt time.Duration, inflight int, ok bool) float64
+}
+
+// aimdLimit grows the limit by one per successful request and cuts it by the
+// backoff ratio when a request fails under overload or exceeds the threshold.
+type aimdLimit struct {
+	threshold time.Duration
+	backoff   float64
+}
+
+func (t *aimdLimit) update(limit float64, rtt time.Duration, inflight int, ok bool) float64 {
+	if !ok || (t.threshold > 0 && rtt > t.threshold) {
+		return limit * t.backoff
+	}
+	// an idle process says nothing about how much more it could take
+	if float64(inflight)*2 < limit {
+		return limit
+	}
+	return limit + 1
+}
+
+const (
+	gradientShortWindow = 10
+	gradientLongWindow  = 600
+	gradientSmoothing   = 0.2
+)
+
+/*
+gradientLimit compares the recent latency with a long-term baseline. While the
+two stay within the tolerance the limit grows by its square root, the allowance
+for queueing; as recent latency rises above the baseline the limit shrinks in
+proportion, down to half of it per step.
+*/
+type gradientLimit struct {
+	tolerance float64
+	shortRtt  float64 // moving averages in nanoseconds
+	longRtt   float64
+}
+
+func (t *gradientLimit) update(limit float64, rtt time.Duration, inflight int, ok bool) float64 {
+	sample := float64(rtt)
+	if t.longRtt == 0 {
+		t.shortRtt, t.longRtt = sample, sample
+	}
+	t.shortRtt += (sample - t.shortRtt) / gradientShortWindow
+	t.longRtt += (sample - t.longRtt) / gradientLongWindow
+
+	// after a sustained latency drop the baseline catches up quickly
+	if t.longRtt > t.shortRtt*2 {
+		t.longRtt *= 0.95
+	}
+
+	if ok && float64(inflight)*2 < limit {
+		return limit
+	}
+
+	gradient := 1.0
+	if t.shortRtt > 0 {
+		gradient = math.Max(0.5, math.Min(1, t.tolerance*t.longRtt/t.shortRtt))
+	}
+	next := limit * gradient
+	if ok {
+		next += math.Sqrt(limit)
+	}
+	return limit*(1-gradientSmoothing) + next*gradientSmoothing
+}
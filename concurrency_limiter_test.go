@@ -0,0 +1,293 @@
+package servion
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestConcurrencyLimiter(t *testing.T, limit, queue int, configure func(l *implConcurrencyLimiter)) *implConcurrencyLimiter {
+	t.Helper()
+	l := &implConcurrencyLimiter{
+		Algorithm:        ConcurrencyAIMD,
+		InitialLimit:     limit,
+		MinLimit:         1,
+		MaxLimit:         limit, // fixed unless a test widens it
+		QueueSize:        queue,
+		QueueTimeout:     time.Second,
+		LatencyThreshold: time.Second,
+		Backoff:          90,
+		Tolerance:        150,
+	}
+	if configure != nil {
+		configure(l)
+	}
+	if err := l.PostConstruct(); err != nil {
+		t.Fatalf("PostConstruct: %v", err)
+	}
+	return l
+}
+
+func TestConcurrencyLimiter_AdmitsUpToLimit(t *testing.T) {
+	l := newTestConcurrencyLimiter(t, 2, 0, nil)
+	ctx := context.Background()
+
+	r1, err := l.Acquire(ctx, PriorityNormal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r2, err := l.Acquire(ctx, PriorityNormal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.Acquire(ctx, PriorityNormal); !errors.Is(err, ErrOverloaded) {
+		t.Fatalf("third: expected ErrOverloaded, got %v", err)
+	}
+
+	// critical requests are never shed
+	if _, err := l.Acquire(ctx, PriorityCritical); err != nil {
+		t.Fatalf("critical: %v", err)
+	}
+
+	r1(true)
+	r1(true) // releasing twice must not free a second slot
+	r3, err := l.Acquire(ctx, PriorityNormal)
+	if err != nil {
+		t.Fatalf("after release: %v", err)
+	}
+	if _, err := l.Acquire(ctx, PriorityNormal); !errors.Is(err, ErrOverloaded) {
+		t.Fatalf("expected ErrOverloaded after double release, got %v", err)
+	}
+	r2(true)
+	r3(true)
+
+	stats := map[string]string{}
+	l.GetStats(func(name, value string) bool { stats[name] = value; return true })
+	if stats["inflight"] != "0" || stats["shed"] != "2" {
+		t.Errorf("stats = %v", stats)
+	}
+}
+
+func TestConcurrencyLimiter_QueueByPriority(t *testing.T) {
+	l := newTestConcurrencyLimiter(t, 1, 3, nil)
+	ctx := context.Background()
+
+	hold, err := l.Acquire(ctx, PriorityNormal)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	var order []Priority
+	var wg sync.WaitGroup
+	enqueue := func(p Priority) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release, err := l.Acquire(ctx, p)
+			if err != nil {
+				t.Errorf("%s: %v", p, err)
+				return
+			}
+			mu.Lock()
+			order = append(order, p)
+			mu.Unlock()
+			release(true)
+		}()
+		waitQueued(t, l)
+	}
+	enqueue(PriorityLow)
+	enqueue(PriorityNormal)
+	enqueue(PriorityHigh)
+
+	hold(true)
+	wg.Wait()
+
+	want := []Priority{PriorityHigh, PriorityNormal, PriorityLow}
+	for i := range want {
+		if i >= len(order) || order[i] != want[i] {
+			t.Fatalf("admission order = %v, want %v", order, want)
+		}
+	}
+}
+
+func TestConcurrencyLimiter_FullQueueEvictsLowerClass(t *testing.T) {
+	l := newTestConcurrencyLimiter(t, 1, 1, nil)
+	ctx := context.Background()
+
+	hold, err := l.Acquire(ctx, PriorityNormal)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lowErr := make(chan error, 1)
+	go func() {
+		_, err := l.Acquire(ctx, PriorityLow)
+		lowErr <- err
+	}()
+	waitQueued(t, l)
+
+	// an equal class can not take the place of the queued low request...
+	if _, err := l.Acquire(ctx, PriorityLow); !errors.Is(err, ErrOverloaded) {
+		t.Fatalf("expected ErrOverloaded, got %v", err)
+	}
+
+	// ...but a higher one evicts it
+	highDone := make(chan error, 1)
+	go func() {
+		release, err := l.Acquire(ctx, PriorityHigh)
+		if err == nil {
+			release(true)
+		}
+		highDone <- err
+	}()
+
+	if err := <-lowErr; !errors.Is(err, ErrOverloaded) {
+		t.Fatalf("evicted low: expected ErrOverloaded, got %v", err)
+	}
+	hold(true)
+	if err := <-highDone; err != nil {
+		t.Fatalf("high: %v", err)
+	}
+}
+
+func TestConcurrencyLimiter_QueueTimeoutAndCancel(t *testing.T) {
+	l := newTestConcurrencyLimiter(t, 1, 2, func(l *implConcurrencyLimiter) {
+		l.QueueTimeout = 20 * time.Millisecond
+	})
+	hold, err := l.Acquire(context.Background(), PriorityNormal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer hold(true)
+
+	if _, err := l.Acquire(context.Background(), PriorityNormal); !errors.Is(err, ErrOverloaded) {
+		t.Fatalf("expected ErrOverloaded after queue timeout, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := l.Acquire(ctx, PriorityNormal); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if l.queued != 0 {
+		t.Errorf("queued = %d, want 0", l.queued)
+	}
+}
+
+func TestAIMDLimit(t *testing.T) {
+	a := &aimdLimit{threshold: 100 * time.Millisecond, backoff: 0.5}
+
+	if got := a.update(10, time.Millisecond, 8, true); got != 11 {
+		t.Errorf("busy and fast: %v, want 11", got)
+	}
+	if got := a.update(10, time.Millisecond, 2, true); got != 10 {
+		t.Errorf("idle: %v, want 10", got)
+	}
+	if got := a.update(10, time.Second, 8, true); got != 5 {
+		t.Errorf("slow: %v, want 5", got)
+	}
+	if got := a.update(10, time.Millisecond, 8, false); got != 5 {
+		t.Errorf("failed: %v, want 5", got)
+	}
+}
+
+func TestGradientLimit(t *testing.T) {
+	g := &gradientLimit{tolerance: 1.5}
+
+	// steady latency under load grows the limit
+	limit := 20.0
+	for i := 0; i < 50; i++ {
+		limit = g.update(limit, 10*time.Millisecond, int(limit), true)
+	}
+	if limit <= 20 {
+		t.Fatalf("steady latency: limit = %v, want growth above 20", limit)
+	}
+
+	// latency climbing far above the baseline shrinks it
+	grown := limit
+	for i := 0; i < 50; i++ {
+		limit = g.update(limit, 200*time.Millisecond, int(limit), true)
+	}
+	if limit >= grown {
+		t.Fatalf("rising latency: limit = %v, want below %v", limit, grown)
+	}
+}
+
+func TestConcurrencyLimiter_AdaptsLimit(t *testing.T) {
+	l := newTestConcurrencyLimiter(t, 4, 0, func(l *implConcurrencyLimiter) {
+		l.MaxLimit = 100
+		l.Backoff = 50
+	})
+	var releases []func(bool)
+	for i := 0; i < 4; i++ {
+		release, err := l.Acquire(context.Background(), PriorityNormal)
+		if err != nil {
+			t.Fatal(err)
+		}
+		releases = append(releases, release)
+	}
+
+	// a busy, fast success grows the limit by one
+	releases[0](true)
+	if l.limit != 5 {
+		t.Errorf("limit after success = %v, want 5", l.limit)
+	}
+	// an overloaded request halves it
+	releases[1](false)
+	if l.limit != 2.5 {
+		t.Errorf("limit after overload = %v, want 2.5", l.limit)
+	}
+	// two requests are still running, so there is no room for a third
+	if _, err := l.Acquire(context.Background(), PriorityNormal); !errors.Is(err, ErrOverloaded) {
+		t.Errorf("expected ErrOverloaded, got %v", err)
+	}
+	releases[2](true)
+	releases[3](true)
+}
+
+func TestConcurrencyLimiter_Validation(t *testing.T) {
+	for _, l := range []*implConcurrencyLimiter{
+		{Algorithm: "bogus", MaxLimit: 10},
+		{Algorithm: ConcurrencyAIMD, MaxLimit: 10, Backoff: 100},
+		{Algorithm: ConcurrencyGradient, MaxLimit: 10, Tolerance: 50},
+		{Algorithm: ConcurrencyGradient, MinLimit: 10, MaxLimit: 5, Tolerance: 150},
+	} {
+		if err := l.PostConstruct(); err == nil {
+			t.Errorf("expected error for %+v", l)
+		}
+	}
+}
+
+func TestParsePriority(t *testing.T) {
+	for _, name := range []string{"low", "normal", "high", "critical"} {
+		p, ok := ParsePriority(" " + name)
+		if !ok || p.String() != name {
+			t.Errorf("ParsePriority(%q) = %v, %v", name, p, ok)
+		}
+	}
+	if _, ok := ParsePriority("urgent"); ok {
+		t.Error("expected unknown priority to fail")
+	}
+}
+
+// waitQueued waits until a goroutine started by the test is parked in the queue.
+func waitQueued(t *testing.T, l *implConcurrencyLimiter) {
+	t.Helper()
+	l.mu.Lock()
+	want := l.queued + 1
+	l.mu.Unlock()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		l.mu.Lock()
+		n := l.queued
+		l.mu.Unlock()
+		if n >= want {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %d queued requests", want)
+}
@@ -0,0 +1,157 @@
+/*
+ * Copyright (c) 2026 Karagatan LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package servion
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/xerrors"
+)
+
+const (
+	HeaderXPriority = "X-Priority"
+)
+
+type implConcurrencyLimitMiddleware struct {
+	beanOrder int
+
+	Log     *zap.Logger        `inject:""`
+	Limiter ConcurrencyLimiter `inject:""`
+
+	Prefixes     []string `value:"concurrency.prefixes,default=/"`
+	SkipPrefixes []string `value:"concurrency.skip,default=/ws"`
+
+	// Semicolon separated path prefixes of each class, other paths are normal
+	Critical string `value:"concurrency.critical,default=/healthz;/metrics"`
+	High     string `value:"concurrency.high,default="`
+	Low      string `value:"concurrency.low,default="`
+
+	PriorityHeader string        `value:"concurrency.priority-header,default=X-Priority"`
+	RetryAfter     time.Duration `value:"concurrency.retry-after,default=1s"`
+
+	critical []string
+	high     []string
+	low      []string
+}
+
+/*
+ConcurrencyLimitMiddleware creates an HttpMiddleware that sheds load when the
+process is overloaded, admitting requests through the ConcurrencyLimiter bean of
+the context (see AdaptiveConcurrencyLimiter). Shed requests get 503 with
+Retry-After. Responses with 503 or 504 and requests that ran out of deadline
+report overload to the limiter.
+
+The priority class of a request comes from its path first; otherwise the
+priority header may pick high, normal or low. Critical is never taken from the
+header, so clients can not exempt themselves from shedding.
+
+Long-lived requests would hold a slot for their whole life and feed their duration
+to the limiter as latency: paths under concurrency.skip, WebSocket upgrades,
+Server-Sent Events and gRPC streams pass without admission.
+
+Configuration properties:
+
+	concurrency.prefixes         – URL prefixes to protect (default "/")
+	concurrency.skip             – URL prefixes passed without admission (default "/ws")
+	concurrency.critical         – prefixes never queued nor shed (default "/healthz;/metrics")
+	concurrency.high             – prefixes admitted before normal traffic
+	concurrency.low              – prefixes shed first
+	concurrency.priority-header  – header naming the class of other requests (default "X-Priority")
+	concurrency.retry-after      – Retry-After sent with 503 (default 1s)
+*/
+func ConcurrencyLimitMiddleware(beanOrder int) HttpMiddleware {
+	return &implConcurrencyLimitMiddleware{
+		beanOrder: beanOrder,
+	}
+}
+
+func (t *implConcurrencyLimitMiddleware) PostConstruct() error {
+	t.critical = ParsePrefixList(t.Critical)
+	t.high = ParsePrefixList(t.High)
+	t.low = ParsePrefixList(t.Low)
+	return nil
+}
+
+func (t *implConcurrencyLimitMiddleware) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		if hasAnyPrefix(r.URL.Path, t.SkipPrefixes) || isStreamingRequest(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		priority := t.priorityOf(r)
+
+		release, err := t.Limiter.Acquire(r.Context(), priority)
+		if err != nil {
+			if xerrors.Is(err, ErrOverloaded) {
+				t.Log.Debug("ConcurrencyLimitShed",
+					zap.String("method", r.Method),
+					zap.String("path", r.URL.Path),
+					zap.Stringer("priority", priority),
+				)
+			}
+			w.Header().Set("Retry-After", strconv.Itoa(max(ceilSeconds(t.RetryAfter), 1)))
+			http.Error(w, "server overloaded", http.StatusServiceUnavailable)
+			return
+		}
+
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		defer func() {
+			overloaded := sw.status == http.StatusServiceUnavailable ||
+				sw.status == http.StatusGatewayTimeout ||
+				xerrors.Is(r.Context().Err(), context.DeadlineExceeded)
+			release(!overloaded)
+		}()
+
+		next.ServeHTTP(sw, r)
+	})
+}
+
+func (t *implConcurrencyLimitMiddleware) priorityOf(r *http.Request) Priority {
+	path := r.URL.Path
+	switch {
+	case hasAnyPrefix(path, t.critical):
+		return PriorityCritical
+	case hasAnyPrefix(path, t.high):
+		return PriorityHigh
+	case hasAnyPrefix(path, t.low):
+		return PriorityLow
+	}
+	if t.PriorityHeader != "" {
+		if p, ok := ParsePriority(r.Header.Get(t.PriorityHeader)); ok && p < PriorityCritical {
+			return p
+		}
+	}
+	return PriorityNormal
+}
+
+func (t *implConcurrencyLimitMiddleware) BeanOrder() int {
+	return t.beanOrder
+}
+
+func (t *implConcurrencyLimitMiddleware) Match(prefix string) bool {
+	for _, p := range t.Prefixes {
+		if strings.HasPrefix(prefix, p) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasAnyPrefix(path string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(path, p) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,165 @@
+package servion
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func newTestConcurrencyMiddleware(t *testing.T, l ConcurrencyLimiter) *implConcurrencyLimitMiddleware {
+	t.Helper()
+	m := &implConcurrencyLimitMiddleware{
+		beanOrder:      1,
+		Log:            zap.NewNop(),
+		Limiter:        l,
+		Prefixes:       []string{"/"},
+		SkipPrefixes:   []string{"/ws"},
+		Critical:       "/healthz",
+		Low:            "/api/report",
+		PriorityHeader: HeaderXPriority,
+		RetryAfter:     2 * time.Second,
+	}
+	if err := m.PostConstruct(); err != nil {
+		t.Fatalf("PostConstruct: %v", err)
+	}
+	return m
+}
+
+func TestConcurrencyLimitMiddleware_ShedsWith503(t *testing.T) {
+	l := newTestConcurrencyLimiter(t, 1, 0, nil)
+	m := newTestConcurrencyMiddleware(t, l)
+
+	entered := make(chan struct{})
+	unblock := make(chan struct{})
+	handler := m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/slow" {
+			close(entered)
+			<-unblock
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/slow", nil))
+	}()
+	<-entered
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/data", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want 503", w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "2" {
+		t.Errorf("Retry-After = %q, want 2", got)
+	}
+
+	// health checks pass even while the process is saturated
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("healthz status = %d, want 200", w.Code)
+	}
+
+	close(unblock)
+	<-done
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/data", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("after release: status = %d, want 200", w.Code)
+	}
+}
+
+func TestConcurrencyLimitMiddleware_OverloadResponsesShrinkLimit(t *testing.T) {
+	l := newTestConcurrencyLimiter(t, 10, 0, func(l *implConcurrencyLimiter) {
+		l.Backoff = 50
+	})
+	m := newTestConcurrencyMiddleware(t, l)
+
+	handler := m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusGatewayTimeout)
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/data", nil))
+
+	if l.limit != 5 {
+		t.Errorf("limit = %v, want 5", l.limit)
+	}
+}
+
+func TestConcurrencyLimitMiddleware_Priority(t *testing.T) {
+	m := newTestConcurrencyMiddleware(t, nil)
+
+	tests := []struct {
+		path   string
+		header string
+		want   Priority
+	}{
+		{"/healthz", "", PriorityCritical},
+		{"/api/report/daily", "high", PriorityLow}, // the route wins over the header
+		{"/api/data", "", PriorityNormal},
+		{"/api/data", "high", PriorityHigh},
+		{"/api/data", "critical", PriorityNormal}, // clients can not claim critical
+		{"/api/data", "bogus", PriorityNormal},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		if tt.header != "" {
+			r.Header.Set(HeaderXPriority, tt.header)
+		}
+		if got := m.priorityOf(r); got != tt.want {
+			t.Errorf("priorityOf(%s, %q) = %s, want %s", tt.path, tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestConcurrencyLimitMiddleware_SkipsStreams(t *testing.T) {
+	l := newTestConcurrencyLimiter(t, 1, 0, nil)
+	m := newTestConcurrencyMiddleware(t, l)
+
+	entered := make(chan struct{})
+	unblock := make(chan struct{})
+	handler := m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/slow" {
+			close(entered)
+			<-unblock
+		}
+		// streaming handlers reach Flush through the wrapped writer
+		if err := http.NewResponseController(w).Flush(); err != nil {
+			t.Errorf("Flush: %v", err)
+		}
+	}))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/slow", nil))
+	}()
+	<-entered
+
+	// the only slot is taken, streams still pass
+	ws := httptest.NewRequest(http.MethodGet, "/ws/chat", nil)
+	sse := httptest.NewRequest(http.MethodGet, "/api/events", nil)
+	sse.Header.Set("Accept", "text/event-stream")
+	upgrade := httptest.NewRequest(http.MethodGet, "/api/live", nil)
+	upgrade.Header.Set("Upgrade", "websocket")
+	for _, r := range []*http.Request{ws, sse, upgrade} {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: status = %d, want 200", r.URL.Path, w.Code)
+		}
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/data", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want 503", w.Code)
+	}
+
+	close(unblock)
+	<-done
+}
@@ -0,0 +1,192 @@
+/*
+ * Copyright (c) 2026 Karagatan LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package servion
+
+import (
+	"context"
+	"crypto/subtle"
+	"mime"
+	"net/http"
+	"strings"
+
+	"go.uber.org/zap"
+	"golang.org/x/xerrors"
+)
+
+const (
+	HeaderXCSRFToken = "X-CSRF-Token"
+
+	CsrfDoubleSubmit = "double-submit"
+	CsrfSynchronizer = "synchronizer"
+)
+
+type csrfContextKeyType struct{}
+
+var csrfContextKey = csrfContextKeyType{}
+
+// CsrfTokenFromContext returns the CSRF token of the request, for forms rendered by handlers.
+func CsrfTokenFromContext(ctx context.Context) string {
+	token, _ := ctx.Value(csrfContextKey).(string)
+	return token
+}
+
+type implCsrfMiddleware struct {
+	beanOrder int
+
+	Log      *zap.Logger    `inject:""`
+	Sessions SessionManager `inject:"optional"`
+
+	Prefixes []string `value:"csrf.prefixes,default=/"`
+
+	// double-submit or synchronizer
+	Mode string `value:"csrf.mode,default=double-submit"`
+
+	CookieName     string `value:"csrf.cookie-name,default=servion_csrf"`
+	CookieInsecure bool   `value:"csrf.cookie-insecure,default=false"`
+	Header         string `value:"csrf.header,default=X-CSRF-Token"`
+	FormField      string `value:"csrf.form-field,default=csrf_token"`
+
+	// URL prefixes never checked, e.g. webhooks authenticated otherwise
+	SkipPrefixes []string `value:"csrf.skip,default="`
+}
+
+/*
+CsrfMiddleware creates an HttpMiddleware rejecting cross-site request forgery on
+cookie-authenticated routes. Unsafe methods (POST, PUT, PATCH, DELETE, ...) must
+echo the CSRF token in the csrf.header header or the csrf.form-field form field,
+or get 403. Requests carrying an Authorization header are not checked, since
+browsers never attach those on their own.
+
+In double-submit mode the token is a random cookie readable by scripts, so an SPA
+copies it into the header. In synchronizer mode the token is kept in the session
+of the SessionManager and sent in the X-CSRF-Token response header of safe
+requests; requests without a session are not checked. Handlers rendering forms
+get the token with CsrfTokenFromContext.
+
+Configuration properties:
+
+	csrf.prefixes         – URL prefixes to protect (default "/")
+	csrf.mode             – double-submit (default) or synchronizer
+	csrf.cookie-name      – token cookie of double-submit mode (default "servion_csrf")
+	csrf.cookie-insecure  – drop the Secure flag, for plain http development (default false)
+	csrf.header           – request header carrying the token (default "X-CSRF-Token")
+	csrf.form-field       – form field carrying the token (default "csrf_token")
+	csrf.skip             – URL prefixes never checked
+*/
+func CsrfMiddleware(beanOrder int) HttpMiddleware {
+	return &implCsrfMiddleware{
+		beanOrder: beanOrder,
+	}
+}
+
+func (t *implCsrfMiddleware) PostConstruct() error {
+	switch t.Mode {
+	case CsrfDoubleSubmit:
+	case CsrfSynchronizer:
+		if t.Sessions == nil {
+			return xerrors.New("csrf.mode=synchronizer needs a SessionManager bean")
+		}
+	default:
+		return xerrors.Errorf("csrf.mode must be %s or %s, got '%s'", CsrfDoubleSubmit, CsrfSynchronizer, t.Mode)
+	}
+	return nil
+}
+
+func (t *implCsrfMiddleware) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		if hasAnyPrefix(r.URL.Path, t.SkipPrefixes) || r.Header.Get("Authorization") != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		token, err := t.tokenOf(w, r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if isSafeMethod(r.Method) {
+			if token != "" && t.Mode == CsrfSynchronizer {
+				w.Header().Set(HeaderXCSRFToken, token)
+			}
+		} else if token != "" || t.Mode == CsrfDoubleSubmit {
+			submitted := r.Header.Get(t.Header)
+			if submitted == "" && isFormRequest(r) {
+				submitted = r.PostFormValue(t.FormField)
+			}
+			if token == "" || subtle.ConstantTimeCompare([]byte(submitted), []byte(token)) != 1 {
+				t.Log.Warn("CsrfRejected",
+					zap.String("method", r.Method),
+					zap.String("path", r.URL.Path),
+					zap.Bool("submitted", submitted != ""),
+				)
+				http.Error(w, "CSRF token missing or invalid", http.StatusForbidden)
+				return
+			}
+		}
+
+		if token != "" {
+			r = r.WithContext(context.WithValue(r.Context(), csrfContextKey, token))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// tokenOf returns the token the request must echo, issuing a double-submit cookie when missing.
+func (t *implCsrfMiddleware) tokenOf(w http.ResponseWriter, r *http.Request) (string, error) {
+	if t.Mode == CsrfSynchronizer {
+		s, err := t.Sessions.Session(r)
+		if err != nil || s == nil {
+			return "", err
+		}
+		return s.CSRFToken, nil
+	}
+
+	if c, err := r.Cookie(t.CookieName); err == nil && c.Value != "" {
+		return c.Value, nil
+	}
+	if !isSafeMethod(r.Method) {
+		// a forged request can not know a token that was never issued
+		return "", nil
+	}
+	token := randomToken()
+	http.SetCookie(w, &http.Cookie{
+		Name:     t.CookieName,
+		Value:    token,
+		Path:     "/",
+		Secure:   !t.CookieInsecure,
+		HttpOnly: false, // read by scripts to fill the header
+		SameSite: http.SameSiteStrictMode,
+	})
+	return token, nil
+}
+
+func (t *implCsrfMiddleware) BeanOrder() int {
+	return t.beanOrder
+}
+
+func (t *implCsrfMiddleware) Match(prefix string) bool {
+	for _, p := range t.Prefixes {
+		if strings.HasPrefix(prefix, p) {
+			return true
+		}
+	}
+	return false
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+func isFormRequest(r *http.Request) bool {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return mediaType == "application/x-www-form-urlencoded" || mediaType == "multipart/form-data"
+}
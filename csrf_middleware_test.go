@@ -0,0 +1,144 @@
+package servion
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func newTestCsrfMiddleware(t *testing.T, mode string, sessions SessionManager) (*implCsrfMiddleware, http.Handler) {
+	t.Helper()
+	m := &implCsrfMiddleware{
+		Log:          zap.NewNop(),
+		Sessions:     sessions,
+		Prefixes:     []string{"/"},
+		Mode:         mode,
+		CookieName:   "servion_csrf",
+		Header:       HeaderXCSRFToken,
+		FormField:    "csrf_token",
+		SkipPrefixes: []string{"/hooks"},
+	}
+	if err := m.PostConstruct(); err != nil {
+		t.Fatalf("PostConstruct: %v", err)
+	}
+	return m, m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(CsrfTokenFromContext(r.Context())))
+	}))
+}
+
+func TestCsrfMiddleware_DoubleSubmit(t *testing.T) {
+	_, h := newTestCsrfMiddleware(t, CsrfDoubleSubmit, nil)
+
+	// a page load issues the token cookie
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin", nil))
+	cookie := responseCookie(w, "servion_csrf")
+	if w.Code != http.StatusOK || cookie == nil || cookie.HttpOnly || cookie.Value != w.Body.String() {
+		t.Fatalf("GET: status %d, cookie %+v, body %q", w.Code, cookie, w.Body)
+	}
+
+	post := func(header, form string, withCookie bool) int {
+		var r *http.Request
+		if form != "" {
+			r = httptest.NewRequest(http.MethodPost, "/admin/users", strings.NewReader(url.Values{"csrf_token": {form}}.Encode()))
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		} else {
+			r = httptest.NewRequest(http.MethodPost, "/admin/users", nil)
+		}
+		if header != "" {
+			r.Header.Set(HeaderXCSRFToken, header)
+		}
+		if withCookie {
+			r.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	if code := post(cookie.Value, "", true); code != http.StatusOK {
+		t.Errorf("header token: status = %d", code)
+	}
+	if code := post("", cookie.Value, true); code != http.StatusOK {
+		t.Errorf("form token: status = %d", code)
+	}
+	if code := post("", "", true); code != http.StatusForbidden {
+		t.Errorf("missing token: status = %d", code)
+	}
+	if code := post("forged", "", true); code != http.StatusForbidden {
+		t.Errorf("wrong token: status = %d", code)
+	}
+	if code := post(cookie.Value, "", false); code != http.StatusForbidden {
+		t.Errorf("missing cookie: status = %d", code)
+	}
+
+	// bearer requests and skipped prefixes are not cookie authenticated
+	r := httptest.NewRequest(http.MethodDelete, "/admin/users/1", nil)
+	r.Header.Set("Authorization", "Bearer abc")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Errorf("bearer: status = %d", w.Code)
+	}
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/hooks/github", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("skipped prefix: status = %d", w.Code)
+	}
+}
+
+func TestCsrfMiddleware_Synchronizer(t *testing.T) {
+	sessions := newTestSessionManager(t, time.Hour, 2*time.Hour)
+	_, h := newTestCsrfMiddleware(t, CsrfSynchronizer, sessions)
+
+	w := httptest.NewRecorder()
+	s, err := sessions.Start(w, requestWithCookie(nil), AuthInfo{Subject: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sid := responseCookie(w, "servion_sid")
+
+	r := httptest.NewRequest(http.MethodGet, "/admin", nil)
+	r.AddCookie(sid)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if got := w.Header().Get(HeaderXCSRFToken); got != s.CSRFToken || w.Body.String() != s.CSRFToken {
+		t.Fatalf("token header %q, body %q, want %q", got, w.Body, s.CSRFToken)
+	}
+
+	post := func(token string) int {
+		r := httptest.NewRequest(http.MethodPost, "/admin/users", nil)
+		r.AddCookie(sid)
+		r.Header.Set(HeaderXCSRFToken, token)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Code
+	}
+	if code := post(s.CSRFToken); code != http.StatusOK {
+		t.Errorf("valid token: status = %d", code)
+	}
+	if code := post("forged"); code != http.StatusForbidden {
+		t.Errorf("forged token: status = %d", code)
+	}
+
+	// without a session there is no ambient credential to forge
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/login", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("no session: status = %d", w.Code)
+	}
+}
+
+func TestCsrfMiddleware_PostConstruct(t *testing.T) {
+	if err := (&implCsrfMiddleware{Mode: CsrfSynchronizer}).PostConstruct(); err == nil {
+		t.Error("expected error for synchronizer mode without SessionManager")
+	}
+	if err := (&implCsrfMiddleware{Mode: "origin"}).PostConstruct(); err == nil {
+		t.Error("expected error for unknown mode")
+	}
+}
@@ -12,6 +12,7 @@ import (
 
 	"go.arpabet.com/glue"
 	"go.arpabet.com/servion"
+	"golang.org/x/xerrors"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
@@ -27,11 +28,15 @@ var defaultAuthExempt = []string{
 }
 
 type implAuthInterceptor struct {
-	Authenticator servion.Authenticator `inject:""`
-	Properties    glue.Properties       `inject:""`
-
-	beanOrder int
-	exempt    []string
+	Authenticators []servion.Authenticator          `inject:"optional"`
+	Certificates   servion.CertificateAuthenticator `inject:"optional"`
+	Properties     glue.Properties                  `inject:""`
+
+	beanOrder     int
+	exempt        []string
+	authenticator servion.Authenticator
+	sources       []servion.CredentialSource
+	optional      bool
 }
 
 /*
@@ -40,13 +45,25 @@ calls using the servion.Authenticator in the context. It is the gRPC counterpart
 of servion.AuthMiddleware and implements both UnaryInterceptor and
 StreamInterceptor.
 
-Each non-exempt call must carry an "authorization: Bearer <token>" header; the
-token is validated by the Authenticator and on success the resulting
-servion.AuthInfo is stored in the context, retrievable downstream with
-servion.AuthFromContext - exactly as with the HTTP middleware. Health and
-reflection methods are exempt by default; extend the exempt list with the
-comma-separated property "grpc.auth.exempt" (fully-qualified method prefixes,
-e.g. "/myapp.Public/").
+Each non-exempt call must carry a credential, by default an
+"authorization: Bearer <token>" header; the comma-separated property
+"grpc.auth.credentials" lists other sources tried in order, "bearer", "basic"
+or "header:<key>" such as "header:x-api-key". The token is validated by the
+Authenticator beans of the context, tried in BeanOrder as with
+servion.ChainAuthenticators, and on success the resulting servion.AuthInfo is
+stored in the context, retrievable downstream with servion.AuthFromContext -
+exactly as with the HTTP middleware.
+
+When the context has a servion.CertificateAuthenticator, calls without an
+authorization header over a connection with a verified client certificate
+(mutual TLS) are authenticated by that certificate instead. At least one
+authenticator must be present. With "grpc.auth.optional=true" calls without
+any credential proceed anonymously, while invalid credentials are still
+refused.
+
+Health and reflection methods are exempt by default; extend the exempt list
+with the comma-separated property "grpc.auth.exempt" (fully-qualified method
+prefixes, e.g. "/myapp.Public/").
 
 beanOrder controls chaining order relative to other interceptors; lower runs
 first.
@@ -56,6 +73,25 @@ func AuthInterceptor(beanOrder int) Interceptor {
 }
 
 func (t *implAuthInterceptor) PostConstruct() error {
+	if len(t.Authenticators) == 0 && t.Certificates == nil {
+		return errors.New("grpc auth interceptor needs a servion.Authenticator or servion.CertificateAuthenticator bean")
+	}
+	if len(t.Authenticators) > 0 {
+		t.authenticator = servion.ChainAuthenticators(servion.OrderAuthenticators(t.Authenticators)...)
+	}
+
+	sources, err := servion.ParseCredentialSources(strings.Split(t.Properties.GetString("grpc.auth.credentials", ""), ","))
+	if err != nil {
+		return xerrors.Errorf("grpc.auth.credentials: %w", err)
+	}
+	for _, src := range sources {
+		if src.Kind == servion.CredentialCookie || src.Kind == servion.CredentialQuery {
+			return xerrors.Errorf("grpc.auth.credentials: %s is not available over gRPC", src)
+		}
+	}
+	t.sources = sources
+	t.optional = t.Properties.GetBool("grpc.auth.optional", false)
+
 	t.exempt = append([]string{}, defaultAuthExempt...)
 	for _, p := range strings.Split(t.Properties.GetString("grpc.auth.exempt", ""), ",") {
 		if p = strings.TrimSpace(p); p != "" {
@@ -96,12 +132,29 @@ func (t *implAuthInterceptor) authenticate(ctx context.Context, fullMethod strin
 		return ctx, nil
 	}
 
-	token, err := bearerFromContext(ctx)
-	if err != nil {
-		return nil, err
+	var (
+		info servion.AuthInfo
+		err  error
+	)
+	md, _ := metadata.FromIncomingContext(ctx)
+	token, found := t.credential(md)
+	hasHeader := len(md.Get("authorization")) > 0
+
+	state := peerTLSState(ctx)
+	switch {
+	case !found && !hasHeader && t.Certificates != nil && state != nil && len(state.VerifiedChains) > 0:
+		info, err = t.Certificates.AuthenticateCertificate(state)
+	case !found && !hasHeader && t.optional:
+		return ctx, nil
+	case !found && hasHeader:
+		return nil, status.Error(codes.Unauthenticated, "invalid authorization header")
+	case !found:
+		return nil, status.Error(codes.Unauthenticated, "missing authorization header")
+	case t.authenticator == nil:
+		return nil, status.Error(codes.Unauthenticated, "missing client certificate")
+	default:
+		info, err = t.authenticator.Authenticate(token)
 	}
-
-	info, err := t.Authenticator.Authenticate(token)
 	if errors.Is(err, servion.ErrUnauthorized) {
 		return nil, status.Error(codes.Unauthenticated, err.Error())
 	}
@@ -144,18 +197,21 @@ type authServerStream struct {
 
 func (s *authServerStream) Context() context.Context { return s.ctx }
 
-func bearerFromContext(ctx context.Context) (string, error) {
-	md, ok := metadata.FromIncomingContext(ctx)
-	if !ok {
-		return "", status.Error(codes.Unauthenticated, "missing metadata")
-	}
-	values := md.Get("authorization")
-	if len(values) == 0 {
-		return "", status.Error(codes.Unauthenticated, "missing authorization header")
-	}
-	parts := strings.Fields(values[0])
-	if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") {
-		return "", status.Error(codes.Unauthenticated, "invalid authorization header")
+// credential returns the credential of the first source present in md.
+func (t *implAuthInterceptor) credential(md metadata.MD) (string, bool) {
+	for _, src := range t.sources {
+		switch src.Kind {
+		case servion.CredentialBearer, servion.CredentialBasic:
+			if values := md.Get("authorization"); len(values) > 0 {
+				if token, ok := src.FromAuthorization(values[0]); ok {
+					return token, true
+				}
+			}
+		case servion.CredentialHeader:
+			if values := md.Get(src.Name); len(values) > 0 && strings.TrimSpace(values[0]) != "" {
+				return strings.TrimSpace(values[0]), true
+			}
+		}
 	}
-	return parts[1], nil
+	return "", false
 }
@@ -0,0 +1,99 @@
+/*
+ * Copyright (c) 2026 Karagatan LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package serviongrpc
+
+import (
+	"context"
+	"errors"
+
+	"go.arpabet.com/glue"
+	"go.arpabet.com/servion"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type implAuthzInterceptor struct {
+	Authorizer servion.Authorizer `inject:""`
+	Properties glue.Properties    `inject:""`
+
+	beanOrder int
+	exempt    []string
+}
+
+/*
+AuthzInterceptor returns an Interceptor bean that authorizes incoming gRPC calls
+with the servion.Authorizer in the context (see servion.PolicyAuthorizer), the
+gRPC counterpart of servion.AuthzMiddleware. Give it a higher bean order than
+AuthInterceptor so the identity is already in the context.
+
+Rules target calls as "grpc:/pkg.Service/Method". Denied calls fail with
+PermissionDenied, anonymous calls to rules needing an identity with
+Unauthenticated. Health and reflection methods are exempt by default; extend the
+exempt list with the comma-separated property "grpc.authz.exempt".
+*/
+func AuthzInterceptor(beanOrder int) Interceptor {
+	return &implAuthzInterceptor{beanOrder: beanOrder}
+}
+
+func (t *implAuthzInterceptor) PostConstruct() error {
+	t.exempt = append(append([]string{}, defaultAuthExempt...),
+		methodPrefixes(t.Properties.GetString("grpc.authz.exempt", ""))...)
+	return nil
+}
+
+func (t *implAuthzInterceptor) BeanName() string { return "grpc-authz-interceptor" }
+
+func (t *implAuthzInterceptor) BeanOrder() int { return t.beanOrder }
+
+func (t *implAuthzInterceptor) authorize(ctx context.Context, fullMethod string) error {
+
+	// same guard as AuthInterceptor, exempt prefixes assume "/Service/Method"
+	if len(fullMethod) == 0 || fullMethod[0] != '/' {
+		return status.Error(codes.Unimplemented, "malformed method name")
+	}
+	if hasMethodPrefix(fullMethod, t.exempt) {
+		return nil
+	}
+
+	var info *servion.AuthInfo
+	if auth, ok := servion.AuthFromContext(ctx); ok {
+		info = &auth
+	}
+
+	err := t.Authorizer.Authorize(ctx, servion.AccessRequest{
+		Transport: servion.TransportGRPC,
+		Resource:  fullMethod,
+	}, info)
+	if errors.Is(err, servion.ErrUnauthorized) {
+		return status.Error(codes.Unauthenticated, "authentication required")
+	}
+	if errors.Is(err, servion.ErrForbidden) {
+		return status.Error(codes.PermissionDenied, "permission denied")
+	}
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+	return nil
+}
+
+func (t *implAuthzInterceptor) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := t.authorize(ctx, info.FullMethod); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func (t *implAuthzInterceptor) StreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := t.authorize(ss.Context(), info.FullMethod); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
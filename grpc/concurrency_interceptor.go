@@ -0,0 +1,161 @@
+/*
+ * Copyright (c) 2026 Karagatan LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package serviongrpc
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.arpabet.com/glue"
+	"go.arpabet.com/servion"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// defaultConcurrencyCritical holds the method prefixes never shed out of the box,
+// so health probes keep answering while the server is saturated.
+var defaultConcurrencyCritical = []string{
+	"/grpc.health.v1.Health/",
+}
+
+// retryPushbackKey is the trailer grpc clients read to delay a retry (gRFC A6).
+const retryPushbackKey = "grpc-retry-pushback-ms"
+
+type implConcurrencyInterceptor struct {
+	Limiter    servion.ConcurrencyLimiter `inject:""`
+	Properties glue.Properties            `inject:""`
+
+	beanOrder      int
+	critical       []string
+	high           []string
+	low            []string
+	priorityHeader string
+	retryAfter     time.Duration
+}
+
+/*
+ConcurrencyInterceptor returns an Interceptor bean that sheds load through the
+servion.ConcurrencyLimiter in the context. It is the gRPC counterpart of
+servion.ConcurrencyLimitMiddleware; with both in one process HTTP and gRPC calls
+share the same adaptive limit.
+
+Shed calls fail with codes.Unavailable and a "grpc-retry-pushback-ms" trailer.
+Calls failing with DeadlineExceeded, Unavailable or ResourceExhausted report
+overload to the limiter.
+
+The priority class comes from the method first, then from the "x-priority"
+metadata (high, normal or low; never critical). Health methods are critical by
+default. Comma-separated fully-qualified method prefixes are read from:
+
+	grpc.concurrency.critical        – never queued nor shed, added to the health service
+	grpc.concurrency.high            – admitted before normal calls
+	grpc.concurrency.low             – shed first
+	grpc.concurrency.priority-header – metadata key naming the class (default "x-priority")
+	concurrency.retry-after          – retry pushback sent with shed calls (default 1s)
+
+beanOrder controls chaining order relative to other interceptors; lower runs
+first.
+*/
+func ConcurrencyInterceptor(beanOrder int) Interceptor {
+	return &implConcurrencyInterceptor{beanOrder: beanOrder}
+}
+
+func (t *implConcurrencyInterceptor) PostConstruct() error {
+	t.critical = append(append([]string{}, defaultConcurrencyCritical...), methodPrefixes(t.Properties.GetString("grpc.concurrency.critical", ""))...)
+	t.high = methodPrefixes(t.Properties.GetString("grpc.concurrency.high", ""))
+	t.low = methodPrefixes(t.Properties.GetString("grpc.concurrency.low", ""))
+	t.priorityHeader = strings.ToLower(t.Properties.GetString("grpc.concurrency.priority-header", "x-priority"))
+	t.retryAfter = t.Properties.GetDuration("concurrency.retry-after", time.Second)
+	return nil
+}
+
+func (t *implConcurrencyInterceptor) BeanName() string { return "grpc-concurrency-interceptor" }
+
+func (t *implConcurrencyInterceptor) BeanOrder() int { return t.beanOrder }
+
+func (t *implConcurrencyInterceptor) priorityOf(ctx context.Context, fullMethod string) servion.Priority {
+	switch {
+	case hasMethodPrefix(fullMethod, t.critical):
+		return servion.PriorityCritical
+	case hasMethodPrefix(fullMethod, t.high):
+		return servion.PriorityHigh
+	case hasMethodPrefix(fullMethod, t.low):
+		return servion.PriorityLow
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok && t.priorityHeader != "" {
+		if values := md.Get(t.priorityHeader); len(values) > 0 {
+			if p, ok := servion.ParsePriority(values[0]); ok && p < servion.PriorityCritical {
+				return p
+			}
+		}
+	}
+	return servion.PriorityNormal
+}
+
+func (t *implConcurrencyInterceptor) acquire(ctx context.Context, fullMethod string) (func(error), error) {
+	release, err := t.Limiter.Acquire(ctx, t.priorityOf(ctx, fullMethod))
+	if err != nil {
+		if errors.Is(err, servion.ErrOverloaded) {
+			grpc.SetTrailer(ctx, metadata.Pairs(retryPushbackKey, strconv.FormatInt(t.retryAfter.Milliseconds(), 10)))
+			return nil, status.Error(codes.Unavailable, err.Error())
+		}
+		return nil, status.FromContextError(err).Err()
+	}
+	return func(err error) {
+		switch status.Code(err) {
+		case codes.DeadlineExceeded, codes.Unavailable, codes.ResourceExhausted:
+			release(false)
+		default:
+			release(true)
+		}
+	}, nil
+}
+
+func (t *implConcurrencyInterceptor) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		release, err := t.acquire(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		defer func() { release(err) }()
+		return handler(ctx, req)
+	}
+}
+
+func (t *implConcurrencyInterceptor) StreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		release, err := t.acquire(ss.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		defer func() { release(err) }()
+		return handler(srv, ss)
+	}
+}
+
+func methodPrefixes(list string) []string {
+	var out []string
+	for _, p := range strings.Split(list, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+func hasMethodPrefix(fullMethod string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(fullMethod, p) {
+			return true
+		}
+	}
+	return false
+}
@@ -21,11 +21,13 @@ import (
 )
 
 type implGrpcClientFactory struct {
-	Log        *zap.Logger     `inject:""`
-	Properties glue.Properties `inject:""`
-	TlsConfig  *tls.Config     `inject:"optional"`
+	Log         *zap.Logger               `inject:""`
+	Properties  glue.Properties           `inject:""`
+	TlsConfig   *tls.Config               `inject:"optional"`
+	TlsProfiles servion.TlsConfigProvider `inject:"optional"`
 
-	beanName string
+	beanName  string
+	tlsConfig *tls.Config
 }
 
 /*
@@ -42,8 +44,9 @@ Recognized properties (prefixed by beanName):
 	<beanName>.connect-address     target host:port (overrides the derivation above)
 	<beanName>.max-recv-msg-size   max inbound message size in bytes
 	<beanName>.auth-token          bearer token sent as per-RPC credentials on every call
+	<beanName>.tls-profile         TLS profile of servion.TlsProfiles, in place of a *tls.Config bean
 
-TLS is used when a *tls.Config bean is present in the context, otherwise the
+TLS is used with a profile or when a *tls.Config bean is present in the context, otherwise the
 connection is insecure (plaintext) which is the common case for in-cluster
 traffic where TLS is terminated by the infrastructure.
 */
@@ -65,10 +68,20 @@ func (t *implGrpcClientFactory) Object() (object interface{}, err error) {
 		connectAddr = localizeAddr(bindAddr)
 	}
 
+	t.tlsConfig = t.TlsConfig
+	if profile := tlsProfile(t.Properties, t.beanName); profile != "" {
+		if t.TlsProfiles == nil {
+			return nil, xerrors.Errorf("property '%s.tls-profile' needs a TlsConfigProvider in context", t.beanName)
+		}
+		if t.tlsConfig, err = t.TlsProfiles.ClientConfig(profile); err != nil {
+			return nil, xerrors.Errorf("property '%s.tls-profile': %w", t.beanName, err)
+		}
+	}
+
 	t.Log.Info("GrpcClientFactory",
 		zap.String("bean", t.beanName),
 		zap.String("connectAddr", connectAddr),
-		zap.Bool("tls", t.TlsConfig != nil))
+		zap.Bool("tls", t.tlsConfig != nil))
 
 	return t.dial(connectAddr)
 }
@@ -80,8 +93,8 @@ func (t *implGrpcClientFactory) ObjectName() string { return t.beanName }
 func (t *implGrpcClientFactory) Singleton() bool { return true }
 
 func (t *implGrpcClientFactory) transportCreds() credentials.TransportCredentials {
-	if t.TlsConfig != nil {
-		return credentials.NewTLS(t.TlsConfig)
+	if t.tlsConfig != nil {
+		return credentials.NewTLS(t.tlsConfig)
 	}
 	return insecure.NewCredentials()
 }
@@ -97,7 +110,7 @@ func (t *implGrpcClientFactory) dial(connectAddr string) (*grpc.ClientConn, erro
 	}
 
 	if token := t.Properties.GetString(fmt.Sprintf("%s.auth-token", t.beanName), ""); token != "" {
-		opts = append(opts, grpc.WithPerRPCCredentials(tokenAuth{token: token, secure: t.TlsConfig != nil}))
+		opts = append(opts, grpc.WithPerRPCCredentials(tokenAuth{token: token, secure: t.tlsConfig != nil}))
 	}
 
 	// non-blocking; the connection is established lazily on first use so server
@@ -0,0 +1,81 @@
+/*
+ * Copyright (c) 2026 Karagatan LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package serviongrpc
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.arpabet.com/glue"
+	"go.arpabet.com/servion"
+	"go.uber.org/zap"
+	"golang.org/x/xerrors"
+	"google.golang.org/grpc"
+)
+
+type implGrpcDispatcher struct {
+	Container  glue.Container  `inject:""`
+	Log        *zap.Logger     `inject:""`
+	Properties glue.Properties `inject:""`
+
+	beanName string
+	srv      *grpc.Server
+}
+
+/*
+GrpcDispatcher serves the *grpc.Server bean named beanName on the port of the HTTP
+server of the same name. HTTP/2 requests with an application/grpc content type go
+to the gRPC server, everything else to the mux router. HTTP/2 is negotiated by
+ALPN under TLS, so the server needs TLS or the h2c option. It is registered
+automatically by GrpcHttpServerScanner.
+*/
+func GrpcDispatcher(beanName string) servion.HttpDispatcher {
+	return &implGrpcDispatcher{beanName: beanName}
+}
+
+func (t *implGrpcDispatcher) PostConstruct() error {
+	options := servion.ParseOptions(t.Properties.GetString(fmt.Sprintf("%s.options", t.beanName), ""))
+	if !options["h2c"] && !options["tls"] && tlsProfile(t.Properties, t.beanName) == "" {
+		return xerrors.Errorf("gRPC on the port of server '%s' needs HTTP/2, enable the tls or h2c option or set a tls-profile", t.beanName)
+	}
+
+	for _, b := range t.Container.Bean(GrpcServerClass, 1) {
+		if b.Name() == t.beanName {
+			srv, ok := b.Object().(*grpc.Server)
+			if !ok {
+				return xerrors.Errorf("bean '%s' is not a *grpc.Server", t.beanName)
+			}
+			t.srv = srv
+			t.Log.Info("GrpcDispatcher", zap.String("bean", t.beanName))
+			return nil
+		}
+	}
+	return xerrors.Errorf("grpc.Server bean '%s' not found in server context", t.beanName)
+}
+
+func (t *implGrpcDispatcher) Dispatch(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor != 2 || !strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		// streams outlive the read and write timeouts of the HTTP server, calls carry their own deadlines
+		rc := http.NewResponseController(w)
+		rc.SetReadDeadline(time.Time{})
+		rc.SetWriteDeadline(time.Time{})
+		t.srv.ServeHTTP(w, r)
+	})
+}
+
+func (t *implGrpcDispatcher) Destroy() error {
+	// the HTTP server has shut down already, end the calls still running
+	if t.srv != nil {
+		t.srv.Stop()
+	}
+	return nil
+}
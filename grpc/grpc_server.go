@@ -30,13 +30,15 @@ const (
 )
 
 type implGrpcServer struct {
-	Container  glue.Container  `inject:""`
-	Log        *zap.Logger     `inject:""`
-	Properties glue.Properties `inject:""`
-	TlsConfig  *tls.Config     `inject:"optional"`
+	Container   glue.Container            `inject:""`
+	Log         *zap.Logger               `inject:""`
+	Properties  glue.Properties           `inject:""`
+	TlsConfig   *tls.Config               `inject:"optional"`
+	TlsProfiles servion.TlsConfigProvider `inject:"optional"`
 
 	beanName   string
 	listenAddr string
+	tlsConfig  *tls.Config
 
 	srv      *grpc.Server
 	listener net.Listener
@@ -82,13 +84,18 @@ func (t *implGrpcServer) Bind() (err error) {
 		return xerrors.Errorf("property '%s.bind-address' not found in server context", t.beanName)
 	}
 
+	t.tlsConfig, err = serverTLSConfig(t.Properties, t.beanName, t.TlsConfig, t.TlsProfiles)
+	if err != nil {
+		return err
+	}
+
 	t.listener, err = net.Listen("tcp", t.listenAddr)
 	if err != nil {
 		return xerrors.Errorf("can not bind to '%s': %w", t.listenAddr, err)
 	}
 
-	if t.TlsConfig != nil {
-		t.listener = tls.NewListener(t.listener, ensureH2(t.TlsConfig.Clone()))
+	if t.tlsConfig != nil {
+		t.listener = tls.NewListener(t.listener, ensureH2(t.tlsConfig))
 	}
 
 	return nil
@@ -177,12 +184,12 @@ func (t *implGrpcServer) Serve() (err error) {
 	defer servion.PanicToError(&err)
 
 	addr := t.ListenAddress()
-	if t.TlsConfig != nil {
+	if t.tlsConfig != nil {
 		t.Log.Info("GrpcServerServe",
 			zap.String("addr", addr.String()),
 			zap.String("network", addr.Network()),
 			zap.Bool("tls", true),
-			zap.Bool("insecure", t.TlsConfig.InsecureSkipVerify))
+			zap.Bool("insecure", t.tlsConfig.InsecureSkipVerify))
 	} else {
 		t.Log.Info("GrpcServerServe",
 			zap.String("addr", addr.String()),
@@ -202,6 +209,30 @@ func (t *implGrpcServer) Serve() (err error) {
 	return err
 }
 
+// tlsProfile returns the <beanName>.tls-profile property; a profile takes precedence over a *tls.Config bean.
+func tlsProfile(props glue.Properties, beanName string) string {
+	return props.GetString(fmt.Sprintf("%s.tls-profile", beanName), "")
+}
+
+// serverTLSConfig returns a copy of the TLS configuration of server beanName, or nil without TLS.
+func serverTLSConfig(props glue.Properties, beanName string, cfg *tls.Config, profiles servion.TlsConfigProvider) (*tls.Config, error) {
+	profile := tlsProfile(props, beanName)
+	if profile == "" {
+		if cfg != nil {
+			return cfg.Clone(), nil
+		}
+		return nil, nil
+	}
+	if profiles == nil {
+		return nil, xerrors.Errorf("property '%s.tls-profile' needs a TlsConfigProvider in server context", beanName)
+	}
+	cfg, err := profiles.ServerConfig(profile)
+	if err != nil {
+		return nil, xerrors.Errorf("property '%s.tls-profile': %w", beanName, err)
+	}
+	return cfg, nil
+}
+
 // ensureH2 makes sure the TLS config advertises the HTTP/2 ALPN protocol, which
 // gRPC requires when serving over TLS.
 func ensureH2(cfg *tls.Config) *tls.Config {
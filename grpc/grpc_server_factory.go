@@ -6,6 +6,7 @@
 package serviongrpc
 
 import (
+	"crypto/tls"
 	"fmt"
 	"reflect"
 	"sort"
@@ -23,6 +24,7 @@ type implGrpcServerFactory struct {
 	Services   []GrpcService       `inject:"optional,level=1"`
 	Unary      []UnaryInterceptor  `inject:"optional,level=1"`
 	Stream     []StreamInterceptor `inject:"optional,level=1"`
+	TlsConfig  *tls.Config         `inject:"optional"`
 
 	beanName string
 }
@@ -38,6 +40,7 @@ Recognized properties (prefixed by beanName):
 	<beanName>.options             semicolon separated flags: "health;reflection"
 	<beanName>.max-recv-msg-size   max inbound message size in bytes (0 = grpc default)
 	<beanName>.max-send-msg-size   max outbound message size in bytes (0 = grpc default)
+	<beanName>.tls-profile         TLS profile of servion.TlsProfiles, in place of a *tls.Config bean
 
 The "health" flag installs the standard grpc.health.v1.Health service (useful for
 Kubernetes gRPC probes); the "reflection" flag enables server reflection (useful
@@ -62,6 +65,11 @@ func (t *implGrpcServerFactory) Object() (object interface{}, err error) {
 		opts = append(opts, grpc.MaxSendMsgSize(n))
 	}
 
+	if t.TlsConfig != nil || tlsProfile(t.Properties, t.beanName) != "" {
+		// GrpcServer terminates TLS in its listener, expose the client certificates to interceptors
+		opts = append(opts, grpc.Creds(listenerTLSCredentials{}))
+	}
+
 	if unary := t.unaryInterceptors(); len(unary) > 0 {
 		opts = append(opts, grpc.ChainUnaryInterceptor(unary...))
 	}
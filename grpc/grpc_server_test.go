@@ -7,6 +7,18 @@ package serviongrpc_test
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -16,9 +28,11 @@ import (
 	serviongrpc "go.arpabet.com/servion/grpc"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
 	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/wrapperspb"
 )
@@ -284,3 +298,421 @@ func TestGrpcClientFactory_Connect(t *testing.T) {
 		t.Fatalf("expected SERVING, got %v", resp.Status)
 	}
 }
+
+// writeTlsFiles writes a CA and a localhost certificate signed by it as PEM files into dir.
+func writeTlsFiles(t *testing.T, dir string) {
+	t.Helper()
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDer, err := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caCert, _ := x509.ParseCertificate(caDer)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, caCert, &key.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, block := range map[string]*pem.Block{
+		"ca.crt":  {Type: "CERTIFICATE", Bytes: caDer},
+		"tls.crt": {Type: "CERTIFICATE", Bytes: der},
+		"tls.key": {Type: "PRIVATE KEY", Bytes: keyDer},
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), pem.EncodeToMemory(block), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestGrpcServer_TlsProfile(t *testing.T) {
+
+	dir := t.TempDir()
+	writeTlsFiles(t, dir)
+	profile := glue.MapPropertySource{
+		"tls.profiles":             "internal",
+		"tls.internal.cert-file":   filepath.Join(dir, "tls.crt"),
+		"tls.internal.key-file":    filepath.Join(dir, "tls.key"),
+		"tls.internal.ca-file":     filepath.Join(dir, "ca.crt"),
+		"tls.internal.client-auth": "require-and-verify",
+		"tls.internal.server-name": "localhost",
+	}
+
+	addr, teardown := startServer(t,
+		profile,
+		glue.MapPropertySource{
+			"grpc-server.options":     "health",
+			"grpc-server.tls-profile": "internal",
+		},
+		servion.TlsProfiles(),
+		serviongrpc.GrpcServerScanner("grpc-server"),
+	)
+	defer teardown()
+
+	// a plaintext client is refused
+	plain := dial(t, addr)
+	defer plain.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if _, err := healthpb.NewHealthClient(plain).Check(ctx, &healthpb.HealthCheckRequest{}); err == nil {
+		t.Fatal("plaintext health check succeeded on a TLS server")
+	}
+
+	clientCtx, err := glue.New(
+		profile,
+		glue.MapPropertySource{
+			"grpc-client.connect-address": addr,
+			"grpc-client.tls-profile":     "internal",
+		},
+		servion.ZapLogFactory(true),
+		servion.TlsProfiles(),
+		serviongrpc.GrpcClientScanner("grpc-client"),
+	)
+	if err != nil {
+		t.Fatalf("client context: %v", err)
+	}
+	defer clientCtx.Close()
+
+	list := clientCtx.Bean(serviongrpc.GrpcClientConnClass, glue.DefaultSearchLevel)
+	if len(list) != 1 {
+		t.Fatalf("expected exactly 1 *grpc.ClientConn, got %d", len(list))
+	}
+	conn := list[0].Object().(*grpc.ClientConn)
+
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatalf("health check over mutual TLS: %v", err)
+	}
+	if resp.Status != healthpb.HealthCheckResponse_SERVING {
+		t.Fatalf("expected SERVING, got %v", resp.Status)
+	}
+}
+
+func TestConcurrencyInterceptor_ShedsUnderLoad(t *testing.T) {
+
+	ctx, err := glue.New(
+		glue.MapPropertySource{
+			"concurrency.initial-limit": "1",
+			"concurrency.max-limit":     "1",
+			"concurrency.queue-size":    "0",
+		},
+		servion.ZapLogFactory(true),
+		servion.AdaptiveConcurrencyLimiter(),
+		serviongrpc.ConcurrencyInterceptor(5),
+	)
+	if err != nil {
+		t.Fatalf("context: %v", err)
+	}
+	defer ctx.Close()
+
+	list := ctx.Bean(serviongrpc.UnaryInterceptorClass, glue.DefaultSearchLevel)
+	if len(list) != 1 {
+		t.Fatalf("expected exactly 1 UnaryInterceptor, got %d", len(list))
+	}
+	fn := list[0].Object().(serviongrpc.UnaryInterceptor).UnaryInterceptor()
+
+	entered := make(chan struct{})
+	unblock := make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		_, err := fn(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: helloMethod},
+			func(context.Context, interface{}) (interface{}, error) {
+				close(entered)
+				<-unblock
+				return nil, nil
+			})
+		done <- err
+	}()
+	<-entered
+
+	ok := func(context.Context, interface{}) (interface{}, error) { return nil, nil }
+
+	// the only slot is taken, a second call is shed
+	_, err = fn(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: helloMethod}, ok)
+	if status.Code(err) != codes.Unavailable {
+		t.Fatalf("expected Unavailable, got %v", err)
+	}
+
+	// health checks are critical and never shed
+	if _, err := fn(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/grpc.health.v1.Health/Check"}, ok); err != nil {
+		t.Fatalf("health check: %v", err)
+	}
+
+	close(unblock)
+	if err := <-done; err != nil {
+		t.Fatalf("first call: %v", err)
+	}
+	if _, err := fn(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: helloMethod}, ok); err != nil {
+		t.Fatalf("after release: %v", err)
+	}
+}
+
+func TestAuthzInterceptor_EnforcesPolicy(t *testing.T) {
+
+	ctx, err := glue.New(
+		glue.MapPropertySource{
+			"authz.rules": "grpc:/servion.test.Echo/* = role:admin",
+		},
+		servion.ZapLogFactory(true),
+		servion.PolicyAuthorizer(),
+		serviongrpc.AuthzInterceptor(20),
+	)
+	if err != nil {
+		t.Fatalf("context: %v", err)
+	}
+	defer ctx.Close()
+
+	list := ctx.Bean(serviongrpc.UnaryInterceptorClass, glue.DefaultSearchLevel)
+	if len(list) != 1 {
+		t.Fatalf("expected exactly 1 UnaryInterceptor, got %d", len(list))
+	}
+	fn := list[0].Object().(serviongrpc.UnaryInterceptor).UnaryInterceptor()
+
+	ok := func(context.Context, interface{}) (interface{}, error) { return nil, nil }
+	call := func(ctx context.Context, method string) error {
+		_, err := fn(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method}, ok)
+		return err
+	}
+
+	if err := call(context.Background(), helloMethod); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("anonymous: expected Unauthenticated, got %v", err)
+	}
+	user := servion.ContextWithAuth(context.Background(), servion.AuthInfo{Subject: "bob"})
+	if err := call(user, helloMethod); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("user: expected PermissionDenied, got %v", err)
+	}
+	admin := servion.ContextWithAuth(context.Background(), servion.AuthInfo{Subject: "root", Roles: []string{"admin"}})
+	if err := call(admin, helloMethod); err != nil {
+		t.Fatalf("admin: %v", err)
+	}
+	// health checks stay reachable without a policy rule
+	if err := call(context.Background(), "/grpc.health.v1.Health/Check"); err != nil {
+		t.Fatalf("health check: %v", err)
+	}
+}
+
+func TestAuthInterceptor_ClientCertificate(t *testing.T) {
+
+	ctx, err := glue.New(
+		glue.MapPropertySource{
+			"mtls.rules": "cn:svc-* = roles:service",
+		},
+		servion.CertAuthProvider(),
+		serviongrpc.AuthInterceptor(10),
+	)
+	if err != nil {
+		t.Fatalf("context: %v", err)
+	}
+	defer ctx.Close()
+
+	list := ctx.Bean(serviongrpc.UnaryInterceptorClass, glue.DefaultSearchLevel)
+	if len(list) != 1 {
+		t.Fatalf("expected exactly 1 UnaryInterceptor, got %d", len(list))
+	}
+	fn := list[0].Object().(serviongrpc.UnaryInterceptor).UnaryInterceptor()
+
+	var got servion.AuthInfo
+	handler := func(ctx context.Context, _ interface{}) (interface{}, error) {
+		got, _ = servion.AuthFromContext(ctx)
+		return nil, nil
+	}
+	call := func(ctx context.Context) error {
+		_, err := fn(ctx, nil, &grpc.UnaryServerInfo{FullMethod: helloMethod}, handler)
+		return err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(7),
+		Subject:      pkix.Name{CommonName: "svc-orders"},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	mtls := peer.NewContext(context.Background(), &peer.Peer{
+		AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{
+			PeerCertificates: []*x509.Certificate{cert},
+			VerifiedChains:   [][]*x509.Certificate{{cert}},
+		}},
+	})
+
+	if err := call(mtls); err != nil {
+		t.Fatalf("client certificate: %v", err)
+	}
+	if got.Subject != "svc-orders" || len(got.Roles) != 1 || got.Roles[0] != "service" {
+		t.Fatalf("unexpected auth info %+v", got)
+	}
+	if err := call(context.Background()); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("no certificate: expected Unauthenticated, got %v", err)
+	}
+}
+
+func TestAuthInterceptor_CredentialSourcesAndOptional(t *testing.T) {
+
+	ctx, err := glue.New(
+		glue.MapPropertySource{
+			"grpc.auth.credentials": "header:x-api-key,bearer",
+			"grpc.auth.optional":    "true",
+		},
+		stubAuthBean(),
+		serviongrpc.AuthInterceptor(10),
+	)
+	if err != nil {
+		t.Fatalf("context: %v", err)
+	}
+	defer ctx.Close()
+
+	list := ctx.Bean(serviongrpc.UnaryInterceptorClass, glue.DefaultSearchLevel)
+	if len(list) != 1 {
+		t.Fatalf("expected exactly 1 UnaryInterceptor, got %d", len(list))
+	}
+	fn := list[0].Object().(serviongrpc.UnaryInterceptor).UnaryInterceptor()
+
+	subject := func(ctx context.Context) (string, error) {
+		var got string
+		_, err := fn(ctx, nil, &grpc.UnaryServerInfo{FullMethod: helloMethod}, func(ctx context.Context, _ interface{}) (interface{}, error) {
+			if auth, ok := servion.AuthFromContext(ctx); ok {
+				got = auth.Subject
+			}
+			return nil, nil
+		})
+		return got, err
+	}
+
+	incoming := func(kv ...string) context.Context {
+		return metadata.NewIncomingContext(context.Background(), metadata.Pairs(kv...))
+	}
+	if got, err := subject(incoming("x-api-key", "good-token")); err != nil || got != "alice" {
+		t.Fatalf("api key header: %q, %v", got, err)
+	}
+	if got, err := subject(incoming("authorization", "Bearer good-token")); err != nil || got != "alice" {
+		t.Fatalf("bearer: %q, %v", got, err)
+	}
+	if got, err := subject(context.Background()); err != nil || got != "" {
+		t.Fatalf("anonymous: %q, %v", got, err)
+	}
+	if _, err := subject(incoming("x-api-key", "nope")); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("bad key: expected Unauthenticated, got %v", err)
+	}
+}
+
+// pingHandler is a plain HTTP endpoint next to the gRPC services.
+type pingHandler struct{}
+
+func (pingHandler) Pattern() string { return "/ping" }
+func (pingHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	io.WriteString(w, "pong")
+}
+
+func TestGrpcHttpServer_SharedPort(t *testing.T) {
+
+	ctx, err := glue.New(
+		glue.MapPropertySource{
+			"api-server.bind-address": "127.0.0.1:0",
+			"api-server.options":      "handlers;h2c;health",
+		},
+		servion.ZapLogFactory(true),
+		serviongrpc.GrpcHttpServerScanner("api-server", &echoService{}, pingHandler{}),
+	)
+	if err != nil {
+		t.Fatalf("context: %v", err)
+	}
+	defer ctx.Close()
+
+	// one *http.Server and no separate gRPC listener, wrapped the way servion.RunCommand does
+	if list := ctx.Bean(servion.ServerClass, glue.DefaultSearchLevel); len(list) != 0 {
+		t.Fatalf("expected no servion.Server, got %d", len(list))
+	}
+	list := ctx.Bean(servion.HttpServerClass, glue.DefaultSearchLevel)
+	if len(list) != 1 {
+		t.Fatalf("expected exactly 1 *http.Server, got %d", len(list))
+	}
+	srv := servion.NewHttpServer(list[0].Object().(*http.Server))
+	if err := ctx.Inject(srv); err != nil {
+		t.Fatalf("inject: %v", err)
+	}
+	if err := srv.Bind(); err != nil {
+		t.Fatalf("bind: %v", err)
+	}
+	go srv.Serve()
+	defer srv.Shutdown()
+	addr := srv.ListenAddress().String()
+
+	conn := dial(t, addr)
+	defer conn.Close()
+	callCtx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	out := new(wrapperspb.StringValue)
+	if err := conn.Invoke(callCtx, helloMethod, wrapperspb.String("bob"), out); err != nil {
+		t.Fatalf("gRPC call on the shared port: %v", err)
+	}
+	if out.Value != "hello bob from anonymous" {
+		t.Fatalf("unexpected reply %q", out.Value)
+	}
+	if _, err := healthpb.NewHealthClient(conn).Check(callCtx, &healthpb.HealthCheckRequest{}); err != nil {
+		t.Fatalf("health check: %v", err)
+	}
+
+	resp, err := http.Get("http://" + addr + "/ping")
+	if err != nil {
+		t.Fatalf("HTTP on the shared port: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "pong" {
+		t.Fatalf("unexpected body %q", body)
+	}
+}
+
+func TestGrpcHttpServer_NeedsHTTP2(t *testing.T) {
+
+	ctx, err := glue.New(
+		glue.MapPropertySource{
+			"api-server.bind-address": "127.0.0.1:0",
+			"api-server.options":      "handlers",
+		},
+		servion.ZapLogFactory(true),
+		serviongrpc.GrpcHttpServerScanner("api-server", &echoService{}),
+	)
+	if err == nil {
+		ctx.Close()
+		t.Fatal("expected error for a shared port without TLS or h2c")
+	}
+	if !strings.Contains(err.Error(), "h2c") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
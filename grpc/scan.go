@@ -6,6 +6,8 @@
 package serviongrpc
 
 import (
+	"net/http"
+
 	"go.arpabet.com/glue"
 	"go.arpabet.com/servion"
 	"google.golang.org/grpc"
@@ -50,6 +52,46 @@ func (t *grpcServerScanner) ScannerBeans() []interface{} {
 	return append(beans, t.scan...)
 }
 
+type grpcHttpServerScanner struct {
+	beanName string
+	scan     []interface{}
+}
+
+/*
+GrpcHttpServerScanner registers an HTTP server and a gRPC server named beanName
+that share one port. The HTTP server binds, serves and shuts down both; gRPC calls
+are told apart by HTTP/2 and their content type, so the server needs the tls or
+h2c option. The extra beans may be HTTP handlers as well as gRPC services.
+
+	servion.RunCommand(
+		serviongrpc.GrpcHttpServerScanner("api-server",
+			servion.HealthHandler(),
+			&echoService{},
+		),
+	)
+*/
+func GrpcHttpServerScanner(beanName string, scan ...interface{}) glue.Scanner {
+	return &grpcHttpServerScanner{
+		beanName: beanName,
+		scan:     scan,
+	}
+}
+
+func (t *grpcHttpServerScanner) ScannerBeans() []interface{} {
+	beans := []interface{}{
+		servion.HttpServerFactory(t.beanName),
+		GrpcServerFactory(t.beanName),
+		GrpcDispatcher(t.beanName),
+		&struct {
+			// make them visible / force construction
+			Servers     []servion.Server `inject:"optional"`
+			HttpServers []*http.Server   `inject:""`
+			GrpcServers []*grpc.Server   `inject:"optional"`
+		}{},
+	}
+	return append(beans, t.scan...)
+}
+
 type grpcClientScanner struct {
 	beanName string
 	scan     []interface{}
@@ -0,0 +1,65 @@
+/*
+ * Copyright (c) 2026 Karagatan LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package serviongrpc
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+/*
+listenerTLSCredentials are server transport credentials for connections the
+GrpcServer listener already wraps in TLS. They finish the handshake and report
+its state as credentials.TLSInfo, so handlers and interceptors see the client
+certificates through peer.FromContext, exactly as with credentials.NewTLS.
+Plain connections pass through without auth info.
+*/
+type listenerTLSCredentials struct{}
+
+func (listenerTLSCredentials) ClientHandshake(context.Context, string, net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	return nil, nil, errors.New("listener TLS credentials are server side only")
+}
+
+func (listenerTLSCredentials) ServerHandshake(conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	tc, ok := conn.(*tls.Conn)
+	if !ok {
+		return conn, nil, nil
+	}
+	// grpc sets the connection timeout as deadline of the raw connection before calling us
+	if err := tc.Handshake(); err != nil {
+		return nil, nil, err
+	}
+	return conn, credentials.TLSInfo{
+		State:          tc.ConnectionState(),
+		CommonAuthInfo: credentials.CommonAuthInfo{SecurityLevel: credentials.PrivacyAndIntegrity},
+	}, nil
+}
+
+func (listenerTLSCredentials) Info() credentials.ProtocolInfo {
+	return credentials.ProtocolInfo{SecurityProtocol: "tls"}
+}
+
+func (c listenerTLSCredentials) Clone() credentials.TransportCredentials { return c }
+
+func (listenerTLSCredentials) OverrideServerName(string) error { return nil }
+
+// peerTLSState returns the TLS state of the connection of an incoming call, nil for plain connections.
+func peerTLSState(ctx context.Context) *tls.ConnectionState {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok {
+		return nil
+	}
+	return &info.State
+}
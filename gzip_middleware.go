@@ -10,10 +10,12 @@ import (
 )
 
 type implGzipMiddleware struct {
-	beanOrder    int
-	Level        int      `value:"gzip.level,default=1"`                  // gzip compression level
-	Threshold    int      `value:"gzip.threshold,default=1024"`           // bytes, default 1024
-	SkipPrefixes []string `value:"gzip.skip,default=/images;/videos;/ws"` // URL prefixes NOT to gzip
+	beanOrder       int
+	Level           int      `value:"gzip.level,default=1"`                   // gzip compression level
+	Threshold       int      `value:"gzip.threshold,default=1024"`            // bytes, default 1024
+	SkipPrefixes    []string `value:"gzip.skip,default=/images;/videos;/ws"`  // URL prefixes NOT to gzip
+	MaxDecompressed int      `value:"gzip.max-decompressed,default=10485760"` // bytes of a decompressed request body, 0 unlimited
+	MaxRatio        int      `value:"gzip.max-ratio,default=100"`             // decompressed to compressed ratio of a request body, 0 unlimited
 }
 
 func GzipMiddleware(beanOrder int) HttpMiddleware {
@@ -25,7 +27,7 @@ func (t *implGzipMiddleware) Middleware(next http.Handler) http.Handler {
 
 		// ---- REQUEST ----
 		if isGzippedRequest(r) {
-			r2, err := decompressRequest(r)
+			r2, err := decompressRequest(r, int64(t.MaxDecompressed), int64(t.MaxRatio))
 			if err != nil {
 				http.Error(w, "invalid gzip request body", http.StatusBadRequest)
 				return
@@ -94,8 +96,12 @@ func isGzippedRequest(r *http.Request) bool {
 	return strings.Contains(r.Header.Get(hContentEncoding), encGzip)
 }
 
-func decompressRequest(r *http.Request) (*http.Request, error) {
-	zr, err := gzip.NewReader(r.Body)
+// decompressRequest replaces the body with its decompressed stream. Reading past
+// maxSize bytes, or past maxRatio times the compressed bytes read so far, fails
+// with *http.MaxBytesError, so a small gzip bomb can not expand into memory.
+func decompressRequest(r *http.Request, maxSize, maxRatio int64) (*http.Request, error) {
+	compressed := &countingReader{r: r.Body}
+	zr, err := gzip.NewReader(compressed)
 	if err != nil {
 		return nil, err
 	}
@@ -105,7 +111,7 @@ func decompressRequest(r *http.Request) (*http.Request, error) {
 		io.Reader
 		io.Closer
 	}{
-		Reader: zr,
+		Reader: &gzipBombGuard{zr: zr, compressed: compressed, maxSize: maxSize, maxRatio: maxRatio},
 		Closer: zr,
 	}
 
@@ -116,6 +122,41 @@ func decompressRequest(r *http.Request) (*http.Request, error) {
 	return r2, nil
 }
 
+// gzipRatioGrace is the decompressed size below which the ratio is not checked,
+// small bodies of repetitive JSON legitimately compress very well.
+const gzipRatioGrace = 64 << 10
+
+type gzipBombGuard struct {
+	zr         *gzip.Reader
+	compressed *countingReader
+	size       int64
+	maxSize    int64
+	maxRatio   int64
+}
+
+func (g *gzipBombGuard) Read(p []byte) (int, error) {
+	n, err := g.zr.Read(p)
+	g.size += int64(n)
+	if g.maxSize > 0 && g.size > g.maxSize {
+		return 0, &http.MaxBytesError{Limit: g.maxSize}
+	}
+	if g.maxRatio > 0 && g.size > gzipRatioGrace && g.size > g.compressed.n*g.maxRatio {
+		return 0, &http.MaxBytesError{Limit: g.compressed.n * g.maxRatio}
+	}
+	return n, err
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
 type adaptiveGzipWriter struct {
 	http.ResponseWriter
 
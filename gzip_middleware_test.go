@@ -3,6 +3,7 @@ package servion
 import (
 	"bytes"
 	"compress/gzip"
+	"errors"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -427,3 +428,58 @@ func TestGzipMiddleware_ResponseWithStatus(t *testing.T) {
 		t.Errorf("status = %d, want %d", w.Code, http.StatusCreated)
 	}
 }
+
+func TestGzipMiddleware_DecompressionLimits(t *testing.T) {
+	compress := func(data []byte) *bytes.Buffer {
+		var buf bytes.Buffer
+		gw := gzip.NewWriter(&buf)
+		gw.Write(data)
+		gw.Close()
+		return &buf
+	}
+
+	tests := []struct {
+		name            string
+		body            []byte
+		maxDecompressed int
+		maxRatio        int
+		wantErr         bool
+	}{
+		{"within limits", bytes.Repeat([]byte("a"), 1000), 10000, 100, false},
+		{"size exceeded", bytes.Repeat([]byte("a"), 200000), 100000, 0, true},
+		{"ratio exceeded", bytes.Repeat([]byte("a"), 1<<20), 0, 100, true},
+		{"small bodies skip the ratio", bytes.Repeat([]byte("a"), 32<<10), 0, 10, false},
+		{"unlimited", bytes.Repeat([]byte("a"), 1<<20), 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mw := &implGzipMiddleware{
+				beanOrder:       1,
+				Level:           1,
+				Threshold:       1024,
+				MaxDecompressed: tt.maxDecompressed,
+				MaxRatio:        tt.maxRatio,
+			}
+			var readErr error
+			var n int
+			handler := mw.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var b []byte
+				b, readErr = io.ReadAll(r.Body)
+				n = len(b)
+			}))
+
+			r := httptest.NewRequest(http.MethodPost, "/", compress(tt.body))
+			r.Header.Set(hContentEncoding, encGzip)
+			handler.ServeHTTP(httptest.NewRecorder(), r)
+
+			var maxErr *http.MaxBytesError
+			if tt.wantErr != errors.As(readErr, &maxErr) {
+				t.Fatalf("read error = %v, wantErr %v", readErr, tt.wantErr)
+			}
+			if !tt.wantErr && n != len(tt.body) {
+				t.Errorf("read %d bytes, want %d", n, len(tt.body))
+			}
+		})
+	}
+}
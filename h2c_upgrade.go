@@ -0,0 +1,147 @@
+/*
+ * Copyright (c) 2026 Karagatan LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package servion
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/net/http/httpguts"
+	"golang.org/x/net/http2"
+)
+
+/*
+h2cUpgrade switches HTTP/1.1 requests with "Upgrade: h2c" to HTTP/2 on their
+connection (RFC 7540 section 3.2) and answers them on stream 1; other requests
+go to next. net/http no longer tracks a connection once it is hijacked, so the
+upgraded ones are kept here: the http2.Server registered on the server by
+ConfigureServer sends them GOAWAY on Shutdown, and implHttpServer closes them
+with the server.
+*/
+type h2cUpgrade struct {
+	next    http.Handler
+	h2s     *http2.Server
+	maxBody int64
+
+	mu     sync.Mutex
+	conns  map[net.Conn]struct{}
+	closed bool
+}
+
+func newH2CUpgrade(next http.Handler, h2s *http2.Server, maxBody int64) *h2cUpgrade {
+	return &h2cUpgrade{next: next, h2s: h2s, maxBody: maxBody, conns: make(map[net.Conn]struct{})}
+}
+
+func (t *h2cUpgrade) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.ProtoMajor != 1 ||
+		!httpguts.HeaderValuesContainsToken(r.Header["Upgrade"], "h2c") ||
+		!httpguts.HeaderValuesContainsToken(r.Header["Connection"], "HTTP2-Settings") {
+		t.next.ServeHTTP(w, r)
+		return
+	}
+	values := r.Header["Http2-Settings"]
+	if len(values) != 1 {
+		http.Error(w, "expected one HTTP2-Settings header", http.StatusBadRequest)
+		return
+	}
+	settings, err := base64.RawURLEncoding.DecodeString(values[0])
+	if err != nil {
+		http.Error(w, "malformed HTTP2-Settings header", http.StatusBadRequest)
+		return
+	}
+
+	// the body of the upgrade request is read before the connection switches protocols
+	body := r.Body
+	if t.maxBody > 0 {
+		body = http.MaxBytesReader(w, r.Body, t.maxBody)
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+		} else {
+			http.Error(w, "can not read request body", http.StatusBadRequest)
+		}
+		return
+	}
+	r.Body = io.NopCloser(bytes.NewReader(data))
+	// stream 1 answers it over HTTP/2, as handlers such as GrpcDispatcher must see
+	r.Proto, r.ProtoMajor, r.ProtoMinor = "HTTP/2.0", 2, 0
+
+	conn, rw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		http.Error(w, "h2c upgrade not supported", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Close()
+	if !t.track(conn) {
+		return
+	}
+	defer t.untrack(conn)
+
+	rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: h2c\r\n\r\n")
+	if rw.Flush() != nil {
+		return
+	}
+	// the read and write timeouts of the HTTP/1.1 request must not end the connection
+	conn.SetDeadline(time.Time{})
+	t.h2s.ServeConn(&bufferedConn{Conn: conn, r: rw.Reader}, &http2.ServeConnOpts{
+		Context:        r.Context(),
+		Handler:        t.next,
+		UpgradeRequest: r,
+		Settings:       settings,
+	})
+}
+
+func (t *h2cUpgrade) track(conn net.Conn) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return false
+	}
+	t.conns[conn] = struct{}{}
+	return true
+}
+
+func (t *h2cUpgrade) untrack(conn net.Conn) {
+	t.mu.Lock()
+	delete(t.conns, conn)
+	t.mu.Unlock()
+}
+
+// closeUpgraded closes the upgraded connections and refuses further upgrades.
+func (t *h2cUpgrade) closeUpgraded() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.closed = true
+	for conn := range t.conns {
+		conn.Close()
+	}
+}
+
+// bufferedConn reads what the HTTP/1.1 server buffered before the connection.
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(p []byte) (int, error) {
+	if c.r != nil {
+		if c.r.Buffered() > 0 {
+			return c.r.Read(p[:min(len(p), c.r.Buffered())])
+		}
+		c.r = nil
+	}
+	return c.Conn.Read(p)
+}
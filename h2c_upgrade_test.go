@@ -0,0 +1,124 @@
+package servion
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"go.arpabet.com/glue"
+	"go.uber.org/zap"
+	"golang.org/x/net/http2"
+)
+
+// slowHandler answers /slow once released.
+type slowHandler struct {
+	release chan struct{}
+}
+
+func (h slowHandler) Pattern() string { return "/slow" }
+func (h slowHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	select {
+	case <-h.release:
+	case <-r.Context().Done():
+	}
+	w.Write([]byte("done"))
+}
+
+func newH2CServer(t *testing.T, props map[string]string, handlers ...HttpHandler) *implHttpServer {
+	t.Helper()
+	p := glue.NewProperties()
+	p.Set("test-server.bind-address", "127.0.0.1:0")
+	p.Set("test-server.options", "handlers;h2c")
+	for k, v := range props {
+		p.Set(k, v)
+	}
+	f := &implHttpServerFactory{Log: zap.NewNop(), Properties: p, Handlers: handlers, beanName: "test-server"}
+	obj, err := f.Object()
+	if err != nil {
+		t.Fatalf("Object: %v", err)
+	}
+	s := NewHttpServer(obj.(*http.Server)).(*implHttpServer)
+	s.Log = zap.NewNop()
+	if err := s.Bind(); err != nil {
+		t.Fatalf("Bind: %v", err)
+	}
+	go s.Serve()
+	t.Cleanup(func() { s.Shutdown() })
+	return s
+}
+
+// dialUpgraded opens a connection switched to HTTP/2 by Upgrade: h2c for path.
+func dialUpgraded(t *testing.T, addr, path string) (net.Conn, *http2.Framer) {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	framer := http2.NewFramer(conn, upgradeH2C(t, conn, path))
+	if err := framer.WriteSettings(); err != nil {
+		t.Fatal(err)
+	}
+	return conn, framer
+}
+
+func TestH2CUpgrade_Shutdown(t *testing.T) {
+	release := make(chan struct{})
+	s := newH2CServer(t, nil, slowHandler{release: release})
+
+	// Shutdown of the http.Server sends GOAWAY to upgraded connections too
+	_, framer := dialUpgraded(t, s.ListenAddress().String(), "/slow")
+	if _, err := framer.ReadFrame(); err != nil {
+		t.Fatalf("ReadFrame: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	s.srv.Shutdown(ctx)
+	for {
+		frame, err := framer.ReadFrame()
+		if err != nil {
+			t.Fatalf("connection ended without GOAWAY: %v", err)
+		}
+		if _, ok := frame.(*http2.GoAwayFrame); ok {
+			break
+		}
+	}
+	close(release)
+}
+
+func TestH2CUpgrade_CloseWithServer(t *testing.T) {
+	s := newH2CServer(t, nil, slowHandler{release: make(chan struct{})})
+
+	// the upgrade request still runs on stream 1 when the server shuts down
+	_, framer := dialUpgraded(t, s.ListenAddress().String(), "/slow")
+	if _, err := framer.ReadFrame(); err != nil {
+		t.Fatalf("ReadFrame: %v", err)
+	}
+	if err := s.Shutdown(); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	expectClosed(t, framer)
+}
+
+func TestH2CUpgrade_BodyLimit(t *testing.T) {
+	s := newH2CServer(t, map[string]string{"test-server.max-body-bytes": "16"}, protoHandler{})
+
+	conn, err := net.Dial("tcp", s.ListenAddress().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	body := strings.Repeat("x", 64)
+	conn.Write([]byte("POST /proto HTTP/1.1\r\nHost: localhost\r\nConnection: Upgrade, HTTP2-Settings\r\n" +
+		"Upgrade: h2c\r\nHTTP2-Settings: AAMAAABkAAQAoAAAAAIAAAAA\r\nContent-Length: 64\r\n\r\n" + body))
+	buf := make([]byte, 64)
+	n, _ := conn.Read(buf)
+	if !strings.HasPrefix(string(buf[:n]), "HTTP/1.1 413") {
+		t.Errorf("oversized upgrade request: %q", buf[:n])
+	}
+}
@@ -26,6 +26,9 @@ type implHealthHandler struct {
 //
 //	health.pattern  – URL pattern (default "/healthz")
 //	health.detailed – include per-component stats (default false)
+//
+// Components implementing HealthWarner add their warnings to the response,
+// such as certificates close to expiry; they do not change the status.
 func HealthHandler() HttpHandler {
 	return &implHealthHandler{}
 }
@@ -37,6 +40,7 @@ func (t *implHealthHandler) Pattern() string {
 type healthResponse struct {
 	Status     string                       `json:"status"`
 	Components map[string]map[string]string `json:"components,omitempty"`
+	Warnings   []string                     `json:"warnings,omitempty"`
 }
 
 func (t *implHealthHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -55,6 +59,12 @@ func (t *implHealthHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	resp := healthResponse{Status: status}
 
+	for _, comp := range t.Components {
+		if w, ok := comp.(HealthWarner); ok {
+			resp.Warnings = append(resp.Warnings, w.HealthWarnings()...)
+		}
+	}
+
 	if t.Detailed && len(t.Components) > 0 {
 		resp.Components = make(map[string]map[string]string, len(t.Components))
 		for _, comp := range t.Components {
@@ -209,3 +209,37 @@ func TestHealthHandler_ContentType(t *testing.T) {
 		t.Errorf("Content-Type = %q, want application/json", ct)
 	}
 }
+
+// mockWarner is a Component reporting health warnings.
+type mockWarner struct {
+	mockComponent
+	warnings []string
+}
+
+func (m *mockWarner) HealthWarnings() []string { return m.warnings }
+
+func TestHealthHandler_Warnings(t *testing.T) {
+	h := &implHealthHandler{
+		Runtime: newMockRuntime(true),
+		Components: []Component{
+			&mockComponent{name: "cache"},
+			&mockWarner{mockComponent: mockComponent{name: "tls-profiles"}, warnings: []string{"certificate expires soon"}},
+		},
+		HealthPattern: "/healthz",
+	}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+	h.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusOK)
+	}
+	var resp healthResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if resp.Status != "UP" || len(resp.Warnings) != 1 || resp.Warnings[0] != "certificate expires soon" {
+		t.Errorf("response = %+v", resp)
+	}
+}
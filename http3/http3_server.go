@@ -0,0 +1,201 @@
+/*
+ * Copyright (c) 2026 Karagatan LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package servionhttp3
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/quic-go/quic-go/http3"
+	"go.arpabet.com/glue"
+	"go.arpabet.com/servion"
+	"go.uber.org/atomic"
+	"go.uber.org/zap"
+	"golang.org/x/xerrors"
+)
+
+const (
+	gracefulShutdownTimeout = 2 * time.Second
+
+	// altSvcMaxAge is how long, in seconds, clients may remember the HTTP/3 endpoint.
+	altSvcMaxAge = 24 * 60 * 60
+)
+
+type implHttp3Server struct {
+	Container  glue.Container  `inject:""`
+	Log        *zap.Logger     `inject:""`
+	Properties glue.Properties `inject:""`
+
+	beanName string
+	enabled  bool
+
+	srv  *http.Server
+	h3   *http3.Server
+	conn net.PacketConn
+	port atomic.Int64
+
+	alive        atomic.Bool
+	shutdownOnce sync.Once
+	shutdownCh   chan struct{}
+}
+
+/*
+Http3Server serves the *http.Server bean named beanName over QUIC as well, when
+"http3" is among its <beanName>.options. It binds a UDP socket on the bind
+address of the server, answers with the same router and TLS configuration, and
+adds an Alt-Svc header to the TCP responses so that browsers upgrade. Without
+the option it stays idle. It is registered automatically by HttpServerScanner.
+*/
+func Http3Server(beanName string) servion.Server {
+	return &implHttp3Server{beanName: beanName, shutdownCh: make(chan struct{})}
+}
+
+func (t *implHttp3Server) PostConstruct() error {
+	t.alive.Store(false)
+
+	options := servion.ParseOptions(t.Properties.GetString(fmt.Sprintf("%s.options", t.beanName), ""))
+	if !options["http3"] {
+		t.Log.Info("Http3Disabled", zap.String("bean", t.beanName))
+		return nil
+	}
+
+	// the *http.Server produced by servion.HttpServerFactory in this same container
+	for _, b := range t.Container.Bean(servion.HttpServerClass, 1) {
+		if b.Name() == t.beanName {
+			srv, ok := b.Object().(*http.Server)
+			if !ok {
+				return xerrors.Errorf("bean '%s' is not a *http.Server", t.beanName)
+			}
+			return t.attach(srv)
+		}
+	}
+	return xerrors.Errorf("http.Server bean '%s' not found in server context", t.beanName)
+}
+
+// attach prepares the QUIC server for srv and advertises it on the TCP responses of srv.
+func (t *implHttp3Server) attach(srv *http.Server) error {
+	if srv.TLSConfig == nil {
+		return xerrors.Errorf("option 'http3' of server '%s' needs TLS, enable the tls option or set a tls-profile", t.beanName)
+	}
+
+	handler := srv.Handler
+	t.h3 = &http3.Server{
+		Handler:        handler,
+		TLSConfig:      http3.ConfigureTLSConfig(srv.TLSConfig.Clone()),
+		IdleTimeout:    srv.IdleTimeout,
+		MaxHeaderBytes: srv.MaxHeaderBytes,
+	}
+	srv.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if port := t.port.Load(); port > 0 {
+			w.Header().Set("Alt-Svc", fmt.Sprintf(`h3=":%d"; ma=%d`, port, altSvcMaxAge))
+		}
+		handler.ServeHTTP(w, r)
+	})
+
+	t.srv = srv
+	t.enabled = true
+	return nil
+}
+
+func (t *implHttp3Server) Bind() (err error) {
+	if !t.enabled {
+		return nil
+	}
+
+	t.conn, err = net.ListenPacket("udp", t.srv.Addr)
+	if err != nil {
+		return xerrors.Errorf("can not bind to udp '%s': %w", t.srv.Addr, err)
+	}
+	if addr, ok := t.conn.LocalAddr().(*net.UDPAddr); ok {
+		t.port.Store(int64(addr.Port))
+	}
+	return nil
+}
+
+func (t *implHttp3Server) Alive() bool {
+	return t.alive.Load()
+}
+
+func (t *implHttp3Server) ListenAddress() net.Addr {
+	if t.conn != nil {
+		return t.conn.LocalAddr()
+	}
+	return servion.EmptyAddr
+}
+
+func (t *implHttp3Server) Shutdown() (err error) {
+
+	t.shutdownOnce.Do(func() {
+
+		if t.conn != nil {
+			addr := t.ListenAddress()
+			t.Log.Info("Http3ServerShutdown",
+				zap.String("addr", addr.String()),
+				zap.String("network", addr.Network()))
+		}
+
+		// notify everyone that we are shutting down
+		close(t.shutdownCh)
+
+		if t.h3 != nil {
+			ctx, cancel := context.WithTimeout(context.Background(), gracefulShutdownTimeout)
+			if t.h3.Shutdown(ctx) != nil {
+				t.h3.Close()
+			}
+			cancel()
+		}
+
+		// the QUIC server does not close a connection it did not open
+		if t.conn != nil {
+			t.conn.Close()
+		}
+	})
+
+	return
+}
+
+func (t *implHttp3Server) ShutdownCh() <-chan struct{} {
+	return t.shutdownCh
+}
+
+func (t *implHttp3Server) Destroy() error {
+	// safe to call twice
+	t.Shutdown()
+	return nil
+}
+
+func (t *implHttp3Server) Serve() (err error) {
+
+	defer servion.PanicToError(&err)
+
+	if !t.enabled {
+		// an idle server must not end the serving group of the others
+		<-t.shutdownCh
+		return nil
+	}
+
+	addr := t.ListenAddress()
+	t.Log.Info("Http3ServerServe",
+		zap.String("addr", addr.String()),
+		zap.String("network", addr.Network()),
+		zap.String("bean", t.beanName))
+
+	t.alive.Store(true)
+	err = t.h3.Serve(t.conn)
+	t.alive.Store(false)
+
+	if err == nil || err == http.ErrServerClosed || strings.Contains(err.Error(), "closed") {
+		return nil
+	}
+
+	t.Log.Warn("Http3ServerClose", zap.Error(err))
+	return err
+}
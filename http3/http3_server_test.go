@@ -0,0 +1,137 @@
+/*
+ * Copyright (c) 2026 Karagatan LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package servionhttp3
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/quic-go/quic-go/http3"
+	"go.uber.org/zap"
+)
+
+// newTestTLS returns a server configuration for localhost and a client configuration trusting it.
+func newTestTLS(t *testing.T) (*tls.Config, *tls.Config) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "localhost"},
+		DNSNames:              []string{"localhost"},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	server := &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key, Leaf: cert}}}
+	return server, &tls.Config{RootCAs: pool, ServerName: "localhost"}
+}
+
+func TestHttp3Server_Serve(t *testing.T) {
+	serverCfg, clientCfg := newTestTLS(t)
+	srv := &http.Server{
+		Addr: "127.0.0.1:0",
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, "%s %s", r.Proto, r.URL.Path)
+		}),
+		TLSConfig: serverCfg,
+	}
+
+	s := Http3Server("test-server").(*implHttp3Server)
+	s.Log = zap.NewNop()
+	if err := s.attach(srv); err != nil {
+		t.Fatalf("attach: %v", err)
+	}
+	if err := s.Bind(); err != nil {
+		t.Fatalf("Bind: %v", err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- s.Serve() }()
+
+	tr := &http3.Transport{TLSClientConfig: clientCfg}
+	defer tr.Close()
+	client := &http.Client{Transport: tr, Timeout: 5 * time.Second}
+	resp, err := client.Get(fmt.Sprintf("https://localhost:%d/hello", s.port.Load()))
+	if err != nil {
+		t.Fatalf("GET over QUIC: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "HTTP/3.0 /hello" {
+		t.Errorf("body = %q", body)
+	}
+
+	// the TCP side advertises the QUIC port
+	w := httptest.NewRecorder()
+	srv.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "https://localhost/hello", nil))
+	want := fmt.Sprintf(`h3=":%d"; ma=86400`, s.port.Load())
+	if got := w.Header().Get("Alt-Svc"); got != want {
+		t.Errorf("Alt-Svc = %q, want %q", got, want)
+	}
+
+	if err := s.Shutdown(); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Serve: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Serve did not return after Shutdown")
+	}
+	s.Destroy()
+}
+
+func TestHttp3Server_NeedsTLS(t *testing.T) {
+	s := Http3Server("test-server").(*implHttp3Server)
+	s.Log = zap.NewNop()
+	if err := s.attach(&http.Server{Addr: "127.0.0.1:0", Handler: http.NotFoundHandler()}); err == nil {
+		t.Error("expected error for a server without TLS")
+	}
+}
+
+func TestHttp3Server_Disabled(t *testing.T) {
+	s := Http3Server("test-server").(*implHttp3Server)
+	s.Log = zap.NewNop()
+	if err := s.Bind(); err != nil {
+		t.Fatalf("Bind: %v", err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- s.Serve() }()
+	select {
+	case <-done:
+		t.Fatal("idle server returned before Shutdown")
+	case <-time.After(50 * time.Millisecond):
+	}
+	s.Shutdown()
+	if err := <-done; err != nil {
+		t.Errorf("Serve: %v", err)
+	}
+}
@@ -0,0 +1,28 @@
+/*
+ * Copyright (c) 2026 Karagatan LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package servionhttp3
+
+import (
+	"go.arpabet.com/glue"
+	"go.arpabet.com/servion"
+)
+
+/*
+HttpServerScanner is a drop-in replacement of servion.HttpServerScanner that
+also registers the Http3Server of beanName, so the "http3" option of
+<beanName>.options serves the same router over QUIC.
+
+	servion.RunCommand(
+		servionhttp3.HttpServerScanner("main-server",
+			servion.HealthHandler(),
+			&helloHandler{},
+		),
+	)
+*/
+func HttpServerScanner(beanName string, scan ...interface{}) glue.Scanner {
+	beans := append([]interface{}{Http3Server(beanName)}, scan...)
+	return servion.HttpServerScanner(beanName, beans...)
+}
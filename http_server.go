@@ -91,6 +91,11 @@ func (t *implHttpServer) Shutdown() (err error) {
 
 		err = t.srv.Close()
 
+		// connections upgraded by the h2c option are hijacked, Close does not see them
+		if upgrade, ok := t.srv.Handler.(interface{ closeUpgraded() }); ok {
+			upgrade.closeUpgraded()
+		}
+
 		if t.listener != nil {
 			t.listener.Close()
 		}
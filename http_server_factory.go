@@ -18,16 +18,21 @@ import (
 	"github.com/gorilla/mux"
 	"go.arpabet.com/glue"
 	"go.uber.org/zap"
+	"golang.org/x/net/http2"
 	"golang.org/x/xerrors"
 )
 
 type implHttpServerFactory struct {
 	Log         *zap.Logger            `inject:""`
 	Properties  glue.Properties        `inject:""`
+	Runtime     Runtime                `inject:"optional"`
 	Handlers    []HttpHandler          `inject:"optional,level=1"`
 	Middlewares []HttpMiddleware       `inject:"optional,level=1"`
 	Resources   []*glue.ResourceSource `inject:"optional"`
 	TlsConfig   *tls.Config            `inject:"optional"`
+	TlsProfiles TlsConfigProvider      `inject:"optional"`
+	Acme        AcmeProvider           `inject:"optional"`
+	Dispatchers []HttpDispatcher       `inject:"optional,level=1"`
 
 	beanName string
 }
@@ -120,8 +125,32 @@ func (t *implHttpServerFactory) Object() (object interface{}, err error) {
 		}
 	}
 
+	var acmeManager AcmeProvider
+	if options["acme"] {
+		// one manager for all servers, the plain one answers the HTTP-01 challenges of the TLS one
+		if t.Acme == nil {
+			return nil, xerrors.Errorf("option acme of server '%s' needs an AcmeManager in server context", t.beanName)
+		}
+		acmeManager = t.Acme
+	}
+
 	var tlsConfig *tls.Config
-	if options["tls"] {
+	profile := t.Properties.GetString(fmt.Sprintf("%s.%s", t.beanName, "tls-profile"), "")
+	if profile != "" && acmeManager != nil {
+		return nil, xerrors.Errorf("property '%s.tls-profile' conflicts with the acme option", t.beanName)
+	}
+	if acmeManager != nil && options["tls"] {
+		// certificates per SNI host name, TLS-ALPN-01 challenges answered in the handshake
+		tlsConfig = acmeManager.TLSConfig()
+	} else if profile != "" {
+		// a profile enables TLS by itself and takes precedence over a *tls.Config bean
+		if t.TlsProfiles == nil {
+			return nil, xerrors.Errorf("property '%s.tls-profile' needs a TlsConfigProvider in server context", t.beanName)
+		}
+		if tlsConfig, err = t.TlsProfiles.ServerConfig(profile); err != nil {
+			return nil, xerrors.Errorf("property '%s.tls-profile': %w", t.beanName, err)
+		}
+	} else if options["tls"] {
 		if t.TlsConfig != nil {
 			tlsConfig = t.TlsConfig.Clone()
 		} else {
@@ -132,6 +161,57 @@ func (t *implHttpServerFactory) Object() (object interface{}, err error) {
 	readTimeout := t.Properties.GetDuration(fmt.Sprintf("%s.%s", t.beanName, "read-timeout"), 30*time.Second)
 	writeTimeout := t.Properties.GetDuration(fmt.Sprintf("%s.%s", t.beanName, "write-timeout"), 30*time.Second)
 	idleTimeout := t.Properties.GetDuration(fmt.Sprintf("%s.%s", t.beanName, "idle-timeout"), time.Minute)
+	readHeaderTimeout := t.Properties.GetDuration(fmt.Sprintf("%s.%s", t.beanName, "read-header-timeout"), 10*time.Second)
+	maxHeaderBytes := t.Properties.GetInt(fmt.Sprintf("%s.%s", t.beanName, "max-header-bytes"), http.DefaultMaxHeaderBytes)
+	maxBodyBytes := t.Properties.GetInt(fmt.Sprintf("%s.%s", t.beanName, "max-body-bytes"), 10<<20)
+
+	// server-wide ceiling of request bodies, BodyLimitMiddleware can only tighten it per route
+	var rootHandler http.Handler = serveMux
+	if maxBodyBytes > 0 {
+		for _, middleware := range t.Middlewares {
+			if bl, ok := middleware.(interface{ limitsAbove(int64) []string }); ok {
+				if above := bl.limitsAbove(int64(maxBodyBytes)); len(above) > 0 {
+					t.Log.Warn("BodyLimitAboveServerCeiling",
+						zap.String("bean", t.beanName),
+						zap.Int("maxBodyBytes", maxBodyBytes),
+						zap.Strings("rules", above),
+						zap.String("hint", fmt.Sprintf("raise %s.max-body-bytes, or set it to 0", t.beanName)))
+				}
+			}
+		}
+		rootHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			limitRequestBody(w, r, serveMux, int64(maxBodyBytes))
+		})
+	}
+
+	// other protocols on the same port bypass the router and its body limit
+	for _, dispatcher := range t.Dispatchers {
+		rootHandler = dispatcher.Dispatch(rootHandler)
+	}
+
+	if acmeManager != nil && tlsConfig == nil {
+		// a plain server answers HTTP-01 challenges and serves everything else as before
+		rootHandler = acmeManager.HTTPHandler(rootHandler)
+	}
+
+	if options["h2c"] && tlsConfig != nil {
+		return nil, xerrors.Errorf("option h2c of server '%s' conflicts with TLS, HTTP/2 over TLS is negotiated by ALPN", t.beanName)
+	}
+	h2s, err := t.http2Server(idleTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	protocols := new(http.Protocols)
+	protocols.SetHTTP1(true)
+	if tlsConfig != nil {
+		protocols.SetHTTP2(true)
+	}
+	if options["h2c"] {
+		// prior-knowledge connections stay connections of the server, Upgrade ones are handed over by h2cUpgrade
+		protocols.SetUnencryptedHTTP2(true)
+		rootHandler = newH2CUpgrade(rootHandler, h2s, int64(maxBodyBytes))
+	}
 
 	t.Log.Info("HTTPServerFactory",
 		zap.String("listenAddr", listenAddr),
@@ -139,21 +219,60 @@ func (t *implHttpServerFactory) Object() (object interface{}, err error) {
 		zap.Strings("handlers", handlerList),
 		zap.Strings("assets", assetList),
 		zap.Any("options", options),
-		zap.Bool("tls", tlsConfig != nil))
+		zap.Int("maxBodyBytes", maxBodyBytes),
+		zap.Bool("tls", tlsConfig != nil),
+		zap.Bool("acme", acmeManager != nil),
+		zap.Bool("h2c", options["h2c"]),
+		zap.Int("dispatchers", len(t.Dispatchers)))
 
 	srv := &http.Server{
-		Addr:         listenAddr,
-		Handler:      serveMux,
-		ReadTimeout:  readTimeout,
-		WriteTimeout: writeTimeout,
-		IdleTimeout:  idleTimeout,
-		TLSConfig:    tlsConfig,
+		Addr:              listenAddr,
+		Handler:           rootHandler,
+		ReadTimeout:       readTimeout,
+		ReadHeaderTimeout: readHeaderTimeout,
+		WriteTimeout:      writeTimeout,
+		IdleTimeout:       idleTimeout,
+		MaxHeaderBytes:    maxHeaderBytes,
+		TLSConfig:         tlsConfig,
+		Protocols:         protocols,
+	}
+
+	if tlsConfig != nil || options["h2c"] {
+		// HTTP/2 with the settings of the properties, Shutdown sends GOAWAY to all its connections
+		if err := http2.ConfigureServer(srv, h2s); err != nil {
+			t.Log.Warn("HTTP2Disabled", zap.String("bean", t.beanName), zap.Error(err))
+		}
+		// ConfigureServer gives plain servers a TLS config too, implHttpServer would serve TLS then
+		srv.TLSConfig = tlsConfig
 	}
 
 	return srv, nil
 
 }
 
+/*
+http2Server returns the HTTP/2 settings of the server from its properties:
+
+	<bean>.http2-max-concurrent-streams  – streams a client may open at a time per connection (default 250)
+	<bean>.http2-max-frame-size          – largest frame read, 16384 to 16777215 bytes (default 1048576)
+	<bean>.http2-idle-timeout            – idle connections get a GOAWAY after it (default <bean>.idle-timeout)
+*/
+func (t *implHttpServerFactory) http2Server(idleTimeout time.Duration) (*http2.Server, error) {
+	maxStreams := t.Properties.GetInt(fmt.Sprintf("%s.%s", t.beanName, "http2-max-concurrent-streams"), 250)
+	if maxStreams <= 0 {
+		return nil, xerrors.Errorf("property '%s.http2-max-concurrent-streams' must be positive, got %d", t.beanName, maxStreams)
+	}
+	maxFrameSize := t.Properties.GetInt(fmt.Sprintf("%s.%s", t.beanName, "http2-max-frame-size"), 1<<20)
+	if maxFrameSize < 1<<14 || maxFrameSize > 1<<24-1 {
+		return nil, xerrors.Errorf("property '%s.http2-max-frame-size' must be between 16384 and 16777215, got %d", t.beanName, maxFrameSize)
+	}
+	return &http2.Server{
+		MaxConcurrentStreams: uint32(maxStreams),
+		MaxReadFrameSize:     uint32(maxFrameSize),
+		IdleTimeout:          t.Properties.GetDuration(fmt.Sprintf("%s.%s", t.beanName, "http2-idle-timeout"), idleTimeout),
+	}, nil
+}
+
 func (t *implHttpServerFactory) ObjectType() reflect.Type { return HttpServerClass }
 
 func (t *implHttpServerFactory) ObjectName() string {
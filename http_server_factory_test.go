@@ -1,10 +1,14 @@
 package servion
 
 import (
+	"bufio"
 	"bytes"
 	"compress/gzip"
+	"context"
 	"crypto/tls"
+	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -15,6 +19,8 @@ import (
 
 	"go.arpabet.com/glue"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	"golang.org/x/net/http2"
 )
 
 func TestHttpServerFactory_MissingBindAddress(t *testing.T) {
@@ -115,6 +121,69 @@ func TestHttpServerFactory_DefaultTimeouts(t *testing.T) {
 	if srv.IdleTimeout != time.Minute {
 		t.Errorf("IdleTimeout = %v, want 1m", srv.IdleTimeout)
 	}
+	if srv.ReadHeaderTimeout != 10*time.Second {
+		t.Errorf("ReadHeaderTimeout = %v, want 10s", srv.ReadHeaderTimeout)
+	}
+	if srv.MaxHeaderBytes != http.DefaultMaxHeaderBytes {
+		t.Errorf("MaxHeaderBytes = %d, want %d", srv.MaxHeaderBytes, http.DefaultMaxHeaderBytes)
+	}
+}
+
+func TestHttpServerFactory_RequestLimits(t *testing.T) {
+	props := glue.NewProperties()
+	props.Set("test-server.bind-address", "127.0.0.1:0")
+	props.Set("test-server.options", "handlers")
+	props.Set("test-server.read-header-timeout", "2s")
+	props.Set("test-server.max-header-bytes", "8192")
+	props.Set("test-server.max-body-bytes", "16")
+
+	f := &implHttpServerFactory{
+		Log:        zap.NewNop(),
+		Properties: props,
+		Handlers:   []HttpHandler{&echoBodyHandler{pattern: "/echo"}},
+		beanName:   "test-server",
+	}
+
+	obj, err := f.Object()
+	if err != nil {
+		t.Fatalf("Object: %v", err)
+	}
+	srv := obj.(*http.Server)
+
+	if srv.ReadHeaderTimeout != 2*time.Second {
+		t.Errorf("ReadHeaderTimeout = %v, want 2s", srv.ReadHeaderTimeout)
+	}
+	if srv.MaxHeaderBytes != 8192 {
+		t.Errorf("MaxHeaderBytes = %d, want 8192", srv.MaxHeaderBytes)
+	}
+
+	// declared too large: rejected before the handler runs
+	rec := httptest.NewRecorder()
+	srv.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader(strings.Repeat("x", 17))))
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("status = %d, want 413", rec.Code)
+	}
+
+	// within the limit
+	rec = httptest.NewRecorder()
+	srv.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader("hello")))
+	if rec.Code != http.StatusOK || rec.Body.String() != "hello" {
+		t.Errorf("got %d %q, want 200 hello", rec.Code, rec.Body.String())
+	}
+}
+
+type echoBodyHandler struct {
+	pattern string
+}
+
+func (h *echoBodyHandler) Pattern() string { return h.pattern }
+func (h *echoBodyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	b, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+	w.Write(b)
 }
 
 type testHandler struct {
@@ -621,6 +690,47 @@ func TestHttpServerFactory_TLSWarning(t *testing.T) {
 	}
 }
 
+func TestHttpServerFactory_TLSProfile(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	writeTestPair(t, ca, filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key"), "localhost", time.Now().Add(time.Hour))
+	props := glue.NewProperties()
+	props.Set("tls.public.cert-file", filepath.Join(dir, "tls.crt"))
+	props.Set("tls.public.key-file", filepath.Join(dir, "tls.key"))
+	profiles, err := newTestTlsProfiles(t, props, "public")
+	if err != nil {
+		t.Fatalf("PostConstruct: %v", err)
+	}
+	props.Set("test-server.bind-address", "127.0.0.1:0")
+	props.Set("test-server.tls-profile", "public")
+
+	f := &implHttpServerFactory{
+		Log:         zap.NewNop(),
+		Properties:  props,
+		TlsConfig:   &tls.Config{InsecureSkipVerify: true},
+		TlsProfiles: profiles,
+		beanName:    "test-server",
+	}
+	obj, err := f.Object()
+	if err != nil {
+		t.Fatalf("Object: %v", err)
+	}
+	// the profile enables TLS without the tls option and wins over the bean
+	cfg := obj.(*http.Server).TLSConfig
+	if cfg == nil || cfg.GetCertificate == nil || cfg.InsecureSkipVerify {
+		t.Fatalf("TLSConfig = %+v, want the profile", cfg)
+	}
+
+	props.Set("test-server.tls-profile", "missing")
+	if _, err := f.Object(); err == nil {
+		t.Error("expected error for an unknown profile")
+	}
+	f.TlsProfiles = nil
+	if _, err := f.Object(); err == nil {
+		t.Error("expected error without a TlsConfigProvider")
+	}
+}
+
 func newSpaTestFactory(t *testing.T, options string) *implHttpServerFactory {
 	t.Helper()
 
@@ -825,3 +935,388 @@ func TestHttpServerFactory_SpaFallback_GzipVariant(t *testing.T) {
 		t.Errorf("expected gzipped index content, got %q", string(content))
 	}
 }
+
+// protoHandler answers with the protocol of the request.
+type protoHandler struct{}
+
+func (protoHandler) Pattern() string { return "/proto" }
+func (protoHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	io.WriteString(w, r.Proto)
+}
+
+func TestHttpServerFactory_H2C(t *testing.T) {
+	props := glue.NewProperties()
+	props.Set("test-server.bind-address", "127.0.0.1:0")
+	props.Set("test-server.options", "handlers;h2c")
+	props.Set("test-server.http2-max-concurrent-streams", "32")
+	props.Set("test-server.http2-max-frame-size", "65536")
+
+	f := &implHttpServerFactory{
+		Log:        zap.NewNop(),
+		Properties: props,
+		Handlers:   []HttpHandler{protoHandler{}},
+		beanName:   "test-server",
+	}
+	obj, err := f.Object()
+	if err != nil {
+		t.Fatalf("Object: %v", err)
+	}
+	srv := obj.(*http.Server)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.Serve(ln)
+	defer srv.Close()
+	addr := ln.Addr().String()
+
+	// prior knowledge
+	tr := &http.Transport{Protocols: new(http.Protocols)}
+	tr.Protocols.SetUnencryptedHTTP2(true)
+	defer tr.CloseIdleConnections()
+	resp, err := (&http.Client{Transport: tr}).Get("http://" + addr + "/proto")
+	if err != nil {
+		t.Fatalf("prior knowledge: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "HTTP/2.0" {
+		t.Errorf("prior knowledge served %q", body)
+	}
+
+	// plain HTTP/1.1 is unchanged
+	resp, err = http.Get("http://" + addr + "/proto")
+	if err != nil {
+		t.Fatalf("HTTP/1.1: %v", err)
+	}
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "HTTP/1.1" {
+		t.Errorf("HTTP/1.1 served %q", body)
+	}
+
+	// Upgrade: h2c switches protocols and answers on stream 1 with the settings of the properties
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	br := upgradeH2C(t, conn, "/proto")
+	framer := http2.NewFramer(conn, br)
+	if err := framer.WriteSettings(); err != nil {
+		t.Fatal(err)
+	}
+	settings := map[http2.SettingID]uint32{}
+	var proto string
+	for proto == "" {
+		frame, err := framer.ReadFrame()
+		if err != nil {
+			t.Fatalf("ReadFrame: %v", err)
+		}
+		switch fr := frame.(type) {
+		case *http2.SettingsFrame:
+			if !fr.IsAck() {
+				fr.ForeachSetting(func(s http2.Setting) error {
+					settings[s.ID] = s.Val
+					return nil
+				})
+			}
+		case *http2.DataFrame:
+			if fr.StreamID == 1 {
+				proto = string(fr.Data())
+			}
+		}
+	}
+	if proto != "HTTP/2.0" {
+		t.Errorf("upgraded request served %q", proto)
+	}
+	if settings[http2.SettingMaxConcurrentStreams] != 32 || settings[http2.SettingMaxFrameSize] != 65536 {
+		t.Errorf("settings = %v", settings)
+	}
+}
+
+// upgradeH2C sends an Upgrade: h2c request for path on conn and the client preface after the 101.
+func upgradeH2C(t *testing.T, conn net.Conn, path string) *bufio.Reader {
+	t.Helper()
+	io.WriteString(conn, "GET "+path+" HTTP/1.1\r\nHost: localhost\r\nConnection: Upgrade, HTTP2-Settings\r\n"+
+		"Upgrade: h2c\r\nHTTP2-Settings: AAMAAABkAAQAoAAAAAIAAAAA\r\n\r\n")
+	br := bufio.NewReader(conn)
+	status, err := br.ReadString('\n')
+	if err != nil || !strings.HasPrefix(status, "HTTP/1.1 101") {
+		t.Fatalf("upgrade status = %q, %v", status, err)
+	}
+	for line, _ := br.ReadString('\n'); line != "\r\n" && line != ""; line, _ = br.ReadString('\n') {
+	}
+	io.WriteString(conn, http2.ClientPreface)
+	return br
+}
+
+// h2cConn is a raw prior-knowledge HTTP/2 connection.
+type h2cConn struct {
+	net.Conn
+	*http2.Framer
+}
+
+func dialH2C(t *testing.T, addr string) *h2cConn {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	io.WriteString(conn, http2.ClientPreface)
+	framer := http2.NewFramer(conn, conn)
+	if err := framer.WriteSettings(); err != nil {
+		t.Fatal(err)
+	}
+	return &h2cConn{Conn: conn, Framer: framer}
+}
+
+func TestHttpServerFactory_H2CShutdown(t *testing.T) {
+	props := glue.NewProperties()
+	props.Set("test-server.bind-address", "127.0.0.1:0")
+	props.Set("test-server.options", "handlers;h2c")
+	f := &implHttpServerFactory{
+		Log:        zap.NewNop(),
+		Properties: props,
+		Handlers:   []HttpHandler{protoHandler{}},
+		beanName:   "test-server",
+	}
+	serve := func() (*http.Server, string) {
+		obj, err := f.Object()
+		if err != nil {
+			t.Fatalf("Object: %v", err)
+		}
+		srv := obj.(*http.Server)
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		go srv.Serve(ln)
+		return srv, ln.Addr().String()
+	}
+
+	// Shutdown tells h2c clients to go away and waits for their connections
+	srv, addr := serve()
+	conn := dialH2C(t, addr)
+	defer conn.Close()
+	if _, err := conn.ReadFrame(); err != nil {
+		t.Fatalf("ReadFrame: %v", err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		done <- srv.Shutdown(ctx)
+	}()
+	for {
+		frame, err := conn.ReadFrame()
+		if err != nil {
+			t.Fatalf("connection ended without GOAWAY: %v", err)
+		}
+		if _, ok := frame.(*http2.GoAwayFrame); ok {
+			break
+		}
+	}
+	if err := <-done; err != nil {
+		t.Errorf("Shutdown: %v", err)
+	}
+
+	// Close ends them at once
+	srv, addr = serve()
+	conn = dialH2C(t, addr)
+	defer conn.Close()
+	if _, err := conn.ReadFrame(); err != nil {
+		t.Fatalf("ReadFrame: %v", err)
+	}
+	srv.Close()
+	expectClosed(t, conn.Framer)
+}
+
+// expectClosed reads frames until the server ends the connection.
+func expectClosed(t *testing.T, framer *http2.Framer) {
+	t.Helper()
+	for {
+		if _, err := framer.ReadFrame(); err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Timeout() {
+				t.Fatal("connection still open")
+			}
+			return
+		}
+	}
+}
+
+func TestHttpServerFactory_HTTP2IdleTimeout(t *testing.T) {
+	props := glue.NewProperties()
+	props.Set("test-server.bind-address", "127.0.0.1:0")
+	props.Set("test-server.options", "handlers;h2c")
+	props.Set("test-server.idle-timeout", "1m")
+	props.Set("test-server.http2-idle-timeout", "200ms")
+	f := &implHttpServerFactory{
+		Log:        zap.NewNop(),
+		Properties: props,
+		Handlers:   []HttpHandler{protoHandler{}},
+		beanName:   "test-server",
+	}
+	obj, err := f.Object()
+	if err != nil {
+		t.Fatalf("Object: %v", err)
+	}
+	srv := obj.(*http.Server)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.Serve(ln)
+	defer srv.Close()
+
+	// an idle HTTP/2 connection goes away after http2-idle-timeout, not the idle-timeout of HTTP/1.1
+	start := time.Now()
+	conn := dialH2C(t, ln.Addr().String())
+	defer conn.Close()
+	for {
+		frame, err := conn.ReadFrame()
+		if err != nil {
+			t.Fatalf("connection ended without GOAWAY: %v", err)
+		}
+		if _, ok := frame.(*http2.GoAwayFrame); ok {
+			break
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("GOAWAY after %v", elapsed)
+	}
+}
+
+func TestHttpServerFactory_HTTP2Settings(t *testing.T) {
+	props := glue.NewProperties()
+	props.Set("test-server.bind-address", "127.0.0.1:0")
+	props.Set("test-server.options", "tls")
+	f := &implHttpServerFactory{
+		Log:        zap.NewNop(),
+		Properties: props,
+		TlsConfig:  &tls.Config{},
+		beanName:   "test-server",
+	}
+	obj, err := f.Object()
+	if err != nil {
+		t.Fatalf("Object: %v", err)
+	}
+	// TLS servers negotiate HTTP/2 by ALPN
+	srv := obj.(*http.Server)
+	if !containsString(srv.TLSConfig.NextProtos, "h2") || srv.TLSNextProto["h2"] == nil {
+		t.Errorf("NextProtos = %v, want h2", srv.TLSConfig.NextProtos)
+	}
+
+	props.Set("test-server.options", "tls;h2c")
+	if _, err := f.Object(); err == nil {
+		t.Error("expected error for h2c with TLS")
+	}
+
+	props.Set("test-server.http2-idle-timeout", "5s")
+	h2s, err := f.http2Server(time.Minute)
+	if err != nil {
+		t.Fatalf("http2Server: %v", err)
+	}
+	if h2s.MaxConcurrentStreams != 250 || h2s.MaxReadFrameSize != 1<<20 || h2s.IdleTimeout != 5*time.Second {
+		t.Errorf("defaults = %+v", h2s)
+	}
+	for prop, value := range map[string]string{
+		"http2-max-frame-size":         "1024",
+		"http2-max-concurrent-streams": "0",
+	} {
+		bad := glue.NewProperties()
+		bad.Set("test-server."+prop, value)
+		f.Properties = bad
+		if _, err := f.http2Server(time.Minute); err == nil {
+			t.Errorf("expected error for %s=%s", prop, value)
+		}
+	}
+}
+
+// headerDispatcher serves the requests carrying its header.
+type headerDispatcher struct {
+	header string
+}
+
+func (d headerDispatcher) Dispatch(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(d.header) == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		n, _ := io.Copy(io.Discard, r.Body)
+		fmt.Fprintf(w, "%s %d", d.header, n)
+	})
+}
+
+func TestHttpServerFactory_Dispatchers(t *testing.T) {
+	props := glue.NewProperties()
+	props.Set("test-server.bind-address", "127.0.0.1:0")
+	props.Set("test-server.options", "handlers")
+	props.Set("test-server.max-body-bytes", "16")
+
+	f := &implHttpServerFactory{
+		Log:         zap.NewNop(),
+		Properties:  props,
+		Handlers:    []HttpHandler{&testHandler{pattern: "/hello"}},
+		Dispatchers: []HttpDispatcher{headerDispatcher{header: "X-Rpc"}},
+		beanName:    "test-server",
+	}
+	obj, err := f.Object()
+	if err != nil {
+		t.Fatalf("Object: %v", err)
+	}
+	srv := obj.(*http.Server)
+
+	// the dispatcher takes its requests before the router and the body limit
+	req := httptest.NewRequest(http.MethodPost, "/pkg.Service/Method", strings.NewReader(strings.Repeat("x", 64)))
+	req.Header.Set("X-Rpc", "1")
+	w := httptest.NewRecorder()
+	srv.Handler.ServeHTTP(w, req)
+	if w.Body.String() != "X-Rpc 64" {
+		t.Errorf("dispatched = %d %q", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	srv.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/hello", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "ok" {
+		t.Errorf("routed = %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestHttpServerFactory_BodyLimitAboveCeiling(t *testing.T) {
+	core, logs := observer.New(zap.WarnLevel)
+	props := glue.NewProperties()
+	props.Set("test-server.bind-address", "127.0.0.1:0")
+	props.Set("test-server.options", "handlers")
+
+	f := &implHttpServerFactory{
+		Log:         zap.New(core),
+		Properties:  props,
+		Handlers:    []HttpHandler{&testHandler{pattern: "/api/upload"}},
+		Middlewares: []HttpMiddleware{newTestBodyLimitMiddleware(t, 1024, "/api/upload=100MB;/api/stream=0;/api=1MB", "")},
+		beanName:    "test-server",
+	}
+	if _, err := f.Object(); err != nil {
+		t.Fatalf("Object: %v", err)
+	}
+	entries := logs.FilterMessage("BodyLimitAboveServerCeiling").All()
+	if len(entries) != 1 {
+		t.Fatalf("expected one warning, got %d", len(entries))
+	}
+	rules := fmt.Sprint(entries[0].ContextMap()["rules"])
+	if !strings.Contains(rules, "/api/upload=104857600") || !strings.Contains(rules, "/api/stream=0") || strings.Contains(rules, "/api=") {
+		t.Errorf("rules = %s", rules)
+	}
+
+	// a raised ceiling, or none, silences it
+	props.Set("test-server.max-body-bytes", "0")
+	if _, err := f.Object(); err != nil {
+		t.Fatalf("Object: %v", err)
+	}
+	if n := logs.FilterMessage("BodyLimitAboveServerCeiling").Len(); n != 1 {
+		t.Errorf("warnings without a ceiling = %d", n)
+	}
+}
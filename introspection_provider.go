@@ -0,0 +1,192 @@
+/*
+ * Copyright (c) 2026 Karagatan LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package servion
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+	"golang.org/x/xerrors"
+)
+
+type implIntrospectionProvider struct {
+	Log *zap.Logger `inject:"optional"`
+
+	// RFC 7662 introspection endpoint of the authorization server
+	URL string `value:"introspection.url,default="`
+
+	// Client credentials of this resource server at the authorization server
+	ClientID     string `value:"introspection.client-id,default="`
+	ClientSecret string `value:"introspection.client-secret,default="`
+
+	// Expected issuer and audience (optional, validated if set)
+	Issuer   string `value:"introspection.issuer,default="`
+	Audience string `value:"introspection.audience,default="`
+
+	Timeout     time.Duration `value:"introspection.timeout,default=5s"`
+	CacheTTL    time.Duration `value:"introspection.cache-ttl,default=1m"`
+	NegativeTTL time.Duration `value:"introspection.negative-ttl,default=10s"`
+	CacheSize   int           `value:"introspection.cache-size,default=10000"`
+
+	RolesClaim  string `value:"introspection.roles-claim,default=roles"`
+	ScopesClaim string `value:"introspection.scopes-claim,default=scope"`
+
+	client *http.Client
+	cached Authenticator
+}
+
+/*
+IntrospectionAuthProvider creates an Authenticator for opaque access tokens,
+asking the authorization server whether a token is active with RFC 7662 token
+introspection. The provider authenticates to the endpoint with its client
+credentials (HTTP Basic). Results are cached by token hash for
+introspection.cache-ttl, never past the token expiry, and refused tokens for
+introspection.negative-ttl (see CachingAuthenticator). When the endpoint can
+not be reached or fails, Authenticate returns ErrServiceUnavailable, which
+AuthMiddleware answers with 503 instead of rejecting a valid token.
+
+Configuration properties:
+
+	introspection.url            – introspection endpoint, https unless on a loopback address
+	introspection.client-id      – client ID of this resource server
+	introspection.client-secret  – client secret of this resource server
+	introspection.issuer         – expected "iss" of the response (optional)
+	introspection.audience       – expected "aud" of the response (optional)
+	introspection.timeout        – timeout of an introspection call (default 5s)
+	introspection.cache-ttl      – how long an active token is cached (default 1m)
+	introspection.negative-ttl   – how long an inactive token is cached, 0 for never (default 10s)
+	introspection.cache-size     – cached tokens at most (default 10000)
+	introspection.roles-claim    – response member with roles (default "roles")
+	introspection.scopes-claim   – response member with scopes (default "scope")
+*/
+func IntrospectionAuthProvider() Authenticator {
+	return &implIntrospectionProvider{}
+}
+
+func (t *implIntrospectionProvider) PostConstruct() error {
+	if t.Log == nil {
+		t.Log = zap.NewNop()
+	}
+	if t.URL == "" || t.ClientID == "" {
+		return xerrors.New("introspection: introspection.url and introspection.client-id must be configured")
+	}
+	u, err := url.Parse(t.URL)
+	if err != nil || !u.IsAbs() {
+		return xerrors.Errorf("introspection: invalid introspection.url '%s'", t.URL)
+	}
+	if u.Scheme != "https" && !(u.Scheme == "http" && isLoopbackHost(u.Hostname())) {
+		return xerrors.Errorf("introspection: introspection.url must use https, got '%s'", t.URL)
+	}
+	if t.Timeout <= 0 {
+		return xerrors.New("introspection: introspection.timeout must be positive")
+	}
+	t.client = &http.Client{Timeout: t.Timeout}
+	t.cached = CachingAuthenticator(authenticatorFunc(t.introspect), t.CacheTTL, t.NegativeTTL, t.CacheSize)
+	return nil
+}
+
+func (t *implIntrospectionProvider) Authenticate(token string) (AuthInfo, error) {
+	if token == "" {
+		return AuthInfo{}, ErrUnauthorized
+	}
+	return t.cached.Authenticate(token)
+}
+
+// introspect asks the endpoint about token, RFC 7662 section 2.
+func (t *implIntrospectionProvider) introspect(token string) (AuthInfo, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), t.Timeout)
+	defer cancel()
+
+	form := url.Values{
+		"token":           {token},
+		"token_type_hint": {"access_token"},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.URL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return AuthInfo{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(t.ClientID), url.QueryEscape(t.ClientSecret))
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		t.Log.Warn("IntrospectionFailed", zap.String("url", t.URL), zap.Error(err))
+		return AuthInfo{}, ErrServiceUnavailable
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Log.Warn("IntrospectionFailed", zap.String("url", t.URL), zap.Int("status", resp.StatusCode))
+		return AuthInfo{}, ErrServiceUnavailable
+	}
+
+	var claims jwt.MapClaims
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxJWKSSize)).Decode(&claims); err != nil {
+		t.Log.Warn("IntrospectionFailed", zap.String("url", t.URL), zap.Error(err))
+		return AuthInfo{}, ErrServiceUnavailable
+	}
+	if active, _ := claims["active"].(bool); !active {
+		return AuthInfo{}, ErrUnauthorized
+	}
+	return t.authInfo(token, claims, time.Now())
+}
+
+func (t *implIntrospectionProvider) authInfo(token string, claims jwt.MapClaims, now time.Time) (AuthInfo, error) {
+	info := AuthInfo{
+		HashedToken: hashToken(token),
+		Subject:     claimString(claims, "sub"),
+		Issuer:      claimString(claims, "iss"),
+		Roles:       claimStringSlice(claims, t.RolesClaim),
+		Scopes:      parseScopeClaim(claims, t.ScopesClaim),
+		Attributes:  map[string]string{"auth": "introspection"},
+	}
+	if info.Subject == "" {
+		// tokens of the client credentials grant often identify only the client
+		info.Subject = claimString(claims, "client_id")
+	}
+
+	if exp, err := claims.GetExpirationTime(); err != nil {
+		return AuthInfo{}, ErrUnauthorized
+	} else if exp != nil {
+		if !now.Before(exp.Time) {
+			return AuthInfo{}, ErrUnauthorized
+		}
+		info.Attributes["expires"] = exp.UTC().Format(time.RFC3339)
+	}
+	if nbf, err := claims.GetNotBefore(); err != nil || (nbf != nil && now.Before(nbf.Time)) {
+		return AuthInfo{}, ErrUnauthorized
+	}
+	if t.Issuer != "" && info.Issuer != t.Issuer {
+		return AuthInfo{}, ErrUnauthorized
+	}
+	if t.Audience != "" {
+		aud, err := claims.GetAudience()
+		if err != nil || !containsString(aud, t.Audience) {
+			return AuthInfo{}, ErrUnauthorized
+		}
+	}
+
+	for _, key := range []string{"client_id", "username", "token_type", "jti"} {
+		if v := claimString(claims, key); v != "" {
+			info.Attributes[key] = v
+		}
+	}
+	return info, nil
+}
+
+// authenticatorFunc adapts a function to the Authenticator interface.
+type authenticatorFunc func(token string) (AuthInfo, error)
+
+func (f authenticatorFunc) Authenticate(token string) (AuthInfo, error) {
+	return f(token)
+}
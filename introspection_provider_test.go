@@ -0,0 +1,140 @@
+package servion
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newIntrospectionServer(t *testing.T, calls *int32) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(calls, 1)
+		user, password, ok := r.BasicAuth()
+		if !ok || user != "api" || password != "api-secret" {
+			http.Error(w, "unauthorized client", http.StatusUnauthorized)
+			return
+		}
+		var resp map[string]interface{}
+		switch r.PostFormValue("token") {
+		case "active":
+			resp = map[string]interface{}{
+				"active": true,
+				"sub":    "alice",
+				"iss":    "https://as.example.com",
+				"aud":    []string{"orders"},
+				"scope":  "orders.read orders.write",
+				"roles":  []string{"admin"},
+				"exp":    time.Now().Add(time.Hour).Unix(),
+			}
+		case "service":
+			resp = map[string]interface{}{"active": true, "client_id": "billing", "aud": "orders"}
+		case "other-audience":
+			resp = map[string]interface{}{"active": true, "sub": "bob", "aud": "billing"}
+		case "expired":
+			resp = map[string]interface{}{"active": true, "sub": "bob", "aud": "orders", "exp": time.Now().Add(-time.Minute).Unix()}
+		case "boom":
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		default:
+			resp = map[string]interface{}{"active": false}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func newIntrospectionProvider(t *testing.T, url string) *implIntrospectionProvider {
+	t.Helper()
+	p := &implIntrospectionProvider{
+		URL:          url,
+		ClientID:     "api",
+		ClientSecret: "api-secret",
+		Audience:     "orders",
+		Timeout:      time.Second,
+		CacheTTL:     time.Minute,
+		NegativeTTL:  time.Minute,
+		CacheSize:    100,
+		RolesClaim:   "roles",
+		ScopesClaim:  "scope",
+	}
+	if err := p.PostConstruct(); err != nil {
+		t.Fatalf("PostConstruct: %v", err)
+	}
+	return p
+}
+
+func TestIntrospectionProvider_Authenticate(t *testing.T) {
+	var calls int32
+	p := newIntrospectionProvider(t, newIntrospectionServer(t, &calls).URL)
+
+	info, err := p.Authenticate("active")
+	if err != nil {
+		t.Fatalf("active: %v", err)
+	}
+	if info.Subject != "alice" || info.Issuer != "https://as.example.com" || info.HashedToken != hashToken("active") {
+		t.Errorf("unexpected identity %+v", info)
+	}
+	if len(info.Scopes) != 2 || len(info.Roles) != 1 || info.Roles[0] != "admin" {
+		t.Errorf("Roles = %v, Scopes = %v", info.Roles, info.Scopes)
+	}
+	if info.Attributes["auth"] != "introspection" || info.Attributes["expires"] == "" {
+		t.Errorf("Attributes = %v", info.Attributes)
+	}
+
+	info, err = p.Authenticate("service")
+	if err != nil || info.Subject != "billing" {
+		t.Errorf("service: %+v, %v", info, err)
+	}
+
+	for _, token := range []string{"inactive", "other-audience", "expired"} {
+		if _, err := p.Authenticate(token); !errors.Is(err, ErrUnauthorized) {
+			t.Errorf("%s: expected ErrUnauthorized, got %v", token, err)
+		}
+	}
+
+	// active and refused tokens are both answered from the cache
+	atomic.StoreInt32(&calls, 0)
+	p.Authenticate("active")
+	p.Authenticate("inactive")
+	if n := atomic.LoadInt32(&calls); n != 0 {
+		t.Errorf("endpoint called %d times for cached tokens", n)
+	}
+}
+
+func TestIntrospectionProvider_Unavailable(t *testing.T) {
+	var calls int32
+	srv := newIntrospectionServer(t, &calls)
+	p := newIntrospectionProvider(t, srv.URL)
+
+	if _, err := p.Authenticate("boom"); !errors.Is(err, ErrServiceUnavailable) {
+		t.Errorf("failing endpoint: expected ErrServiceUnavailable, got %v", err)
+	}
+
+	p.ClientSecret = "wrong"
+	p.PostConstruct()
+	if _, err := p.Authenticate("active"); !errors.Is(err, ErrServiceUnavailable) {
+		t.Errorf("rejected client: expected ErrServiceUnavailable, got %v", err)
+	}
+
+	srv.Close()
+	p = newIntrospectionProvider(t, srv.URL)
+	if _, err := p.Authenticate("active"); !errors.Is(err, ErrServiceUnavailable) {
+		t.Errorf("unreachable endpoint: expected ErrServiceUnavailable, got %v", err)
+	}
+}
+
+func TestIntrospectionProvider_Config(t *testing.T) {
+	for _, u := range []string{"", "not a url", "http://as.example.com/introspect"} {
+		p := &implIntrospectionProvider{URL: u, ClientID: "api", Timeout: time.Second}
+		if err := p.PostConstruct(); err == nil {
+			t.Errorf("%q: expected an error", u)
+		}
+	}
+}
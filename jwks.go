@@ -0,0 +1,196 @@
+/*
+ * Copyright (c) 2026 Karagatan LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package servion
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/xerrors"
+)
+
+// minRSAKeyBits rejects RSA keys too short to be trusted for signatures.
+const minRSAKeyBits = 2048
+
+// jsonWebKey is a public key of a JSON Web Key Set, RFC 7517.
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type jsonWebKeySet struct {
+	Keys []jsonWebKey `json:"keys"`
+}
+
+// jwksKey is a parsed verification key with the identifiers tokens select it by.
+type jwksKey struct {
+	kid string
+	alg string
+	key interface{}
+}
+
+/*
+parseJWKS returns the signature keys of a JSON Web Key Set. RSA, EC (P-256,
+P-384, P-521) and OKP (Ed25519) keys are supported; encryption keys, symmetric
+keys and malformed entries are skipped, so one bad key does not block a rotation.
+A set without any usable key is an error.
+*/
+func parseJWKS(data []byte) ([]jwksKey, error) {
+	var set jsonWebKeySet
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, xerrors.Errorf("invalid JWKS: %w", err)
+	}
+	var (
+		keys    []jwksKey
+		lastErr error
+	)
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		keys = append(keys, jwksKey{kid: jwk.Kid, alg: jwk.Alg, key: key})
+	}
+	if len(keys) == 0 {
+		if lastErr != nil {
+			return nil, xerrors.Errorf("no usable key in JWKS: %w", lastErr)
+		}
+		return nil, xerrors.New("no usable key in JWKS")
+	}
+	return keys, nil
+}
+
+func (k jsonWebKey) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeJWKInt(k.N)
+		if err != nil {
+			return nil, xerrors.Errorf("key '%s': modulus: %w", k.Kid, err)
+		}
+		e, err := decodeJWKInt(k.E)
+		if err != nil {
+			return nil, xerrors.Errorf("key '%s': exponent: %w", k.Kid, err)
+		}
+		if n.BitLen() < minRSAKeyBits {
+			return nil, xerrors.Errorf("key '%s': RSA key of %d bits is too short", k.Kid, n.BitLen())
+		}
+		if !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 {
+			return nil, xerrors.Errorf("key '%s': invalid RSA exponent", k.Kid)
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, xerrors.Errorf("key '%s': unsupported curve '%s'", k.Kid, k.Crv)
+		}
+		size := (curve.Params().BitSize + 7) / 8
+		x, err := decodeJWKBytes(k.X)
+		if err != nil || len(x) != size {
+			return nil, xerrors.Errorf("key '%s': invalid x coordinate", k.Kid)
+		}
+		y, err := decodeJWKBytes(k.Y)
+		if err != nil || len(y) != size {
+			return nil, xerrors.Errorf("key '%s': invalid y coordinate", k.Kid)
+		}
+		pub := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(pub.X, pub.Y) {
+			return nil, xerrors.Errorf("key '%s': point is not on curve %s", k.Kid, k.Crv)
+		}
+		return pub, nil
+
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, xerrors.Errorf("key '%s': unsupported curve '%s'", k.Kid, k.Crv)
+		}
+		x, err := decodeJWKBytes(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, xerrors.Errorf("key '%s': invalid Ed25519 key", k.Kid)
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, xerrors.Errorf("key '%s': unsupported key type '%s'", k.Kid, k.Kty)
+}
+
+// decodeJWKBytes decodes base64url, tolerating the padding some issuers add.
+func decodeJWKBytes(s string) ([]byte, error) {
+	if s == "" {
+		return nil, xerrors.New("missing value")
+	}
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
+
+func decodeJWKInt(s string) (*big.Int, error) {
+	b, err := decodeJWKBytes(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// jwtKeyAccepts reports whether key may verify tokens signed with method.
+func jwtKeyAccepts(key interface{}, method jwt.SigningMethod) bool {
+	switch key.(type) {
+	case *rsa.PublicKey:
+		_, rs := method.(*jwt.SigningMethodRSA)
+		_, ps := method.(*jwt.SigningMethodRSAPSS)
+		return rs || ps
+	case *ecdsa.PublicKey:
+		_, ok := method.(*jwt.SigningMethodECDSA)
+		return ok
+	case ed25519.PublicKey:
+		_, ok := method.(*jwt.SigningMethodEd25519)
+		return ok
+	}
+	return false
+}
+
+/*
+selectJWKSKeys returns the keys that may verify token: those with its "kid", or
+every key when the token names none, narrowed to keys whose type and declared
+"alg" fit the token algorithm.
+*/
+func selectJWKSKeys(keys []jwksKey, token *jwt.Token) []jwt.VerificationKey {
+	kid, _ := token.Header["kid"].(string)
+	var out []jwt.VerificationKey
+	for _, k := range keys {
+		if kid != "" && k.kid != kid {
+			continue
+		}
+		if k.alg != "" && k.alg != token.Method.Alg() {
+			continue
+		}
+		if jwtKeyAccepts(k.key, token.Method) {
+			out = append(out, k.key)
+		}
+	}
+	return out
+}
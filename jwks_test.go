@@ -0,0 +1,135 @@
+package servion
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// toJWK encodes a public key as a JSON Web Key.
+func toJWK(t *testing.T, kid string, pub interface{}) jsonWebKey {
+	t.Helper()
+	enc := base64.RawURLEncoding.EncodeToString
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		return jsonWebKey{Kty: "RSA", Kid: kid, N: enc(k.N.Bytes()), E: enc(big.NewInt(int64(k.E)).Bytes())}
+	case *ecdsa.PublicKey:
+		size := (k.Curve.Params().BitSize + 7) / 8
+		return jsonWebKey{Kty: "EC", Kid: kid, Crv: k.Curve.Params().Name, X: enc(k.X.FillBytes(make([]byte, size))), Y: enc(k.Y.FillBytes(make([]byte, size)))}
+	case ed25519.PublicKey:
+		return jsonWebKey{Kty: "OKP", Kid: kid, Crv: "Ed25519", X: enc(k)}
+	}
+	t.Fatalf("unsupported key %T", pub)
+	return jsonWebKey{}
+}
+
+func marshalJWKS(t *testing.T, keys ...jsonWebKey) []byte {
+	t.Helper()
+	data, err := json.Marshal(jsonWebKeySet{Keys: keys})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func generateRSAKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate RSA key: %v", err)
+	}
+	return priv
+}
+
+func TestParseJWKS(t *testing.T) {
+	rsaKey := generateRSAKey(t)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	edPub, _, _ := ed25519.GenerateKey(rand.Reader)
+
+	enc := toJWK(t, "enc", &rsaKey.PublicKey)
+	enc.Use = "enc"
+	oct := jsonWebKey{Kty: "oct", Kid: "secret"}
+	offCurve := toJWK(t, "bad", &ecKey.PublicKey)
+	offCurve.Y = offCurve.X
+
+	keys, err := parseJWKS(marshalJWKS(t,
+		toJWK(t, "rsa", &rsaKey.PublicKey),
+		toJWK(t, "ec", &ecKey.PublicKey),
+		toJWK(t, "ed", edPub),
+		enc, oct, offCurve,
+	))
+	if err != nil {
+		t.Fatalf("parseJWKS: %v", err)
+	}
+	if len(keys) != 3 || keys[0].kid != "rsa" || keys[1].kid != "ec" || keys[2].kid != "ed" {
+		t.Fatalf("keys = %+v", keys)
+	}
+	if pub, ok := keys[0].key.(*rsa.PublicKey); !ok || !pub.Equal(&rsaKey.PublicKey) {
+		t.Error("RSA key does not round trip")
+	}
+	if pub, ok := keys[1].key.(*ecdsa.PublicKey); !ok || !pub.Equal(&ecKey.PublicKey) {
+		t.Error("EC key does not round trip")
+	}
+	if pub, ok := keys[2].key.(ed25519.PublicKey); !ok || !pub.Equal(edPub) {
+		t.Error("Ed25519 key does not round trip")
+	}
+
+	if _, err := parseJWKS(marshalJWKS(t, oct)); err == nil {
+		t.Error("expected error for a set without usable keys")
+	}
+	if _, err := parseJWKS([]byte("{")); err == nil {
+		t.Error("expected error for invalid JSON")
+	}
+
+	short, _ := rsa.GenerateKey(rand.Reader, 1024)
+	if _, err := parseJWKS(marshalJWKS(t, toJWK(t, "short", &short.PublicKey))); err == nil {
+		t.Error("expected error for a 1024 bit RSA key")
+	}
+}
+
+func TestSelectJWKSKeys(t *testing.T) {
+	rsaKey := generateRSAKey(t)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	keys := []jwksKey{
+		{kid: "r1", alg: "RS256", key: &rsaKey.PublicKey},
+		{kid: "r2", key: &rsaKey.PublicKey},
+		{kid: "e1", key: &ecKey.PublicKey},
+	}
+	token := func(method jwt.SigningMethod, kid string) *jwt.Token {
+		tok := jwt.New(method)
+		if kid != "" {
+			tok.Header["kid"] = kid
+		}
+		return tok
+	}
+
+	if got := selectJWKSKeys(keys, token(jwt.SigningMethodRS256, "r1")); len(got) != 1 {
+		t.Errorf("RS256 r1: %d keys", len(got))
+	}
+	// r1 pins RS256
+	if got := selectJWKSKeys(keys, token(jwt.SigningMethodPS256, "r1")); len(got) != 0 {
+		t.Errorf("PS256 r1: %d keys", len(got))
+	}
+	if got := selectJWKSKeys(keys, token(jwt.SigningMethodPS256, "r2")); len(got) != 1 {
+		t.Errorf("PS256 r2: %d keys", len(got))
+	}
+	// a key type never verifies another family
+	if got := selectJWKSKeys(keys, token(jwt.SigningMethodRS256, "e1")); len(got) != 0 {
+		t.Errorf("RS256 e1: %d keys", len(got))
+	}
+	if got := selectJWKSKeys(keys, token(jwt.SigningMethodHS256, "")); len(got) != 0 {
+		t.Errorf("HS256: %d keys", len(got))
+	}
+	// without kid every fitting key is a candidate
+	if got := selectJWKSKeys(keys, token(jwt.SigningMethodRS256, "")); len(got) != 2 {
+		t.Errorf("RS256 no kid: %d keys", len(got))
+	}
+}
@@ -6,23 +6,57 @@
 package servion
 
 import (
-	"crypto/ecdsa"
+	"context"
 	"crypto/x509"
 	"encoding/base64"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
 	"golang.org/x/xerrors"
 )
 
+// maxJWKSSize caps the JWKS document read from a URL or file.
+const maxJWKSSize = 1 << 20
+
 type implJwtAuthProvider struct {
+	Log     *zap.Logger `inject:"optional"`
+	Runtime Runtime     `inject:"optional"`
+
+	// Revoked tokens and subjects, checked after a token verifies
+	Revocations RevocationStore `inject:"optional"`
+
 	// HMAC shared secret (used for HS256/HS384/HS512)
 	Secret string `value:"jwt.secret,default="`
 
-	// ECDSA public key as base64-encoded DER (PKIX format), used for ES256/ES384/ES512.
+	// Public key as base64-encoded DER (PKIX format): ECDSA, RSA or Ed25519.
 	// This is the raw key content without PEM header/footer lines.
 	PublicKeyB64 string `value:"jwt.public-key,default="`
 
+	// JWKS endpoint of the identity provider, e.g. https://idp.example.com/.well-known/jwks.json
+	JwksURL string `value:"jwt.jwks-url,default="`
+
+	// Local JWKS file, relative paths resolve against the home directory
+	JwksFile string `value:"jwt.jwks-file,default="`
+
+	// Interval of background keyset refreshes
+	JwksRefresh time.Duration `value:"jwt.jwks-refresh,default=15m"`
+
+	// Least time between refreshes triggered by an unknown kid
+	JwksMinRefresh time.Duration `value:"jwt.jwks-min-refresh,default=30s"`
+
+	// Timeout of a JWKS download
+	JwksTimeout time.Duration `value:"jwt.jwks-timeout,default=5s"`
+
 	// Expected issuer (optional, validated if set)
 	Issuer string `value:"jwt.issuer,default="`
 
@@ -35,34 +69,68 @@ type implJwtAuthProvider struct {
 	// JWT claim name for scopes (default "scope")
 	ScopesClaim string `value:"jwt.scopes-claim,default=scope"`
 
-	keyFunc  jwt.Keyfunc
-	ecdsaPub *ecdsa.PublicKey
+	keyFunc jwt.Keyfunc
+	pubKey  interface{}
+
+	client    *http.Client
+	jwksPath  string
+	keysMu    sync.RWMutex
+	keys      []jwksKey
+	fileMod   time.Time
+	refreshMu sync.Mutex
+	lastLoad  time.Time
+	stopCh    chan struct{}
+	wg        sync.WaitGroup
 }
 
 // JwtAuthProvider creates a JWT-based Authenticator.
 //
+// Tokens are verified with exactly one key source: an HMAC secret, a single
+// public key, or a JSON Web Key Set fetched from an identity provider or read
+// from a local file. A keyset selects the key by the "kid" header and accepts
+// RS256/384/512, PS256/384/512, ES256/384/512 and EdDSA. It is refreshed in the
+// background and on demand when a token names an unknown kid, so keys rotated
+// by the issuer, or added to the file next to the old one for an overlap
+// window, are picked up without a restart. With a RevocationStore in the
+// context, tokens revoked by their "jti" claim or by subject are refused too.
+//
 // Configuration properties:
 //
-//	jwt.secret       – HMAC shared secret for HS* algorithms
-//	jwt.public-key   – ECDSA public key as base64 string (DER/PKIX content without PEM header/footer)
-//	jwt.issuer       – expected issuer claim (optional)
-//	jwt.audience     – expected audience claim (optional)
-//	jwt.roles-claim  – claim name containing roles (default "roles")
-//	jwt.scopes-claim – claim name containing scopes (default "scope")
+//	jwt.secret           – HMAC shared secret for HS* algorithms
+//	jwt.public-key       – ECDSA, RSA or Ed25519 public key as base64 string (DER/PKIX content without PEM header/footer)
+//	jwt.jwks-url         – JWKS endpoint, https unless on a loopback address
+//	jwt.jwks-file        – local JWKS file, reloaded when it changes
+//	jwt.jwks-refresh     – background refresh interval (default 15m)
+//	jwt.jwks-min-refresh – least time between refreshes for an unknown kid (default 30s)
+//	jwt.jwks-timeout     – JWKS download timeout (default 5s)
+//	jwt.issuer           – expected issuer claim (optional)
+//	jwt.audience         – expected audience claim (optional)
+//	jwt.roles-claim      – claim name containing roles (default "roles")
+//	jwt.scopes-claim     – claim name containing scopes (default "scope")
 func JwtAuthProvider() Authenticator {
 	return &implJwtAuthProvider{}
 }
 
 func (t *implJwtAuthProvider) PostConstruct() error {
-	if t.Secret == "" && t.PublicKeyB64 == "" {
-		return xerrors.New("jwt: either jwt.secret or jwt.public-key must be configured")
+	if t.Log == nil {
+		t.Log = zap.NewNop()
 	}
 
-	if t.Secret != "" && t.PublicKeyB64 != "" {
-		return xerrors.New("jwt: jwt.secret and jwt.public-key are mutually exclusive")
+	sources := 0
+	for _, s := range []string{t.Secret, t.PublicKeyB64, t.JwksURL, t.JwksFile} {
+		if s != "" {
+			sources++
+		}
+	}
+	if sources == 0 {
+		return xerrors.New("jwt: one of jwt.secret, jwt.public-key, jwt.jwks-url or jwt.jwks-file must be configured")
+	}
+	if sources > 1 {
+		return xerrors.New("jwt: jwt.secret, jwt.public-key, jwt.jwks-url and jwt.jwks-file are mutually exclusive")
 	}
 
-	if t.PublicKeyB64 != "" {
+	switch {
+	case t.PublicKeyB64 != "":
 		der, err := base64.StdEncoding.DecodeString(t.PublicKeyB64)
 		if err != nil {
 			return xerrors.Errorf("jwt: failed to base64-decode jwt.public-key: %w", err)
@@ -71,18 +139,24 @@ func (t *implJwtAuthProvider) PostConstruct() error {
 		if err != nil {
 			return xerrors.Errorf("jwt: failed to parse public key: %w", err)
 		}
-		ecPub, ok := pub.(*ecdsa.PublicKey)
-		if !ok {
-			return xerrors.Errorf("jwt: public key is not ECDSA, got %T", pub)
+		if !jwtKeyAccepts(pub, jwt.SigningMethodES256) && !jwtKeyAccepts(pub, jwt.SigningMethodRS256) && !jwtKeyAccepts(pub, jwt.SigningMethodEdDSA) {
+			return xerrors.Errorf("jwt: unsupported public key type %T", pub)
 		}
-		t.ecdsaPub = ecPub
+		t.pubKey = pub
 		t.keyFunc = func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodECDSA); !ok {
+			if !jwtKeyAccepts(t.pubKey, token.Method) {
 				return nil, xerrors.Errorf("jwt: unexpected signing method %v", token.Header["alg"])
 			}
-			return t.ecdsaPub, nil
+			return t.pubKey, nil
 		}
-	} else {
+
+	case t.JwksURL != "" || t.JwksFile != "":
+		if err := t.initJWKS(); err != nil {
+			return err
+		}
+		t.keyFunc = t.jwksKeyFunc
+
+	default:
 		t.keyFunc = func(token *jwt.Token) (interface{}, error) {
 			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 				return nil, xerrors.Errorf("jwt: unexpected signing method %v", token.Header["alg"])
@@ -94,7 +168,236 @@ func (t *implJwtAuthProvider) PostConstruct() error {
 	return nil
 }
 
+func (t *implJwtAuthProvider) initJWKS() error {
+	if t.JwksURL != "" {
+		u, err := url.Parse(t.JwksURL)
+		if err != nil || u.Host == "" {
+			return xerrors.Errorf("jwt: invalid jwt.jwks-url '%s'", t.JwksURL)
+		}
+		if u.Scheme != "https" && !(u.Scheme == "http" && isLoopbackHost(u.Hostname())) {
+			return xerrors.Errorf("jwt: jwt.jwks-url must use https, got '%s'", t.JwksURL)
+		}
+		t.client = &http.Client{Timeout: t.JwksTimeout}
+	} else {
+		t.jwksPath = t.JwksFile
+		if !filepath.IsAbs(t.jwksPath) && t.Runtime != nil {
+			t.jwksPath = filepath.Join(t.Runtime.HomeDir(), t.jwksPath)
+		}
+	}
+
+	if err := t.refreshJWKS(); err != nil {
+		if t.JwksFile != "" {
+			return xerrors.Errorf("jwt: %w", err)
+		}
+		// the identity provider may come up later, tokens are refused until it does
+		t.Log.Warn("JwksFetchFailed", zap.String("url", t.JwksURL), zap.Error(err))
+	}
+
+	if t.JwksRefresh > 0 {
+		t.stopCh = make(chan struct{})
+		t.wg.Add(1)
+		go t.refreshLoop(t.stopCh)
+	}
+	return nil
+}
+
+func (t *implJwtAuthProvider) Destroy() error {
+	if t.stopCh != nil {
+		close(t.stopCh)
+		t.stopCh = nil
+	}
+	t.wg.Wait()
+	return nil
+}
+
+func (t *implJwtAuthProvider) refreshLoop(stopCh <-chan struct{}) {
+	defer t.wg.Done()
+	ticker := time.NewTicker(t.JwksRefresh)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := t.refreshJWKS(); err != nil {
+				t.Log.Warn("JwksRefreshFailed", zap.String("source", t.jwksSource()), zap.Error(err))
+			}
+		case <-stopCh:
+			return
+		}
+	}
+}
+
+func (t *implJwtAuthProvider) jwksSource() string {
+	if t.JwksURL != "" {
+		return t.JwksURL
+	}
+	return t.jwksPath
+}
+
+// refreshJWKS reloads the keyset; a file is only parsed again when it changed.
+// On failure the keys loaded before stay in use.
+func (t *implJwtAuthProvider) refreshJWKS() error {
+	t.refreshMu.Lock()
+	defer t.refreshMu.Unlock()
+	return t.loadJWKS()
+}
+
+// refreshIfDue reloads the keyset unless it was loaded less than jwt.jwks-min-refresh ago.
+func (t *implJwtAuthProvider) refreshIfDue() (bool, error) {
+	t.refreshMu.Lock()
+	defer t.refreshMu.Unlock()
+	if time.Since(t.lastLoad) < t.JwksMinRefresh {
+		return false, nil
+	}
+	return true, t.loadJWKS()
+}
+
+func (t *implJwtAuthProvider) loadJWKS() error {
+	t.lastLoad = time.Now()
+
+	var (
+		data []byte
+		mod  time.Time
+		err  error
+	)
+	if t.JwksURL != "" {
+		data, err = t.fetchJWKS()
+	} else {
+		data, mod, err = t.readJWKSFile()
+		if err == nil && data == nil {
+			return nil
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	keys, err := parseJWKS(data)
+	if err != nil {
+		return xerrors.Errorf("%s: %w", t.jwksSource(), err)
+	}
+	t.keysMu.Lock()
+	t.keys = keys
+	t.fileMod = mod
+	t.keysMu.Unlock()
+	t.Log.Info("JwksLoaded", zap.String("source", t.jwksSource()), zap.Int("keys", len(keys)))
+	return nil
+}
+
+func (t *implJwtAuthProvider) fetchJWKS() ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), t.JwksTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, t.JwksURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, xerrors.Errorf("fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, xerrors.Errorf("fetch JWKS %s: status %d", t.JwksURL, resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxJWKSSize))
+}
+
+// readJWKSFile returns nil data when the file did not change since the last load.
+func (t *implJwtAuthProvider) readJWKSFile() ([]byte, time.Time, error) {
+	fi, err := os.Stat(t.jwksPath)
+	if err != nil {
+		return nil, time.Time{}, xerrors.Errorf("stat JWKS file: %w", err)
+	}
+	t.keysMu.RLock()
+	unchanged := t.keys != nil && fi.ModTime().Equal(t.fileMod)
+	t.keysMu.RUnlock()
+	if unchanged {
+		return nil, time.Time{}, nil
+	}
+	if fi.Size() > maxJWKSSize {
+		return nil, time.Time{}, xerrors.Errorf("JWKS file %s exceeds %d bytes", t.jwksPath, maxJWKSSize)
+	}
+	data, err := os.ReadFile(t.jwksPath)
+	if err != nil {
+		return nil, time.Time{}, xerrors.Errorf("read JWKS file: %w", err)
+	}
+	return data, fi.ModTime(), nil
+}
+
+func (t *implJwtAuthProvider) jwksKeyFunc(token *jwt.Token) (interface{}, error) {
+	t.keysMu.RLock()
+	keys := selectJWKSKeys(t.keys, token)
+	t.keysMu.RUnlock()
+
+	// an unknown kid may be a key the issuer just rotated in
+	if len(keys) == 0 {
+		refreshed, err := t.refreshIfDue()
+		if err != nil {
+			t.Log.Warn("JwksRefreshFailed", zap.String("source", t.jwksSource()), zap.Error(err))
+		}
+		if refreshed {
+			t.keysMu.RLock()
+			keys = selectJWKSKeys(t.keys, token)
+			t.keysMu.RUnlock()
+		}
+	}
+
+	switch len(keys) {
+	case 0:
+		t.keysMu.RLock()
+		loaded := t.keys != nil
+		t.keysMu.RUnlock()
+		if !loaded {
+			return nil, ErrServiceUnavailable
+		}
+		return nil, xerrors.Errorf("jwt: no key for kid %v and alg %v", token.Header["kid"], token.Header["alg"])
+	case 1:
+		return keys[0], nil
+	}
+	return jwt.VerificationKeySet{Keys: keys}, nil
+}
+
+func isLoopbackHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
 func (t *implJwtAuthProvider) Authenticate(tokenStr string) (AuthInfo, error) {
+	claims, err := t.verify(tokenStr)
+	if err != nil {
+		return AuthInfo{}, err
+	}
+	if err := t.checkRevoked(claims); err != nil {
+		return AuthInfo{}, err
+	}
+	return t.authInfo(tokenStr, claims), nil
+}
+
+// checkRevoked refuses tokens revoked by jti or by subject; a failing store fails closed with 503.
+func (t *implJwtAuthProvider) checkRevoked(claims jwt.MapClaims) error {
+	if t.Revocations == nil {
+		return nil
+	}
+	var issuedAt time.Time
+	if iat, err := claims.GetIssuedAt(); err == nil && iat != nil {
+		issuedAt = iat.Time
+	}
+	revoked, err := t.Revocations.IsRevoked(claimString(claims, "jti"), claimString(claims, "sub"), issuedAt)
+	if err != nil {
+		t.Log.Warn("RevocationCheckFailed", zap.Error(err))
+		return ErrServiceUnavailable
+	}
+	if revoked {
+		return ErrUnauthorized
+	}
+	return nil
+}
+
+// verify checks the signature and the standard claims of tokenStr.
+func (t *implJwtAuthProvider) verify(tokenStr string) (jwt.MapClaims, error) {
 	opts := []jwt.ParserOption{jwt.WithExpirationRequired()}
 	if t.Issuer != "" {
 		opts = append(opts, jwt.WithIssuer(t.Issuer))
@@ -104,15 +407,21 @@ func (t *implJwtAuthProvider) Authenticate(tokenStr string) (AuthInfo, error) {
 	}
 
 	token, err := jwt.Parse(tokenStr, t.keyFunc, opts...)
+	if errors.Is(err, ErrServiceUnavailable) {
+		return nil, ErrServiceUnavailable
+	}
 	if err != nil {
-		return AuthInfo{}, ErrUnauthorized
+		return nil, ErrUnauthorized
 	}
 
 	claims, ok := token.Claims.(jwt.MapClaims)
 	if !ok || !token.Valid {
-		return AuthInfo{}, ErrUnauthorized
+		return nil, ErrUnauthorized
 	}
+	return claims, nil
+}
 
+func (t *implJwtAuthProvider) authInfo(tokenStr string, claims jwt.MapClaims) AuthInfo {
 	info := AuthInfo{
 		HashedToken: hashToken(tokenStr),
 		Subject:     claimString(claims, "sub"),
@@ -129,7 +438,7 @@ func (t *implJwtAuthProvider) Authenticate(tokenStr string) (AuthInfo, error) {
 		}
 	}
 
-	return info, nil
+	return info
 }
 
 func claimString(claims jwt.MapClaims, key string) string {
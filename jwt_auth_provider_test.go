@@ -2,11 +2,17 @@ package servion
 
 import (
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/x509"
 	"encoding/base64"
 	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
 	"testing"
 	"time"
 
@@ -468,3 +474,278 @@ func TestJwtAuth_Factory(t *testing.T) {
 		t.Fatal("JwtAuthProvider() returned nil")
 	}
 }
+
+func signToken(t *testing.T, method jwt.SigningMethod, kid string, key interface{}) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, jwt.MapClaims{
+		"sub": "user-123",
+		"exp": jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	})
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	s, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("sign %s token: %v", method.Alg(), err)
+	}
+	return s
+}
+
+// jwksServer serves the current keyset and counts downloads.
+type jwksServer struct {
+	mu    sync.Mutex
+	body  []byte
+	hits  int
+	srv   *httptest.Server
+	fails bool
+}
+
+func newJwksServer(t *testing.T, body []byte) *jwksServer {
+	s := &jwksServer{body: body}
+	s.srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.hits++
+		if s.fails {
+			http.Error(w, "down", http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(s.body)
+	}))
+	t.Cleanup(s.srv.Close)
+	return s
+}
+
+func (s *jwksServer) set(body []byte) {
+	s.mu.Lock()
+	s.body = body
+	s.mu.Unlock()
+}
+
+func (s *jwksServer) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.hits
+}
+
+func newJwksProvider(t *testing.T, configure func(p *implJwtAuthProvider)) *implJwtAuthProvider {
+	t.Helper()
+	p := &implJwtAuthProvider{
+		JwksRefresh:    time.Hour,
+		JwksMinRefresh: 0,
+		JwksTimeout:    time.Second,
+		RolesClaim:     "roles",
+		ScopesClaim:    "scope",
+	}
+	configure(p)
+	if err := p.PostConstruct(); err != nil {
+		t.Fatalf("PostConstruct: %v", err)
+	}
+	t.Cleanup(func() { p.Destroy() })
+	return p
+}
+
+func TestJwtAuth_JwksAlgorithms(t *testing.T) {
+	rsaKey := generateRSAKey(t)
+	ecKey, _ := generateECDSAKeys(t)
+	edPub, edPriv, _ := ed25519.GenerateKey(rand.Reader)
+
+	server := newJwksServer(t, marshalJWKS(t,
+		toJWK(t, "rsa", &rsaKey.PublicKey),
+		toJWK(t, "ec", &ecKey.PublicKey),
+		toJWK(t, "ed", edPub),
+	))
+	p := newJwksProvider(t, func(p *implJwtAuthProvider) { p.JwksURL = server.srv.URL })
+
+	for _, tc := range []struct {
+		method jwt.SigningMethod
+		kid    string
+		key    interface{}
+	}{
+		{jwt.SigningMethodRS256, "rsa", rsaKey},
+		{jwt.SigningMethodPS256, "rsa", rsaKey},
+		{jwt.SigningMethodES256, "ec", ecKey},
+		{jwt.SigningMethodEdDSA, "ed", edPriv},
+		{jwt.SigningMethodEdDSA, "", edPriv},
+	} {
+		info, err := p.Authenticate(signToken(t, tc.method, tc.kid, tc.key))
+		if err != nil {
+			t.Errorf("%s kid=%q: %v", tc.method.Alg(), tc.kid, err)
+			continue
+		}
+		if info.Subject != "user-123" {
+			t.Errorf("%s: Subject = %q", tc.method.Alg(), info.Subject)
+		}
+	}
+
+	// a kid naming a key of another type is refused
+	if _, err := p.Authenticate(signToken(t, jwt.SigningMethodRS256, "ec", rsaKey)); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("expected ErrUnauthorized for mismatched key type, got %v", err)
+	}
+	// HMAC tokens are never verified with a keyset
+	if _, err := p.Authenticate(makeHmacToken(t, "secret", jwt.MapClaims{"sub": "x", "exp": jwt.NewNumericDate(time.Now().Add(time.Hour))})); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("expected ErrUnauthorized for HS256, got %v", err)
+	}
+}
+
+func TestJwtAuth_JwksRotation(t *testing.T) {
+	oldKey := generateRSAKey(t)
+	newKey := generateRSAKey(t)
+
+	server := newJwksServer(t, marshalJWKS(t, toJWK(t, "k1", &oldKey.PublicKey)))
+	p := newJwksProvider(t, func(p *implJwtAuthProvider) {
+		p.JwksURL = server.srv.URL
+		p.JwksMinRefresh = time.Hour
+	})
+	if _, err := p.Authenticate(signToken(t, jwt.SigningMethodRS256, "k1", oldKey)); err != nil {
+		t.Fatalf("old key: %v", err)
+	}
+
+	// the issuer rotates; the first refresh for an unknown kid is allowed
+	p.lastLoad = time.Time{}
+	server.set(marshalJWKS(t, toJWK(t, "k1", &oldKey.PublicKey), toJWK(t, "k2", &newKey.PublicKey)))
+	if _, err := p.Authenticate(signToken(t, jwt.SigningMethodRS256, "k2", newKey)); err != nil {
+		t.Fatalf("new key: %v", err)
+	}
+	hits := server.count()
+
+	// unknown kids do not hammer the issuer within jwt.jwks-min-refresh
+	for i := 0; i < 3; i++ {
+		if _, err := p.Authenticate(signToken(t, jwt.SigningMethodRS256, "k3", newKey)); !errors.Is(err, ErrUnauthorized) {
+			t.Fatalf("unknown kid: expected ErrUnauthorized, got %v", err)
+		}
+	}
+	if server.count() != hits {
+		t.Errorf("JWKS downloads = %d, want %d", server.count(), hits)
+	}
+
+	// a failing refresh keeps the keys loaded before
+	server.mu.Lock()
+	server.fails = true
+	server.mu.Unlock()
+	if err := p.refreshJWKS(); err == nil {
+		t.Fatal("expected refresh error")
+	}
+	if _, err := p.Authenticate(signToken(t, jwt.SigningMethodRS256, "k2", newKey)); err != nil {
+		t.Fatalf("after failed refresh: %v", err)
+	}
+}
+
+func TestJwtAuth_JwksUnavailable(t *testing.T) {
+	server := newJwksServer(t, nil)
+	server.fails = true
+	p := newJwksProvider(t, func(p *implJwtAuthProvider) { p.JwksURL = server.srv.URL })
+
+	rsaKey := generateRSAKey(t)
+	if _, err := p.Authenticate(signToken(t, jwt.SigningMethodRS256, "k1", rsaKey)); !errors.Is(err, ErrServiceUnavailable) {
+		t.Fatalf("expected ErrServiceUnavailable before the first keyset, got %v", err)
+	}
+}
+
+func TestJwtAuth_JwksFile(t *testing.T) {
+	oldKey := generateRSAKey(t)
+	_, newKey, _ := ed25519.GenerateKey(rand.Reader)
+
+	file := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(file, marshalJWKS(t, toJWK(t, "old", &oldKey.PublicKey)), 0600); err != nil {
+		t.Fatal(err)
+	}
+	p := newJwksProvider(t, func(p *implJwtAuthProvider) { p.JwksFile = file })
+	if _, err := p.Authenticate(signToken(t, jwt.SigningMethodRS256, "old", oldKey)); err != nil {
+		t.Fatalf("old key: %v", err)
+	}
+
+	// overlap window: both keys verify until the old one is removed
+	overlap := marshalJWKS(t, toJWK(t, "old", &oldKey.PublicKey), toJWK(t, "new", newKey.Public()))
+	if err := os.WriteFile(file, overlap, 0600); err != nil {
+		t.Fatal(err)
+	}
+	os.Chtimes(file, time.Now(), time.Now().Add(time.Second))
+	if _, err := p.Authenticate(signToken(t, jwt.SigningMethodEdDSA, "new", newKey)); err != nil {
+		t.Fatalf("new key: %v", err)
+	}
+	if _, err := p.Authenticate(signToken(t, jwt.SigningMethodRS256, "old", oldKey)); err != nil {
+		t.Fatalf("old key during overlap: %v", err)
+	}
+
+	if err := os.WriteFile(file, marshalJWKS(t, toJWK(t, "new", newKey.Public())), 0600); err != nil {
+		t.Fatal(err)
+	}
+	os.Chtimes(file, time.Now(), time.Now().Add(2*time.Second))
+	if err := p.refreshJWKS(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Authenticate(signToken(t, jwt.SigningMethodRS256, "old", oldKey)); !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("retired key: expected ErrUnauthorized, got %v", err)
+	}
+}
+
+func TestJwtAuth_PostConstruct_JwksConfig(t *testing.T) {
+	for _, p := range []*implJwtAuthProvider{
+		{JwksURL: "http://idp.example.com/jwks.json"},
+		{JwksURL: "https://idp.example.com/jwks.json", Secret: "secret"},
+		{JwksFile: filepath.Join(t.TempDir(), "missing.json")},
+	} {
+		if err := p.PostConstruct(); err == nil {
+			p.Destroy()
+			t.Errorf("expected error for %+v", p)
+		}
+	}
+}
+
+func TestJwtAuth_RsaAndEd25519PublicKey(t *testing.T) {
+	rsaKey := generateRSAKey(t)
+	edPub, edPriv, _ := ed25519.GenerateKey(rand.Reader)
+
+	for _, tc := range []struct {
+		pub    interface{}
+		method jwt.SigningMethod
+		key    interface{}
+	}{
+		{&rsaKey.PublicKey, jwt.SigningMethodPS384, rsaKey},
+		{edPub, jwt.SigningMethodEdDSA, edPriv},
+	} {
+		der, err := x509.MarshalPKIXPublicKey(tc.pub)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p := &implJwtAuthProvider{PublicKeyB64: base64.StdEncoding.EncodeToString(der), RolesClaim: "roles", ScopesClaim: "scope"}
+		if err := p.PostConstruct(); err != nil {
+			t.Fatalf("PostConstruct: %v", err)
+		}
+		if _, err := p.Authenticate(signToken(t, tc.method, "", tc.key)); err != nil {
+			t.Errorf("%s: %v", tc.method.Alg(), err)
+		}
+	}
+}
+
+func TestJwtAuth_Revocations(t *testing.T) {
+	secret := "test-secret-key-at-least-32-chars"
+	store := MemoryRevocationStore()
+	p := &implJwtAuthProvider{Secret: secret, Revocations: store}
+	if err := p.PostConstruct(); err != nil {
+		t.Fatalf("PostConstruct: %v", err)
+	}
+	exp := jwt.NewNumericDate(time.Now().Add(time.Hour))
+	issued := jwt.NewNumericDate(time.Now().Add(-time.Minute))
+	first := makeHmacToken(t, secret, jwt.MapClaims{"sub": "alice", "jti": "t-1", "iat": issued, "exp": exp})
+	second := makeHmacToken(t, secret, jwt.MapClaims{"sub": "alice", "jti": "t-2", "iat": issued, "exp": exp})
+
+	store.RevokeToken("t-1", exp.Time)
+	if _, err := p.Authenticate(first); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("revoked jti: expected ErrUnauthorized, got %v", err)
+	}
+	if _, err := p.Authenticate(second); err != nil {
+		t.Errorf("other token: %v", err)
+	}
+
+	store.RevokeSubject("alice", time.Now(), time.Now().Add(time.Hour))
+	if _, err := p.Authenticate(second); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("revoked subject: expected ErrUnauthorized, got %v", err)
+	}
+	later := makeHmacToken(t, secret, jwt.MapClaims{"sub": "alice", "iat": jwt.NewNumericDate(time.Now().Add(time.Minute)), "exp": exp})
+	if _, err := p.Authenticate(later); err != nil {
+		t.Errorf("token issued after the revocation: %v", err)
+	}
+}
@@ -0,0 +1,196 @@
+/*
+ * Copyright (c) 2026 Karagatan LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package servion
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/xerrors"
+)
+
+/*
+jwtSigner signs tokens that JwtAuthProvider verifies: with an HMAC secret
+(jwt.secret), or with a private key whose public half is jwt.public-key or a
+member of the JWKS. Asymmetric tokens carry the RFC 7638 thumbprint of the key
+as "kid" unless one is configured, the kid "jwttool jwks" assigns by default.
+*/
+type jwtSigner struct {
+	method jwt.SigningMethod
+	key    interface{}
+	kid    string
+}
+
+/*
+newJwtSigner creates a signer from exactly one of secret, privateKey (PEM text
+or base64 PKCS#8 DER) or keyFile (PEM or base64 DER). The algorithm defaults to
+HS256 for a secret, ES256/384/512 by curve, RS256 for RSA and EdDSA for Ed25519.
+*/
+func newJwtSigner(secret, privateKey, keyFile, alg, kid string) (*jwtSigner, error) {
+	sources := 0
+	for _, s := range []string{secret, privateKey, keyFile} {
+		if s != "" {
+			sources++
+		}
+	}
+	if sources != 1 {
+		return nil, xerrors.New("exactly one of a secret, a private key or a private key file is required")
+	}
+
+	if secret != "" {
+		if alg == "" {
+			alg = jwt.SigningMethodHS256.Alg()
+		}
+		method, ok := jwt.GetSigningMethod(alg).(*jwt.SigningMethodHMAC)
+		if !ok {
+			return nil, xerrors.Errorf("algorithm '%s' does not fit a secret, expected HS256, HS384 or HS512", alg)
+		}
+		return &jwtSigner{method: method, key: []byte(secret), kid: kid}, nil
+	}
+
+	data := []byte(privateKey)
+	if keyFile != "" {
+		var err error
+		if data, err = os.ReadFile(keyFile); err != nil {
+			return nil, xerrors.Errorf("read private key: %w", err)
+		}
+	}
+	key, err := parseSigningKey(data)
+	if err != nil {
+		return nil, err
+	}
+	method, err := signingMethodOf(key.Public(), alg)
+	if err != nil {
+		return nil, err
+	}
+	if kid == "" {
+		if kid, err = jwkThumbprint(key.Public()); err != nil {
+			return nil, err
+		}
+	}
+	return &jwtSigner{method: method, key: key, kid: kid}, nil
+}
+
+func (s *jwtSigner) sign(claims jwt.MapClaims) (string, error) {
+	token := jwt.NewWithClaims(s.method, claims)
+	if s.kid != "" {
+		token.Header["kid"] = s.kid
+	}
+	return token.SignedString(s.key)
+}
+
+// verificationKey returns the key verifying the tokens of s: the secret, or the public half of the private key.
+func (s *jwtSigner) verificationKey() interface{} {
+	if signer, ok := s.key.(crypto.Signer); ok {
+		return signer.Public()
+	}
+	return s.key
+}
+
+// parseSigningKey reads a PKCS#8, SEC 1 or PKCS#1 private key from PEM, or PKCS#8 from base64 DER.
+func parseSigningKey(data []byte) (crypto.Signer, error) {
+	var key interface{}
+	if block, _ := pem.Decode(data); block != nil {
+		var err error
+		switch block.Type {
+		case "PRIVATE KEY":
+			key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+		case "EC PRIVATE KEY":
+			key, err = x509.ParseECPrivateKey(block.Bytes)
+		case "RSA PRIVATE KEY":
+			key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+		default:
+			return nil, xerrors.Errorf("PEM block '%s' is not a private key", block.Type)
+		}
+		if err != nil {
+			return nil, xerrors.Errorf("parse private key: %w", err)
+		}
+	} else {
+		der, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(string(data)), ""))
+		if err != nil {
+			return nil, xerrors.New("private key is neither PEM nor base64 DER")
+		}
+		if key, err = x509.ParsePKCS8PrivateKey(der); err != nil {
+			return nil, xerrors.Errorf("parse private key: %w", err)
+		}
+	}
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		if k.N.BitLen() < minRSAKeyBits {
+			return nil, xerrors.Errorf("RSA key of %d bits is too short", k.N.BitLen())
+		}
+	case *ecdsa.PrivateKey, ed25519.PrivateKey:
+	default:
+		return nil, xerrors.Errorf("unsupported private key %T", key)
+	}
+	return key.(crypto.Signer), nil
+}
+
+// signingMethodOf returns alg when it fits the public key pub, else the default algorithm of the key.
+func signingMethodOf(pub interface{}, alg string) (jwt.SigningMethod, error) {
+	if alg != "" {
+		method := jwt.GetSigningMethod(alg)
+		if method == nil {
+			return nil, xerrors.Errorf("unknown algorithm '%s'", alg)
+		}
+		if m, ok := method.(*jwt.SigningMethodECDSA); ok {
+			if k, isEC := pub.(*ecdsa.PublicKey); isEC && m.CurveBits != k.Curve.Params().BitSize {
+				return nil, xerrors.Errorf("algorithm '%s' does not fit curve %s", alg, k.Curve.Params().Name)
+			}
+		}
+		if !jwtKeyAccepts(pub, method) {
+			return nil, xerrors.Errorf("algorithm '%s' does not fit a %T key", alg, pub)
+		}
+		return method, nil
+	}
+	switch k := pub.(type) {
+	case *ecdsa.PublicKey:
+		switch k.Curve.Params().BitSize {
+		case 256:
+			return jwt.SigningMethodES256, nil
+		case 384:
+			return jwt.SigningMethodES384, nil
+		case 521:
+			return jwt.SigningMethodES512, nil
+		}
+	case *rsa.PublicKey:
+		return jwt.SigningMethodRS256, nil
+	case ed25519.PublicKey:
+		return jwt.SigningMethodEdDSA, nil
+	}
+	return nil, xerrors.Errorf("unsupported key %T", pub)
+}
+
+// jwkThumbprint returns the RFC 7638 thumbprint of a public key, base64url SHA-256 over its required JWK members.
+func jwkThumbprint(pub interface{}) (string, error) {
+	enc := base64.RawURLEncoding.EncodeToString
+	var members string
+	switch k := pub.(type) {
+	case *ecdsa.PublicKey:
+		size := (k.Curve.Params().BitSize + 7) / 8
+		members = fmt.Sprintf(`{"crv":%q,"kty":"EC","x":%q,"y":%q}`, k.Curve.Params().Name,
+			enc(k.X.FillBytes(make([]byte, size))), enc(k.Y.FillBytes(make([]byte, size))))
+	case *rsa.PublicKey:
+		members = fmt.Sprintf(`{"e":%q,"kty":"RSA","n":%q}`, enc(big.NewInt(int64(k.E)).Bytes()), enc(k.N.Bytes()))
+	case ed25519.PublicKey:
+		members = fmt.Sprintf(`{"crv":"Ed25519","kty":"OKP","x":%q}`, enc(k))
+	default:
+		return "", xerrors.Errorf("unsupported key %T", pub)
+	}
+	sum := sha256.Sum256([]byte(members))
+	return enc(sum[:]), nil
+}
@@ -0,0 +1,90 @@
+package servion
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestJwkThumbprint(t *testing.T) {
+	// RFC 7638 section 3.1
+	n, _ := base64.RawURLEncoding.DecodeString("0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw")
+	pub := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: 65537}
+	kid, err := jwkThumbprint(pub)
+	if err != nil || kid != "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs" {
+		t.Errorf("thumbprint = %q, %v", kid, err)
+	}
+}
+
+func TestJwtSigner_Keys(t *testing.T) {
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecDER, _ := x509.MarshalECPrivateKey(ecKey)
+	edDER, _ := x509.MarshalPKCS8PrivateKey(edKey)
+
+	keyFile := filepath.Join(t.TempDir(), "signing.key")
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)}), 0600)
+
+	cases := []struct {
+		name    string
+		key     string
+		file    string
+		alg     string
+		wantAlg string
+		pub     interface{}
+	}{
+		{"SEC 1 PEM", string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: ecDER})), "", "", "ES384", &ecKey.PublicKey},
+		{"PKCS#8 base64", base64.StdEncoding.EncodeToString(edDER), "", "", "EdDSA", edKey.Public()},
+		{"PKCS#1 file", "", keyFile, "PS256", "PS256", &rsaKey.PublicKey},
+	}
+	for _, c := range cases {
+		s, err := newJwtSigner("", c.key, c.file, c.alg, "")
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		tokenStr, err := s.sign(jwt.MapClaims{"sub": "svc", "exp": time.Now().Add(time.Minute).Unix()})
+		if err != nil {
+			t.Fatalf("%s: sign: %v", c.name, err)
+		}
+		token, err := jwt.Parse(tokenStr, func(*jwt.Token) (interface{}, error) { return c.pub, nil })
+		if err != nil {
+			t.Fatalf("%s: verify: %v", c.name, err)
+		}
+		kid, _ := jwkThumbprint(c.pub)
+		if token.Method.Alg() != c.wantAlg || token.Header["kid"] != kid {
+			t.Errorf("%s: alg %s kid %v, want %s %s", c.name, token.Method.Alg(), token.Header["kid"], c.wantAlg, kid)
+		}
+	}
+}
+
+func TestJwtSigner_Invalid(t *testing.T) {
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	der, _ := x509.MarshalPKCS8PrivateKey(ecKey)
+	key := base64.StdEncoding.EncodeToString(der)
+
+	for name, fn := range map[string]func() error{
+		"no source":      func() error { _, err := newJwtSigner("", "", "", "", ""); return err },
+		"two sources":    func() error { _, err := newJwtSigner("secret", key, "", "", ""); return err },
+		"RS256 for EC":   func() error { _, err := newJwtSigner("", key, "", "RS256", ""); return err },
+		"ES384 for P256": func() error { _, err := newJwtSigner("", key, "", "ES384", ""); return err },
+		"ES256 secret":   func() error { _, err := newJwtSigner("secret", "", "", "ES256", ""); return err },
+		"garbage":        func() error { _, err := newJwtSigner("", "not a key!", "", "", ""); return err },
+	} {
+		if fn() == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,312 @@
+/*
+ * Copyright (c) 2026 Karagatan LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package servion
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+	"golang.org/x/xerrors"
+)
+
+const (
+	LoginResponseToken   = "token"
+	LoginResponseSession = "session"
+)
+
+// maxLoginRequestSize caps the body of a login request.
+const maxLoginRequestSize = 16 << 10
+
+type implLoginHandler struct {
+	Log      *zap.Logger    `inject:"optional"`
+	Runtime  Runtime        `inject:"optional"`
+	Users    UserStore      `inject:""`
+	Sessions SessionManager `inject:"optional"`
+
+	HandlerPattern string `value:"user.login-pattern,default=/auth/password"`
+
+	// token answers with a JWT, session starts a session of the SessionManager
+	Response string `value:"user.login-response,default=token"`
+
+	// Signing key of the tokens, exactly one of them in token mode
+	Secret         string `value:"user.secret,default="`
+	PrivateKey     string `value:"user.private-key,default="`
+	PrivateKeyFile string `value:"user.private-key-file,default="`
+	Alg            string `value:"user.alg,default="`
+	Kid            string `value:"user.kid,default="`
+
+	Issuer   string        `value:"user.issuer,default="`
+	Audience string        `value:"user.audience,default="`
+	Lifetime time.Duration `value:"user.token-lifetime,default=1h"`
+
+	// Failed logins in a row that lock an account, 0 never locks
+	MaxFailed int           `value:"user.max-failed-logins,default=5"`
+	Lockout   time.Duration `value:"user.lockout,default=15m"`
+
+	// Algorithm of new password hashes, older hashes are replaced at login
+	PasswordHash string `value:"user.password-hash,default=argon2id"`
+
+	signer *jwtSigner
+	// dummyHash is verified for unknown users, so they take as long as known ones
+	dummyHash string
+	// mu orders the updates of lockout counters
+	mu sync.Mutex
+}
+
+type loginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	ReturnTo string `json:"return_to"`
+}
+
+/*
+LoginHandler creates an HttpHandler logging in the local users of the UserStore
+in its context. It takes a POST of a form or JSON with username and password,
+and in token mode answers with a JWT that UserAuthProvider accepts:
+
+	curl -d username=alice -d password=... https://tool.example.com/auth/password
+	{"access_token":"eyJ...","expires_in":3600,"token_type":"Bearer"}
+
+In session mode it starts a session of the SessionManager instead and redirects
+a form to its return_to, a local path, or answers 204. Wrong passwords, unknown,
+disabled and locked users all get the same 401 in about the same time. After
+user.max-failed-logins failures in a row an account is locked for user.lockout;
+a successful login resets the count. Password hashes of another algorithm or of
+weaker parameters than user.password-hash are replaced at the next login.
+Limit the login path with RateLimitMiddleware, e.g. ratelimit.rules=/auth/password=10/m,
+as each attempt costs a password hash.
+
+Configuration properties:
+
+	user.login-pattern       – URL pattern (default "/auth/password")
+	user.login-response      – token or session (default "token")
+	user.secret              – HMAC secret of the tokens
+	user.private-key         – signing key as PEM or base64 PKCS#8 DER
+	user.private-key-file    – signing key file, relative to the home directory
+	user.alg                 – signing algorithm (default by key)
+	user.kid                 – key ID (default the RFC 7638 thumbprint of the key)
+	user.issuer              – iss claim
+	user.audience            – aud claim
+	user.token-lifetime      – token lifetime (default 1h)
+	user.max-failed-logins   – failed logins that lock an account, 0 never (default 5)
+	user.lockout             – how long an account stays locked (default 15m)
+	user.password-hash       – argon2id or bcrypt (default "argon2id")
+*/
+func LoginHandler() HttpHandler {
+	return &implLoginHandler{}
+}
+
+func (t *implLoginHandler) PostConstruct() error {
+	if t.Log == nil {
+		t.Log = zap.NewNop()
+	}
+	switch t.Response {
+	case LoginResponseToken:
+		if t.Lifetime <= 0 {
+			return xerrors.Errorf("user: user.token-lifetime must be positive, got %s", t.Lifetime)
+		}
+		signer, err := newUserTokenSigner(t.Runtime, t.Secret, t.PrivateKey, t.PrivateKeyFile, t.Alg, t.Kid)
+		if err != nil {
+			return err
+		}
+		t.signer = signer
+	case LoginResponseSession:
+		if t.Sessions == nil {
+			return xerrors.New("user: user.login-response=session needs a SessionManager in the context")
+		}
+	default:
+		return xerrors.Errorf("user: unknown user.login-response '%s', expected token or session", t.Response)
+	}
+	dummy, err := hashPassword(randomToken(), t.PasswordHash)
+	if err != nil {
+		return xerrors.Errorf("user: user.password-hash: %w", err)
+	}
+	t.dummyHash = dummy
+	return nil
+}
+
+// newUserTokenSigner creates the signer of login tokens from the user.* key properties.
+func newUserTokenSigner(runtime Runtime, secret, privateKey, keyFile, alg, kid string) (*jwtSigner, error) {
+	if keyFile != "" && !filepath.IsAbs(keyFile) && runtime != nil {
+		keyFile = filepath.Join(runtime.HomeDir(), keyFile)
+	}
+	signer, err := newJwtSigner(secret, privateKey, keyFile, alg, kid)
+	if err != nil {
+		return nil, xerrors.Errorf("user: user.secret, user.private-key, user.private-key-file: %w", err)
+	}
+	return signer, nil
+}
+
+func (t *implLoginHandler) Pattern() string {
+	return t.HandlerPattern
+}
+
+func (t *implLoginHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	req, isJSON, err := readLoginRequest(w, r)
+	if err != nil || req.Username == "" || req.Password == "" {
+		http.Error(w, "username and password required", http.StatusBadRequest)
+		return
+	}
+
+	user, err := t.login(req.Username, req.Password, time.Now())
+	if err != nil {
+		if errors.Is(err, ErrUnauthorized) {
+			t.Log.Info("LoginFailed", zap.String("user", req.Username), zap.Error(err))
+			http.Error(w, "invalid user name or password", http.StatusUnauthorized)
+		} else {
+			t.Log.Error("Login", zap.String("user", req.Username), zap.Error(err))
+			http.Error(w, "service unavailable", http.StatusServiceUnavailable)
+		}
+		return
+	}
+	t.Log.Info("LoggedIn", zap.String("user", user.Username))
+	info := AuthInfo{
+		Subject:    user.Username,
+		Roles:      user.Roles,
+		Attributes: map[string]string{"auth": "password"},
+	}
+
+	if t.Response == LoginResponseSession {
+		if _, err := t.Sessions.Start(w, r, info); err != nil {
+			t.Log.Error("LoginSession", zap.String("user", user.Username), zap.Error(err))
+			http.Error(w, "service unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		if !isJSON && isLocalPath(req.ReturnTo) {
+			http.Redirect(w, r, req.ReturnTo, http.StatusSeeOther)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"sub": user.Username,
+		"iat": now.Unix(),
+		"exp": now.Add(t.Lifetime).Unix(),
+		"jti": randomToken(),
+		// RFC 8176, marks tokens of LoginHandler for UserAuthProvider
+		"amr": []string{"pwd"},
+	}
+	if len(user.Roles) > 0 {
+		claims["roles"] = user.Roles
+	}
+	if t.Issuer != "" {
+		claims["iss"] = t.Issuer
+	}
+	if t.Audience != "" {
+		claims["aud"] = t.Audience
+	}
+	token, err := t.signer.sign(claims)
+	if err != nil {
+		t.Log.Error("LoginSign", zap.String("user", user.Username), zap.Error(err))
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"access_token": token,
+		"token_type":   "Bearer",
+		"expires_in":   int64(t.Lifetime.Seconds()),
+	})
+}
+
+/*
+login checks the password of username and keeps the lockout counters. It returns
+an error wrapping ErrUnauthorized for every kind of refusal, and another error
+when the store fails.
+*/
+func (t *implLoginHandler) login(username, password string, now time.Time) (*UserAccount, error) {
+	user, err := t.Users.Load(username)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		verifyPassword(t.dummyHash, password, t.PasswordHash)
+		return nil, xerrors.Errorf("unknown user: %w", ErrUnauthorized)
+	}
+	ok, rehash, err := verifyPassword(user.PasswordHash, password, t.PasswordHash)
+	if err != nil {
+		t.Log.Error("LoginPasswordHash", zap.String("user", username), zap.Error(err))
+		ok = false
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	// read again, the account may have changed while the password was hashed
+	if user, err = t.Users.Load(username); err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, xerrors.Errorf("unknown user: %w", ErrUnauthorized)
+	}
+	switch {
+	case user.Disabled:
+		return nil, xerrors.Errorf("user is disabled: %w", ErrUnauthorized)
+	case now.Before(user.LockedUntil):
+		return nil, xerrors.Errorf("user is locked until %s: %w", user.LockedUntil.UTC().Format(time.RFC3339), ErrUnauthorized)
+	case !ok:
+		user.FailedLogins++
+		if t.MaxFailed > 0 && user.FailedLogins >= t.MaxFailed {
+			user.FailedLogins = 0
+			user.LockedUntil = now.Add(t.Lockout)
+			t.Log.Warn("UserLocked", zap.String("user", username), zap.Time("until", user.LockedUntil))
+		}
+		if err := t.Users.Store(user); err != nil {
+			return nil, err
+		}
+		return nil, xerrors.Errorf("wrong password: %w", ErrUnauthorized)
+	}
+
+	changed := user.FailedLogins != 0 || !user.LockedUntil.IsZero()
+	user.FailedLogins, user.LockedUntil = 0, time.Time{}
+	if rehash {
+		if hash, err := hashPassword(password, t.PasswordHash); err == nil {
+			// same password, so tokens issued before stay valid
+			user.PasswordHash, changed = hash, true
+		}
+	}
+	if changed {
+		if err := t.Users.Store(user); err != nil {
+			return nil, err
+		}
+	}
+	return user, nil
+}
+
+// readLoginRequest reads a JSON or form login request; isJSON tells which it was.
+func readLoginRequest(w http.ResponseWriter, r *http.Request) (req loginRequest, isJSON bool, err error) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxLoginRequestSize)
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "application/json" {
+		err = json.NewDecoder(io.LimitReader(r.Body, maxLoginRequestSize)).Decode(&req)
+		req.Username = strings.TrimSpace(req.Username)
+		return req, true, err
+	}
+	if err = r.ParseForm(); err != nil {
+		return req, false, err
+	}
+	req.Username = strings.TrimSpace(r.PostForm.Get("username"))
+	req.Password = r.PostForm.Get("password")
+	req.ReturnTo = r.PostForm.Get("return_to")
+	return req, false, nil
+}
@@ -0,0 +1,212 @@
+package servion
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func newTestUserStore(t *testing.T, users ...*UserAccount) *implFileUserStore {
+	t.Helper()
+	s := &implFileUserStore{File: filepath.Join(t.TempDir(), "users.json")}
+	if err := s.PostConstruct(); err != nil {
+		t.Fatal(err)
+	}
+	for _, u := range users {
+		if err := s.Store(u); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return s
+}
+
+func newTestUser(t *testing.T, name, password string, roles ...string) *UserAccount {
+	t.Helper()
+	hash, err := hashPassword(password, PasswordHashArgon2id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &UserAccount{Username: name, PasswordHash: hash, Roles: roles, PasswordChanged: time.Now().Add(-time.Hour)}
+}
+
+func newTestLoginHandler(t *testing.T, users UserStore, configure func(h *implLoginHandler)) *implLoginHandler {
+	t.Helper()
+	h := &implLoginHandler{
+		Log:            zap.NewNop(),
+		Users:          users,
+		HandlerPattern: "/auth/password",
+		Response:       LoginResponseToken,
+		Secret:         "login-secret",
+		Lifetime:       time.Hour,
+		MaxFailed:      3,
+		Lockout:        time.Minute,
+		PasswordHash:   PasswordHashArgon2id,
+	}
+	if configure != nil {
+		configure(h)
+	}
+	if err := h.PostConstruct(); err != nil {
+		t.Fatalf("PostConstruct: %v", err)
+	}
+	return h
+}
+
+func postLogin(h http.Handler, username, password string) *httptest.ResponseRecorder {
+	form := url.Values{"username": {username}, "password": {password}}
+	r := httptest.NewRequest(http.MethodPost, "/auth/password", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+	return rec
+}
+
+func TestLoginHandler_Token(t *testing.T) {
+	users := newTestUserStore(t, newTestUser(t, "alice", "correct horse", "admin"))
+	h := newTestLoginHandler(t, users, nil)
+
+	rec := postLogin(h, "alice", "correct horse")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d %s", rec.Code, rec.Body)
+	}
+	var resp map[string]interface{}
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	if resp["token_type"] != "Bearer" || resp["expires_in"].(float64) != 3600 {
+		t.Errorf("unexpected response %v", resp)
+	}
+
+	// a JwtAuthProvider sharing the secret accepts the token as well
+	p := &implJwtAuthProvider{Secret: "login-secret", RolesClaim: "roles", ScopesClaim: "scope"}
+	if err := p.PostConstruct(); err != nil {
+		t.Fatal(err)
+	}
+	info, err := p.Authenticate(resp["access_token"].(string))
+	if err != nil || info.Subject != "alice" || strings.Join(info.Roles, ",") != "admin" {
+		t.Errorf("Authenticate = %+v, %v", info, err)
+	}
+
+	// JSON body
+	r := httptest.NewRequest(http.MethodPost, "/auth/password", strings.NewReader(`{"username":"alice","password":"correct horse"}`))
+	r.Header.Set("Content-Type", "application/json; charset=utf-8")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+	if rec.Code != http.StatusOK {
+		t.Errorf("JSON login: expected 200, got %d", rec.Code)
+	}
+
+	if rec := postLogin(h, "alice", ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("no password: expected 400, got %d", rec.Code)
+	}
+}
+
+func TestLoginHandler_Refusals(t *testing.T) {
+	disabled := newTestUser(t, "bob", "correct horse")
+	disabled.Disabled = true
+	users := newTestUserStore(t, newTestUser(t, "alice", "correct horse"), disabled)
+	h := newTestLoginHandler(t, users, nil)
+
+	for _, c := range []struct{ user, password string }{
+		{"alice", "wrong"},
+		{"nobody", "correct horse"},
+		{"bob", "correct horse"},
+	} {
+		rec := postLogin(h, c.user, c.password)
+		if rec.Code != http.StatusUnauthorized || !strings.Contains(rec.Body.String(), "invalid user name or password") {
+			t.Errorf("%s: expected the generic 401, got %d %s", c.user, rec.Code, rec.Body)
+		}
+	}
+	if u, _ := users.Load("alice"); u.FailedLogins != 1 {
+		t.Errorf("FailedLogins = %d, want 1", u.FailedLogins)
+	}
+}
+
+func TestLoginHandler_Lockout(t *testing.T) {
+	users := newTestUserStore(t, newTestUser(t, "alice", "correct horse"))
+	h := newTestLoginHandler(t, users, nil)
+	now := time.Now()
+
+	for i := 0; i < 3; i++ {
+		if _, err := h.login("alice", "wrong", now); !errors.Is(err, ErrUnauthorized) {
+			t.Fatalf("attempt %d: %v", i, err)
+		}
+	}
+	u, _ := users.Load("alice")
+	if !u.LockedUntil.Equal(now.Add(time.Minute)) || u.FailedLogins != 0 {
+		t.Fatalf("expected a lockout, got %+v", u)
+	}
+	if _, err := h.login("alice", "correct horse", now.Add(30*time.Second)); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("locked user logged in: %v", err)
+	}
+
+	if _, err := h.login("alice", "wrong", now.Add(2*time.Minute)); err == nil {
+		t.Fatal("wrong password accepted")
+	}
+	if _, err := h.login("alice", "correct horse", now.Add(2*time.Minute)); err != nil {
+		t.Fatalf("login after the lockout: %v", err)
+	}
+	if u, _ := users.Load("alice"); u.FailedLogins != 0 || !u.LockedUntil.IsZero() {
+		t.Errorf("counters not reset: %+v", u)
+	}
+}
+
+func TestLoginHandler_Rehash(t *testing.T) {
+	user := newTestUser(t, "alice", "correct horse")
+	users := newTestUserStore(t, user)
+	h := newTestLoginHandler(t, users, func(h *implLoginHandler) { h.PasswordHash = PasswordHashBcrypt })
+
+	if _, err := h.login("alice", "correct horse", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	u, _ := users.Load("alice")
+	if !strings.HasPrefix(u.PasswordHash, "$2a$") || !u.PasswordChanged.Equal(user.PasswordChanged) {
+		t.Errorf("expected a bcrypt rehash keeping PasswordChanged, got %+v", u)
+	}
+}
+
+func TestLoginHandler_Session(t *testing.T) {
+	users := newTestUserStore(t, newTestUser(t, "alice", "correct horse", "admin"))
+	sessions := newTestSessionManager(t, time.Hour, 2*time.Hour)
+	h := newTestLoginHandler(t, users, func(h *implLoginHandler) {
+		h.Response = LoginResponseSession
+		h.Secret = ""
+		h.Sessions = sessions
+	})
+
+	form := url.Values{"username": {"alice"}, "password": {"correct horse"}, "return_to": {"/dashboard"}}
+	r := httptest.NewRequest(http.MethodPost, "/auth/password", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+	if rec.Code != http.StatusSeeOther || rec.Header().Get("Location") != "/dashboard" {
+		t.Fatalf("expected a redirect to /dashboard, got %d %s", rec.Code, rec.Header().Get("Location"))
+	}
+	cookies := rec.Result().Cookies()
+	if len(cookies) == 0 {
+		t.Fatal("no session cookie")
+	}
+	info, ok, err := sessions.AuthenticateSession(requestWithCookie(cookies[0]))
+	if !ok || err != nil || info.Subject != "alice" || info.Attributes["auth"] != "password" {
+		t.Errorf("session = %+v, %v, %v", info, ok, err)
+	}
+}
+
+func TestLoginHandler_PostConstruct(t *testing.T) {
+	users := newTestUserStore(t)
+	for name, h := range map[string]*implLoginHandler{
+		"no key":                  {Users: users, Response: LoginResponseToken, Lifetime: time.Hour},
+		"session without manager": {Users: users, Response: LoginResponseSession},
+		"unknown response":        {Users: users, Response: "cookie", Secret: "x", Lifetime: time.Hour},
+		"unknown hash":            {Users: users, Response: LoginResponseToken, Secret: "x", Lifetime: time.Hour, PasswordHash: "md5"},
+	} {
+		if err := h.PostConstruct(); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,493 @@
+/*
+ * Copyright (c) 2026 Karagatan LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package servion
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/xerrors"
+)
+
+// oidcFlowTTL bounds the time between /login and /callback.
+const oidcFlowTTL = 10 * time.Minute
+
+type implOidcHandler struct {
+	Log *zap.Logger `inject:""`
+
+	// Server-side sessions, replacing the session cookie when present
+	Sessions SessionManager `inject:"optional"`
+
+	// Issuer URL, the discovery document is {issuer}/.well-known/openid-configuration
+	Issuer       string `value:"oidc.issuer,default="`
+	ClientID     string `value:"oidc.client-id,default="`
+	ClientSecret string `value:"oidc.client-secret,default="`
+
+	// Absolute URL of the callback route registered at the IdP
+	RedirectURL string `value:"oidc.redirect-url,default="`
+
+	Scopes []string `value:"oidc.scopes,default=openid;profile;email"`
+
+	// Base path of the login, callback and logout routes
+	HandlerPattern string `value:"oidc.pattern,default=/auth/"`
+
+	PostLoginPath  string `value:"oidc.post-login-path,default=/"`
+	PostLogoutURL  string `value:"oidc.post-logout-url,default=/"`
+	CookieName     string `value:"oidc.cookie-name,default=servion_session"`
+	CookieSecret   string `value:"oidc.cookie-secret,default="`
+	CookieInsecure bool   `value:"oidc.cookie-insecure,default=false"`
+
+	SessionTTL  time.Duration `value:"oidc.session-ttl,default=8h"`
+	Timeout     time.Duration `value:"oidc.timeout,default=10s"`
+	RolesClaim  string        `value:"oidc.roles-claim,default=roles"`
+	ScopesClaim string        `value:"oidc.scopes-claim,default=scope"`
+
+	codec  *cookieCodec
+	client *http.Client
+
+	discoveryMu sync.Mutex
+	discovery   *oidcDiscovery
+	verifier    *implJwtAuthProvider
+}
+
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JwksURI               string `json:"jwks_uri"`
+	EndSessionEndpoint    string `json:"end_session_endpoint"`
+}
+
+// oidcFlow is the state of one login, sealed in a short lived cookie.
+type oidcFlow struct {
+	State    string `json:"s"`
+	Nonce    string `json:"n"`
+	Verifier string `json:"v"`
+	ReturnTo string `json:"r,omitempty"`
+}
+
+// oidcSession is the identity sealed in the session cookie.
+type oidcSession struct {
+	Subject    string            `json:"sub"`
+	Issuer     string            `json:"iss,omitempty"`
+	Roles      []string          `json:"roles,omitempty"`
+	Scopes     []string          `json:"scopes,omitempty"`
+	Attributes map[string]string `json:"attrs,omitempty"`
+}
+
+/*
+OidcHandler creates an HttpHandler that signs browser users in with an OpenID
+Connect provider, using the authorization code flow with PKCE. It serves three
+routes under oidc.pattern:
+
+	/auth/login     – redirects to the provider; ?return_to=/path picks the page to land on
+	/auth/callback  – exchanges the code, verifies the ID token and starts the session
+	/auth/logout    – ends the session, and at the provider when it supports RP logout
+
+The identity is kept in a cookie encrypted and signed with oidc.cookie-secret, so
+no server state is needed. The handler is also a SessionAuthenticator:
+AuthMiddleware accepts the session cookie for requests without a bearer token,
+and AuthFromContext returns the same AuthInfo as for a JWT. With a SessionManager
+in the context the login starts a server-side session instead, which can be
+ended before it expires.
+
+Configuration properties:
+
+	oidc.issuer           – issuer URL of the provider
+	oidc.client-id        – client registered at the provider
+	oidc.client-secret    – client secret, empty for public clients
+	oidc.redirect-url     – absolute URL of the callback route
+	oidc.scopes           – requested scopes (default "openid;profile;email")
+	oidc.pattern          – base path of the routes (default "/auth/")
+	oidc.post-login-path  – page after login without return_to (default "/")
+	oidc.post-logout-url  – page after logout (default "/")
+	oidc.cookie-name      – session cookie name (default "servion_session")
+	oidc.cookie-secret    – secret of at least 32 characters sealing the cookies
+	oidc.cookie-insecure  – drop the Secure flag, for plain http development (default false)
+	oidc.session-ttl      – session lifetime (default 8h)
+	oidc.timeout          – timeout of calls to the provider (default 10s)
+	oidc.roles-claim      – ID token claim with roles (default "roles")
+	oidc.scopes-claim     – ID token claim with scopes (default "scope")
+*/
+func OidcHandler() HttpHandler {
+	return &implOidcHandler{}
+}
+
+func (t *implOidcHandler) PostConstruct() (err error) {
+	if t.Issuer == "" || t.ClientID == "" || t.RedirectURL == "" {
+		return xerrors.New("oidc: oidc.issuer, oidc.client-id and oidc.redirect-url must be configured")
+	}
+	if u, err := url.Parse(t.RedirectURL); err != nil || !u.IsAbs() {
+		return xerrors.Errorf("oidc: oidc.redirect-url must be an absolute URL, got '%s'", t.RedirectURL)
+	}
+	if !strings.HasPrefix(t.HandlerPattern, "/") || !strings.HasSuffix(t.HandlerPattern, "/") {
+		return xerrors.Errorf("oidc: oidc.pattern must start and end with /, got '%s'", t.HandlerPattern)
+	}
+	if t.codec, err = newCookieCodec(t.CookieSecret); err != nil {
+		return xerrors.Errorf("oidc: oidc.cookie-secret: %w", err)
+	}
+	t.client = &http.Client{Timeout: t.Timeout}
+	return nil
+}
+
+func (t *implOidcHandler) Destroy() error {
+	t.discoveryMu.Lock()
+	defer t.discoveryMu.Unlock()
+	if t.verifier != nil {
+		return t.verifier.Destroy()
+	}
+	return nil
+}
+
+func (t *implOidcHandler) Pattern() string {
+	return t.HandlerPattern
+}
+
+func (t *implOidcHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch strings.TrimPrefix(r.URL.Path, t.HandlerPattern) {
+	case "login":
+		t.login(w, r)
+	case "callback":
+		t.callback(w, r)
+	case "logout":
+		t.logout(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (t *implOidcHandler) login(w http.ResponseWriter, r *http.Request) {
+	provider, err := t.provider(r.Context())
+	if err != nil {
+		t.Log.Error("OidcDiscoveryFailed", zap.String("issuer", t.Issuer), zap.Error(err))
+		http.Error(w, "identity provider unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	flow := oidcFlow{
+		State:    randomToken(),
+		Nonce:    randomToken(),
+		Verifier: randomToken(),
+	}
+	if returnTo := r.URL.Query().Get("return_to"); isLocalPath(returnTo) {
+		flow.ReturnTo = returnTo
+	}
+	if err := t.setCookie(w, t.flowCookie(), flow, oidcFlowTTL); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	challenge := sha256.Sum256([]byte(flow.Verifier))
+	q := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {t.ClientID},
+		"redirect_uri":          {t.RedirectURL},
+		"scope":                 {strings.Join(t.Scopes, " ")},
+		"state":                 {flow.State},
+		"nonce":                 {flow.Nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+	http.Redirect(w, r, appendQuery(provider.AuthorizationEndpoint, q), http.StatusFound)
+}
+
+func (t *implOidcHandler) callback(w http.ResponseWriter, r *http.Request) {
+	var flow oidcFlow
+	if err := t.readCookie(r, t.flowCookie(), &flow); err != nil {
+		t.loginFailed(w, "login session expired", err)
+		return
+	}
+	t.clearCookie(w, t.flowCookie())
+
+	q := r.URL.Query()
+	if subtle.ConstantTimeCompare([]byte(q.Get("state")), []byte(flow.State)) != 1 {
+		t.loginFailed(w, "state mismatch", nil)
+		return
+	}
+	if e := q.Get("error"); e != "" {
+		t.loginFailed(w, "provider error", xerrors.Errorf("%s: %s", e, q.Get("error_description")))
+		return
+	}
+	code := q.Get("code")
+	if code == "" {
+		t.loginFailed(w, "missing code", nil)
+		return
+	}
+
+	provider, err := t.provider(r.Context())
+	if err != nil {
+		t.Log.Error("OidcDiscoveryFailed", zap.String("issuer", t.Issuer), zap.Error(err))
+		http.Error(w, "identity provider unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	idToken, scope, err := t.exchange(r.Context(), provider, code, flow.Verifier)
+	if err != nil {
+		t.loginFailed(w, "code exchange failed", err)
+		return
+	}
+
+	claims, err := t.verifier.verify(idToken)
+	if err != nil {
+		t.loginFailed(w, "invalid id token", err)
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(claimString(claims, "nonce")), []byte(flow.Nonce)) != 1 {
+		t.loginFailed(w, "nonce mismatch", nil)
+		return
+	}
+
+	info := t.verifier.authInfo(idToken, claims)
+	if len(info.Scopes) == 0 && scope != "" {
+		info.Scopes = strings.Fields(scope)
+	}
+	if err := t.startSession(w, r, info); err != nil {
+		t.Log.Error("OidcSessionFailed", zap.String("subject", info.Subject), zap.Error(err))
+		http.Error(w, "session could not be created", http.StatusInternalServerError)
+		return
+	}
+	t.Log.Info("OidcLogin", zap.String("subject", info.Subject), zap.String("issuer", info.Issuer))
+
+	returnTo := flow.ReturnTo
+	if returnTo == "" {
+		returnTo = t.PostLoginPath
+	}
+	http.Redirect(w, r, returnTo, http.StatusFound)
+}
+
+func (t *implOidcHandler) startSession(w http.ResponseWriter, r *http.Request, info AuthInfo) error {
+	if t.Sessions != nil {
+		_, err := t.Sessions.Start(w, r, info)
+		return err
+	}
+	session := oidcSession{
+		Subject:    info.Subject,
+		Issuer:     info.Issuer,
+		Roles:      info.Roles,
+		Scopes:     info.Scopes,
+		Attributes: info.Attributes,
+	}
+	return t.setCookie(w, t.CookieName, session, t.SessionTTL)
+}
+
+func (t *implOidcHandler) logout(w http.ResponseWriter, r *http.Request) {
+	if t.Sessions != nil {
+		if err := t.Sessions.End(w, r); err != nil {
+			t.Log.Warn("SessionEndFailed", zap.Error(err))
+		}
+	} else {
+		t.clearCookie(w, t.CookieName)
+	}
+
+	target := t.PostLogoutURL
+	if provider, err := t.provider(r.Context()); err == nil && provider.EndSessionEndpoint != "" {
+		q := url.Values{"client_id": {t.ClientID}}
+		if u, err := url.Parse(t.PostLogoutURL); err == nil && u.IsAbs() {
+			q.Set("post_logout_redirect_uri", t.PostLogoutURL)
+		}
+		target = appendQuery(provider.EndSessionEndpoint, q)
+	}
+	http.Redirect(w, r, target, http.StatusFound)
+}
+
+func (t *implOidcHandler) loginFailed(w http.ResponseWriter, reason string, err error) {
+	fields := []zap.Field{zap.String("reason", reason)}
+	if err != nil {
+		fields = append(fields, zap.Error(err))
+	}
+	t.Log.Warn("OidcLoginFailed", fields...)
+	http.Error(w, "login failed: "+reason, http.StatusUnauthorized)
+}
+
+func (t *implOidcHandler) AuthenticateSession(r *http.Request) (AuthInfo, bool, error) {
+	if t.Sessions != nil {
+		// the SessionManager authenticates its own sessions
+		return AuthInfo{}, false, nil
+	}
+	var session oidcSession
+	if err := t.readCookie(r, t.CookieName, &session); err != nil || session.Subject == "" {
+		return AuthInfo{}, false, nil
+	}
+	info := AuthInfo{
+		Subject:    session.Subject,
+		Issuer:     session.Issuer,
+		Roles:      session.Roles,
+		Scopes:     session.Scopes,
+		Attributes: session.Attributes,
+	}
+	if info.Attributes == nil {
+		info.Attributes = make(map[string]string)
+	}
+	return info, true, nil
+}
+
+// provider returns the discovery document, fetched on first use so the
+// service starts while the provider is down.
+func (t *implOidcHandler) provider(ctx context.Context) (*oidcDiscovery, error) {
+	t.discoveryMu.Lock()
+	defer t.discoveryMu.Unlock()
+	if t.discovery != nil {
+		return t.discovery, nil
+	}
+
+	var doc oidcDiscovery
+	if err := t.getJSON(ctx, strings.TrimSuffix(t.Issuer, "/")+"/.well-known/openid-configuration", &doc); err != nil {
+		return nil, err
+	}
+	if doc.Issuer != t.Issuer {
+		return nil, xerrors.Errorf("discovery issuer '%s' does not match oidc.issuer", doc.Issuer)
+	}
+	if doc.AuthorizationEndpoint == "" || doc.TokenEndpoint == "" || doc.JwksURI == "" {
+		return nil, xerrors.New("discovery document lacks authorization, token or jwks endpoint")
+	}
+
+	verifier := &implJwtAuthProvider{
+		Log:            t.Log,
+		JwksURL:        doc.JwksURI,
+		JwksRefresh:    time.Hour,
+		JwksMinRefresh: 30 * time.Second,
+		JwksTimeout:    t.Timeout,
+		Issuer:         t.Issuer,
+		Audience:       t.ClientID,
+		RolesClaim:     t.RolesClaim,
+		ScopesClaim:    t.ScopesClaim,
+	}
+	if err := verifier.PostConstruct(); err != nil {
+		return nil, err
+	}
+	t.discovery, t.verifier = &doc, verifier
+	return t.discovery, nil
+}
+
+func (t *implOidcHandler) exchange(ctx context.Context, provider *oidcDiscovery, code, verifier string) (idToken, scope string, err error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {t.RedirectURL},
+		"code_verifier": {verifier},
+		"client_id":     {t.ClientID},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, provider.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if t.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(t.ClientID), url.QueryEscape(t.ClientSecret))
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", "", xerrors.Errorf("token endpoint status %d", resp.StatusCode)
+	}
+	var body struct {
+		IDToken string `json:"id_token"`
+		Scope   string `json:"scope"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxJWKSSize)).Decode(&body); err != nil {
+		return "", "", xerrors.Errorf("token response: %w", err)
+	}
+	if body.IDToken == "" {
+		return "", "", xerrors.New("token response lacks id_token")
+	}
+	return body.IDToken, body.Scope, nil
+}
+
+func (t *implOidcHandler) getJSON(ctx context.Context, u string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return xerrors.Errorf("GET %s: status %d", u, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, maxJWKSSize)).Decode(v)
+}
+
+func (t *implOidcHandler) flowCookie() string {
+	return t.CookieName + "_flow"
+}
+
+func (t *implOidcHandler) setCookie(w http.ResponseWriter, name string, v interface{}, ttl time.Duration) error {
+	expires := time.Now().Add(ttl)
+	value, err := t.codec.seal(name, v, expires)
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		Expires:  expires,
+		MaxAge:   int(ttl / time.Second),
+		Secure:   !t.CookieInsecure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return nil
+}
+
+func (t *implOidcHandler) readCookie(r *http.Request, name string, v interface{}) error {
+	c, err := r.Cookie(name)
+	if err != nil {
+		return err
+	}
+	return t.codec.open(name, c.Value, v)
+}
+
+func (t *implOidcHandler) clearCookie(w http.ResponseWriter, name string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		Secure:   !t.CookieInsecure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// randomToken returns 32 random bytes, base64url encoded; also a valid PKCE verifier.
+func randomToken() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// isLocalPath accepts paths of this site only, so return_to can not redirect elsewhere.
+func isLocalPath(p string) bool {
+	return strings.HasPrefix(p, "/") && !strings.HasPrefix(p, "//") && !strings.ContainsAny(p, "\\\r\n")
+}
+
+func appendQuery(endpoint string, q url.Values) string {
+	if strings.Contains(endpoint, "?") {
+		return endpoint + "&" + q.Encode()
+	}
+	return endpoint + "?" + q.Encode()
+}
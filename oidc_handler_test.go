@@ -0,0 +1,312 @@
+package servion
+
+import (
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+)
+
+// mockIdP is a minimal OpenID provider: discovery, JWKS and a token endpoint
+// that checks the PKCE verifier against the challenge of the login redirect.
+type mockIdP struct {
+	t      *testing.T
+	srv    *httptest.Server
+	key    *rsa.PrivateKey
+	client string
+
+	mu        sync.Mutex
+	challenge string
+	nonce     string
+	subject   string
+}
+
+func newMockIdP(t *testing.T) *mockIdP {
+	idp := &mockIdP{t: t, key: generateRSAKey(t), client: "web-app", subject: "alice"}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 idp.srv.URL,
+			"authorization_endpoint": idp.srv.URL + "/authorize",
+			"token_endpoint":         idp.srv.URL + "/token",
+			"jwks_uri":               idp.srv.URL + "/jwks",
+			"end_session_endpoint":   idp.srv.URL + "/logout",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(marshalJWKS(t, toJWK(t, "idp-1", &idp.key.PublicKey)))
+	})
+	mux.HandleFunc("/token", idp.token)
+	idp.srv = httptest.NewServer(mux)
+	t.Cleanup(idp.srv.Close)
+	return idp
+}
+
+// authorize plays the user consenting at the provider.
+func (idp *mockIdP) authorize(location string) string {
+	u, err := url.Parse(location)
+	if err != nil {
+		idp.t.Fatal(err)
+	}
+	q := u.Query()
+	idp.mu.Lock()
+	idp.challenge = q.Get("code_challenge")
+	idp.nonce = q.Get("nonce")
+	idp.mu.Unlock()
+	return q.Get("state")
+}
+
+func (idp *mockIdP) token(w http.ResponseWriter, r *http.Request) {
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	r.ParseForm()
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if r.Method != http.MethodPost || r.PostForm.Get("grant_type") != "authorization_code" ||
+		r.PostForm.Get("code") != "the-code" ||
+		base64.RawURLEncoding.EncodeToString(sum[:]) != idp.challenge {
+		http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+		return
+	}
+	if user, pass, ok := r.BasicAuth(); !ok || user != idp.client || pass != "client-secret" {
+		http.Error(w, `{"error":"invalid_client"}`, http.StatusUnauthorized)
+		return
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":   idp.srv.URL,
+		"aud":   idp.client,
+		"sub":   idp.subject,
+		"nonce": idp.nonce,
+		"email": "alice@example.com",
+		"roles": []string{"admin"},
+		"exp":   jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	})
+	token.Header["kid"] = "idp-1"
+	idToken, _ := token.SignedString(idp.key)
+	json.NewEncoder(w).Encode(map[string]string{
+		"access_token": "opaque",
+		"token_type":   "Bearer",
+		"id_token":     idToken,
+		"scope":        "openid profile email",
+	})
+}
+
+func newTestOidcHandler(t *testing.T, issuer string) *implOidcHandler {
+	t.Helper()
+	h := &implOidcHandler{
+		Log:            zap.NewNop(),
+		Issuer:         issuer,
+		ClientID:       "web-app",
+		ClientSecret:   "client-secret",
+		RedirectURL:    "https://app.example.com/auth/callback",
+		Scopes:         []string{"openid", "profile", "email"},
+		HandlerPattern: "/auth/",
+		PostLoginPath:  "/",
+		PostLogoutURL:  "https://app.example.com/",
+		CookieName:     "servion_session",
+		CookieSecret:   strings.Repeat("k", 32),
+		SessionTTL:     time.Hour,
+		Timeout:        5 * time.Second,
+		RolesClaim:     "roles",
+		ScopesClaim:    "scope",
+	}
+	if err := h.PostConstruct(); err != nil {
+		t.Fatalf("PostConstruct: %v", err)
+	}
+	t.Cleanup(func() { h.Destroy() })
+	return h
+}
+
+func serveOidc(h http.Handler, target string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, target, nil)
+	for _, c := range cookies {
+		r.AddCookie(c)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func responseCookie(w *httptest.ResponseRecorder, name string) *http.Cookie {
+	for _, c := range w.Result().Cookies() {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+func TestOidcHandler_LoginFlow(t *testing.T) {
+	idp := newMockIdP(t)
+	h := newTestOidcHandler(t, idp.srv.URL)
+
+	w := serveOidc(h, "/auth/login?return_to=/app/reports")
+	if w.Code != http.StatusFound {
+		t.Fatalf("login: status = %d, body %s", w.Code, w.Body)
+	}
+	location := w.Header().Get("Location")
+	u, _ := url.Parse(location)
+	q := u.Query()
+	if !strings.HasPrefix(location, idp.srv.URL+"/authorize?") || q.Get("code_challenge_method") != "S256" ||
+		q.Get("client_id") != "web-app" || q.Get("redirect_uri") != h.RedirectURL || q.Get("scope") != "openid profile email" {
+		t.Fatalf("unexpected authorize redirect %s", location)
+	}
+	flow := responseCookie(w, "servion_session_flow")
+	if flow == nil || !flow.HttpOnly || !flow.Secure || flow.SameSite != http.SameSiteLaxMode {
+		t.Fatalf("flow cookie = %+v", flow)
+	}
+
+	state := idp.authorize(location)
+	w = serveOidc(h, "/auth/callback?code=the-code&state="+url.QueryEscape(state), flow)
+	if w.Code != http.StatusFound || w.Header().Get("Location") != "/app/reports" {
+		t.Fatalf("callback: status = %d, location %q, body %s", w.Code, w.Header().Get("Location"), w.Body)
+	}
+	session := responseCookie(w, "servion_session")
+	if session == nil || session.Value == "" {
+		t.Fatal("session cookie missing")
+	}
+
+	// later requests authenticate with the cookie through AuthMiddleware
+	m := &implAuthMiddleware{Prefixes: []string{"/api"}, Sessions: []SessionAuthenticator{h}}
+	var got AuthInfo
+	api := m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = AuthFromContext(r.Context())
+	}))
+	if w := serveOidc(api, "/api/me", session); w.Code != http.StatusOK {
+		t.Fatalf("api: status = %d", w.Code)
+	}
+	if got.Subject != "alice" || got.Issuer != idp.srv.URL || len(got.Roles) != 1 || got.Roles[0] != "admin" ||
+		got.Attributes["email"] != "alice@example.com" || len(got.Scopes) != 3 {
+		t.Fatalf("AuthInfo = %+v", got)
+	}
+	if w := serveOidc(api, "/api/me"); w.Code != http.StatusUnauthorized {
+		t.Errorf("without cookie: status = %d, want 401", w.Code)
+	}
+
+	// logout clears the session and ends it at the provider
+	w = serveOidc(h, "/auth/logout", session)
+	if w.Code != http.StatusFound || !strings.HasPrefix(w.Header().Get("Location"), idp.srv.URL+"/logout?") {
+		t.Fatalf("logout: status = %d, location %q", w.Code, w.Header().Get("Location"))
+	}
+	if c := responseCookie(w, "servion_session"); c == nil || c.MaxAge >= 0 {
+		t.Errorf("session cookie not cleared: %+v", c)
+	}
+}
+
+func TestOidcHandler_CallbackRejects(t *testing.T) {
+	idp := newMockIdP(t)
+	h := newTestOidcHandler(t, idp.srv.URL)
+
+	login := func(target string) (*http.Cookie, string, string) {
+		w := serveOidc(h, target)
+		location := w.Header().Get("Location")
+		return responseCookie(w, "servion_session_flow"), idp.authorize(location), location
+	}
+
+	flow, state, _ := login("/auth/login")
+	if w := serveOidc(h, "/auth/callback?code=the-code&state=forged", flow); w.Code != http.StatusUnauthorized {
+		t.Errorf("forged state: status = %d", w.Code)
+	}
+	if w := serveOidc(h, "/auth/callback?code=the-code&state="+url.QueryEscape(state)); w.Code != http.StatusUnauthorized {
+		t.Errorf("missing flow cookie: status = %d", w.Code)
+	}
+	if w := serveOidc(h, "/auth/callback?code=wrong&state="+url.QueryEscape(state), flow); w.Code != http.StatusUnauthorized {
+		t.Errorf("bad code: status = %d", w.Code)
+	}
+	if w := serveOidc(h, "/auth/callback?error=access_denied&state="+url.QueryEscape(state), flow); w.Code != http.StatusUnauthorized {
+		t.Errorf("provider error: status = %d", w.Code)
+	}
+
+	// an ID token issued for another login is refused
+	flow, state, _ = login("/auth/login")
+	idp.mu.Lock()
+	idp.nonce = "other"
+	idp.mu.Unlock()
+	if w := serveOidc(h, "/auth/callback?code=the-code&state="+url.QueryEscape(state), flow); w.Code != http.StatusUnauthorized {
+		t.Errorf("nonce mismatch: status = %d", w.Code)
+	}
+
+	// return_to never leaves the site
+	flow, state, _ = login("/auth/login?return_to=//evil.example.com/")
+	w := serveOidc(h, "/auth/callback?code=the-code&state="+url.QueryEscape(state), flow)
+	if w.Code != http.StatusFound || w.Header().Get("Location") != "/" {
+		t.Errorf("open redirect: status = %d, location %q", w.Code, w.Header().Get("Location"))
+	}
+
+	// a tampered session cookie is no session
+	session := responseCookie(w, "servion_session")
+	session.Value = session.Value[:len(session.Value)-2] + "AA"
+	r := httptest.NewRequest(http.MethodGet, "/api/me", nil)
+	r.AddCookie(session)
+	if _, ok, _ := h.AuthenticateSession(r); ok {
+		t.Error("tampered cookie accepted")
+	}
+}
+
+func TestOidcHandler_ProviderUnavailable(t *testing.T) {
+	idp := newMockIdP(t)
+	issuer := idp.srv.URL
+	idp.srv.Close()
+
+	h := newTestOidcHandler(t, issuer)
+	if w := serveOidc(h, "/auth/login"); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want 503", w.Code)
+	}
+	if w := serveOidc(h, "/auth/other"); w.Code != http.StatusNotFound {
+		t.Errorf("unknown route: status = %d, want 404", w.Code)
+	}
+}
+
+func TestOidcHandler_PostConstruct(t *testing.T) {
+	for _, h := range []*implOidcHandler{
+		{ClientID: "c", RedirectURL: "https://a/cb", HandlerPattern: "/auth/", CookieSecret: strings.Repeat("k", 32)},
+		{Issuer: "https://idp", ClientID: "c", RedirectURL: "/cb", HandlerPattern: "/auth/", CookieSecret: strings.Repeat("k", 32)},
+		{Issuer: "https://idp", ClientID: "c", RedirectURL: "https://a/cb", HandlerPattern: "/auth", CookieSecret: strings.Repeat("k", 32)},
+		{Issuer: "https://idp", ClientID: "c", RedirectURL: "https://a/cb", HandlerPattern: "/auth/", CookieSecret: "short"},
+	} {
+		if err := h.PostConstruct(); err == nil {
+			t.Errorf("expected error for %+v", h)
+		}
+	}
+}
+
+func TestOidcHandler_ServerSideSession(t *testing.T) {
+	idp := newMockIdP(t)
+	h := newTestOidcHandler(t, idp.srv.URL)
+	sessions := newTestSessionManager(t, time.Hour, 2*time.Hour)
+	h.Sessions = sessions
+
+	w := serveOidc(h, "/auth/login")
+	flow := responseCookie(w, "servion_session_flow")
+	state := idp.authorize(w.Header().Get("Location"))
+	w = serveOidc(h, "/auth/callback?code=the-code&state="+url.QueryEscape(state), flow)
+	if w.Code != http.StatusFound {
+		t.Fatalf("callback: status = %d, body %s", w.Code, w.Body)
+	}
+	if responseCookie(w, "servion_session") != nil {
+		t.Error("identity cookie set although sessions are server-side")
+	}
+	sid := responseCookie(w, "servion_sid")
+	if sid == nil {
+		t.Fatal("session id cookie missing")
+	}
+	info, ok, _ := sessions.AuthenticateSession(requestWithCookie(sid))
+	if !ok || info.Subject != "alice" {
+		t.Fatalf("session identity = %+v, %v", info, ok)
+	}
+
+	// logout ends the session on the server, a copied cookie is worthless
+	serveOidc(h, "/auth/logout", sid)
+	if _, ok, _ := sessions.AuthenticateSession(requestWithCookie(sid)); ok {
+		t.Error("session survived logout")
+	}
+}
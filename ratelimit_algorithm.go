@@ -0,0 +1,246 @@
+/*
+ * Copyright (c) 2026 Karagatan LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package servion
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+// rateBucket is the per-key state; each algorithm uses only its own fields.
+type rateBucket struct {
+	// fixed-window
+	count     int
+	lastReset time.Time
+
+	// sliding-window: timestamps of accepted requests, oldest first
+	stamps []time.Time
+
+	// token-bucket
+	tokens float64
+	last   time.Time
+
+	// gcra: theoretical arrival time
+	tat time.Time
+
+	// expiry bookkeeping
+	lastSeen time.Time
+	interval time.Duration
+}
+
+type rateAlgorithm interface {
+	take(b *rateBucket, rule RateLimitRule, now time.Time) RateLimitResult
+}
+
+// rateAlgorithmName normalizes a configured algorithm name, fixed-window being the default.
+func rateAlgorithmName(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return RateLimitFixedWindow
+	}
+	return name
+}
+
+func newRateAlgorithm(name string) (rateAlgorithm, error) {
+	switch rateAlgorithmName(name) {
+	case RateLimitFixedWindow:
+		return fixedWindowAlgorithm{}, nil
+	case RateLimitSlidingWindow:
+		return slidingWindowAlgorithm{}, nil
+	case RateLimitTokenBucket:
+		return tokenBucketAlgorithm{}, nil
+	case RateLimitGCRA:
+		return gcraAlgorithm{}, nil
+	default:
+		return nil, xerrors.Errorf("unknown rate limit algorithm '%s'", name)
+	}
+}
+
+// fixedWindowAlgorithm counts requests in consecutive windows of rule.Interval.
+type fixedWindowAlgorithm struct{}
+
+func (fixedWindowAlgorithm) take(b *rateBucket, rule RateLimitRule, now time.Time) RateLimitResult {
+	if b.lastReset.IsZero() || now.Sub(b.lastReset) > rule.Interval {
+		b.count = 0
+		b.lastReset = now
+	}
+	reset := b.lastReset.Add(rule.Interval).Sub(now)
+	if b.count >= rule.Limit {
+		return RateLimitResult{Limit: rule.Limit, Reset: reset, RetryAfter: reset}
+	}
+	b.count++
+	return RateLimitResult{Allowed: true, Limit: rule.Limit, Remaining: rule.Limit - b.count, Reset: reset}
+}
+
+// slidingWindowAlgorithm keeps the log of accepted requests, so any interval-long
+// window contains at most rule.Limit of them.
+type slidingWindowAlgorithm struct{}
+
+func (slidingWindowAlgorithm) take(b *rateBucket, rule RateLimitRule, now time.Time) RateLimitResult {
+	cutoff := now.Add(-rule.Interval)
+	i := sort.Search(len(b.stamps), func(i int) bool { return b.stamps[i].After(cutoff) })
+	b.stamps = b.stamps[i:]
+
+	if len(b.stamps) >= rule.Limit {
+		var retry time.Duration
+		if rule.Limit > 0 {
+			retry = b.stamps[len(b.stamps)-rule.Limit].Add(rule.Interval).Sub(now)
+		} else {
+			retry = rule.Interval
+		}
+		return RateLimitResult{Limit: rule.Limit, Reset: b.lastStampExpiry(rule, now), RetryAfter: retry}
+	}
+	b.stamps = append(b.stamps, now)
+	return RateLimitResult{
+		Allowed:   true,
+		Limit:     rule.Limit,
+		Remaining: rule.Limit - len(b.stamps),
+		Reset:     b.lastStampExpiry(rule, now),
+	}
+}
+
+func (b *rateBucket) lastStampExpiry(rule RateLimitRule, now time.Time) time.Duration {
+	if len(b.stamps) == 0 {
+		return 0
+	}
+	return b.stamps[len(b.stamps)-1].Add(rule.Interval).Sub(now)
+}
+
+// tokenBucketAlgorithm refills rule.Limit tokens per rule.Interval up to a burst of rule.Limit.
+type tokenBucketAlgorithm struct{}
+
+func (tokenBucketAlgorithm) take(b *rateBucket, rule RateLimitRule, now time.Time) RateLimitResult {
+	capacity := float64(rule.Limit)
+	rate := capacity / rule.Interval.Seconds() // tokens per second
+
+	if b.last.IsZero() {
+		b.tokens = capacity
+	} else if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(capacity, b.tokens+elapsed*rate)
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		var retry time.Duration
+		if rate > 0 {
+			retry = secondsToDuration((1 - b.tokens) / rate)
+		} else {
+			retry = rule.Interval
+		}
+		return RateLimitResult{Limit: rule.Limit, Reset: tokenBucketReset(b, capacity, rate), RetryAfter: retry}
+	}
+	b.tokens--
+	return RateLimitResult{
+		Allowed:   true,
+		Limit:     rule.Limit,
+		Remaining: int(b.tokens),
+		Reset:     tokenBucketReset(b, capacity, rate),
+	}
+}
+
+func tokenBucketReset(b *rateBucket, capacity, rate float64) time.Duration {
+	if rate <= 0 {
+		return 0
+	}
+	return secondsToDuration((capacity - b.tokens) / rate)
+}
+
+// gcraAlgorithm is the generic cell rate algorithm: requests are spaced by
+// interval/limit with a burst tolerance of a whole interval, tracked by a single
+// timestamp per key.
+type gcraAlgorithm struct{}
+
+func (gcraAlgorithm) take(b *rateBucket, rule RateLimitRule, now time.Time) RateLimitResult {
+	if rule.Limit <= 0 {
+		return RateLimitResult{Limit: rule.Limit, RetryAfter: rule.Interval}
+	}
+	emission := rule.Interval / time.Duration(rule.Limit)
+
+	tat := b.tat
+	if tat.Before(now) {
+		tat = now
+	}
+	newTat := tat.Add(emission)
+	allowAt := newTat.Add(-rule.Interval)
+
+	if now.Before(allowAt) {
+		return RateLimitResult{
+			Limit:      rule.Limit,
+			Reset:      tat.Sub(now),
+			RetryAfter: allowAt.Sub(now),
+		}
+	}
+	b.tat = newTat
+	return RateLimitResult{
+		Allowed:   true,
+		Limit:     rule.Limit,
+		Remaining: int((rule.Interval - newTat.Sub(now)) / emission),
+		Reset:     newTat.Sub(now),
+	}
+}
+
+func secondsToDuration(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+/*
+parseRateLimitRules parses "prefix=limit/period" entries separated by semicolons,
+e.g. "/api/login=5/m;/api=100/s". The period is s, m, h, d or any Go duration
+such as 10s. Rules are returned longest prefix first, so the most specific one wins.
+*/
+func parseRateLimitRules(str string) ([]RateLimitRule, error) {
+	var rules []RateLimitRule
+	for _, entry := range ParsePrefixList(str) {
+		prefix, spec, ok := strings.Cut(entry, "=")
+		prefix = strings.TrimSpace(prefix)
+		if !ok || prefix == "" {
+			return nil, xerrors.Errorf("invalid rate limit rule '%s', expected prefix=limit/period", entry)
+		}
+		limitStr, period, ok := strings.Cut(strings.TrimSpace(spec), "/")
+		if !ok {
+			return nil, xerrors.Errorf("invalid rate limit rule '%s', expected prefix=limit/period", entry)
+		}
+		limit, err := strconv.Atoi(strings.TrimSpace(limitStr))
+		if err != nil || limit < 0 {
+			return nil, xerrors.Errorf("invalid limit in rate limit rule '%s'", entry)
+		}
+		interval, err := parseRatePeriod(strings.TrimSpace(period))
+		if err != nil {
+			return nil, xerrors.Errorf("invalid period in rate limit rule '%s': %w", entry, err)
+		}
+		rules = append(rules, RateLimitRule{Prefix: prefix, Limit: limit, Interval: interval})
+	}
+	sort.SliceStable(rules, func(i, j int) bool {
+		return len(rules[i].Prefix) > len(rules[j].Prefix)
+	})
+	return rules, nil
+}
+
+func parseRatePeriod(period string) (time.Duration, error) {
+	switch period {
+	case "s":
+		return time.Second, nil
+	case "m":
+		return time.Minute, nil
+	case "h":
+		return time.Hour, nil
+	case "d":
+		return 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(period)
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, xerrors.Errorf("period must be positive, got %s", period)
+	}
+	return d, nil
+}
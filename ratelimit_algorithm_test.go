@@ -0,0 +1,121 @@
+package servion
+
+import (
+	"testing"
+	"time"
+)
+
+func runAlgorithm(t *testing.T, name string, rule RateLimitRule, at ...time.Duration) []RateLimitResult {
+	t.Helper()
+	alg, err := newRateAlgorithm(name)
+	if err != nil {
+		t.Fatalf("newRateAlgorithm(%q): %v", name, err)
+	}
+	start := time.Unix(1700000000, 0)
+	b := &rateBucket{}
+	var out []RateLimitResult
+	for _, offset := range at {
+		out = append(out, alg.take(b, rule, start.Add(offset)))
+	}
+	return out
+}
+
+func allowedPattern(list []RateLimitResult) string {
+	s := make([]byte, len(list))
+	for i, d := range list {
+		if d.Allowed {
+			s[i] = '+'
+		} else {
+			s[i] = '-'
+		}
+	}
+	return string(s)
+}
+
+func TestRateAlgorithm_Burst(t *testing.T) {
+	rule := RateLimitRule{Limit: 3, Interval: time.Second}
+	for _, name := range []string{RateLimitFixedWindow, RateLimitSlidingWindow, RateLimitTokenBucket, RateLimitGCRA} {
+		t.Run(name, func(t *testing.T) {
+			list := runAlgorithm(t, name, rule, 0, 0, 0, 0)
+			if got := allowedPattern(list); got != "+++-" {
+				t.Fatalf("pattern = %s, want +++-", got)
+			}
+			for i, want := range []int{2, 1, 0} {
+				if list[i].Remaining != want {
+					t.Errorf("request %d: remaining = %d, want %d", i, list[i].Remaining, want)
+				}
+			}
+			if list[3].RetryAfter <= 0 {
+				t.Errorf("denied request: retryAfter = %v, want > 0", list[3].RetryAfter)
+			}
+		})
+	}
+}
+
+func TestRateAlgorithm_FixedWindowBoundaryBurst(t *testing.T) {
+	// a fixed window admits a double burst around the boundary, which is exactly
+	// what the sliding window prevents
+	rule := RateLimitRule{Limit: 2, Interval: time.Second}
+	at := []time.Duration{0, 900 * time.Millisecond, 950 * time.Millisecond, 1100 * time.Millisecond, 1150 * time.Millisecond}
+
+	if got := allowedPattern(runAlgorithm(t, RateLimitFixedWindow, rule, at...)); got != "++-++" {
+		t.Errorf("fixed-window pattern = %s, want ++-++", got)
+	}
+	if got := allowedPattern(runAlgorithm(t, RateLimitSlidingWindow, rule, at...)); got != "++-+-" {
+		t.Errorf("sliding-window pattern = %s, want ++-+-", got)
+	}
+}
+
+func TestRateAlgorithm_SteadyRefill(t *testing.T) {
+	// limit 2 per second refills one request every 500ms
+	rule := RateLimitRule{Limit: 2, Interval: time.Second}
+	at := []time.Duration{0, 0, 100 * time.Millisecond, 500 * time.Millisecond, 600 * time.Millisecond, 1000 * time.Millisecond}
+
+	for _, name := range []string{RateLimitTokenBucket, RateLimitGCRA} {
+		t.Run(name, func(t *testing.T) {
+			list := runAlgorithm(t, name, rule, at...)
+			if got := allowedPattern(list); got != "++-+-+" {
+				t.Fatalf("pattern = %s, want ++-+-+", got)
+			}
+			if got := list[2].RetryAfter; got != 400*time.Millisecond {
+				t.Errorf("retryAfter = %v, want 400ms", got)
+			}
+		})
+	}
+}
+
+func TestRateAlgorithm_Unknown(t *testing.T) {
+	if _, err := newRateAlgorithm("leaky-bucket"); err == nil {
+		t.Fatal("expected error for unknown algorithm")
+	}
+	if alg, err := newRateAlgorithm(""); err != nil || alg == nil {
+		t.Fatalf("empty name should select the default algorithm, got %v, %v", alg, err)
+	}
+}
+
+func TestParseRateLimitRules(t *testing.T) {
+	rules, err := parseRateLimitRules("/api=100/s; /api/login=5/m ;/export=2/10s;/day=1000/d")
+	if err != nil {
+		t.Fatalf("parseRateLimitRules: %v", err)
+	}
+	want := []RateLimitRule{
+		{Prefix: "/api/login", Limit: 5, Interval: time.Minute},
+		{Prefix: "/export", Limit: 2, Interval: 10 * time.Second},
+		{Prefix: "/api", Limit: 100, Interval: time.Second},
+		{Prefix: "/day", Limit: 1000, Interval: 24 * time.Hour},
+	}
+	if len(rules) != len(want) {
+		t.Fatalf("got %d rules, want %d: %+v", len(rules), len(want), rules)
+	}
+	for i := range want {
+		if rules[i] != want[i] {
+			t.Errorf("rule %d = %+v, want %+v", i, rules[i], want[i])
+		}
+	}
+
+	for _, bad := range []string{"/api", "/api=5", "=5/s", "/api=x/s", "/api=5/fortnight", "/api=5/-1s"} {
+		if _, err := parseRateLimitRules(bad); err == nil {
+			t.Errorf("parseRateLimitRules(%q): expected error", bad)
+		}
+	}
+}
@@ -2,13 +2,24 @@ package servion
 
 import (
 	"context"
+	"math"
+	"net"
 	"net/http"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"go.uber.org/atomic"
 	"go.uber.org/zap"
+	"golang.org/x/xerrors"
+)
+
+const (
+	HeaderRateLimitLimit     = "RateLimit-Limit"
+	HeaderRateLimitRemaining = "RateLimit-Remaining"
+	HeaderRateLimitReset     = "RateLimit-Reset"
+	HeaderRateLimitPolicy    = "RateLimit-Policy"
 )
 
 // implRateLimiterMiddleware implements HttpMiddleware
@@ -19,46 +30,110 @@ type implRateLimiterMiddleware struct {
 
 	Runtime Runtime `inject:""`
 
+	// Store, when present in the server context, holds the buckets instead of this
+	// process, so replicas share one budget. Errors fall back to the local buckets.
+	Store RateLimitStore `inject:"optional"`
+
 	// Prefixes to apply rate limiting
 	Prefixes []string `value:"ratelimit.prefixes,default=/api"`
 
-	// Maximum requests per interval
+	// Maximum requests per interval, for paths not covered by Rules
 	Limit int `value:"ratelimit.limit,default=10"`
 
-	// Sliding window interval
+	// Interval of the default limit
 	Interval time.Duration `value:"ratelimit.interval,default=1s"`
 
 	ClientIDHeader string `value:"ratelimit.header,default=X-Forwarded-For"`
 
+	// One of fixed-window, sliding-window, token-bucket, gcra
+	Algorithm string `value:"ratelimit.algorithm,default=fixed-window"`
+
+	// Semicolon separated key parts: header, ip, subject, route, header:<Name>
+	Key string `value:"ratelimit.key,default=header"`
+
+	// Per-route limits, e.g. "/api/login=5/m;/api=100/s"
+	Rules string `value:"ratelimit.rules,default="`
+
+	Shards     int  `value:"ratelimit.shards,default=16"`
+	MaxClients int  `value:"ratelimit.max-clients,default=100000"`
+	Headers    bool `value:"ratelimit.headers,default=true"`
+
 	ctx    context.Context
 	cancel context.CancelFunc
 	wg     sync.WaitGroup
 
-	// Internal storage: map clientID -> *bucket
-	mu      sync.Mutex
-	buckets map[string]*rateBucket
+	algorithmName string
+	algorithm     rateAlgorithm
+	degraded      atomic.Bool
+	keyParts      []rateKeyPart
+	rules         []RateLimitRule
+	defaultRule   RateLimitRule
+	store         *rateStore
 }
 
-// rateBucket tracks requests
-type rateBucket struct {
-	count     int
-	lastReset time.Time
-}
+/*
+RateLimiterMiddleware creates a per-client rate limiting HttpMiddleware.
 
+Configuration properties:
+
+	ratelimit.prefixes     – URL prefixes to rate limit (default "/api")
+	ratelimit.limit        – requests per interval for paths without a rule (default 10)
+	ratelimit.interval     – interval of the default limit (default 1s)
+	ratelimit.rules        – per-route limits, longest prefix wins, e.g. "/api/login=5/m;/api=100/s"
+	ratelimit.algorithm    – fixed-window (default), sliding-window, token-bucket or gcra
+	ratelimit.key          – client key parts joined together (default "header"):
+	                         header (first hop of ratelimit.header, unlimited when missing),
+	                         ip (first hop of ratelimit.header, else the peer address),
+	                         subject (AuthInfo.Subject, else the client ip),
+	                         route (no client part: one budget shared per rule),
+	                         header:<Name> (value of a custom header)
+	ratelimit.header       – client identity header set by the proxy (default "X-Forwarded-For")
+	ratelimit.shards       – number of independently locked bucket shards (default 16)
+	ratelimit.max-clients  – bucket cap, least recently seen clients are evicted (default 100000)
+	ratelimit.headers      – send RateLimit-Limit/Remaining/Reset/Policy headers (default true)
+
+When a RateLimitStore bean (MemoryRateLimitStore, RedisRateLimitStore or a custom
+one) is present in the server context, buckets are kept there instead; while the
+store fails, requests are limited by the local buckets.
+*/
 func RateLimiterMiddleware(beanOrder int) HttpMiddleware {
 	return &implRateLimiterMiddleware{
 		beanOrder: beanOrder,
-		buckets:   make(map[string]*rateBucket),
 	}
 }
 
 func (t *implRateLimiterMiddleware) PostConstruct() error {
+	if err := t.init(); err != nil {
+		return err
+	}
 	t.ctx, t.cancel = context.WithCancel(t.Runtime)
 	t.wg.Add(1)
 	go t.cleanerLoop()
 	return nil
 }
 
+func (t *implRateLimiterMiddleware) init() (err error) {
+	t.algorithmName = rateAlgorithmName(t.Algorithm)
+	t.algorithm, err = newRateAlgorithm(t.algorithmName)
+	if err != nil {
+		return err
+	}
+	t.keyParts, err = parseRateKey(t.Key)
+	if err != nil {
+		return err
+	}
+	t.rules, err = parseRateLimitRules(t.Rules)
+	if err != nil {
+		return err
+	}
+	if t.Interval <= 0 {
+		return xerrors.Errorf("ratelimit.interval must be positive, got %s", t.Interval)
+	}
+	t.defaultRule = RateLimitRule{Limit: t.Limit, Interval: t.Interval}
+	t.store = newRateStore(t.Shards, t.MaxClients)
+	return nil
+}
+
 func (t *implRateLimiterMiddleware) Destroy() error {
 	if t.cancel != nil {
 		t.cancel()
@@ -76,8 +151,10 @@ func (t *implRateLimiterMiddleware) Middleware(next http.Handler) http.Handler {
 			return
 		}
 
-		xff := r.Header.Get(t.ClientIDHeader)
-		if xff == "" {
+		rule := t.ruleFor(r.URL.Path)
+
+		clientID, ok := t.clientKey(r, rule)
+		if !ok {
 			// you should make sure that proxy setup correct header
 			// never use remoteAddr, since this rate limiter is designed for app behind proxy, no need to limit proxy itself
 			t.Log.Warn("RateLimiterMissingXFF",
@@ -91,37 +168,69 @@ func (t *implRateLimiterMiddleware) Middleware(next http.Handler) http.Handler {
 			next.ServeHTTP(w, r)
 			return
 		}
-		parts := strings.Split(xff, ",")
-		clientID := strings.TrimSpace(parts[0])
 
-		t.mu.Lock()
-		bucket, ok := t.buckets[clientID]
-		if !ok {
-			bucket = &rateBucket{count: 0, lastReset: time.Now()}
-			t.buckets[clientID] = bucket
-		}
+		// the rule is always part of the key, so routes with different limits never share a bucket
+		d := t.take(r.Context(), rule.Prefix+"|"+clientID, rule)
 
-		// Reset if interval passed
-		now := time.Now()
-		if now.Sub(bucket.lastReset) > t.Interval {
-			bucket.count = 0
-			bucket.lastReset = now
+		if t.Headers {
+			h := w.Header()
+			h.Set(HeaderRateLimitLimit, strconv.Itoa(d.Limit))
+			h.Set(HeaderRateLimitRemaining, strconv.Itoa(max(d.Remaining, 0)))
+			h.Set(HeaderRateLimitReset, strconv.Itoa(ceilSeconds(d.Reset)))
+			h.Set(HeaderRateLimitPolicy, strconv.Itoa(rule.Limit)+";w="+strconv.Itoa(ceilSeconds(rule.Interval)))
 		}
 
-		if bucket.count >= t.Limit {
-			t.mu.Unlock()
-			w.Header().Set("Retry-After", strconv.Itoa(int(t.Interval.Seconds())))
+		if !d.Allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(max(ceilSeconds(d.RetryAfter), 1)))
 			http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
 			return
 		}
 
-		bucket.count++
-		t.mu.Unlock()
-
 		next.ServeHTTP(w, r)
 	})
 }
 
+// take consults the shared store when present and degrades to the local buckets on failure.
+func (t *implRateLimiterMiddleware) take(ctx context.Context, key string, rule RateLimitRule) RateLimitResult {
+	if t.Store != nil {
+		d, err := t.Store.Take(ctx, key, t.algorithmName, rule)
+		if err == nil {
+			if t.degraded.CompareAndSwap(true, false) {
+				t.Log.Info("RateLimitStoreRecovered")
+			}
+			return d
+		}
+		if ctx.Err() == nil && t.degraded.CompareAndSwap(false, true) {
+			t.Log.Warn("RateLimitStoreUnavailable", zap.Error(err))
+		}
+	}
+	return t.store.take(key, t.algorithm, rule, time.Now())
+}
+
+func (t *implRateLimiterMiddleware) ruleFor(path string) RateLimitRule {
+	for _, rule := range t.rules {
+		if strings.HasPrefix(path, rule.Prefix) {
+			return rule
+		}
+	}
+	return t.defaultRule
+}
+
+func (t *implRateLimiterMiddleware) clientKey(r *http.Request, rule RateLimitRule) (string, bool) {
+	if len(t.keyParts) == 1 {
+		return t.keyParts[0](t, r, rule)
+	}
+	parts := make([]string, 0, len(t.keyParts))
+	for _, part := range t.keyParts {
+		s, ok := part(t, r, rule)
+		if !ok {
+			return "", false
+		}
+		parts = append(parts, s)
+	}
+	return strings.Join(parts, "|"), true
+}
+
 func (t *implRateLimiterMiddleware) BeanOrder() int {
 	return t.beanOrder
 }
@@ -138,7 +247,7 @@ func (t *implRateLimiterMiddleware) Match(prefix string) bool {
 func (t *implRateLimiterMiddleware) cleanerLoop() {
 	defer t.wg.Done()
 
-	ticker := time.NewTicker(t.Interval * 10)
+	ticker := time.NewTicker(t.cleanInterval())
 	defer ticker.Stop()
 
 	for {
@@ -152,13 +261,89 @@ func (t *implRateLimiterMiddleware) cleanerLoop() {
 	}
 }
 
+// cleanInterval is ten times the shortest configured interval.
+func (t *implRateLimiterMiddleware) cleanInterval() time.Duration {
+	interval := t.Interval
+	for _, rule := range t.rules {
+		if rule.Interval < interval {
+			interval = rule.Interval
+		}
+	}
+	return interval * 10
+}
+
 func (t *implRateLimiterMiddleware) doCleanExpired() {
-	t.mu.Lock()
-	defer t.mu.Unlock()
-	now := time.Now()
-	for id, b := range t.buckets {
-		if now.Sub(b.lastReset) > t.Interval*5 {
-			delete(t.buckets, id)
+	t.store.cleanExpired(time.Now())
+}
+
+// rateKeyPart extracts one part of the client key; false means the request can not be keyed.
+type rateKeyPart func(t *implRateLimiterMiddleware, r *http.Request, rule RateLimitRule) (string, bool)
+
+func parseRateKey(str string) ([]rateKeyPart, error) {
+	var parts []rateKeyPart
+	for _, name := range ParsePrefixList(str) {
+		switch {
+		case name == "header":
+			parts = append(parts, rateKeyHeader)
+		case name == "ip":
+			parts = append(parts, rateKeyIP)
+		case name == "subject":
+			parts = append(parts, rateKeySubject)
+		case name == "route":
+			parts = append(parts, rateKeyRoute)
+		case strings.HasPrefix(name, "header:") && len(name) > len("header:"):
+			header := http.CanonicalHeaderKey(strings.TrimSpace(name[len("header:"):]))
+			parts = append(parts, func(t *implRateLimiterMiddleware, r *http.Request, rule RateLimitRule) (string, bool) {
+				v := strings.TrimSpace(r.Header.Get(header))
+				return v, v != ""
+			})
+		default:
+			return nil, xerrors.Errorf("unknown rate limit key '%s'", name)
 		}
 	}
+	if len(parts) == 0 {
+		parts = append(parts, rateKeyHeader)
+	}
+	return parts, nil
+}
+
+func rateKeyHeader(t *implRateLimiterMiddleware, r *http.Request, rule RateLimitRule) (string, bool) {
+	id := firstHop(r.Header.Get(t.ClientIDHeader))
+	return id, id != ""
+}
+
+func rateKeyIP(t *implRateLimiterMiddleware, r *http.Request, rule RateLimitRule) (string, bool) {
+	if t.ClientIDHeader != "" {
+		if id := firstHop(r.Header.Get(t.ClientIDHeader)); id != "" {
+			return id, true
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return host, host != ""
+}
+
+func rateKeySubject(t *implRateLimiterMiddleware, r *http.Request, rule RateLimitRule) (string, bool) {
+	if auth, ok := AuthFromContext(r.Context()); ok && auth.Subject != "" {
+		return "sub:" + auth.Subject, true
+	}
+	return rateKeyIP(t, r, rule)
+}
+
+func rateKeyRoute(t *implRateLimiterMiddleware, r *http.Request, rule RateLimitRule) (string, bool) {
+	return "route", true
+}
+
+func firstHop(xff string) string {
+	first, _, _ := strings.Cut(xff, ",")
+	return strings.TrimSpace(first)
+}
+
+func ceilSeconds(d time.Duration) int {
+	if d <= 0 {
+		return 0
+	}
+	return int(math.Ceil(d.Seconds()))
 }
@@ -10,15 +10,28 @@ import (
 )
 
 func newTestRateLimiter(limit int, interval time.Duration) *implRateLimiterMiddleware {
-	return &implRateLimiterMiddleware{
+	return newTestRateLimiterWith(limit, interval, nil)
+}
+
+func newTestRateLimiterWith(limit int, interval time.Duration, configure func(rl *implRateLimiterMiddleware)) *implRateLimiterMiddleware {
+	rl := &implRateLimiterMiddleware{
 		beanOrder:      1,
 		Log:            zap.NewNop(),
 		Prefixes:       []string{"/api"},
 		Limit:          limit,
 		Interval:       interval,
 		ClientIDHeader: "X-Forwarded-For",
-		buckets:        make(map[string]*rateBucket),
+		Shards:         4,
+		MaxClients:     1000,
+		Headers:        true,
+	}
+	if configure != nil {
+		configure(rl)
 	}
+	if err := rl.init(); err != nil {
+		panic(err)
+	}
+	return rl
 }
 
 func TestRateLimiter_AllowUnderLimit(t *testing.T) {
@@ -243,7 +256,7 @@ func TestRateLimiter_PostConstructAndDestroy(t *testing.T) {
 		Limit:          10,
 		Interval:       50 * time.Millisecond,
 		ClientIDHeader: "X-Forwarded-For",
-		buckets:        make(map[string]*rateBucket),
+		Shards:         4,
 	}
 
 	if err := rl.PostConstruct(); err != nil {
@@ -261,22 +274,220 @@ func TestRateLimiter_CleanExpired(t *testing.T) {
 	rl := newTestRateLimiter(10, 10*time.Millisecond)
 
 	// Add a bucket that's expired
-	rl.buckets["old-client"] = &rateBucket{
-		count:     5,
-		lastReset: time.Now().Add(-time.Minute),
-	}
+	rl.store.take("old-client", rl.algorithm, rl.defaultRule, time.Now().Add(-time.Minute))
 	// Add a bucket that's still active
-	rl.buckets["new-client"] = &rateBucket{
-		count:     1,
-		lastReset: time.Now(),
-	}
+	rl.store.take("new-client", rl.algorithm, rl.defaultRule, time.Now())
 
 	rl.doCleanExpired()
 
-	if _, ok := rl.buckets["old-client"]; ok {
+	if _, ok := rl.store.get("old-client"); ok {
 		t.Error("expected expired bucket to be cleaned")
 	}
-	if _, ok := rl.buckets["new-client"]; !ok {
+	if _, ok := rl.store.get("new-client"); !ok {
 		t.Error("expected active bucket to remain")
 	}
 }
+
+func TestRateLimiter_InvalidConfig(t *testing.T) {
+	for name, configure := range map[string]func(rl *implRateLimiterMiddleware){
+		"algorithm": func(rl *implRateLimiterMiddleware) { rl.Algorithm = "leaky" },
+		"key":       func(rl *implRateLimiterMiddleware) { rl.Key = "cookie" },
+		"rules":     func(rl *implRateLimiterMiddleware) { rl.Rules = "/api=ten/s" },
+	} {
+		t.Run(name, func(t *testing.T) {
+			rl := &implRateLimiterMiddleware{Limit: 1, Interval: time.Second}
+			configure(rl)
+			if err := rl.init(); err == nil {
+				t.Error("expected configuration error")
+			}
+		})
+	}
+}
+
+func TestRateLimiter_StandardHeaders(t *testing.T) {
+	rl := newTestRateLimiter(2, time.Minute)
+	handler := rl.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	codes := []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests}
+	remaining := []string{"1", "0", "0"}
+	for i, want := range codes {
+		r := httptest.NewRequest(http.MethodGet, "/api/data", nil)
+		r.Header.Set("X-Forwarded-For", "1.2.3.4")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		if w.Code != want {
+			t.Fatalf("request %d: status = %d, want %d", i, w.Code, want)
+		}
+		if got := w.Header().Get(HeaderRateLimitLimit); got != "2" {
+			t.Errorf("request %d: RateLimit-Limit = %q, want 2", i, got)
+		}
+		if got := w.Header().Get(HeaderRateLimitRemaining); got != remaining[i] {
+			t.Errorf("request %d: RateLimit-Remaining = %q, want %s", i, got, remaining[i])
+		}
+		if got := w.Header().Get(HeaderRateLimitReset); got == "" || got == "0" {
+			t.Errorf("request %d: RateLimit-Reset = %q, want seconds until reset", i, got)
+		}
+		if got := w.Header().Get(HeaderRateLimitPolicy); got != "2;w=60" {
+			t.Errorf("request %d: RateLimit-Policy = %q, want 2;w=60", i, got)
+		}
+	}
+}
+
+func TestRateLimiter_HeadersDisabled(t *testing.T) {
+	rl := newTestRateLimiterWith(2, time.Minute, func(rl *implRateLimiterMiddleware) {
+		rl.Headers = false
+	})
+	handler := rl.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	r := httptest.NewRequest(http.MethodGet, "/api/data", nil)
+	r.Header.Set("X-Forwarded-For", "1.2.3.4")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	if got := w.Header().Get(HeaderRateLimitLimit); got != "" {
+		t.Errorf("RateLimit-Limit = %q, want none", got)
+	}
+}
+
+func TestRateLimiter_PerRouteRules(t *testing.T) {
+	rl := newTestRateLimiterWith(100, time.Minute, func(rl *implRateLimiterMiddleware) {
+		rl.Rules = "/api/login=1/m;/api=3/m"
+	})
+	handler := rl.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	do := func(path string) int {
+		r := httptest.NewRequest(http.MethodPost, path, nil)
+		r.Header.Set("X-Forwarded-For", "1.2.3.4")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	if got := do("/api/login"); got != http.StatusOK {
+		t.Fatalf("first login: status = %d, want %d", got, http.StatusOK)
+	}
+	if got := do("/api/login"); got != http.StatusTooManyRequests {
+		t.Fatalf("second login: status = %d, want %d", got, http.StatusTooManyRequests)
+	}
+
+	// the /api rule has its own budget, untouched by login attempts
+	for i := 0; i < 3; i++ {
+		if got := do("/api/data"); got != http.StatusOK {
+			t.Fatalf("data %d: status = %d, want %d", i, got, http.StatusOK)
+		}
+	}
+	if got := do("/api/data"); got != http.StatusTooManyRequests {
+		t.Fatalf("data over limit: status = %d, want %d", got, http.StatusTooManyRequests)
+	}
+}
+
+func TestRateLimiter_SubjectKey(t *testing.T) {
+	rl := newTestRateLimiterWith(1, time.Minute, func(rl *implRateLimiterMiddleware) {
+		rl.Key = "subject"
+	})
+	handler := rl.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	do := func(subject, remote string) int {
+		r := httptest.NewRequest(http.MethodGet, "/api/data", nil)
+		r.RemoteAddr = remote
+		if subject != "" {
+			r = r.WithContext(ContextWithAuth(r.Context(), AuthInfo{Subject: subject}))
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	// same subject from different addresses shares one budget
+	if got := do("alice", "10.0.0.1:1000"); got != http.StatusOK {
+		t.Fatalf("alice first: status = %d", got)
+	}
+	if got := do("alice", "10.0.0.2:1000"); got != http.StatusTooManyRequests {
+		t.Fatalf("alice second: status = %d, want %d", got, http.StatusTooManyRequests)
+	}
+	if got := do("bob", "10.0.0.1:1000"); got != http.StatusOK {
+		t.Fatalf("bob: status = %d", got)
+	}
+
+	// anonymous requests fall back to the peer address
+	if got := do("", "10.0.0.3:1000"); got != http.StatusOK {
+		t.Fatalf("anonymous first: status = %d", got)
+	}
+	if got := do("", "10.0.0.3:2000"); got != http.StatusTooManyRequests {
+		t.Fatalf("anonymous second: status = %d, want %d", got, http.StatusTooManyRequests)
+	}
+}
+
+func TestRateLimiter_IPKeyUsesRemoteAddr(t *testing.T) {
+	rl := newTestRateLimiterWith(1, time.Minute, func(rl *implRateLimiterMiddleware) {
+		rl.Key = "ip"
+	})
+	handler := rl.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	do := func(remote string) int {
+		r := httptest.NewRequest(http.MethodGet, "/api/data", nil)
+		r.RemoteAddr = remote
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	if got := do("192.0.2.1:1111"); got != http.StatusOK {
+		t.Fatalf("first: status = %d", got)
+	}
+	if got := do("192.0.2.1:2222"); got != http.StatusTooManyRequests {
+		t.Fatalf("same host other port: status = %d, want %d", got, http.StatusTooManyRequests)
+	}
+}
+
+func TestRateLimiter_RouteAndHeaderKeys(t *testing.T) {
+	rl := newTestRateLimiterWith(2, time.Minute, func(rl *implRateLimiterMiddleware) {
+		rl.Key = "route"
+	})
+	handler := rl.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	// route key: all clients share the budget
+	for i, ip := range []string{"1.1.1.1", "2.2.2.2", "3.3.3.3"} {
+		r := httptest.NewRequest(http.MethodGet, "/api/data", nil)
+		r.Header.Set("X-Forwarded-For", ip)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		want := http.StatusOK
+		if i == 2 {
+			want = http.StatusTooManyRequests
+		}
+		if w.Code != want {
+			t.Fatalf("client %s: status = %d, want %d", ip, w.Code, want)
+		}
+	}
+
+	rl = newTestRateLimiterWith(1, time.Minute, func(rl *implRateLimiterMiddleware) {
+		rl.Key = "header:X-Tenant;ip"
+	})
+	handler = rl.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	do := func(tenant string) int {
+		r := httptest.NewRequest(http.MethodGet, "/api/data", nil)
+		r.Header.Set("X-Forwarded-For", "1.2.3.4")
+		if tenant != "" {
+			r.Header.Set("X-Tenant", tenant)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w.Code
+	}
+	if got := do("a"); got != http.StatusOK {
+		t.Fatalf("tenant a: status = %d", got)
+	}
+	if got := do("b"); got != http.StatusOK {
+		t.Fatalf("tenant b: status = %d", got)
+	}
+	if got := do("a"); got != http.StatusTooManyRequests {
+		t.Fatalf("tenant a again: status = %d, want %d", got, http.StatusTooManyRequests)
+	}
+	// missing key part passes through unlimited, like a missing X-Forwarded-For
+	if got := do(""); got != http.StatusOK {
+		t.Fatalf("no tenant: status = %d", got)
+	}
+}
@@ -0,0 +1,234 @@
+/*
+ * Copyright (c) 2026 Karagatan LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package servion
+
+import (
+	"context"
+	"crypto/tls"
+	"strconv"
+	"time"
+
+	"go.uber.org/atomic"
+	"golang.org/x/xerrors"
+)
+
+/*
+The scripts run atomically on the server and use the server clock, so replicas
+with skewed clocks still share one consistent budget. Each one receives the
+limit and the interval in milliseconds and returns
+{allowed, remaining, reset ms, retry-after ms}.
+*/
+
+var redisFixedWindowScript = newRedisScript(`
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local count = redis.call('INCR', KEYS[1])
+local ttl = redis.call('PTTL', KEYS[1])
+if count == 1 or ttl < 0 then
+  redis.call('PEXPIRE', KEYS[1], window)
+  ttl = window
+end
+if count > limit then
+  return {0, 0, ttl, ttl}
+end
+return {1, limit - count, ttl, 0}
+`)
+
+var redisSlidingWindowScript = newRedisScript(`
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now - window)
+local count = redis.call('ZCARD', KEYS[1])
+if count >= limit then
+  local retry = window
+  if limit > 0 then
+    local oldest = redis.call('ZRANGE', KEYS[1], count - limit, count - limit, 'WITHSCORES')
+    retry = tonumber(oldest[2]) + window - now
+  end
+  local newest = redis.call('ZRANGE', KEYS[1], -1, -1, 'WITHSCORES')
+  local reset = 0
+  if newest[2] then reset = tonumber(newest[2]) + window - now end
+  return {0, 0, reset, retry}
+end
+redis.call('ZADD', KEYS[1], now, ARGV[3])
+redis.call('PEXPIRE', KEYS[1], window)
+return {1, limit - count - 1, window, 0}
+`)
+
+var redisTokenBucketScript = newRedisScript(`
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local rate = limit / window
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1])
+local ts = tonumber(state[2])
+if tokens == nil or ts == nil then
+  tokens = limit
+elseif now > ts then
+  tokens = math.min(limit, tokens + (now - ts) * rate)
+end
+local allowed = 0
+local retry = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+elseif rate > 0 then
+  retry = math.ceil((1 - tokens) / rate)
+else
+  retry = window
+end
+redis.call('HMSET', KEYS[1], 'tokens', tostring(tokens), 'ts', string.format('%d', now))
+redis.call('PEXPIRE', KEYS[1], window)
+local reset = 0
+if rate > 0 then reset = math.ceil((limit - tokens) / rate) end
+return {allowed, math.floor(tokens), reset, retry}
+`)
+
+var redisGCRAScript = newRedisScript(`
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+if limit <= 0 then
+  return {0, 0, 0, window}
+end
+local emission = window / limit
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+local tat = tonumber(redis.call('GET', KEYS[1]))
+if tat == nil or tat < now then tat = now end
+local newTat = tat + emission
+local allowAt = newTat - window
+if now < allowAt then
+  return {0, 0, math.ceil(tat - now), math.ceil(allowAt - now)}
+end
+redis.call('SET', KEYS[1], string.format('%d', math.ceil(newTat)), 'PX', math.ceil(newTat - now))
+return {1, math.floor((window - (newTat - now)) / emission), math.ceil(newTat - now), 0}
+`)
+
+var redisRateScripts = map[string]*redisScript{
+	RateLimitFixedWindow:   redisFixedWindowScript,
+	RateLimitSlidingWindow: redisSlidingWindowScript,
+	RateLimitTokenBucket:   redisTokenBucketScript,
+	RateLimitGCRA:          redisGCRAScript,
+}
+
+type implRedisRateLimitStore struct {
+	TlsConfig *tls.Config `inject:"optional"`
+
+	Address   string        `value:"ratelimit.redis.address,default="`
+	Password  string        `value:"ratelimit.redis.password,default="`
+	DB        int           `value:"ratelimit.redis.db,default=0"`
+	TLS       bool          `value:"ratelimit.redis.tls,default=false"`
+	Timeout   time.Duration `value:"ratelimit.redis.timeout,default=100ms"`
+	PoolSize  int           `value:"ratelimit.redis.pool-size,default=8"`
+	KeyPrefix string        `value:"ratelimit.redis.key-prefix,default=servion:ratelimit:"`
+	Backoff   time.Duration `value:"ratelimit.redis.backoff,default=5s"`
+
+	client    *redisClient
+	downUntil atomic.Int64 // unix nanos until which the server is considered unreachable
+}
+
+/*
+RedisRateLimitStore creates a RateLimitStore shared by all replicas through a
+server speaking the Redis protocol (Redis, Valkey, KeyDB, ...). Every algorithm
+runs as one atomic server-side script, so concurrent replicas never over-admit.
+
+After a connection failure the store reports ErrServiceUnavailable without
+dialing for ratelimit.redis.backoff, and the middleware limits locally meanwhile.
+
+Configuration properties:
+
+	ratelimit.redis.address     – host:port of the server (required)
+	ratelimit.redis.password    – AUTH password (optional)
+	ratelimit.redis.db          – database index (default 0)
+	ratelimit.redis.tls         – connect over TLS, using the *tls.Config bean when present (default false)
+	ratelimit.redis.timeout     – dial and command timeout (default 100ms)
+	ratelimit.redis.pool-size   – idle connections kept open (default 8)
+	ratelimit.redis.key-prefix  – prefix of every key (default "servion:ratelimit:")
+	ratelimit.redis.backoff     – pause after a connection failure (default 5s)
+*/
+func RedisRateLimitStore() RateLimitStore {
+	return &implRedisRateLimitStore{}
+}
+
+func (t *implRedisRateLimitStore) PostConstruct() error {
+	if t.Address == "" {
+		return xerrors.New("ratelimit.redis.address must be configured")
+	}
+	var tlsConfig *tls.Config
+	if t.TLS {
+		if t.TlsConfig != nil {
+			tlsConfig = t.TlsConfig.Clone()
+		} else {
+			tlsConfig = &tls.Config{}
+		}
+	}
+	t.client = newRedisClient(t.Address, t.Password, t.DB, t.Timeout, t.PoolSize, tlsConfig)
+	return nil
+}
+
+func (t *implRedisRateLimitStore) Destroy() error {
+	if t.client != nil {
+		t.client.close()
+	}
+	return nil
+}
+
+func (t *implRedisRateLimitStore) Take(ctx context.Context, key string, algorithm string, rule RateLimitRule) (RateLimitResult, error) {
+	algorithm = rateAlgorithmName(algorithm)
+	script, ok := redisRateScripts[algorithm]
+	if !ok {
+		return RateLimitResult{}, xerrors.Errorf("unknown rate limit algorithm '%s'", algorithm)
+	}
+
+	if time.Now().UnixNano() < t.downUntil.Load() {
+		return RateLimitResult{}, ErrServiceUnavailable
+	}
+
+	args := []string{
+		strconv.Itoa(rule.Limit),
+		strconv.FormatInt(max(rule.Interval.Milliseconds(), 1), 10),
+	}
+	if algorithm == RateLimitSlidingWindow {
+		// members of the request log must be unique even within one millisecond
+		args = append(args, generateRequestID())
+	}
+
+	reply, err := t.client.evalScript(ctx, script, []string{t.KeyPrefix + algorithm + ":" + key}, args...)
+	if err != nil {
+		if ctx.Err() != nil {
+			// the request gave up, redis may well be fine
+			return RateLimitResult{}, xerrors.Errorf("redis rate limit: %w", ctx.Err())
+		}
+		var rerr redisError
+		if !xerrors.As(err, &rerr) {
+			t.downUntil.Store(time.Now().Add(t.Backoff).UnixNano())
+			return RateLimitResult{}, xerrors.Errorf("redis %s: %v: %w", t.Address, err, ErrServiceUnavailable)
+		}
+		return RateLimitResult{}, xerrors.Errorf("redis rate limit script: %w", err)
+	}
+
+	values, ok := reply.([]interface{})
+	if !ok || len(values) != 4 {
+		return RateLimitResult{}, xerrors.Errorf("redis rate limit script: unexpected reply %v", reply)
+	}
+	var n [4]int64
+	for i, v := range values {
+		if n[i], ok = v.(int64); !ok {
+			return RateLimitResult{}, xerrors.Errorf("redis rate limit script: unexpected reply %v", reply)
+		}
+	}
+	return RateLimitResult{
+		Allowed:    n[0] == 1,
+		Limit:      rule.Limit,
+		Remaining:  int(n[1]),
+		Reset:      time.Duration(n[2]) * time.Millisecond,
+		RetryAfter: time.Duration(n[3]) * time.Millisecond,
+	}, nil
+}
@@ -0,0 +1,350 @@
+package servion
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRedis is a local stand-in speaking RESP. It understands the commands the
+// store sends and emulates the rate limit scripts with the in-process algorithms,
+// so the protocol, script caching and failure handling are tested without Redis.
+type fakeRedis struct {
+	t        *testing.T
+	ln       net.Listener
+	password string
+
+	mu       sync.Mutex
+	scripts  map[string]string // sha -> algorithm
+	store    *rateStore
+	commands []string
+}
+
+func startFakeRedis(t *testing.T, password string) *fakeRedis {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	f := &fakeRedis{t: t, ln: ln, password: password, scripts: make(map[string]string), store: newRateStore(1, 0)}
+	go f.serve()
+	t.Cleanup(func() { ln.Close() })
+	return f
+}
+
+func (f *fakeRedis) addr() string { return f.ln.Addr().String() }
+
+func (f *fakeRedis) serve() {
+	for {
+		conn, err := f.ln.Accept()
+		if err != nil {
+			return
+		}
+		go f.handle(conn)
+	}
+}
+
+func (f *fakeRedis) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	authed := f.password == ""
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		f.mu.Lock()
+		f.commands = append(f.commands, strings.ToUpper(args[0]))
+		f.mu.Unlock()
+
+		switch strings.ToUpper(args[0]) {
+		case "AUTH":
+			if args[1] != f.password {
+				io.WriteString(conn, "-WRONGPASS invalid password\r\n")
+				continue
+			}
+			authed = true
+			io.WriteString(conn, "+OK\r\n")
+		case "SELECT":
+			io.WriteString(conn, "+OK\r\n")
+		case "EVALSHA", "EVAL":
+			if !authed {
+				io.WriteString(conn, "-NOAUTH Authentication required.\r\n")
+				continue
+			}
+			io.WriteString(conn, f.eval(args))
+		default:
+			io.WriteString(conn, "-ERR unknown command\r\n")
+		}
+	}
+}
+
+func (f *fakeRedis) eval(args []string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	sha := args[1]
+	if strings.ToUpper(args[0]) == "EVAL" {
+		sum := sha1.Sum([]byte(args[1]))
+		sha = hex.EncodeToString(sum[:])
+		for name, script := range redisRateScripts {
+			if script.src == args[1] {
+				f.scripts[sha] = name
+			}
+		}
+	}
+	name, ok := f.scripts[sha]
+	if !ok {
+		return "-NOSCRIPT No matching script. Please use EVAL.\r\n"
+	}
+	key := args[3]
+	limit, _ := strconv.Atoi(args[4])
+	window, _ := strconv.Atoi(args[5])
+	alg, _ := newRateAlgorithm(name)
+	d := f.store.take(key, alg, RateLimitRule{Limit: limit, Interval: time.Duration(window) * time.Millisecond}, time.Now())
+
+	allowed := 0
+	if d.Allowed {
+		allowed = 1
+	}
+	reply := "*4\r\n"
+	for _, n := range []int64{int64(allowed), int64(d.Remaining), d.Reset.Milliseconds(), d.RetryAfter.Milliseconds()} {
+		reply += ":" + strconv.FormatInt(n, 10) + "\r\n"
+	}
+	return reply
+}
+
+func (f *fakeRedis) count(cmd string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n := 0
+	for _, c := range f.commands {
+		if c == cmd {
+			n++
+		}
+	}
+	return n
+}
+
+func readCommand(r *bufio.Reader) ([]string, error) {
+	reply, err := readRedisReply(r)
+	if err != nil {
+		return nil, err
+	}
+	list, ok := reply.([]interface{})
+	if !ok || len(list) == 0 {
+		return nil, errors.New("expected command array")
+	}
+	args := make([]string, len(list))
+	for i, v := range list {
+		args[i], _ = v.(string)
+	}
+	return args, nil
+}
+
+func newTestRedisStore(t *testing.T, addr, password string) *implRedisRateLimitStore {
+	t.Helper()
+	s := &implRedisRateLimitStore{
+		Address:   addr,
+		Password:  password,
+		Timeout:   time.Second,
+		PoolSize:  2,
+		KeyPrefix: "test:",
+		Backoff:   time.Minute,
+	}
+	if err := s.PostConstruct(); err != nil {
+		t.Fatalf("PostConstruct: %v", err)
+	}
+	t.Cleanup(func() { s.Destroy() })
+	return s
+}
+
+func TestRedisRateLimitStore_Take(t *testing.T) {
+	f := startFakeRedis(t, "secret")
+	s := newTestRedisStore(t, f.addr(), "secret")
+	rule := RateLimitRule{Prefix: "/api", Limit: 2, Interval: time.Minute}
+
+	for _, alg := range []string{RateLimitFixedWindow, RateLimitSlidingWindow, RateLimitTokenBucket, RateLimitGCRA} {
+		t.Run(alg, func(t *testing.T) {
+			var pattern string
+			for i := 0; i < 3; i++ {
+				d, err := s.Take(context.Background(), "client", alg, rule)
+				if err != nil {
+					t.Fatalf("Take: %v", err)
+				}
+				if d.Allowed {
+					pattern += "+"
+				} else {
+					pattern += "-"
+					if d.RetryAfter <= 0 {
+						t.Errorf("RetryAfter = %v, want > 0", d.RetryAfter)
+					}
+				}
+				if d.Limit != 2 {
+					t.Errorf("Limit = %d, want 2", d.Limit)
+				}
+			}
+			if pattern != "++-" {
+				t.Errorf("pattern = %s, want ++-", pattern)
+			}
+		})
+	}
+
+	// every script is loaded once with EVAL, later calls reuse it by SHA1
+	if got := f.count("EVAL"); got != 4 {
+		t.Errorf("EVAL count = %d, want 4", got)
+	}
+	if got := f.count("EVALSHA"); got != 12 {
+		t.Errorf("EVALSHA count = %d, want 12", got)
+	}
+	if got := f.count("AUTH"); got < 1 {
+		t.Errorf("AUTH count = %d, want at least 1", got)
+	}
+}
+
+func TestRedisRateLimitStore_WrongPassword(t *testing.T) {
+	f := startFakeRedis(t, "secret")
+	s := newTestRedisStore(t, f.addr(), "wrong")
+
+	_, err := s.Take(context.Background(), "client", RateLimitGCRA, RateLimitRule{Limit: 1, Interval: time.Second})
+	if err == nil {
+		t.Fatal("expected auth error")
+	}
+}
+
+func TestRedisRateLimitStore_UnreachableBacksOff(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	addr := ln.Addr().String()
+	ln.Close() // nothing listens there any more
+
+	s := newTestRedisStore(t, addr, "")
+	rule := RateLimitRule{Limit: 1, Interval: time.Second}
+
+	_, err = s.Take(context.Background(), "client", RateLimitFixedWindow, rule)
+	if !errors.Is(err, ErrServiceUnavailable) {
+		t.Fatalf("expected ErrServiceUnavailable, got %v", err)
+	}
+
+	// within the backoff the store answers immediately without dialing
+	start := time.Now()
+	_, err = s.Take(context.Background(), "client", RateLimitFixedWindow, rule)
+	if !errors.Is(err, ErrServiceUnavailable) {
+		t.Fatalf("expected ErrServiceUnavailable during backoff, got %v", err)
+	}
+	if time.Since(start) > 50*time.Millisecond {
+		t.Errorf("backoff call took %v, expected no dial", time.Since(start))
+	}
+}
+
+func TestRedisRateLimitStore_CanceledRequestKeepsStore(t *testing.T) {
+	f := startFakeRedis(t, "")
+	s := newTestRedisStore(t, f.addr(), "")
+	rule := RateLimitRule{Limit: 5, Interval: time.Minute}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := s.Take(ctx, "client", RateLimitFixedWindow, rule)
+	if !errors.Is(err, context.Canceled) || errors.Is(err, ErrServiceUnavailable) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+
+	// a request that gave up does not switch the replica to local limiting
+	if _, err := s.Take(context.Background(), "client", RateLimitFixedWindow, rule); err != nil {
+		t.Fatalf("Take after a canceled request: %v", err)
+	}
+}
+
+func TestRedisRateLimitStore_MissingAddress(t *testing.T) {
+	s := &implRedisRateLimitStore{}
+	if err := s.PostConstruct(); err == nil {
+		t.Fatal("expected error without ratelimit.redis.address")
+	}
+}
+
+func TestRateLimiter_SharedStoreAcrossReplicas(t *testing.T) {
+	f := startFakeRedis(t, "")
+	store := newTestRedisStore(t, f.addr(), "")
+
+	// two middlewares stand for two replicas behind one load balancer
+	replicas := []http.Handler{
+		newTestRateLimiterWith(3, time.Minute, func(rl *implRateLimiterMiddleware) { rl.Store = store }).
+			Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})),
+		newTestRateLimiterWith(3, time.Minute, func(rl *implRateLimiterMiddleware) { rl.Store = store }).
+			Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})),
+	}
+
+	var pattern string
+	for i := 0; i < 4; i++ {
+		r := httptest.NewRequest(http.MethodGet, "/api/data", nil)
+		r.Header.Set("X-Forwarded-For", "1.2.3.4")
+		w := httptest.NewRecorder()
+		replicas[i%2].ServeHTTP(w, r)
+		if w.Code == http.StatusOK {
+			pattern += "+"
+		} else {
+			pattern += "-"
+		}
+	}
+	if pattern != "+++-" {
+		t.Errorf("pattern = %s, want +++- (one budget across replicas)", pattern)
+	}
+}
+
+func TestRateLimiter_StoreFailureDegradesToLocal(t *testing.T) {
+	failing := &mockRateLimitStore{err: ErrServiceUnavailable}
+	rl := newTestRateLimiterWith(1, time.Minute, func(rl *implRateLimiterMiddleware) {
+		rl.Store = failing
+	})
+	handler := rl.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	do := func() int {
+		r := httptest.NewRequest(http.MethodGet, "/api/data", nil)
+		r.Header.Set("X-Forwarded-For", "1.2.3.4")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	if got := do(); got != http.StatusOK {
+		t.Fatalf("first: status = %d, want %d", got, http.StatusOK)
+	}
+	if got := do(); got != http.StatusTooManyRequests {
+		t.Fatalf("second: status = %d, want %d (local limit while store is down)", got, http.StatusTooManyRequests)
+	}
+	if !rl.degraded.Load() {
+		t.Error("expected middleware to be marked degraded")
+	}
+
+	// once the store answers again its decision is used
+	failing.err = nil
+	failing.result = RateLimitResult{Allowed: true, Limit: 1, Remaining: 0}
+	if got := do(); got != http.StatusOK {
+		t.Fatalf("recovered: status = %d, want %d", got, http.StatusOK)
+	}
+	if rl.degraded.Load() {
+		t.Error("expected middleware to recover")
+	}
+}
+
+type mockRateLimitStore struct {
+	result RateLimitResult
+	err    error
+}
+
+func (m *mockRateLimitStore) Take(ctx context.Context, key string, algorithm string, rule RateLimitRule) (RateLimitResult, error) {
+	return m.result, m.err
+}
@@ -0,0 +1,204 @@
+/*
+ * Copyright (c) 2026 Karagatan LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package servion
+
+import (
+	"container/list"
+	"context"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+type implMemoryRateLimitStore struct {
+	Shards        int           `value:"ratelimit.shards,default=16"`
+	MaxClients    int           `value:"ratelimit.max-clients,default=100000"`
+	CleanInterval time.Duration `value:"ratelimit.clean-interval,default=1m"`
+
+	store      *rateStore
+	algorithms map[string]rateAlgorithm
+
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+/*
+MemoryRateLimitStore creates a process-local RateLimitStore. It keeps the same
+sharded, capped buckets the middleware uses on its own, and is useful to share
+one budget between several rate limiters (for example the HTTP servers of one
+process) or as the reference for custom stores.
+
+Configuration properties:
+
+	ratelimit.shards          – number of independently locked bucket shards (default 16)
+	ratelimit.max-clients     – bucket cap, least recently seen clients are evicted (default 100000)
+	ratelimit.clean-interval  – how often idle buckets are dropped (default 1m)
+*/
+func MemoryRateLimitStore() RateLimitStore {
+	return &implMemoryRateLimitStore{}
+}
+
+func (t *implMemoryRateLimitStore) PostConstruct() error {
+	t.store = newRateStore(t.Shards, t.MaxClients)
+	t.algorithms = make(map[string]rateAlgorithm)
+	for _, name := range []string{RateLimitFixedWindow, RateLimitSlidingWindow, RateLimitTokenBucket, RateLimitGCRA} {
+		alg, _ := newRateAlgorithm(name)
+		t.algorithms[name] = alg
+	}
+	t.stopCh = make(chan struct{})
+	if t.CleanInterval > 0 {
+		t.wg.Add(1)
+		go t.cleanerLoop(t.stopCh)
+	}
+	return nil
+}
+
+func (t *implMemoryRateLimitStore) Destroy() error {
+	if t.stopCh != nil {
+		close(t.stopCh)
+		t.stopCh = nil
+	}
+	t.wg.Wait()
+	return nil
+}
+
+func (t *implMemoryRateLimitStore) Take(ctx context.Context, key string, algorithm string, rule RateLimitRule) (RateLimitResult, error) {
+	alg, ok := t.algorithms[rateAlgorithmName(algorithm)]
+	if !ok {
+		return RateLimitResult{}, xerrors.Errorf("unknown rate limit algorithm '%s'", algorithm)
+	}
+	return t.store.take(key, alg, rule, time.Now()), nil
+}
+
+func (t *implMemoryRateLimitStore) cleanerLoop(stopCh <-chan struct{}) {
+	defer t.wg.Done()
+
+	ticker := time.NewTicker(t.CleanInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+			t.store.cleanExpired(time.Now())
+		}
+	}
+}
+
+/*
+rateStore keeps rate buckets in independently locked shards, so concurrent
+clients do not serialize on one mutex. Each shard holds at most maxPerShard
+buckets; when full, the least recently seen bucket is evicted, which bounds memory
+even when an attacker rotates client keys.
+*/
+type rateStore struct {
+	shards []*rateShard
+}
+
+type rateShard struct {
+	mu      sync.Mutex
+	max     int
+	buckets map[string]*list.Element
+	lru     *list.List // front is most recently seen
+}
+
+type rateEntry struct {
+	key    string
+	bucket *rateBucket
+}
+
+func newRateStore(shards, maxClients int) *rateStore {
+	if shards < 1 {
+		shards = 1
+	}
+	perShard := 0
+	if maxClients > 0 {
+		perShard = (maxClients + shards - 1) / shards
+	}
+	s := &rateStore{shards: make([]*rateShard, shards)}
+	for i := range s.shards {
+		s.shards[i] = &rateShard{
+			max:     perShard,
+			buckets: make(map[string]*list.Element),
+			lru:     list.New(),
+		}
+	}
+	return s
+}
+
+func (t *rateStore) shard(key string) *rateShard {
+	if len(t.shards) == 1 {
+		return t.shards[0]
+	}
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return t.shards[h.Sum32()%uint32(len(t.shards))]
+}
+
+// take runs the algorithm against the bucket of key under the shard lock.
+func (t *rateStore) take(key string, alg rateAlgorithm, rule RateLimitRule, now time.Time) RateLimitResult {
+	s := t.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var b *rateBucket
+	if el, ok := s.buckets[key]; ok {
+		s.lru.MoveToFront(el)
+		b = el.Value.(*rateEntry).bucket
+	} else {
+		if s.max > 0 && s.lru.Len() >= s.max {
+			oldest := s.lru.Back()
+			s.lru.Remove(oldest)
+			delete(s.buckets, oldest.Value.(*rateEntry).key)
+		}
+		b = &rateBucket{}
+		s.buckets[key] = s.lru.PushFront(&rateEntry{key: key, bucket: b})
+	}
+	b.lastSeen = now
+	b.interval = rule.Interval
+	return alg.take(b, rule, now)
+}
+
+// cleanExpired drops buckets idle for more than five of their intervals.
+func (t *rateStore) cleanExpired(now time.Time) {
+	for _, s := range t.shards {
+		s.mu.Lock()
+		for el := s.lru.Back(); el != nil; {
+			prev := el.Prev()
+			e := el.Value.(*rateEntry)
+			if now.Sub(e.bucket.lastSeen) > e.bucket.interval*5 {
+				s.lru.Remove(el)
+				delete(s.buckets, e.key)
+			}
+			el = prev
+		}
+		s.mu.Unlock()
+	}
+}
+
+func (t *rateStore) size() int {
+	n := 0
+	for _, s := range t.shards {
+		s.mu.Lock()
+		n += s.lru.Len()
+		s.mu.Unlock()
+	}
+	return n
+}
+
+// get returns the bucket of key without touching its recency, for tests and stats.
+func (t *rateStore) get(key string) (*rateBucket, bool) {
+	s := t.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if el, ok := s.buckets[key]; ok {
+		return el.Value.(*rateEntry).bucket, true
+	}
+	return nil, false
+}
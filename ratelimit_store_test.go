@@ -0,0 +1,84 @@
+package servion
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRateStore_MaxClientsEvictsLeastRecent(t *testing.T) {
+	s := newRateStore(1, 3)
+	alg := fixedWindowAlgorithm{}
+	rule := RateLimitRule{Limit: 10, Interval: time.Minute}
+	now := time.Now()
+
+	s.take("a", alg, rule, now)
+	s.take("b", alg, rule, now)
+	s.take("c", alg, rule, now)
+	s.take("a", alg, rule, now) // a is now the most recent
+	s.take("d", alg, rule, now) // evicts b
+
+	if got := s.size(); got != 3 {
+		t.Fatalf("size = %d, want 3", got)
+	}
+	if _, ok := s.get("b"); ok {
+		t.Error("expected least recently seen client to be evicted")
+	}
+	for _, key := range []string{"a", "c", "d"} {
+		if _, ok := s.get(key); !ok {
+			t.Errorf("expected %s to remain", key)
+		}
+	}
+}
+
+func TestRateStore_Sharded(t *testing.T) {
+	s := newRateStore(8, 0)
+	alg := fixedWindowAlgorithm{}
+	rule := RateLimitRule{Limit: 1000, Interval: time.Minute}
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				s.take(fmt.Sprintf("client-%d-%d", g, i%10), alg, rule, time.Now())
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	if got := s.size(); got != 80 {
+		t.Fatalf("size = %d, want 80", got)
+	}
+	b, ok := s.get("client-3-7")
+	if !ok {
+		t.Fatal("expected bucket for client-3-7")
+	}
+	if b.count != 10 {
+		t.Errorf("count = %d, want 10", b.count)
+	}
+}
+
+func TestMemoryRateLimitStore_Take(t *testing.T) {
+	s := &implMemoryRateLimitStore{Shards: 2, MaxClients: 100}
+	if err := s.PostConstruct(); err != nil {
+		t.Fatalf("PostConstruct: %v", err)
+	}
+	defer s.Destroy()
+
+	rule := RateLimitRule{Limit: 1, Interval: time.Minute}
+	d, err := s.Take(context.Background(), "a", "", rule)
+	if err != nil || !d.Allowed {
+		t.Fatalf("first take: %+v, %v", d, err)
+	}
+	d, err = s.Take(context.Background(), "a", RateLimitFixedWindow, rule)
+	if err != nil || d.Allowed {
+		t.Fatalf("second take should be denied: %+v, %v", d, err)
+	}
+	if _, err := s.Take(context.Background(), "a", "leaky", rule); err == nil {
+		t.Error("expected error for unknown algorithm")
+	}
+}
@@ -0,0 +1,233 @@
+/*
+ * Copyright (c) 2026 Karagatan LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package servion
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/hex"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+/*
+redisClient is a minimal client for the Redis serialization protocol (RESP2),
+enough to run scripts against Redis, Valkey, KeyDB or any other server that
+speaks it. Keeping it in-house avoids pulling a full Redis driver into the core.
+Connections are pooled; a connection that saw an I/O error is discarded.
+*/
+type redisClient struct {
+	address  string
+	password string
+	db       int
+	timeout  time.Duration
+	tls      *tls.Config
+
+	pool chan *redisConn
+}
+
+type redisConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	w    *bufio.Writer
+}
+
+// redisError is an error reply sent by the server, the connection stays usable.
+type redisError string
+
+func (e redisError) Error() string { return string(e) }
+
+func newRedisClient(address, password string, db int, timeout time.Duration, poolSize int, tlsConfig *tls.Config) *redisClient {
+	if poolSize < 1 {
+		poolSize = 1
+	}
+	return &redisClient{
+		address:  address,
+		password: password,
+		db:       db,
+		timeout:  timeout,
+		tls:      tlsConfig,
+		pool:     make(chan *redisConn, poolSize),
+	}
+}
+
+func (t *redisClient) get(ctx context.Context) (*redisConn, error) {
+	select {
+	case c := <-t.pool:
+		return c, nil
+	default:
+	}
+
+	dialer := &net.Dialer{Timeout: t.timeout}
+	var conn net.Conn
+	var err error
+	if t.tls != nil {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: t.tls}).DialContext(ctx, "tcp", t.address)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", t.address)
+	}
+	if err != nil {
+		return nil, err
+	}
+	c := &redisConn{conn: conn, r: bufio.NewReader(conn), w: bufio.NewWriter(conn)}
+
+	if t.password != "" {
+		if _, err := t.roundTrip(ctx, c, "AUTH", t.password); err != nil {
+			conn.Close()
+			return nil, xerrors.Errorf("redis auth: %w", err)
+		}
+	}
+	if t.db != 0 {
+		if _, err := t.roundTrip(ctx, c, "SELECT", strconv.Itoa(t.db)); err != nil {
+			conn.Close()
+			return nil, xerrors.Errorf("redis select %d: %w", t.db, err)
+		}
+	}
+	return c, nil
+}
+
+func (t *redisClient) put(c *redisConn) {
+	select {
+	case t.pool <- c:
+	default:
+		c.conn.Close()
+	}
+}
+
+// do sends one command and returns its reply; error replies are returned as redisError.
+func (t *redisClient) do(ctx context.Context, args ...string) (interface{}, error) {
+	c, err := t.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	reply, err := t.roundTrip(ctx, c, args...)
+	var rerr redisError
+	if err != nil && !xerrors.As(err, &rerr) {
+		c.conn.Close()
+		return nil, err
+	}
+	t.put(c)
+	return reply, err
+}
+
+func (t *redisClient) roundTrip(ctx context.Context, c *redisConn, args ...string) (interface{}, error) {
+	deadline := time.Now().Add(t.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	c.conn.SetDeadline(deadline)
+
+	c.w.WriteString("*" + strconv.Itoa(len(args)) + "\r\n")
+	for _, arg := range args {
+		c.w.WriteString("$" + strconv.Itoa(len(arg)) + "\r\n")
+		c.w.WriteString(arg)
+		c.w.WriteString("\r\n")
+	}
+	if err := c.w.Flush(); err != nil {
+		return nil, err
+	}
+	return readRedisReply(c.r)
+}
+
+// evalScript runs the script by its SHA1 and loads it with EVAL on a NOSCRIPT reply.
+func (t *redisClient) evalScript(ctx context.Context, script *redisScript, keys []string, args ...string) (interface{}, error) {
+	cmd := make([]string, 0, 3+len(keys)+len(args))
+	cmd = append(cmd, "EVALSHA", script.sha, strconv.Itoa(len(keys)))
+	cmd = append(cmd, keys...)
+	cmd = append(cmd, args...)
+
+	reply, err := t.do(ctx, cmd...)
+	if err != nil && strings.HasPrefix(err.Error(), "NOSCRIPT") {
+		cmd[0], cmd[1] = "EVAL", script.src
+		reply, err = t.do(ctx, cmd...)
+	}
+	return reply, err
+}
+
+func (t *redisClient) close() {
+	for {
+		select {
+		case c := <-t.pool:
+			c.conn.Close()
+		default:
+			return
+		}
+	}
+}
+
+type redisScript struct {
+	src string
+	sha string
+}
+
+func newRedisScript(src string) *redisScript {
+	sum := sha1.Sum([]byte(src))
+	return &redisScript{src: src, sha: hex.EncodeToString(sum[:])}
+}
+
+func readRedisReply(r *bufio.Reader) (interface{}, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, xerrors.Errorf("redis: malformed reply line %q", line)
+	}
+	kind, payload := line[0], line[1:len(line)-2]
+
+	switch kind {
+	case '+':
+		return payload, nil
+	case '-':
+		return nil, redisError(payload)
+	case ':':
+		return strconv.ParseInt(payload, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(payload)
+		if err != nil {
+			return nil, xerrors.Errorf("redis: malformed bulk length %q", payload)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(payload)
+		if err != nil {
+			return nil, xerrors.Errorf("redis: malformed array length %q", payload)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		list := make([]interface{}, n)
+		for i := range list {
+			// error replies nested in an array are values, not failures of the array
+			item, err := readRedisReply(r)
+			var rerr redisError
+			if err != nil && !xerrors.As(err, &rerr) {
+				return nil, err
+			}
+			if err != nil {
+				item = rerr
+			}
+			list[i] = item
+		}
+		return list, nil
+	default:
+		return nil, xerrors.Errorf("redis: unknown reply type %q", kind)
+	}
+}
@@ -0,0 +1,60 @@
+package servion
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestReadRedisReply(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"+OK\r\n", "OK"},
+		{":42\r\n", "42"},
+		{"$5\r\nhello\r\n", "hello"},
+		{"$-1\r\n", "<nil>"},
+		{"*3\r\n:1\r\n$2\r\nab\r\n*1\r\n:7\r\n", "[1 ab [7]]"},
+		{"*2\r\n-ERR inner\r\n:1\r\n", "[ERR inner 1]"},
+	}
+	for _, tt := range tests {
+		reply, err := readRedisReply(bufio.NewReader(strings.NewReader(tt.input)))
+		if err != nil {
+			t.Errorf("readRedisReply(%q): %v", tt.input, err)
+			continue
+		}
+		if got := formatReply(reply); got != tt.want {
+			t.Errorf("readRedisReply(%q) = %s, want %s", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestReadRedisReply_Errors(t *testing.T) {
+	_, err := readRedisReply(bufio.NewReader(strings.NewReader("-NOSCRIPT No matching script\r\n")))
+	if _, ok := err.(redisError); !ok || !strings.HasPrefix(err.Error(), "NOSCRIPT") {
+		t.Errorf("expected NOSCRIPT redisError, got %v", err)
+	}
+
+	for _, bad := range []string{"?1\r\n", "+OK\n", "$x\r\n", "$5\r\nab"} {
+		if _, err := readRedisReply(bufio.NewReader(strings.NewReader(bad))); err == nil {
+			t.Errorf("readRedisReply(%q): expected error", bad)
+		}
+	}
+}
+
+func formatReply(v interface{}) string {
+	switch x := v.(type) {
+	case nil:
+		return "<nil>"
+	case []interface{}:
+		parts := make([]string, len(x))
+		for i, item := range x {
+			parts[i] = formatReply(item)
+		}
+		return "[" + strings.Join(parts, " ") + "]"
+	default:
+		return fmt.Sprint(x)
+	}
+}
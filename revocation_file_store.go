@@ -0,0 +1,272 @@
+/*
+ * Copyright (c) 2026 Karagatan LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package servion
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/xerrors"
+)
+
+// maxRevocationFileSize caps the revocation file read into memory.
+const maxRevocationFileSize = 16 << 20
+
+type implFileRevocationStore struct {
+	Log     *zap.Logger `inject:""`
+	Runtime Runtime     `inject:""`
+
+	// Revocation file, relative paths resolve against the home directory
+	File string `value:"revocation.file,default=revocations"`
+
+	ReloadInterval time.Duration `value:"revocation.reload-interval,default=10s"`
+
+	path    string
+	mu      sync.RWMutex
+	list    *revocationList
+	fileMod time.Time
+	stopCh  chan struct{}
+	wg      sync.WaitGroup
+}
+
+/*
+FileRevocationStore creates a RevocationStore appending revocations to a file
+under the home directory, one per line:
+
+	token <jti> <expires>
+	subject <subject> <issued-before> <expires>
+
+with RFC 3339 times. The file is checked every revocation.reload-interval, so
+revocations written by the "revoke" command or another instance sharing the
+file take effect without a restart. Expired lines are dropped on startup.
+
+Configuration properties:
+
+	revocation.file             – revocation file (default "revocations")
+	revocation.reload-interval  – how often the file is checked for changes (default 10s)
+*/
+func FileRevocationStore() RevocationStore {
+	return &implFileRevocationStore{}
+}
+
+// newFileRevocationStore opens the store at path outside of a context, for the revoke command.
+func newFileRevocationStore(path string) *implFileRevocationStore {
+	return &implFileRevocationStore{Log: zap.NewNop(), path: path, list: newRevocationList()}
+}
+
+func (t *implFileRevocationStore) PostConstruct() error {
+	t.path = t.File
+	if !filepath.IsAbs(t.path) {
+		t.path = filepath.Join(t.Runtime.HomeDir(), t.path)
+	}
+	t.list = newRevocationList()
+	if err := t.compact(); err != nil {
+		return err
+	}
+	if err := t.reload(); err != nil {
+		return err
+	}
+	t.stopCh = make(chan struct{})
+	if t.ReloadInterval > 0 {
+		t.wg.Add(1)
+		go t.reloadLoop(t.stopCh)
+	}
+	return nil
+}
+
+func (t *implFileRevocationStore) Destroy() error {
+	if t.stopCh != nil {
+		close(t.stopCh)
+		t.stopCh = nil
+	}
+	t.wg.Wait()
+	return nil
+}
+
+func (t *implFileRevocationStore) reloadLoop(stopCh <-chan struct{}) {
+	defer t.wg.Done()
+	ticker := time.NewTicker(t.ReloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := t.reload(); err != nil {
+				t.Log.Warn("RevocationsReloadFailed", zap.String("file", t.path), zap.Error(err))
+			}
+		case <-stopCh:
+			return
+		}
+	}
+}
+
+// reload parses the revocation file again when its modification time changed.
+func (t *implFileRevocationStore) reload() error {
+	fi, err := os.Stat(t.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return xerrors.Errorf("stat revocation file: %w", err)
+	}
+	t.mu.RLock()
+	unchanged := fi.ModTime().Equal(t.fileMod)
+	t.mu.RUnlock()
+	if unchanged {
+		return nil
+	}
+	list, err := t.read()
+	if err != nil {
+		return err
+	}
+	t.mu.Lock()
+	t.list = list
+	t.fileMod = fi.ModTime()
+	t.mu.Unlock()
+	t.Log.Info("RevocationsLoaded", zap.String("file", t.path), zap.Int("tokens", len(list.tokens)), zap.Int("subjects", len(list.subjects)))
+	return nil
+}
+
+func (t *implFileRevocationStore) read() (*revocationList, error) {
+	fi, err := os.Stat(t.path)
+	if err != nil {
+		return nil, xerrors.Errorf("stat revocation file: %w", err)
+	}
+	if fi.Size() > maxRevocationFileSize {
+		return nil, xerrors.Errorf("revocation file %s exceeds %d bytes", t.path, maxRevocationFileSize)
+	}
+	data, err := os.ReadFile(t.path)
+	if err != nil {
+		return nil, xerrors.Errorf("read revocation file: %w", err)
+	}
+	list, err := parseRevocations(data)
+	if err != nil {
+		return nil, xerrors.Errorf("%s:%w", t.path, err)
+	}
+	list.prune(time.Now())
+	return list, nil
+}
+
+// compact rewrites the revocation file without expired lines.
+func (t *implFileRevocationStore) compact() error {
+	if _, err := os.Stat(t.path); os.IsNotExist(err) {
+		return nil
+	}
+	list, err := t.read()
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	buf.WriteString("# revocations, written by the revoke command and the revocation handler\n")
+	for jti, expires := range list.tokens {
+		buf.WriteString(formatTokenRevocation(jti, expires))
+	}
+	for subject, r := range list.subjects {
+		buf.WriteString(formatSubjectRevocation(subject, r.before, r.expires))
+	}
+	tmp := t.path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0600); err != nil {
+		return xerrors.Errorf("write revocation file: %w", err)
+	}
+	if err := os.Rename(tmp, t.path); err != nil {
+		return xerrors.Errorf("replace revocation file: %w", err)
+	}
+	return nil
+}
+
+func (t *implFileRevocationStore) RevokeToken(jti string, expires time.Time) error {
+	if err := validRevocationID("jti", jti); err != nil {
+		return err
+	}
+	if err := t.append(formatTokenRevocation(jti, expires)); err != nil {
+		return err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.list.revokeToken(jti, expires)
+	return nil
+}
+
+func (t *implFileRevocationStore) RevokeSubject(subject string, before, expires time.Time) error {
+	if err := validRevocationID("subject", subject); err != nil {
+		return err
+	}
+	if err := t.append(formatSubjectRevocation(subject, before, expires)); err != nil {
+		return err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.list.revokeSubject(subject, before, expires)
+	return nil
+}
+
+func (t *implFileRevocationStore) IsRevoked(jti, subject string, issuedAt time.Time) (bool, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.list.isRevoked(jti, subject, issuedAt, time.Now()), nil
+}
+
+func (t *implFileRevocationStore) append(line string) error {
+	if err := os.MkdirAll(filepath.Dir(t.path), 0700); err != nil {
+		return xerrors.Errorf("create revocation directory: %w", err)
+	}
+	f, err := os.OpenFile(t.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return xerrors.Errorf("open revocation file: %w", err)
+	}
+	if _, err := f.WriteString(line); err != nil {
+		f.Close()
+		return xerrors.Errorf("write revocation file: %w", err)
+	}
+	return f.Close()
+}
+
+func formatTokenRevocation(jti string, expires time.Time) string {
+	return fmt.Sprintf("token %s %s\n", jti, expires.UTC().Format(time.RFC3339Nano))
+}
+
+func formatSubjectRevocation(subject string, before, expires time.Time) string {
+	return fmt.Sprintf("subject %s %s %s\n", subject, before.UTC().Format(time.RFC3339Nano), expires.UTC().Format(time.RFC3339Nano))
+}
+
+// parseRevocations parses the revocation file; comments start with '#'.
+func parseRevocations(data []byte) (*revocationList, error) {
+	list := newRevocationList()
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		times := make([]time.Time, 0, 2)
+		for _, f := range fields[min(2, len(fields)):] {
+			ts, err := time.Parse(time.RFC3339, f)
+			if err != nil {
+				return nil, xerrors.Errorf("%d: invalid time '%s'", n, f)
+			}
+			times = append(times, ts)
+		}
+		switch {
+		case fields[0] == "token" && len(fields) == 3:
+			list.revokeToken(fields[1], times[0])
+		case fields[0] == "subject" && len(fields) == 4:
+			list.revokeSubject(fields[1], times[0], times[1])
+		default:
+			return nil, xerrors.Errorf("%d: expected 'token <jti> <expires>' or 'subject <subject> <before> <expires>'", n)
+		}
+	}
+	return list, scanner.Err()
+}
@@ -0,0 +1,139 @@
+/*
+ * Copyright (c) 2026 Karagatan LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package servion
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+	"golang.org/x/xerrors"
+)
+
+type implRevocationHandler struct {
+	Log         *zap.Logger     `inject:""`
+	Revocations RevocationStore `inject:""`
+
+	HandlerPattern string `value:"revocation.pattern,default=/admin/revocations"`
+
+	// Role callers need to revoke tokens
+	Role string `value:"revocation.role,default=admin"`
+
+	// How long subject revocations, and token revocations without a known expiry, are kept
+	Retention time.Duration `value:"revocation.retention,default=24h"`
+}
+
+/*
+revocationRequest names what to revoke: a token, by itself or by its jti, or
+every token of a subject issued before a time (default now).
+*/
+type revocationRequest struct {
+	Token   string    `json:"token,omitempty"`
+	Jti     string    `json:"jti,omitempty"`
+	Expires time.Time `json:"expires,omitempty"`
+	Subject string    `json:"subject,omitempty"`
+	Before  time.Time `json:"before,omitempty"`
+}
+
+/*
+RevocationHandler creates an HttpHandler revoking JWTs through the
+RevocationStore of the context. Callers need revocation.role; serve it from an
+admin server or behind AuthMiddleware. POST one of
+
+	{"token": "<jwt>"}
+	{"jti": "<jti>", "expires": "2026-01-02T15:04:05Z"}
+	{"subject": "alice", "before": "2026-01-02T15:04:05Z"}
+
+A token is revoked by its jti until its exp. A subject revocation refuses the
+tokens of the subject issued before "before", now when omitted, and is kept for
+revocation.retention, which must exceed the lifetime of issued tokens.
+
+Configuration properties:
+
+	revocation.pattern    – URL pattern (default "/admin/revocations")
+	revocation.role       – role required to revoke (default "admin")
+	revocation.retention  – how long subject revocations are kept (default 24h)
+*/
+func RevocationHandler() HttpHandler {
+	return &implRevocationHandler{}
+}
+
+func (t *implRevocationHandler) Pattern() string {
+	return t.HandlerPattern
+}
+
+func (t *implRevocationHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	auth, ok := AuthFromContext(r.Context())
+	if !ok {
+		http.Error(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+	if !containsString(auth.Roles, t.Role) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	var req revocationRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, 64<<10)).Decode(&req); err != nil {
+		http.Error(w, "invalid revocation request", http.StatusBadRequest)
+		return
+	}
+	if err := req.apply(t.Revocations, t.Retention, time.Now()); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	t.Log.Info("Revoked",
+		zap.String("by", auth.Subject),
+		zap.String("jti", req.Jti),
+		zap.String("subject", req.Subject),
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(req)
+}
+
+// apply revokes what req names in store, filling in the jti, expires and before it resolved.
+func (req *revocationRequest) apply(store RevocationStore, retention time.Duration, now time.Time) error {
+	if req.Token != "" {
+		claims := jwt.MapClaims{}
+		if _, _, err := jwt.NewParser().ParseUnverified(req.Token, claims); err != nil {
+			return xerrors.New("token is not a JWT")
+		}
+		req.Jti = claimString(claims, "jti")
+		if req.Jti == "" {
+			return xerrors.New("token has no jti claim, revoke its subject instead")
+		}
+		if exp, err := claims.GetExpirationTime(); err == nil && exp != nil {
+			req.Expires = exp.Time
+		}
+		req.Token = ""
+	}
+
+	switch {
+	case req.Jti != "" && req.Subject != "":
+		return xerrors.New("revoke either a token or a subject")
+	case req.Jti != "":
+		if req.Expires.IsZero() {
+			req.Expires = now.Add(retention)
+		}
+		return store.RevokeToken(req.Jti, req.Expires)
+	case req.Subject != "":
+		if req.Before.IsZero() {
+			req.Before = now
+		}
+		req.Expires = req.Before.Add(retention)
+		return store.RevokeSubject(req.Subject, req.Before, req.Expires)
+	}
+	return xerrors.New("nothing to revoke, expected token, jti or subject")
+}
@@ -0,0 +1,83 @@
+package servion
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+)
+
+func newRevocationHandler(store RevocationStore) *implRevocationHandler {
+	return &implRevocationHandler{
+		Log:            zap.NewNop(),
+		Revocations:    store,
+		HandlerPattern: "/admin/revocations",
+		Role:           "admin",
+		Retention:      24 * time.Hour,
+	}
+}
+
+func postRevocation(h http.Handler, body string, auth *AuthInfo) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, "/admin/revocations", strings.NewReader(body))
+	if auth != nil {
+		r = r.WithContext(ContextWithAuth(context.Background(), *auth))
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+	return rec
+}
+
+func TestRevocationHandler(t *testing.T) {
+	store := MemoryRevocationStore()
+	h := newRevocationHandler(store)
+	admin := &AuthInfo{Subject: "ops", Roles: []string{"admin"}}
+
+	exp := time.Now().Add(time.Hour).Truncate(time.Second)
+	token := makeHmacToken(t, "any-secret", jwt.MapClaims{"sub": "bob", "jti": "t-1", "exp": jwt.NewNumericDate(exp)})
+	rec := postRevocation(h, `{"token":"`+token+`"}`, admin)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("revoke token: %d %s", rec.Code, rec.Body)
+	}
+	var resp revocationRequest
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	if resp.Jti != "t-1" || !resp.Expires.Equal(exp) || resp.Token != "" {
+		t.Errorf("response %+v", resp)
+	}
+	if revoked, _ := store.IsRevoked("t-1", "bob", time.Now()); !revoked {
+		t.Error("token not revoked")
+	}
+
+	if rec := postRevocation(h, `{"subject":"alice"}`, admin); rec.Code != http.StatusOK {
+		t.Fatalf("revoke subject: %d %s", rec.Code, rec.Body)
+	}
+	if revoked, _ := store.IsRevoked("", "alice", time.Now().Add(-time.Minute)); !revoked {
+		t.Error("subject not revoked")
+	}
+
+	for _, body := range []string{`{}`, `{"jti":"a","subject":"b"}`, `{"token":"garbage"}`, `not json`} {
+		if rec := postRevocation(h, body, admin); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: %d", body, rec.Code)
+		}
+	}
+}
+
+func TestRevocationHandler_Access(t *testing.T) {
+	h := newRevocationHandler(MemoryRevocationStore())
+	if rec := postRevocation(h, `{"subject":"alice"}`, nil); rec.Code != http.StatusUnauthorized {
+		t.Errorf("anonymous: %d", rec.Code)
+	}
+	if rec := postRevocation(h, `{"subject":"alice"}`, &AuthInfo{Subject: "bob", Roles: []string{"user"}}); rec.Code != http.StatusForbidden {
+		t.Errorf("without role: %d", rec.Code)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/revocations", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET: %d", rec.Code)
+	}
+}
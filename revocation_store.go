@@ -0,0 +1,126 @@
+/*
+ * Copyright (c) 2026 Karagatan LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package servion
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+type subjectRevocation struct {
+	before  time.Time
+	expires time.Time
+}
+
+// revocationList holds the revocations of a store; the store guards it.
+type revocationList struct {
+	tokens   map[string]time.Time
+	subjects map[string]subjectRevocation
+}
+
+func newRevocationList() *revocationList {
+	return &revocationList{
+		tokens:   make(map[string]time.Time),
+		subjects: make(map[string]subjectRevocation),
+	}
+}
+
+func (l *revocationList) revokeToken(jti string, expires time.Time) {
+	if exp, ok := l.tokens[jti]; !ok || expires.After(exp) {
+		l.tokens[jti] = expires
+	}
+}
+
+func (l *revocationList) revokeSubject(subject string, before, expires time.Time) {
+	r := l.subjects[subject]
+	if before.After(r.before) {
+		r.before = before
+	}
+	if expires.After(r.expires) {
+		r.expires = expires
+	}
+	l.subjects[subject] = r
+}
+
+func (l *revocationList) isRevoked(jti, subject string, issuedAt, now time.Time) bool {
+	if jti != "" {
+		if exp, ok := l.tokens[jti]; ok && now.Before(exp) {
+			return true
+		}
+	}
+	if subject != "" {
+		if r, ok := l.subjects[subject]; ok && now.Before(r.expires) && issuedAt.Before(r.before) {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *revocationList) prune(now time.Time) {
+	for jti, exp := range l.tokens {
+		if !now.Before(exp) {
+			delete(l.tokens, jti)
+		}
+	}
+	for subject, r := range l.subjects {
+		if !now.Before(r.expires) {
+			delete(l.subjects, subject)
+		}
+	}
+}
+
+// validRevocationID refuses IDs that would break the line format of the revocation file.
+func validRevocationID(kind, id string) error {
+	if id == "" || strings.ContainsAny(id, " \t\r\n#") {
+		return xerrors.Errorf("%s '%s' must be non-empty without spaces or '#'", kind, id)
+	}
+	return nil
+}
+
+type implMemoryRevocationStore struct {
+	mu   sync.RWMutex
+	list *revocationList
+}
+
+/*
+MemoryRevocationStore creates a process-local RevocationStore. Revocations are
+lost on restart and not shared between replicas, which suits tests and single
+instances issuing short-lived tokens.
+*/
+func MemoryRevocationStore() RevocationStore {
+	return &implMemoryRevocationStore{list: newRevocationList()}
+}
+
+func (t *implMemoryRevocationStore) RevokeToken(jti string, expires time.Time) error {
+	if err := validRevocationID("jti", jti); err != nil {
+		return err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.list.prune(time.Now())
+	t.list.revokeToken(jti, expires)
+	return nil
+}
+
+func (t *implMemoryRevocationStore) RevokeSubject(subject string, before, expires time.Time) error {
+	if err := validRevocationID("subject", subject); err != nil {
+		return err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.list.prune(time.Now())
+	t.list.revokeSubject(subject, before, expires)
+	return nil
+}
+
+func (t *implMemoryRevocationStore) IsRevoked(jti, subject string, issuedAt time.Time) (bool, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.list.isRevoked(jti, subject, issuedAt, time.Now()), nil
+}
@@ -0,0 +1,145 @@
+package servion
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func testRevocationStore(t *testing.T, store RevocationStore) {
+	t.Helper()
+	now := time.Now()
+	if err := store.RevokeToken("jti-1", now.Add(time.Hour)); err != nil {
+		t.Fatalf("RevokeToken: %v", err)
+	}
+	if err := store.RevokeToken("jti-old", now.Add(-time.Second)); err != nil {
+		t.Fatalf("RevokeToken: %v", err)
+	}
+	if err := store.RevokeSubject("alice", now, now.Add(time.Hour)); err != nil {
+		t.Fatalf("RevokeSubject: %v", err)
+	}
+
+	cases := []struct {
+		jti, subject string
+		issuedAt     time.Time
+		revoked      bool
+	}{
+		{"jti-1", "bob", now, true},
+		{"jti-2", "bob", now, false},
+		{"jti-old", "bob", now, false},
+		{"jti-3", "alice", now.Add(-time.Minute), true},
+		{"jti-3", "alice", time.Time{}, true},
+		{"jti-4", "alice", now.Add(time.Minute), false},
+	}
+	for _, c := range cases {
+		revoked, err := store.IsRevoked(c.jti, c.subject, c.issuedAt)
+		if err != nil || revoked != c.revoked {
+			t.Errorf("IsRevoked(%s, %s) = %v, %v; want %v", c.jti, c.subject, revoked, err, c.revoked)
+		}
+	}
+
+	if err := store.RevokeToken("two words", now.Add(time.Hour)); err == nil {
+		t.Error("jti with a space accepted")
+	}
+	if err := store.RevokeSubject("", now, now.Add(time.Hour)); err == nil {
+		t.Error("empty subject accepted")
+	}
+}
+
+func TestMemoryRevocationStore(t *testing.T) {
+	testRevocationStore(t, MemoryRevocationStore())
+}
+
+func newFileRevocationStoreBean(t *testing.T, path string) *implFileRevocationStore {
+	t.Helper()
+	s := &implFileRevocationStore{Log: zap.NewNop(), Runtime: &mockRuntime{}, File: path}
+	if err := s.PostConstruct(); err != nil {
+		t.Fatalf("PostConstruct: %v", err)
+	}
+	t.Cleanup(func() { s.Destroy() })
+	return s
+}
+
+func TestFileRevocationStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "revocations")
+	testRevocationStore(t, newFileRevocationStoreBean(t, path))
+
+	// a new instance reads the revocations back, dropping the expired one
+	s := newFileRevocationStoreBean(t, path)
+	if revoked, _ := s.IsRevoked("jti-1", "", time.Now()); !revoked {
+		t.Error("jti-1 not revoked after restart")
+	}
+	if revoked, _ := s.IsRevoked("", "alice", time.Now().Add(-time.Minute)); !revoked {
+		t.Error("alice not revoked after restart")
+	}
+	data, _ := os.ReadFile(path)
+	if strings.Contains(string(data), "jti-old") {
+		t.Errorf("expired revocation kept:\n%s", data)
+	}
+}
+
+func TestFileRevocationStore_Reload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "revocations")
+	s := newFileRevocationStoreBean(t, path)
+
+	// another process, e.g. the revoke command, appends to the file
+	other := newFileRevocationStore(path)
+	if err := other.RevokeToken("jti-9", time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	future := time.Now().Add(time.Minute)
+	os.Chtimes(path, future, future)
+	if err := s.reload(); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if revoked, _ := s.IsRevoked("jti-9", "", time.Now()); !revoked {
+		t.Error("revocation of another process not picked up")
+	}
+}
+
+func TestFileRevocationStore_SubSecond(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "revocations")
+	s := newFileRevocationStoreBean(t, path)
+
+	// a token issued earlier within the same second as the revocation
+	before := time.Now().Truncate(time.Second).Add(500 * time.Millisecond)
+	issuedAt := before.Add(-250 * time.Millisecond)
+	if err := s.RevokeSubject("alice", before, before.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded := newFileRevocationStoreBean(t, path)
+	if revoked, _ := reloaded.IsRevoked("", "alice", issuedAt); !revoked {
+		t.Error("token issued in the second of the revocation valid after reload")
+	}
+	if revoked, _ := reloaded.IsRevoked("", "alice", before.Add(time.Millisecond)); revoked {
+		t.Error("token issued after the revocation revoked after reload")
+	}
+}
+
+func TestParseRevocations(t *testing.T) {
+	list, err := parseRevocations([]byte(`# comment
+token abc 2030-01-01T00:00:00Z
+subject alice 2029-01-01T00:00:00Z 2030-01-01T00:00:00Z  # trailing comment
+`))
+	if err != nil {
+		t.Fatalf("parseRevocations: %v", err)
+	}
+	if len(list.tokens) != 1 || len(list.subjects) != 1 {
+		t.Errorf("tokens = %v, subjects = %v", list.tokens, list.subjects)
+	}
+	for _, bad := range []string{
+		"token abc",
+		"token abc tomorrow",
+		"subject alice 2029-01-01T00:00:00Z",
+		"user alice 2029-01-01T00:00:00Z",
+	} {
+		if _, err := parseRevocations([]byte(bad)); err == nil {
+			t.Errorf("%q: expected error", bad)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package servion
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRevokeCommand(t *testing.T) {
+	home := t.TempDir()
+	var out bytes.Buffer
+	cmd := &implRevokeCommand{HomeDir: home, File: "revocations", Subject: "alice", Retention: "7d", out: &out}
+	if err := cmd.Run(context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if !strings.Contains(out.String(), "revoked tokens of alice") {
+		t.Errorf("output %q", out.String())
+	}
+	cmd = &implRevokeCommand{HomeDir: home, File: "revocations", Jti: "t-1", Retention: "1h", out: io.Discard}
+	if err := cmd.Run(context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	s := newFileRevocationStoreBean(t, filepath.Join(home, "revocations"))
+	if revoked, _ := s.IsRevoked("t-1", "", time.Now()); !revoked {
+		t.Error("jti not revoked")
+	}
+	if revoked, _ := s.IsRevoked("", "alice", time.Now().Add(-time.Second)); !revoked {
+		t.Error("subject not revoked")
+	}
+}
+
+func TestRevokeCommand_InvalidOptions(t *testing.T) {
+	home := t.TempDir()
+	for _, cmd := range []*implRevokeCommand{
+		{Retention: "24h"},
+		{Subject: "alice", Retention: "forever"},
+		{Subject: "alice", Before: "yesterday", Retention: "24h"},
+		{Subject: "alice", Jti: "t-1", Retention: "24h"},
+	} {
+		cmd.HomeDir, cmd.File, cmd.out = home, "revocations", io.Discard
+		if err := cmd.Run(context.Background()); err == nil {
+			t.Errorf("%+v: expected error", cmd)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+/*
+ * Copyright (c) 2026 Karagatan LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package servion
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+// minCookieSecret is the shortest secret accepted for sealing cookies.
+const minCookieSecret = 32
+
+// maxCookieSize keeps sealed cookies under the 4KB browsers are required to store.
+const maxCookieSize = 4000
+
+var errInvalidCookie = xerrors.New("invalid cookie")
+
+/*
+cookieCodec seals values into cookies with AES-256-GCM, which both encrypts and
+authenticates them. The cookie name is bound as additional data, so a value can
+not be replayed under another name, and the expiry is sealed with the value.
+*/
+type cookieCodec struct {
+	aead cipher.AEAD
+}
+
+func newCookieCodec(secret string) (*cookieCodec, error) {
+	if len(secret) < minCookieSecret {
+		return nil, xerrors.Errorf("cookie secret must be at least %d characters", minCookieSecret)
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("servion cookie encryption"))
+	block, err := aes.NewCipher(mac.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &cookieCodec{aead: aead}, nil
+}
+
+// seal encodes v as JSON and returns the cookie value, valid until expires.
+func (c *cookieCodec) seal(name string, v interface{}, expires time.Time) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	plain := make([]byte, 8, 8+len(data))
+	binary.BigEndian.PutUint64(plain, uint64(expires.Unix()))
+	plain = append(plain, data...)
+
+	nonce := make([]byte, c.aead.NonceSize(), c.aead.NonceSize()+len(plain)+c.aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	value := base64.RawURLEncoding.EncodeToString(c.aead.Seal(nonce, nonce, plain, []byte(name)))
+	if len(name)+len(value) > maxCookieSize {
+		return "", xerrors.Errorf("cookie %s of %d bytes is too large", name, len(value))
+	}
+	return value, nil
+}
+
+// open decodes a value sealed under name into v; tampered or expired values are errInvalidCookie.
+func (c *cookieCodec) open(name, value string, v interface{}) error {
+	sealed, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(sealed) < c.aead.NonceSize() {
+		return errInvalidCookie
+	}
+	nonce, sealed := sealed[:c.aead.NonceSize()], sealed[c.aead.NonceSize():]
+	plain, err := c.aead.Open(nil, nonce, sealed, []byte(name))
+	if err != nil || len(plain) < 8 {
+		return errInvalidCookie
+	}
+	if time.Now().Unix() >= int64(binary.BigEndian.Uint64(plain)) {
+		return errInvalidCookie
+	}
+	if err := json.Unmarshal(plain[8:], v); err != nil {
+		return errInvalidCookie
+	}
+	return nil
+}
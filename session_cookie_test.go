@@ -0,0 +1,65 @@
+package servion
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCookieCodec_RoundTrip(t *testing.T) {
+	codec, err := newCookieCodec(strings.Repeat("s", 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, err := codec.seal("session", map[string]string{"sub": "alice"}, time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(value, "alice") {
+		t.Error("cookie value is not encrypted")
+	}
+
+	var got map[string]string
+	if err := codec.open("session", value, &got); err != nil || got["sub"] != "alice" {
+		t.Fatalf("open = %v, %v", got, err)
+	}
+
+	// bound to the cookie name
+	if err := codec.open("other", value, &got); !errors.Is(err, errInvalidCookie) {
+		t.Errorf("other name: error = %v", err)
+	}
+	// tampering is detected
+	tampered := []byte(value)
+	tampered[len(tampered)/2] ^= 1
+	if err := codec.open("session", string(tampered), &got); !errors.Is(err, errInvalidCookie) {
+		t.Errorf("tampered: error = %v", err)
+	}
+	// another secret can not open it
+	otherCodec, _ := newCookieCodec(strings.Repeat("t", 32))
+	if err := otherCodec.open("session", value, &got); !errors.Is(err, errInvalidCookie) {
+		t.Errorf("other secret: error = %v", err)
+	}
+}
+
+func TestCookieCodec_Expiry(t *testing.T) {
+	codec, _ := newCookieCodec(strings.Repeat("s", 32))
+	value, err := codec.seal("session", "x", time.Now().Add(-time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got string
+	if err := codec.open("session", value, &got); !errors.Is(err, errInvalidCookie) {
+		t.Errorf("expired: error = %v", err)
+	}
+}
+
+func TestCookieCodec_Limits(t *testing.T) {
+	if _, err := newCookieCodec("short"); err == nil {
+		t.Error("expected error for a short secret")
+	}
+	codec, _ := newCookieCodec(strings.Repeat("s", 32))
+	if _, err := codec.seal("session", strings.Repeat("x", maxCookieSize), time.Now().Add(time.Minute)); err == nil {
+		t.Error("expected error for an oversized cookie")
+	}
+}
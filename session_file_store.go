@@ -0,0 +1,170 @@
+/*
+ * Copyright (c) 2026 Karagatan LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package servion
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/xerrors"
+)
+
+type implFileSessionStore struct {
+	Log     *zap.Logger `inject:""`
+	Runtime Runtime     `inject:""`
+
+	// Directory of session files, relative paths resolve against the home directory
+	Dir string `value:"session.dir,default=sessions"`
+
+	CleanInterval time.Duration `value:"session.clean-interval,default=1m"`
+
+	dir    string
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+/*
+FileSessionStore creates a SessionStore keeping one file per session under the
+home directory, so sessions survive restarts of a single instance. Files are
+named by a hash of the session ID and readable by the owner only.
+
+Configuration properties:
+
+	session.dir             – directory of session files (default "sessions")
+	session.clean-interval  – how often expired session files are removed (default 1m)
+*/
+func FileSessionStore() SessionStore {
+	return &implFileSessionStore{}
+}
+
+func (t *implFileSessionStore) PostConstruct() error {
+	t.dir = t.Dir
+	if !filepath.IsAbs(t.dir) {
+		t.dir = filepath.Join(t.Runtime.HomeDir(), t.dir)
+	}
+	if err := os.MkdirAll(t.dir, 0700); err != nil {
+		return xerrors.Errorf("create session directory: %w", err)
+	}
+	t.stopCh = make(chan struct{})
+	if t.CleanInterval > 0 {
+		t.wg.Add(1)
+		go t.cleanerLoop(t.stopCh)
+	}
+	return nil
+}
+
+func (t *implFileSessionStore) Destroy() error {
+	if t.stopCh != nil {
+		close(t.stopCh)
+		t.stopCh = nil
+	}
+	t.wg.Wait()
+	return nil
+}
+
+func (t *implFileSessionStore) Load(id string) (*Session, error) {
+	e, err := t.read(t.fileOf(id))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	// the hash matched, make sure the ID did too
+	if e.Session == nil || e.Session.ID != id || !time.Now().Before(e.Expires) {
+		return nil, nil
+	}
+	return e.Session, nil
+}
+
+func (t *implFileSessionStore) Store(s *Session, expires time.Time) error {
+	data, err := json.Marshal(sessionEntry{Session: s, Expires: expires})
+	if err != nil {
+		return err
+	}
+	name := t.fileOf(s.ID)
+	tmp, err := os.CreateTemp(t.dir, ".tmp-*")
+	if err != nil {
+		return xerrors.Errorf("store session: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return xerrors.Errorf("store session: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return xerrors.Errorf("store session: %w", err)
+	}
+	// rename is atomic, readers never see a partial file
+	if err := os.Rename(tmp.Name(), name); err != nil {
+		return xerrors.Errorf("store session: %w", err)
+	}
+	return nil
+}
+
+func (t *implFileSessionStore) Delete(id string) error {
+	if err := os.Remove(t.fileOf(id)); err != nil && !os.IsNotExist(err) {
+		return xerrors.Errorf("delete session: %w", err)
+	}
+	return nil
+}
+
+func (t *implFileSessionStore) fileOf(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return filepath.Join(t.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+func (t *implFileSessionStore) read(name string) (*sessionEntry, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	var e sessionEntry
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, xerrors.Errorf("session file %s: %w", name, err)
+	}
+	return &e, nil
+}
+
+func (t *implFileSessionStore) cleanerLoop(stopCh <-chan struct{}) {
+	defer t.wg.Done()
+
+	ticker := time.NewTicker(t.CleanInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopCh:
+			return
+		case now := <-ticker.C:
+			t.removeExpired(now)
+		}
+	}
+}
+
+func (t *implFileSessionStore) removeExpired(now time.Time) {
+	entries, err := os.ReadDir(t.dir)
+	if err != nil {
+		t.Log.Warn("SessionCleanFailed", zap.String("dir", t.dir), zap.Error(err))
+		return
+	}
+	for _, de := range entries {
+		if !strings.HasSuffix(de.Name(), ".json") {
+			continue
+		}
+		name := filepath.Join(t.dir, de.Name())
+		if e, err := t.read(name); err != nil || !now.Before(e.Expires) {
+			os.Remove(name)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+/*
+ * Copyright (c) 2026 Karagatan LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package servion
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/xerrors"
+)
+
+// sessionTouchInterval bounds how often the idle timer of a session is written back.
+const sessionTouchInterval = time.Minute
+
+type implSessionManager struct {
+	Log   *zap.Logger  `inject:""`
+	Store SessionStore `inject:"optional"`
+
+	CookieName     string `value:"session.cookie-name,default=servion_sid"`
+	CookiePath     string `value:"session.cookie-path,default=/"`
+	CookieDomain   string `value:"session.cookie-domain,default="`
+	CookieInsecure bool   `value:"session.cookie-insecure,default=false"`
+
+	// SameSite of the cookie: lax, strict or none
+	CookieSameSite string `value:"session.cookie-same-site,default=lax"`
+
+	// Sessions end after this long without a request
+	IdleTimeout time.Duration `value:"session.idle-timeout,default=30m"`
+
+	// Sessions end this long after login, however active
+	AbsoluteTimeout time.Duration `value:"session.absolute-timeout,default=12h"`
+
+	sameSite   http.SameSite
+	localStore *implMemorySessionStore
+}
+
+/*
+CookieSessionManager creates the SessionManager of the context. The cookie carries
+only a random session ID; the session lives in the SessionStore bean of the
+context (see FileSessionStore), or in memory when there is none.
+
+A session ends after session.idle-timeout without requests or
+session.absolute-timeout after it started, whichever comes first. Start always
+issues a new ID, and Renew moves a session to a new ID, so an ID seen before a
+privilege change is worthless after it.
+
+Configuration properties:
+
+	session.cookie-name       – session cookie name (default "servion_sid")
+	session.cookie-path       – cookie path (default "/")
+	session.cookie-domain     – cookie domain, empty for the host only
+	session.cookie-insecure   – drop the Secure flag, for plain http development (default false)
+	session.cookie-same-site  – lax, strict or none (default lax)
+	session.idle-timeout      – inactivity limit (default 30m)
+	session.absolute-timeout  – lifetime limit (default 12h)
+*/
+func CookieSessionManager() SessionManager {
+	return &implSessionManager{}
+}
+
+func (t *implSessionManager) PostConstruct() error {
+	switch strings.ToLower(t.CookieSameSite) {
+	case "lax", "":
+		t.sameSite = http.SameSiteLaxMode
+	case "strict":
+		t.sameSite = http.SameSiteStrictMode
+	case "none":
+		if t.CookieInsecure {
+			return xerrors.New("session.cookie-same-site=none requires a secure cookie")
+		}
+		t.sameSite = http.SameSiteNoneMode
+	default:
+		return xerrors.Errorf("session.cookie-same-site must be lax, strict or none, got '%s'", t.CookieSameSite)
+	}
+	if t.IdleTimeout <= 0 || t.AbsoluteTimeout <= 0 {
+		return xerrors.New("session.idle-timeout and session.absolute-timeout must be positive")
+	}
+	if t.Store == nil {
+		t.localStore = &implMemorySessionStore{CleanInterval: time.Minute}
+		if err := t.localStore.PostConstruct(); err != nil {
+			return err
+		}
+		t.Store = t.localStore
+	}
+	return nil
+}
+
+func (t *implSessionManager) Destroy() error {
+	if t.localStore != nil {
+		return t.localStore.Destroy()
+	}
+	return nil
+}
+
+func (t *implSessionManager) AuthenticateSession(r *http.Request) (AuthInfo, bool, error) {
+	s, err := t.Session(r)
+	if err != nil || s == nil || s.Auth.Subject == "" {
+		return AuthInfo{}, false, err
+	}
+	return s.Auth, true, nil
+}
+
+func (t *implSessionManager) Session(r *http.Request) (*Session, error) {
+	c, err := r.Cookie(t.CookieName)
+	if err != nil || c.Value == "" {
+		return nil, nil
+	}
+	s, err := t.Store.Load(c.Value)
+	if err != nil || s == nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if now.Sub(s.LastSeen) >= t.IdleTimeout || now.Sub(s.Created) >= t.AbsoluteTimeout {
+		return nil, t.Store.Delete(s.ID)
+	}
+	if now.Sub(s.LastSeen) >= min(sessionTouchInterval, t.IdleTimeout/4) {
+		s.LastSeen = now
+		if err := t.Store.Store(s, t.expiresAt(s)); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func (t *implSessionManager) Start(w http.ResponseWriter, r *http.Request, info AuthInfo) (*Session, error) {
+	if old, _ := r.Cookie(t.CookieName); old != nil && old.Value != "" {
+		if err := t.Store.Delete(old.Value); err != nil {
+			return nil, err
+		}
+	}
+	now := time.Now()
+	s := &Session{
+		ID:        randomToken(),
+		Auth:      info,
+		CSRFToken: randomToken(),
+		Created:   now,
+		LastSeen:  now,
+	}
+	if err := t.Store.Store(s, t.expiresAt(s)); err != nil {
+		return nil, err
+	}
+	t.setCookie(w, s)
+	t.Log.Info("SessionStarted", zap.String("subject", info.Subject))
+	return s, nil
+}
+
+func (t *implSessionManager) Renew(w http.ResponseWriter, r *http.Request) (*Session, error) {
+	s, err := t.Session(r)
+	if err != nil {
+		return nil, err
+	}
+	if s == nil {
+		return nil, xerrors.New("no session to renew")
+	}
+	oldID := s.ID
+	s.ID = randomToken()
+	s.CSRFToken = randomToken()
+	s.LastSeen = time.Now()
+	if err := t.Store.Store(s, t.expiresAt(s)); err != nil {
+		return nil, err
+	}
+	if err := t.Store.Delete(oldID); err != nil {
+		return nil, err
+	}
+	t.setCookie(w, s)
+	return s, nil
+}
+
+func (t *implSessionManager) Save(s *Session) error {
+	return t.Store.Store(s, t.expiresAt(s))
+}
+
+func (t *implSessionManager) End(w http.ResponseWriter, r *http.Request) error {
+	http.SetCookie(w, t.cookie("", -1))
+	if c, err := r.Cookie(t.CookieName); err == nil && c.Value != "" {
+		return t.Store.Delete(c.Value)
+	}
+	return nil
+}
+
+// expiresAt is the earlier of the idle and the absolute deadline of s.
+func (t *implSessionManager) expiresAt(s *Session) time.Time {
+	idle := s.LastSeen.Add(t.IdleTimeout)
+	absolute := s.Created.Add(t.AbsoluteTimeout)
+	if idle.Before(absolute) {
+		return idle
+	}
+	return absolute
+}
+
+func (t *implSessionManager) setCookie(w http.ResponseWriter, s *Session) {
+	// the cookie lives as long as the session could, the store decides earlier ends
+	maxAge := int(time.Until(s.Created.Add(t.AbsoluteTimeout)) / time.Second)
+	http.SetCookie(w, t.cookie(s.ID, maxAge))
+}
+
+func (t *implSessionManager) cookie(value string, maxAge int) *http.Cookie {
+	return &http.Cookie{
+		Name:     t.CookieName,
+		Value:    value,
+		Path:     t.CookiePath,
+		Domain:   t.CookieDomain,
+		MaxAge:   maxAge,
+		Secure:   !t.CookieInsecure,
+		HttpOnly: true,
+		SameSite: t.sameSite,
+	}
+}
@@ -0,0 +1,159 @@
+package servion
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func newTestSessionManager(t *testing.T, idle, absolute time.Duration) *implSessionManager {
+	t.Helper()
+	m := &implSessionManager{
+		Log:             zap.NewNop(),
+		CookieName:      "servion_sid",
+		CookiePath:      "/",
+		CookieSameSite:  "strict",
+		IdleTimeout:     idle,
+		AbsoluteTimeout: absolute,
+	}
+	if err := m.PostConstruct(); err != nil {
+		t.Fatalf("PostConstruct: %v", err)
+	}
+	t.Cleanup(func() { m.Destroy() })
+	return m
+}
+
+func requestWithCookie(c *http.Cookie) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	if c != nil {
+		r.AddCookie(c)
+	}
+	return r
+}
+
+func TestSessionManager_Lifecycle(t *testing.T) {
+	m := newTestSessionManager(t, time.Hour, 2*time.Hour)
+
+	w := httptest.NewRecorder()
+	s, err := m.Start(w, requestWithCookie(nil), AuthInfo{Subject: "alice", Roles: []string{"user"}})
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	cookie := responseCookie(w, "servion_sid")
+	if cookie == nil || cookie.Value != s.ID || !cookie.HttpOnly || !cookie.Secure || cookie.SameSite != http.SameSiteStrictMode {
+		t.Fatalf("cookie = %+v", cookie)
+	}
+	if s.CSRFToken == "" {
+		t.Error("session has no CSRF token")
+	}
+
+	info, ok, err := m.AuthenticateSession(requestWithCookie(cookie))
+	if err != nil || !ok || info.Subject != "alice" {
+		t.Fatalf("AuthenticateSession = %+v, %v, %v", info, ok, err)
+	}
+
+	// values persist with Save
+	s.Values = map[string]string{"cart": "3"}
+	if err := m.Save(s); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := m.Session(requestWithCookie(cookie)); got == nil || got.Values["cart"] != "3" {
+		t.Fatalf("Session after Save = %+v", got)
+	}
+
+	// privilege change: the old ID stops working
+	w = httptest.NewRecorder()
+	renewed, err := m.Renew(w, requestWithCookie(cookie))
+	if err != nil {
+		t.Fatalf("Renew: %v", err)
+	}
+	if renewed.ID == s.ID || renewed.CSRFToken == s.CSRFToken || renewed.Values["cart"] != "3" {
+		t.Errorf("renewed session = %+v", renewed)
+	}
+	if got, _ := m.Session(requestWithCookie(cookie)); got != nil {
+		t.Error("old session id still valid after Renew")
+	}
+	cookie = responseCookie(w, "servion_sid")
+
+	// a new login replaces the session
+	w = httptest.NewRecorder()
+	again, err := m.Start(w, requestWithCookie(cookie), AuthInfo{Subject: "alice", Roles: []string{"admin"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := m.Session(requestWithCookie(cookie)); got != nil {
+		t.Error("session before login still valid")
+	}
+	cookie = responseCookie(w, "servion_sid")
+	if cookie.Value != again.ID {
+		t.Fatal("cookie does not carry the new session")
+	}
+
+	w = httptest.NewRecorder()
+	if err := m.End(w, requestWithCookie(cookie)); err != nil {
+		t.Fatal(err)
+	}
+	if c := responseCookie(w, "servion_sid"); c == nil || c.MaxAge >= 0 {
+		t.Errorf("cookie not cleared: %+v", c)
+	}
+	if _, ok, _ := m.AuthenticateSession(requestWithCookie(cookie)); ok {
+		t.Error("ended session still authenticates")
+	}
+}
+
+func TestSessionManager_Expiry(t *testing.T) {
+	m := newTestSessionManager(t, time.Hour, 2*time.Hour)
+
+	start := func() (*Session, *http.Cookie) {
+		w := httptest.NewRecorder()
+		s, err := m.Start(w, requestWithCookie(nil), AuthInfo{Subject: "alice"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s, responseCookie(w, "servion_sid")
+	}
+
+	// idle: no request for longer than the idle timeout
+	s, cookie := start()
+	s.LastSeen = time.Now().Add(-61 * time.Minute)
+	m.Store.Store(s, time.Now().Add(time.Hour))
+	if got, _ := m.Session(requestWithCookie(cookie)); got != nil {
+		t.Error("idle session still valid")
+	}
+
+	// absolute: active, but started too long ago
+	s, cookie = start()
+	s.Created = time.Now().Add(-121 * time.Minute)
+	m.Store.Store(s, time.Now().Add(time.Hour))
+	if got, _ := m.Session(requestWithCookie(cookie)); got != nil {
+		t.Error("session past absolute timeout still valid")
+	}
+
+	// activity moves the idle deadline
+	s, cookie = start()
+	s.LastSeen = time.Now().Add(-50 * time.Minute)
+	m.Store.Store(s, m.expiresAt(s))
+	if got, _ := m.Session(requestWithCookie(cookie)); got == nil || time.Since(got.LastSeen) > time.Second {
+		t.Fatalf("active session = %+v", got)
+	}
+
+	if got, _ := m.Session(requestWithCookie(&http.Cookie{Name: "servion_sid", Value: "forged"})); got != nil {
+		t.Error("unknown session id accepted")
+	}
+}
+
+func TestSessionManager_PostConstruct(t *testing.T) {
+	for _, m := range []*implSessionManager{
+		{CookieSameSite: "sometimes", IdleTimeout: time.Minute, AbsoluteTimeout: time.Hour},
+		{CookieSameSite: "none", CookieInsecure: true, IdleTimeout: time.Minute, AbsoluteTimeout: time.Hour},
+		{CookieSameSite: "lax", AbsoluteTimeout: time.Hour},
+	} {
+		if err := m.PostConstruct(); err == nil {
+			m.Destroy()
+			t.Errorf("expected error for %+v", m)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+/*
+ * Copyright (c) 2026 Karagatan LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package servion
+
+import (
+	"sync"
+	"time"
+)
+
+type sessionEntry struct {
+	Session *Session  `json:"session"`
+	Expires time.Time `json:"expires"`
+}
+
+type implMemorySessionStore struct {
+	CleanInterval time.Duration `value:"session.clean-interval,default=1m"`
+
+	mu       sync.Mutex
+	sessions map[string]sessionEntry
+
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+/*
+MemorySessionStore creates a process-local SessionStore. Sessions are lost on
+restart and not shared between replicas; SessionManager uses one when the
+context has no SessionStore bean.
+
+Configuration properties:
+
+	session.clean-interval  – how often expired sessions are dropped (default 1m)
+*/
+func MemorySessionStore() SessionStore {
+	return &implMemorySessionStore{}
+}
+
+func (t *implMemorySessionStore) PostConstruct() error {
+	t.sessions = make(map[string]sessionEntry)
+	t.stopCh = make(chan struct{})
+	if t.CleanInterval > 0 {
+		t.wg.Add(1)
+		go t.cleanerLoop(t.stopCh)
+	}
+	return nil
+}
+
+func (t *implMemorySessionStore) Destroy() error {
+	if t.stopCh != nil {
+		close(t.stopCh)
+		t.stopCh = nil
+	}
+	t.wg.Wait()
+	return nil
+}
+
+func (t *implMemorySessionStore) Load(id string) (*Session, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	e, ok := t.sessions[id]
+	if !ok || !time.Now().Before(e.Expires) {
+		return nil, nil
+	}
+	return cloneSession(e.Session), nil
+}
+
+func (t *implMemorySessionStore) Store(s *Session, expires time.Time) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.sessions[s.ID] = sessionEntry{Session: cloneSession(s), Expires: expires}
+	return nil
+}
+
+func (t *implMemorySessionStore) Delete(id string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.sessions, id)
+	return nil
+}
+
+func (t *implMemorySessionStore) cleanerLoop(stopCh <-chan struct{}) {
+	defer t.wg.Done()
+
+	ticker := time.NewTicker(t.CleanInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopCh:
+			return
+		case now := <-ticker.C:
+			t.mu.Lock()
+			for id, e := range t.sessions {
+				if !now.Before(e.Expires) {
+					delete(t.sessions, id)
+				}
+			}
+			t.mu.Unlock()
+		}
+	}
+}
+
+// cloneSession copies s, so callers never share maps with the store.
+func cloneSession(s *Session) *Session {
+	c := *s
+	c.Auth.Roles = append([]string(nil), s.Auth.Roles...)
+	c.Auth.Scopes = append([]string(nil), s.Auth.Scopes...)
+	c.Auth.Attributes = cloneStringMap(s.Auth.Attributes)
+	c.Values = cloneStringMap(s.Values)
+	return &c
+}
+
+func cloneStringMap(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	c := make(map[string]string, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
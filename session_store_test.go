@@ -0,0 +1,103 @@
+package servion
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func testSessionStore(t *testing.T, store SessionStore) {
+	t.Helper()
+	s := &Session{
+		ID:       "sid-1",
+		Auth:     AuthInfo{Subject: "alice", Roles: []string{"admin"}},
+		Values:   map[string]string{"theme": "dark"},
+		Created:  time.Now(),
+		LastSeen: time.Now(),
+	}
+	if err := store.Store(s, time.Now().Add(time.Minute)); err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+	s.Values["theme"] = "light" // the store keeps its own copy
+
+	got, err := store.Load("sid-1")
+	if err != nil || got == nil {
+		t.Fatalf("Load = %v, %v", got, err)
+	}
+	if got.Auth.Subject != "alice" || got.Auth.Roles[0] != "admin" || got.Values["theme"] != "dark" {
+		t.Errorf("loaded session = %+v", got)
+	}
+	if got, _ := store.Load("sid-2"); got != nil {
+		t.Error("unknown id loaded")
+	}
+
+	if err := store.Delete("sid-1"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if got, _ := store.Load("sid-1"); got != nil {
+		t.Error("deleted session loaded")
+	}
+	if err := store.Delete("sid-1"); err != nil {
+		t.Errorf("Delete of unknown id: %v", err)
+	}
+
+	if err := store.Store(s, time.Now().Add(-time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := store.Load("sid-1"); got != nil {
+		t.Error("expired session loaded")
+	}
+}
+
+func TestMemorySessionStore(t *testing.T) {
+	store := &implMemorySessionStore{}
+	if err := store.PostConstruct(); err != nil {
+		t.Fatal(err)
+	}
+	defer store.Destroy()
+	testSessionStore(t, store)
+}
+
+func TestFileSessionStore(t *testing.T) {
+	dir := t.TempDir()
+	store := &implFileSessionStore{Log: zap.NewNop(), Runtime: newMockRuntime(true), Dir: filepath.Join(dir, "sessions")}
+	if err := store.PostConstruct(); err != nil {
+		t.Fatal(err)
+	}
+	defer store.Destroy()
+	testSessionStore(t, store)
+
+	// sessions survive a new store on the same directory
+	s := &Session{ID: "sid-3", Auth: AuthInfo{Subject: "bob"}, Created: time.Now(), LastSeen: time.Now()}
+	if err := store.Store(s, time.Now().Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	reopened := &implFileSessionStore{Log: zap.NewNop(), Runtime: newMockRuntime(true), Dir: store.Dir}
+	if err := reopened.PostConstruct(); err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Destroy()
+	if got, err := reopened.Load("sid-3"); err != nil || got == nil || got.Auth.Subject != "bob" {
+		t.Fatalf("Load after reopen = %v, %v", got, err)
+	}
+
+	info, err := os.Stat(reopened.fileOf("sid-3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("session file mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	// expired files are removed by the cleaner
+	if err := store.Store(s, time.Now().Add(-time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	store.removeExpired(time.Now())
+	if _, err := os.Stat(store.fileOf("sid-3")); !os.IsNotExist(err) {
+		t.Errorf("expired session file still present: %v", err)
+	}
+}
@@ -0,0 +1,426 @@
+/*
+ * Copyright (c) 2026 Karagatan LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package servion
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.arpabet.com/glue"
+	"go.uber.org/zap"
+	"golang.org/x/xerrors"
+)
+
+const (
+	SignatureRFC9421 = "rfc9421"
+	SignatureHMAC    = "hmac"
+	SignatureGitHub  = "github"
+	SignatureStripe  = "stripe"
+	SignatureSlack   = "slack"
+)
+
+var errSignatureMissing = xerrors.New("no signature")
+
+type implSignatureMiddleware struct {
+	beanOrder int
+
+	Log        *zap.Logger     `inject:""`
+	Properties glue.Properties `inject:""`
+
+	Prefixes []string `value:"signature.prefixes,default=/webhooks"`
+
+	// Partner IDs, each configured with signature.partner.<id>.* properties
+	Partners []string `value:"signature.partners,default="`
+
+	// Largest accepted difference between the signature timestamp and now
+	MaxSkew time.Duration `value:"signature.max-skew,default=5m"`
+
+	// Largest body read for verification
+	MaxBody string `value:"signature.max-body,default=1MB"`
+
+	// Components RFC 9421 signatures must cover; content-digest only applies to requests with a body
+	Components []string `value:"signature.rfc9421.components,default=@method;@path;content-digest"`
+
+	// Signatures remembered to refuse replays
+	NonceCacheSize int `value:"signature.nonce-cache-size,default=100000"`
+
+	partners []*signaturePartner
+	maxBody  int64
+	replays  *replayCache
+}
+
+type signaturePartner struct {
+	id              string
+	scheme          string
+	secret          []byte
+	roles           []string
+	prefix          string
+	header          string
+	timestampHeader string
+}
+
+// signedMessage is what a webhook scheme says was signed, and how.
+type signedMessage struct {
+	message    []byte
+	signatures [][]byte
+	timestamp  int64 // unix seconds, 0 when the scheme carries none
+	expires    int64 // unix seconds, 0 when the signature sets no expiry
+}
+
+/*
+SignatureMiddleware creates an HttpMiddleware authenticating requests by an HMAC
+signature over their content, for inbound webhooks that carry no bearer token.
+Each partner shares a secret with the service; a verified request gets an
+AuthInfo whose Subject is the partner ID, other requests get 401.
+
+Supported schemes, per partner:
+
+	rfc9421  HTTP Message Signatures with hmac-sha256, keyid = partner ID; the
+	         covered components must include signature.rfc9421.components, and a
+	         covered Content-Digest (RFC 9530) must match the body
+	hmac     hex or base64 HMAC-SHA256 in the partner header (default
+	         X-Signature) over "<timestamp>.<body>", the timestamp taken from
+	         the required timestamp header
+	github   X-Hub-Signature-256: sha256=<hex> over the body
+	stripe   Stripe-Signature: t=<ts>,v1=<hex> over "<ts>.<body>"
+	slack    X-Slack-Signature: v0=<hex> over "v0:<ts>:<body>"
+
+Timestamps further than signature.max-skew from now are refused, and every
+accepted signature is remembered for twice that window so a captured request can
+not be replayed. GitHub signs no timestamp, so for the github scheme a replay is
+only refused within twice signature.max-skew of the first delivery; use a
+scheme that signs a timestamp where that matters.
+
+Configuration properties:
+
+	signature.prefixes                  – URL prefixes to protect (default "/webhooks")
+	signature.partners                  – partner IDs, semicolon separated
+	signature.partner.<id>.secret       – shared secret of the partner (required)
+	signature.partner.<id>.scheme       – rfc9421 (default), hmac, github, stripe or slack
+	signature.partner.<id>.prefix       – URL prefix the partner posts to, empty for any
+	signature.partner.<id>.roles        – comma separated roles of the partner
+	signature.partner.<id>.header       – signature header of the hmac scheme (default "X-Signature")
+	signature.partner.<id>.timestamp-header – timestamp header of the hmac scheme (required)
+	signature.max-skew                  – replay window (default 5m)
+	signature.max-body                  – largest verified body (default 1MB)
+	signature.rfc9421.components        – components RFC 9421 signatures must cover
+	signature.nonce-cache-size          – remembered signatures (default 100000)
+*/
+func SignatureMiddleware(beanOrder int) HttpMiddleware {
+	return &implSignatureMiddleware{
+		beanOrder: beanOrder,
+	}
+}
+
+func (t *implSignatureMiddleware) PostConstruct() error {
+	maxBody, err := ParseByteSize(t.MaxBody)
+	if err != nil || maxBody <= 0 {
+		return xerrors.Errorf("signature.max-body must be a positive size, got '%s'", t.MaxBody)
+	}
+	t.maxBody = maxBody
+	if t.MaxSkew <= 0 {
+		return xerrors.New("signature.max-skew must be positive")
+	}
+	if t.NonceCacheSize <= 0 {
+		return xerrors.New("signature.nonce-cache-size must be positive")
+	}
+	t.replays = newReplayCache(t.NonceCacheSize)
+
+	for _, id := range t.Partners {
+		if id = strings.TrimSpace(id); id == "" {
+			continue
+		}
+		p, err := t.loadPartner(id)
+		if err != nil {
+			return err
+		}
+		t.partners = append(t.partners, p)
+	}
+	if len(t.partners) == 0 {
+		return xerrors.New("signature.partners lists no partner")
+	}
+	return nil
+}
+
+func (t *implSignatureMiddleware) loadPartner(id string) (*signaturePartner, error) {
+	prop := func(name, def string) string {
+		return strings.TrimSpace(t.Properties.GetString(fmt.Sprintf("signature.partner.%s.%s", id, name), def))
+	}
+	p := &signaturePartner{
+		id:              id,
+		scheme:          strings.ToLower(prop("scheme", SignatureRFC9421)),
+		secret:          []byte(prop("secret", "")),
+		prefix:          prop("prefix", ""),
+		header:          prop("header", "X-Signature"),
+		timestampHeader: prop("timestamp-header", ""),
+	}
+	for _, role := range strings.Split(prop("roles", ""), ",") {
+		if role = strings.TrimSpace(role); role != "" {
+			p.roles = append(p.roles, role)
+		}
+	}
+	if len(p.secret) < 16 {
+		return nil, xerrors.Errorf("signature.partner.%s.secret must be at least 16 characters", id)
+	}
+	switch p.scheme {
+	case SignatureRFC9421, SignatureHMAC, SignatureGitHub, SignatureStripe, SignatureSlack:
+	default:
+		return nil, xerrors.Errorf("signature.partner.%s.scheme must be rfc9421, hmac, github, stripe or slack, got '%s'", id, p.scheme)
+	}
+	// without a signed timestamp a captured request is good again once it leaves the replay cache
+	if p.scheme == SignatureHMAC && p.timestampHeader == "" {
+		return nil, xerrors.Errorf("signature.partner.%s.timestamp-header is required by the hmac scheme", id)
+	}
+	return p, nil
+}
+
+func (t *implSignatureMiddleware) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		if r.ContentLength > t.maxBody {
+			http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		var body []byte
+		if r.Body != nil {
+			var err error
+			body, err = io.ReadAll(io.LimitReader(r.Body, t.maxBody+1))
+			if err != nil {
+				http.Error(w, "can not read request body", http.StatusBadRequest)
+				return
+			}
+			if int64(len(body)) > t.maxBody {
+				http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+		}
+
+		partner, err := t.verify(r, body, time.Now())
+		if err != nil {
+			t.Log.Warn("SignatureRejected",
+				zap.String("path", r.URL.Path),
+				zap.String("remote", r.RemoteAddr),
+				zap.Error(err),
+			)
+			http.Error(w, "invalid request signature", http.StatusUnauthorized)
+			return
+		}
+
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		info := AuthInfo{
+			Subject:    partner.id,
+			Roles:      partner.roles,
+			Attributes: map[string]string{"auth": "signature", "scheme": partner.scheme},
+		}
+		next.ServeHTTP(w, r.WithContext(ContextWithAuth(r.Context(), info)))
+	})
+}
+
+// verify returns the partner whose signature r carries, trying every partner configured for its path.
+func (t *implSignatureMiddleware) verify(r *http.Request, body []byte, now time.Time) (*signaturePartner, error) {
+	lastErr := errSignatureMissing
+	for _, p := range t.partners {
+		if p.prefix != "" && !strings.HasPrefix(r.URL.Path, p.prefix) {
+			continue
+		}
+		msg, err := t.signedMessage(p, r, body)
+		if err == errSignatureMissing {
+			continue
+		}
+		if err == nil {
+			err = t.check(p, msg, now)
+		}
+		if err == nil {
+			return p, nil
+		}
+		lastErr = xerrors.Errorf("partner %s: %w", p.id, err)
+	}
+	return nil, lastErr
+}
+
+func (t *implSignatureMiddleware) check(p *signaturePartner, msg *signedMessage, now time.Time) error {
+	if msg.timestamp != 0 {
+		skew := now.Sub(time.Unix(msg.timestamp, 0))
+		if skew > t.MaxSkew || skew < -t.MaxSkew {
+			return xerrors.Errorf("timestamp %d outside the replay window", msg.timestamp)
+		}
+	}
+	if msg.expires != 0 && now.Unix() > msg.expires {
+		return xerrors.New("signature expired")
+	}
+	mac := hmac.New(sha256.New, p.secret)
+	mac.Write(msg.message)
+	expected := mac.Sum(nil)
+	for _, sig := range msg.signatures {
+		if hmac.Equal(sig, expected) {
+			// keyed on what was signed, an unsigned header such as a delivery ID is the sender's to change
+			switch t.replays.add(p.id+" "+hex.EncodeToString(sig), now.Add(2*t.MaxSkew)) {
+			case replayDuplicate:
+				return xerrors.New("replayed signature")
+			case replayFull:
+				return xerrors.New("replay cache is full")
+			}
+			return nil
+		}
+	}
+	return xerrors.New("signature mismatch")
+}
+
+// signedMessage extracts what the scheme of p signed; errSignatureMissing when r carries no such signature.
+func (t *implSignatureMiddleware) signedMessage(p *signaturePartner, r *http.Request, body []byte) (*signedMessage, error) {
+	switch p.scheme {
+	case SignatureRFC9421:
+		return t.rfc9421Message(p, r, body)
+
+	case SignatureGitHub:
+		v := r.Header.Get("X-Hub-Signature-256")
+		if v == "" {
+			return nil, errSignatureMissing
+		}
+		sig, err := hex.DecodeString(strings.TrimPrefix(v, "sha256="))
+		if err != nil {
+			return nil, xerrors.New("malformed X-Hub-Signature-256")
+		}
+		return &signedMessage{message: body, signatures: [][]byte{sig}}, nil
+
+	case SignatureStripe:
+		v := r.Header.Get("Stripe-Signature")
+		if v == "" {
+			return nil, errSignatureMissing
+		}
+		msg := &signedMessage{}
+		var ts string
+		for _, part := range strings.Split(v, ",") {
+			key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+			switch key {
+			case "t":
+				ts = value
+			case "v1":
+				if sig, err := hex.DecodeString(value); err == nil {
+					msg.signatures = append(msg.signatures, sig)
+				}
+			}
+		}
+		if err := msg.setTimestamp(ts); err != nil || len(msg.signatures) == 0 {
+			return nil, xerrors.New("malformed Stripe-Signature")
+		}
+		msg.message = append([]byte(ts+"."), body...)
+		return msg, nil
+
+	case SignatureSlack:
+		v := r.Header.Get("X-Slack-Signature")
+		if v == "" {
+			return nil, errSignatureMissing
+		}
+		ts := r.Header.Get("X-Slack-Request-Timestamp")
+		sig, err := hex.DecodeString(strings.TrimPrefix(v, "v0="))
+		msg := &signedMessage{signatures: [][]byte{sig}}
+		if err != nil || msg.setTimestamp(ts) != nil {
+			return nil, xerrors.New("malformed Slack signature")
+		}
+		msg.message = append([]byte("v0:"+ts+":"), body...)
+		return msg, nil
+	}
+
+	v := strings.TrimSpace(r.Header.Get(p.header))
+	if v == "" {
+		return nil, errSignatureMissing
+	}
+	sig, err := decodeSignature(strings.TrimPrefix(v, "sha256="))
+	if err != nil {
+		return nil, xerrors.Errorf("malformed %s", p.header)
+	}
+	ts := r.Header.Get(p.timestampHeader)
+	msg := &signedMessage{signatures: [][]byte{sig}}
+	if err := msg.setTimestamp(ts); err != nil {
+		return nil, xerrors.Errorf("malformed %s", p.timestampHeader)
+	}
+	msg.message = append([]byte(ts+"."), body...)
+	return msg, nil
+}
+
+func (m *signedMessage) setTimestamp(ts string) error {
+	n, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || n <= 0 {
+		return xerrors.Errorf("invalid timestamp '%s'", ts)
+	}
+	m.timestamp = n
+	return nil
+}
+
+// decodeSignature accepts the hex and base64 encodings webhook senders use.
+func decodeSignature(s string) ([]byte, error) {
+	if len(s) == 2*sha256.Size {
+		if b, err := hex.DecodeString(s); err == nil {
+			return b, nil
+		}
+	}
+	if b, err := base64.StdEncoding.DecodeString(s); err == nil {
+		return b, nil
+	}
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
+
+func (t *implSignatureMiddleware) BeanOrder() int {
+	return t.beanOrder
+}
+
+func (t *implSignatureMiddleware) Match(prefix string) bool {
+	for _, p := range t.Prefixes {
+		if strings.HasPrefix(prefix, p) {
+			return true
+		}
+	}
+	return false
+}
+
+const (
+	replayAdded = iota
+	replayDuplicate
+	replayFull
+)
+
+// replayCache remembers accepted signatures until they fall out of the replay window.
+type replayCache struct {
+	mu      sync.Mutex
+	size    int
+	entries map[string]time.Time
+}
+
+func newReplayCache(size int) *replayCache {
+	return &replayCache{size: size, entries: make(map[string]time.Time)}
+}
+
+func (c *replayCache) add(key string, expires time.Time) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	if exp, ok := c.entries[key]; ok && now.Before(exp) {
+		return replayDuplicate
+	}
+	if len(c.entries) >= c.size {
+		for k, exp := range c.entries {
+			if !now.Before(exp) {
+				delete(c.entries, k)
+			}
+		}
+		if len(c.entries) >= c.size {
+			return replayFull
+		}
+	}
+	c.entries[key] = expires
+	return replayAdded
+}
@@ -0,0 +1,262 @@
+package servion
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"go.arpabet.com/glue"
+	"go.uber.org/zap"
+)
+
+const testWebhookSecret = "0123456789abcdef-secret"
+
+func newSignatureMiddleware(t *testing.T, props map[string]string, partners ...string) *implSignatureMiddleware {
+	t.Helper()
+	p := glue.NewProperties()
+	for k, v := range props {
+		p.Set(k, v)
+	}
+	m := &implSignatureMiddleware{
+		Log:            zap.NewNop(),
+		Properties:     p,
+		Prefixes:       []string{"/webhooks"},
+		Partners:       partners,
+		MaxSkew:        5 * time.Minute,
+		MaxBody:        "1KB",
+		Components:     []string{"@method", "@path", "content-digest"},
+		NonceCacheSize: 100,
+	}
+	if err := m.PostConstruct(); err != nil {
+		t.Fatalf("PostConstruct: %v", err)
+	}
+	return m
+}
+
+func webhookMac(message string) []byte {
+	mac := hmac.New(sha256.New, []byte(testWebhookSecret))
+	mac.Write([]byte(message))
+	return mac.Sum(nil)
+}
+
+// serveSigned runs r through the middleware and returns the status and the authenticated subject.
+func serveSigned(m *implSignatureMiddleware, r *http.Request) (int, string) {
+	var subject string
+	h := m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if info, ok := AuthFromContext(r.Context()); ok {
+			subject = info.Subject
+		}
+		body, _ := io.ReadAll(r.Body)
+		w.Write(body)
+	}))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+	return rec.Code, subject
+}
+
+func rfc9421Request(body string, created int64, nonce string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/webhooks/acme", strings.NewReader(body))
+	sum := sha256.Sum256([]byte(body))
+	r.Header.Set("Content-Digest", "sha-256=:"+base64.StdEncoding.EncodeToString(sum[:])+":")
+	params := `("@method" "@path" "content-digest");created=` + strconv.FormatInt(created, 10) + `;keyid="acme";alg="hmac-sha256"`
+	if nonce != "" {
+		params += `;nonce="` + nonce + `"`
+	}
+	base := `"@method": POST` + "\n" +
+		`"@path": /webhooks/acme` + "\n" +
+		`"content-digest": ` + r.Header.Get("Content-Digest") + "\n" +
+		`"@signature-params": ` + params
+	r.Header.Set("Signature-Input", "sig1="+params)
+	r.Header.Set("Signature", "sig1=:"+base64.StdEncoding.EncodeToString(webhookMac(base))+":")
+	return r
+}
+
+func TestSignatureMiddleware_RFC9421(t *testing.T) {
+	m := newSignatureMiddleware(t, map[string]string{
+		"signature.partner.acme.secret": testWebhookSecret,
+		"signature.partner.acme.roles":  "partner",
+	}, "acme")
+	now := time.Now().Unix()
+
+	if code, subject := serveSigned(m, rfc9421Request(`{"event":"paid"}`, now, "n-1")); code != http.StatusOK || subject != "acme" {
+		t.Fatalf("signed request: %d %q", code, subject)
+	}
+	if code, _ := serveSigned(m, rfc9421Request(`{"event":"paid"}`, now, "n-1")); code != http.StatusUnauthorized {
+		t.Errorf("replayed signature: %d", code)
+	}
+	if code, _ := serveSigned(m, rfc9421Request(`{"event":"paid"}`, now-600, "n-2")); code != http.StatusUnauthorized {
+		t.Errorf("stale signature: %d", code)
+	}
+
+	r := rfc9421Request(`{"event":"paid"}`, now, "n-3")
+	r.Body = io.NopCloser(strings.NewReader(`{"event":"refund"}`))
+	if code, _ := serveSigned(m, r); code != http.StatusUnauthorized {
+		t.Errorf("tampered body: %d", code)
+	}
+
+	r = rfc9421Request(`{"event":"paid"}`, now, "n-4")
+	r.Header.Set("Signature-Input", strings.Replace(r.Header.Get("Signature-Input"), `"@path" `, "", 1))
+	if code, _ := serveSigned(m, r); code != http.StatusUnauthorized {
+		t.Errorf("uncovered @path: %d", code)
+	}
+
+	if code, _ := serveSigned(m, httptest.NewRequest(http.MethodPost, "/webhooks/acme", strings.NewReader("{}"))); code != http.StatusUnauthorized {
+		t.Errorf("unsigned request: %d", code)
+	}
+}
+
+func TestSignatureMiddleware_ProviderSchemes(t *testing.T) {
+	m := newSignatureMiddleware(t, map[string]string{
+		"signature.partner.gh.scheme":               "github",
+		"signature.partner.gh.secret":               testWebhookSecret,
+		"signature.partner.stripe.scheme":           "stripe",
+		"signature.partner.stripe.secret":           testWebhookSecret,
+		"signature.partner.slack.scheme":            "slack",
+		"signature.partner.slack.secret":            testWebhookSecret,
+		"signature.partner.custom.scheme":           "hmac",
+		"signature.partner.custom.secret":           testWebhookSecret,
+		"signature.partner.custom.prefix":           "/webhooks/custom",
+		"signature.partner.custom.timestamp-header": "X-Timestamp",
+	}, "gh", "stripe", "slack", "custom")
+	body := `{"id":1}`
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+
+	r := httptest.NewRequest(http.MethodPost, "/webhooks/github", strings.NewReader(body))
+	r.Header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(webhookMac(body)))
+	r.Header.Set("X-GitHub-Delivery", "d-1")
+	if code, subject := serveSigned(m, r); code != http.StatusOK || subject != "gh" {
+		t.Errorf("github: %d %q", code, subject)
+	}
+
+	r = httptest.NewRequest(http.MethodPost, "/webhooks/stripe", strings.NewReader(body))
+	r.Header.Set("Stripe-Signature", "t="+ts+",v1=00ff,v1="+hex.EncodeToString(webhookMac(ts+"."+body)))
+	if code, subject := serveSigned(m, r); code != http.StatusOK || subject != "stripe" {
+		t.Errorf("stripe: %d %q", code, subject)
+	}
+
+	r = httptest.NewRequest(http.MethodPost, "/webhooks/slack", strings.NewReader(body))
+	r.Header.Set("X-Slack-Request-Timestamp", ts)
+	r.Header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(webhookMac("v0:"+ts+":"+body)))
+	if code, subject := serveSigned(m, r); code != http.StatusOK || subject != "slack" {
+		t.Errorf("slack: %d %q", code, subject)
+	}
+
+	sig := base64.StdEncoding.EncodeToString(webhookMac(ts + "." + body))
+	r = httptest.NewRequest(http.MethodPost, "/webhooks/custom", strings.NewReader(body))
+	r.Header.Set("X-Timestamp", ts)
+	r.Header.Set("X-Signature", sig)
+	if code, subject := serveSigned(m, r); code != http.StatusOK || subject != "custom" {
+		t.Errorf("hmac: %d %q", code, subject)
+	}
+
+	// the custom partner only posts under its prefix
+	r = httptest.NewRequest(http.MethodPost, "/webhooks/other", strings.NewReader(body))
+	r.Header.Set("X-Timestamp", ts)
+	r.Header.Set("X-Signature", sig)
+	if code, _ := serveSigned(m, r); code != http.StatusUnauthorized {
+		t.Errorf("hmac outside prefix: %d", code)
+	}
+
+	r = httptest.NewRequest(http.MethodPost, "/webhooks/github", strings.NewReader(body))
+	mac := hmac.New(sha256.New, []byte("wrong-secret-0123456789"))
+	mac.Write([]byte(body))
+	r.Header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	if code, _ := serveSigned(m, r); code != http.StatusUnauthorized {
+		t.Errorf("wrong secret: %d", code)
+	}
+}
+
+func TestSignatureMiddleware_GitHubReplay(t *testing.T) {
+	m := newSignatureMiddleware(t, map[string]string{
+		"signature.partner.gh.scheme": "github",
+		"signature.partner.gh.secret": testWebhookSecret,
+	}, "gh")
+	body := `{"id":2}`
+	deliver := func(id string) int {
+		r := httptest.NewRequest(http.MethodPost, "/webhooks/github", strings.NewReader(body))
+		r.Header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(webhookMac(body)))
+		r.Header.Set("X-GitHub-Delivery", id)
+		code, _ := serveSigned(m, r)
+		return code
+	}
+	if code := deliver("d-1"); code != http.StatusOK {
+		t.Fatalf("first delivery: %d", code)
+	}
+	// the delivery ID is not signed, a fresh one must not make the capture new
+	if code := deliver("d-2"); code != http.StatusUnauthorized {
+		t.Errorf("replay with another delivery ID: %d", code)
+	}
+}
+
+func TestSignatureMiddleware_BodyLimit(t *testing.T) {
+	m := newSignatureMiddleware(t, map[string]string{
+		"signature.partner.gh.scheme": "github",
+		"signature.partner.gh.secret": testWebhookSecret,
+	}, "gh")
+	body := strings.Repeat("x", 2048)
+	r := httptest.NewRequest(http.MethodPost, "/webhooks/github", strings.NewReader(body))
+	r.Header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(webhookMac(body)))
+	if code, _ := serveSigned(m, r); code != http.StatusRequestEntityTooLarge {
+		t.Errorf("large body: %d", code)
+	}
+}
+
+func TestSignatureMiddleware_InvalidConfig(t *testing.T) {
+	cases := []struct {
+		name     string
+		props    map[string]string
+		partners []string
+	}{
+		{"no partners", nil, nil},
+		{"short secret", map[string]string{"signature.partner.a.secret": "short"}, []string{"a"}},
+		{"unknown scheme", map[string]string{
+			"signature.partner.a.secret": testWebhookSecret,
+			"signature.partner.a.scheme": "md5",
+		}, []string{"a"}},
+		{"hmac without timestamp", map[string]string{
+			"signature.partner.a.secret": testWebhookSecret,
+			"signature.partner.a.scheme": "hmac",
+		}, []string{"a"}},
+	}
+	for _, c := range cases {
+		p := glue.NewProperties()
+		for k, v := range c.props {
+			p.Set(k, v)
+		}
+		m := &implSignatureMiddleware{
+			Properties:     p,
+			Partners:       c.partners,
+			MaxSkew:        time.Minute,
+			MaxBody:        "1MB",
+			NonceCacheSize: 10,
+		}
+		if err := m.PostConstruct(); err == nil {
+			t.Errorf("%s: expected an error", c.name)
+		}
+	}
+}
+
+func TestReplayCache(t *testing.T) {
+	c := newReplayCache(1)
+	if c.add("a", time.Now().Add(time.Minute)) != replayAdded {
+		t.Fatal("first add")
+	}
+	if c.add("a", time.Now().Add(time.Minute)) != replayDuplicate {
+		t.Error("duplicate not detected")
+	}
+	if c.add("b", time.Now().Add(time.Minute)) != replayFull {
+		t.Error("full cache accepted a new entry")
+	}
+	c.entries["a"] = time.Now().Add(-time.Second)
+	if c.add("b", time.Now().Add(time.Minute)) != replayAdded {
+		t.Error("expired entry not evicted")
+	}
+}
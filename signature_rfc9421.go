@@ -0,0 +1,266 @@
+/*
+ * Copyright (c) 2026 Karagatan LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package servion
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"golang.org/x/xerrors"
+)
+
+// signatureInput is one member of the Signature-Input field of RFC 9421.
+type signatureInput struct {
+	label      string
+	components []string
+	params     map[string]string
+	raw        string // serialized inner list with parameters, the @signature-params value
+}
+
+/*
+rfc9421Message builds the signature base of RFC 9421 for the signature of r
+whose keyid is the partner ID. Only hmac-sha256 is accepted, a created
+parameter is required and the components of signature.rfc9421.components must
+be covered.
+*/
+func (t *implSignatureMiddleware) rfc9421Message(p *signaturePartner, r *http.Request, body []byte) (*signedMessage, error) {
+	inputField := r.Header.Get("Signature-Input")
+	if inputField == "" {
+		return nil, errSignatureMissing
+	}
+	inputs, err := parseSignatureInputs(inputField)
+	if err != nil {
+		return nil, err
+	}
+	var input *signatureInput
+	for _, in := range inputs {
+		if in.params["keyid"] == p.id {
+			input = in
+			break
+		}
+	}
+	if input == nil {
+		return nil, errSignatureMissing
+	}
+	if alg, ok := input.params["alg"]; ok && alg != "hmac-sha256" {
+		return nil, xerrors.Errorf("unsupported signature algorithm '%s'", alg)
+	}
+
+	sig, err := signatureValue(r.Header.Get("Signature"), input.label)
+	if err != nil {
+		return nil, err
+	}
+
+	created, err := strconv.ParseInt(input.params["created"], 10, 64)
+	if err != nil || created <= 0 {
+		return nil, xerrors.New("signature without created parameter")
+	}
+	var expires int64
+	if v, ok := input.params["expires"]; ok {
+		if expires, err = strconv.ParseInt(v, 10, 64); err != nil || expires <= created {
+			return nil, xerrors.New("invalid expires parameter")
+		}
+	}
+
+	covered := make(map[string]bool, len(input.components))
+	for _, c := range input.components {
+		covered[c] = true
+	}
+	for _, c := range t.Components {
+		c = strings.ToLower(strings.TrimSpace(c))
+		if c == "" || (c == "content-digest" && len(body) == 0) {
+			continue
+		}
+		if !covered[c] {
+			return nil, xerrors.Errorf("signature does not cover %s", c)
+		}
+	}
+	if covered["content-digest"] {
+		if err := checkContentDigest(r.Header.Get("Content-Digest"), body); err != nil {
+			return nil, err
+		}
+	}
+
+	var base strings.Builder
+	for _, c := range input.components {
+		value, err := signatureComponent(r, c)
+		if err != nil {
+			return nil, err
+		}
+		base.WriteString(`"` + c + `": ` + value + "\n")
+	}
+	base.WriteString(`"@signature-params": ` + input.raw)
+
+	return &signedMessage{
+		message:    []byte(base.String()),
+		signatures: [][]byte{sig},
+		timestamp:  created,
+		expires:    expires,
+	}, nil
+}
+
+// signatureComponent returns the value of a covered component, RFC 9421 section 2.
+func signatureComponent(r *http.Request, name string) (string, error) {
+	switch name {
+	case "@method":
+		return r.Method, nil
+	case "@authority":
+		return strings.ToLower(r.Host), nil
+	case "@scheme":
+		if r.TLS != nil {
+			return "https", nil
+		}
+		return "http", nil
+	case "@target-uri":
+		scheme := "http"
+		if r.TLS != nil {
+			scheme = "https"
+		}
+		return scheme + "://" + strings.ToLower(r.Host) + r.URL.RequestURI(), nil
+	case "@request-target":
+		return r.URL.RequestURI(), nil
+	case "@path":
+		if p := r.URL.EscapedPath(); p != "" {
+			return p, nil
+		}
+		return "/", nil
+	case "@query":
+		return "?" + r.URL.RawQuery, nil
+	}
+	if strings.HasPrefix(name, "@") {
+		return "", xerrors.Errorf("unsupported signature component %s", name)
+	}
+	values := r.Header.Values(name)
+	if len(values) == 0 {
+		return "", xerrors.Errorf("signed header %s is missing", name)
+	}
+	for i, v := range values {
+		values[i] = strings.TrimSpace(v)
+	}
+	return strings.Join(values, ", "), nil
+}
+
+/*
+parseSignatureInputs parses the Signature-Input dictionary, e.g.
+
+	sig1=("@method" "@path" "content-digest");created=1618884473;keyid="acme"
+*/
+func parseSignatureInputs(field string) ([]*signatureInput, error) {
+	var inputs []*signatureInput
+	for _, member := range splitStructuredList(field) {
+		label, value, ok := strings.Cut(member, "=")
+		value = strings.TrimSpace(value)
+		if !ok || !strings.HasPrefix(value, "(") {
+			return nil, xerrors.Errorf("malformed Signature-Input member '%s'", member)
+		}
+		end := strings.IndexByte(value, ')')
+		if end < 0 {
+			return nil, xerrors.Errorf("malformed Signature-Input member '%s'", member)
+		}
+		in := &signatureInput{label: strings.TrimSpace(label), params: make(map[string]string), raw: value}
+		for _, item := range strings.Fields(value[1:end]) {
+			if len(item) < 2 || item[0] != '"' || item[len(item)-1] != '"' {
+				return nil, xerrors.Errorf("unsupported signature component %s", item)
+			}
+			in.components = append(in.components, strings.ToLower(item[1:len(item)-1]))
+		}
+		for _, param := range strings.Split(value[end+1:], ";") {
+			key, v, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if key != "" {
+				in.params[key] = strings.Trim(v, `"`)
+			}
+		}
+		inputs = append(inputs, in)
+	}
+	return inputs, nil
+}
+
+// signatureValue returns the signature labeled label in the Signature dictionary, sig1=:<base64>:.
+func signatureValue(field, label string) ([]byte, error) {
+	for _, member := range splitStructuredList(field) {
+		name, value, _ := strings.Cut(member, "=")
+		if strings.TrimSpace(name) != label {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if len(value) < 2 || value[0] != ':' || value[len(value)-1] != ':' {
+			return nil, xerrors.Errorf("malformed signature %s", label)
+		}
+		return base64.StdEncoding.DecodeString(value[1 : len(value)-1])
+	}
+	return nil, xerrors.Errorf("no signature labeled %s", label)
+}
+
+// checkContentDigest verifies a Content-Digest field of RFC 9530 against body.
+func checkContentDigest(field string, body []byte) error {
+	verified := false
+	for _, member := range splitStructuredList(field) {
+		alg, value, _ := strings.Cut(member, "=")
+		value = strings.Trim(strings.TrimSpace(value), ":")
+		var sum []byte
+		switch strings.ToLower(strings.TrimSpace(alg)) {
+		case "sha-256":
+			s := sha256.Sum256(body)
+			sum = s[:]
+		case "sha-512":
+			s := sha512.Sum512(body)
+			sum = s[:]
+		default:
+			continue
+		}
+		digest, err := base64.StdEncoding.DecodeString(value)
+		if err != nil || subtle.ConstantTimeCompare(digest, sum) != 1 {
+			return xerrors.New("Content-Digest does not match the body")
+		}
+		verified = true
+	}
+	if !verified {
+		return xerrors.New("Content-Digest with sha-256 or sha-512 is missing")
+	}
+	return nil
+}
+
+// splitStructuredList splits a structured field list or dictionary at the commas outside strings and inner lists.
+func splitStructuredList(field string) []string {
+	var (
+		members []string
+		current bytes.Buffer
+		quoted  bool
+		depth   int
+	)
+	for i := 0; i < len(field); i++ {
+		c := field[i]
+		switch {
+		case quoted && c == '\\' && i+1 < len(field):
+			current.WriteByte(c)
+			i++
+			c = field[i]
+		case c == '"':
+			quoted = !quoted
+		case !quoted && c == '(':
+			depth++
+		case !quoted && c == ')':
+			depth--
+		case !quoted && depth == 0 && c == ',':
+			if m := strings.TrimSpace(current.String()); m != "" {
+				members = append(members, m)
+			}
+			current.Reset()
+			continue
+		}
+		current.WriteByte(c)
+	}
+	if m := strings.TrimSpace(current.String()); m != "" {
+		members = append(members, m)
+	}
+	return members
+}
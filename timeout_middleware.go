@@ -0,0 +1,236 @@
+/*
+ * Copyright (c) 2026 Karagatan LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package servion
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/xerrors"
+)
+
+type timeoutRule struct {
+	prefix  string
+	timeout time.Duration
+}
+
+type implTimeoutMiddleware struct {
+	beanOrder int
+
+	Log *zap.Logger `inject:""`
+
+	Prefixes []string `value:"timeout.prefixes,default=/"`
+
+	// Per-route timeouts, e.g. "/api/report=60s;/api=5s"
+	Rules string `value:"timeout.rules,default="`
+
+	// Timeout of paths not covered by Rules, 0 means none
+	Default time.Duration `value:"timeout.default,default=0"`
+
+	// URL prefixes never timed out, for streaming responses
+	SkipPrefixes []string `value:"timeout.skip,default=/ws"`
+
+	// Status sent when the deadline passes, 503 or 504
+	Status int `value:"timeout.status,default=504"`
+
+	rules []timeoutRule
+}
+
+/*
+TimeoutMiddleware creates an HttpMiddleware that bounds the time of each request
+by the rule of its path. The handler runs with a context deadline, so outgoing
+gRPC and vRPC calls made with the request context are cancelled with it; when the
+deadline passes the client gets a clean timeout response instead of a connection
+killed at the server write deadline, and late writes of the handler are dropped.
+
+The response is buffered until the handler returns, so streaming routes must opt
+out: paths under timeout.skip are never timed out, nor are WebSocket upgrades,
+Server-Sent Events (Accept: text/event-stream) and gRPC requests.
+
+Configuration properties:
+
+	timeout.prefixes  – URL prefixes the middleware applies to (default "/")
+	timeout.rules     – per-route timeouts, longest prefix wins, e.g. "/api/report=60s;/api=5s"
+	timeout.default   – timeout of other paths, 0 for none (default 0)
+	timeout.skip      – URL prefixes never timed out (default "/ws")
+	timeout.status    – status of timed out requests, 503 or 504 (default 504)
+*/
+func TimeoutMiddleware(beanOrder int) HttpMiddleware {
+	return &implTimeoutMiddleware{
+		beanOrder: beanOrder,
+	}
+}
+
+func (t *implTimeoutMiddleware) PostConstruct() (err error) {
+	if t.Status != http.StatusServiceUnavailable && t.Status != http.StatusGatewayTimeout {
+		return xerrors.Errorf("timeout.status must be 503 or 504, got %d", t.Status)
+	}
+	t.rules, err = parseTimeoutRules(t.Rules)
+	return err
+}
+
+func (t *implTimeoutMiddleware) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		timeout := t.timeoutFor(r)
+		if timeout <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+		r = r.WithContext(ctx)
+
+		tw := &timeoutWriter{header: make(http.Header)}
+		done := make(chan struct{})
+		panicCh := make(chan interface{}, 1)
+		go func() {
+			defer func() {
+				if p := recover(); p != nil {
+					panicCh <- p
+				}
+			}()
+			next.ServeHTTP(tw, r)
+			close(done)
+		}()
+
+		select {
+		case p := <-panicCh:
+			panic(p)
+
+		case <-done:
+			tw.mu.Lock()
+			defer tw.mu.Unlock()
+			dst := w.Header()
+			for k, vv := range tw.header {
+				dst[k] = vv
+			}
+			if tw.code == 0 {
+				tw.code = http.StatusOK
+			}
+			w.WriteHeader(tw.code)
+			w.Write(tw.buf.Bytes())
+
+		case <-ctx.Done():
+			tw.mu.Lock()
+			defer tw.mu.Unlock()
+			tw.err = http.ErrHandlerTimeout
+			if xerrors.Is(ctx.Err(), context.DeadlineExceeded) {
+				t.Log.Warn("RequestTimeout",
+					zap.String("method", r.Method),
+					zap.String("path", r.URL.Path),
+					zap.Duration("timeout", timeout),
+				)
+				http.Error(w, "request timed out", t.Status)
+			} else {
+				// the client went away, nobody reads the response
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+		}
+	})
+}
+
+func (t *implTimeoutMiddleware) timeoutFor(r *http.Request) time.Duration {
+	path := r.URL.Path
+	if hasAnyPrefix(path, t.SkipPrefixes) || isStreamingRequest(r) {
+		return 0
+	}
+	for _, rule := range t.rules {
+		if strings.HasPrefix(path, rule.prefix) {
+			return rule.timeout
+		}
+	}
+	return t.Default
+}
+
+func (t *implTimeoutMiddleware) BeanOrder() int {
+	return t.beanOrder
+}
+
+func (t *implTimeoutMiddleware) Match(prefix string) bool {
+	for _, p := range t.Prefixes {
+		if strings.HasPrefix(prefix, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// isStreamingRequest reports requests whose response can not be buffered.
+func isStreamingRequest(r *http.Request) bool {
+	return strings.EqualFold(r.Header.Get("Upgrade"), "websocket") ||
+		strings.Contains(r.Header.Get("Accept"), "text/event-stream") ||
+		strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc")
+}
+
+/*
+parseTimeoutRules parses "prefix=duration" entries separated by semicolons,
+e.g. "/api/report=60s;/api=5s". Rules are returned longest prefix first.
+*/
+func parseTimeoutRules(str string) ([]timeoutRule, error) {
+	var rules []timeoutRule
+	for _, entry := range ParsePrefixList(str) {
+		prefix, value, ok := strings.Cut(entry, "=")
+		prefix = strings.TrimSpace(prefix)
+		if !ok || prefix == "" {
+			return nil, xerrors.Errorf("invalid timeout rule '%s', expected prefix=duration", entry)
+		}
+		timeout, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil {
+			return nil, xerrors.Errorf("invalid duration in timeout rule '%s': %w", entry, err)
+		}
+		if timeout <= 0 {
+			return nil, xerrors.Errorf("timeout must be positive in rule '%s'", entry)
+		}
+		rules = append(rules, timeoutRule{prefix: prefix, timeout: timeout})
+	}
+	sort.SliceStable(rules, func(i, j int) bool {
+		return len(rules[i].prefix) > len(rules[j].prefix)
+	})
+	return rules, nil
+}
+
+// timeoutWriter buffers the response until the handler returns; after the
+// deadline every write fails with http.ErrHandlerTimeout.
+type timeoutWriter struct {
+	mu     sync.Mutex
+	header http.Header
+	buf    bytes.Buffer
+	code   int
+	err    error
+}
+
+func (w *timeoutWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *timeoutWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err != nil {
+		return 0, w.err
+	}
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	return w.buf.Write(p)
+}
+
+func (w *timeoutWriter) WriteHeader(code int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err != nil || w.code != 0 {
+		return
+	}
+	w.code = code
+}
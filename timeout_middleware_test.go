@@ -0,0 +1,184 @@
+package servion
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func newTestTimeoutMiddleware(t *testing.T, rules string) *implTimeoutMiddleware {
+	t.Helper()
+	m := &implTimeoutMiddleware{
+		beanOrder:    1,
+		Log:          zap.NewNop(),
+		Prefixes:     []string{"/"},
+		Rules:        rules,
+		SkipPrefixes: []string{"/ws"},
+		Status:       http.StatusGatewayTimeout,
+	}
+	if err := m.PostConstruct(); err != nil {
+		t.Fatalf("PostConstruct: %v", err)
+	}
+	return m
+}
+
+func TestTimeoutMiddleware_CompletesInTime(t *testing.T) {
+	m := newTestTimeoutMiddleware(t, "/api=1s")
+
+	handler := m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Context().Deadline(); !ok {
+			t.Error("expected a context deadline")
+		}
+		w.Header().Set("X-Test", "yes")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("created"))
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/items", nil))
+
+	if w.Code != http.StatusCreated || w.Body.String() != "created" || w.Header().Get("X-Test") != "yes" {
+		t.Errorf("got %d %q %v", w.Code, w.Body.String(), w.Header())
+	}
+}
+
+func TestTimeoutMiddleware_DeadlineExceeded(t *testing.T) {
+	m := newTestTimeoutMiddleware(t, "/api=20ms")
+
+	handlerDone := make(chan error, 1)
+	responded := make(chan struct{})
+	handler := m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		<-responded
+		w.Header().Set("X-Late", "yes")
+		_, err := w.Write([]byte("too late"))
+		handlerDone <- err
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/report", nil))
+	close(responded)
+
+	if w.Code != http.StatusGatewayTimeout {
+		t.Fatalf("status = %d, want 504", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "request timed out") {
+		t.Errorf("body = %q", w.Body.String())
+	}
+	if err := <-handlerDone; err != http.ErrHandlerTimeout {
+		t.Errorf("late write error = %v, want ErrHandlerTimeout", err)
+	}
+	if w.Header().Get("X-Late") != "" {
+		t.Error("late headers must not reach the client")
+	}
+}
+
+func TestTimeoutMiddleware_Status503(t *testing.T) {
+	m := newTestTimeoutMiddleware(t, "/api=10ms")
+	m.Status = http.StatusServiceUnavailable
+
+	handler := m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/x", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want 503", w.Code)
+	}
+}
+
+func TestTimeoutMiddleware_RulesAndOptOut(t *testing.T) {
+	m := newTestTimeoutMiddleware(t, "/api=5s;/api/report=60s")
+	m.Default = time.Second
+
+	tests := []struct {
+		path   string
+		header [2]string
+		want   time.Duration
+	}{
+		{"/api/report/daily", [2]string{}, 60 * time.Second},
+		{"/api/users", [2]string{}, 5 * time.Second},
+		{"/index.html", [2]string{}, time.Second},
+		{"/ws/chat", [2]string{}, 0},
+		{"/api/users", [2]string{"Upgrade", "websocket"}, 0},
+		{"/api/users", [2]string{"Accept", "text/event-stream"}, 0},
+		{"/api/users", [2]string{"Content-Type", "application/grpc+proto"}, 0},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		if tt.header[0] != "" {
+			r.Header.Set(tt.header[0], tt.header[1])
+		}
+		if got := m.timeoutFor(r); got != tt.want {
+			t.Errorf("timeoutFor(%s %v) = %v, want %v", tt.path, tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestTimeoutMiddleware_NoDeadlineWithoutRule(t *testing.T) {
+	m := newTestTimeoutMiddleware(t, "/api=1s")
+
+	handler := m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Context().Deadline(); ok {
+			t.Error("unexpected deadline")
+		}
+		// unbuffered: the writer is the original one
+		if _, ok := w.(*httptest.ResponseRecorder); !ok {
+			t.Errorf("writer = %T, want the original", w)
+		}
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/static/app.js", nil))
+}
+
+func TestTimeoutMiddleware_PanicPropagates(t *testing.T) {
+	m := newTestTimeoutMiddleware(t, "/api=1s")
+	handler := m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+
+	defer func() {
+		if p := recover(); p != "boom" {
+			t.Errorf("recovered %v, want boom", p)
+		}
+	}()
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/x", nil))
+}
+
+func TestTimeoutMiddleware_ClientGone(t *testing.T) {
+	m := newTestTimeoutMiddleware(t, "/api=1s")
+	handler := m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/x", nil).WithContext(ctx))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want 503", w.Code)
+	}
+}
+
+func TestParseTimeoutRules(t *testing.T) {
+	rules, err := parseTimeoutRules("/api=5s; /api/report=60s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 2 || rules[0].prefix != "/api/report" || rules[0].timeout != time.Minute {
+		t.Errorf("rules = %+v", rules)
+	}
+	for _, bad := range []string{"/api", "/api=fast", "/api=0s", "=5s"} {
+		if _, err := parseTimeoutRules(bad); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+	m := &implTimeoutMiddleware{Status: http.StatusTeapot}
+	if err := m.PostConstruct(); err == nil {
+		t.Error("expected error for timeout.status=418")
+	}
+}
@@ -0,0 +1,401 @@
+/*
+ * Copyright (c) 2026 Karagatan LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package servion
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.arpabet.com/glue"
+	"go.uber.org/zap"
+	"golang.org/x/xerrors"
+)
+
+var tlsCertificateExpiry = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Namespace: "servion",
+		Name:      "tls_certificate_expiry_timestamp_seconds",
+		Help:      "Expiry of the certificate of a TLS profile as a Unix timestamp.",
+	},
+	[]string{"profile"},
+)
+
+func init() {
+	prometheus.MustRegister(tlsCertificateExpiry)
+}
+
+type implTlsProfiles struct {
+	Log        *zap.Logger     `inject:"optional"`
+	Runtime    Runtime         `inject:"optional"`
+	Properties glue.Properties `inject:""`
+
+	// Profile names, each configured by tls.<name>.* properties
+	Names []string `value:"tls.profiles,default="`
+
+	// How often certificate files are checked for changes
+	ReloadInterval time.Duration `value:"tls.reload-interval,default=1m"`
+
+	// Health warns of certificates expiring within this period, 0 only of expired ones
+	ExpiryWarning time.Duration `value:"tls.expiry-warning,default=336h"`
+
+	profiles map[string]*tlsProfile
+	stopCh   chan struct{}
+	wg       sync.WaitGroup
+}
+
+// tlsProfile is one named profile; its certificate is replaced on reload, the rest is fixed.
+type tlsProfile struct {
+	name       string
+	certFile   string
+	keyFile    string
+	clientAuth tls.ClientAuthType
+	minVersion uint16
+	ciphers    []uint16
+	serverName string
+	pool       *x509.CertPool
+
+	mu      sync.RWMutex
+	cert    *tls.Certificate
+	certMod time.Time
+	keyMod  time.Time
+}
+
+/*
+TlsProfiles creates the TlsConfigProvider of named TLS profiles. Every profile
+listed in tls.profiles is configured by its own properties:
+
+	tls.profiles=public;internal
+	tls.public.cert-file=tls/public.crt
+	tls.public.key-file=tls/public.key
+	tls.internal.cert-file=tls/internal.crt
+	tls.internal.key-file=tls/internal.key
+	tls.internal.ca-file=tls/ca.crt
+	tls.internal.client-auth=require-and-verify
+	tls.internal.min-version=1.3
+
+	http-server.tls-profile=public
+	grpc-server.tls-profile=internal
+	grpc-client.tls-profile=internal
+
+The CA bundle verifies client certificates on servers and server certificates on
+clients, which use the system roots without it; a client profile with a
+certificate presents it to servers. Certificate and key files are checked every
+tls.reload-interval and replaced when they changed, so a renewed certificate
+applies to new connections without a restart; a pair that fails to load is logged
+and the one loaded before stays in use. The CA bundle is read at startup.
+
+The expiry of every certificate is exported as the
+servion_tls_certificate_expiry_timestamp_seconds gauge, and HealthHandler warns
+of certificates expiring within tls.expiry-warning.
+
+Configuration properties:
+
+	tls.profiles               – semicolon separated profile names
+	tls.reload-interval        – how often certificate files are checked (default 1m)
+	tls.expiry-warning         – health warning period before expiry (default 336h)
+	tls.<name>.cert-file       – PEM certificate chain, relative to the home directory
+	tls.<name>.key-file        – PEM private key, relative to the home directory
+	tls.<name>.ca-file         – PEM CA bundle verifying the peer
+	tls.<name>.client-auth     – none, request, require, verify-if-given or require-and-verify (default "none")
+	tls.<name>.min-version     – 1.2 or 1.3 (default "1.2")
+	tls.<name>.cipher-suites   – comma separated TLS 1.2 cipher suites (default Go's)
+	tls.<name>.server-name     – server name clients verify (default the host dialed)
+*/
+func TlsProfiles() TlsConfigProvider {
+	return &implTlsProfiles{}
+}
+
+func (t *implTlsProfiles) PostConstruct() error {
+	if t.Log == nil {
+		t.Log = zap.NewNop()
+	}
+	t.profiles = make(map[string]*tlsProfile, len(t.Names))
+	for _, name := range t.Names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if _, dup := t.profiles[name]; dup {
+			return xerrors.Errorf("tls: duplicate profile '%s' in tls.profiles", name)
+		}
+		p, err := t.newProfile(name)
+		if err != nil {
+			return xerrors.Errorf("tls: profile '%s': %w", name, err)
+		}
+		if p.certFile != "" {
+			if err := t.load(p); err != nil {
+				return xerrors.Errorf("tls: profile '%s': %w", name, err)
+			}
+		}
+		t.profiles[name] = p
+	}
+	if t.ReloadInterval > 0 && len(t.profiles) > 0 {
+		t.stopCh = make(chan struct{})
+		t.wg.Add(1)
+		go t.reloadLoop(t.stopCh)
+	}
+	return nil
+}
+
+func (t *implTlsProfiles) Destroy() error {
+	if t.stopCh != nil {
+		close(t.stopCh)
+		t.stopCh = nil
+	}
+	t.wg.Wait()
+	return nil
+}
+
+func (t *implTlsProfiles) property(name, key string) string {
+	return strings.TrimSpace(t.Properties.GetString(fmt.Sprintf("tls.%s.%s", name, key), ""))
+}
+
+func (t *implTlsProfiles) resolve(file string) string {
+	if file != "" && !filepath.IsAbs(file) && t.Runtime != nil {
+		return filepath.Join(t.Runtime.HomeDir(), file)
+	}
+	return file
+}
+
+func (t *implTlsProfiles) newProfile(name string) (*tlsProfile, error) {
+	p := &tlsProfile{
+		name:       name,
+		certFile:   t.resolve(t.property(name, "cert-file")),
+		keyFile:    t.resolve(t.property(name, "key-file")),
+		serverName: t.property(name, "server-name"),
+	}
+	if (p.certFile == "") != (p.keyFile == "") {
+		return nil, xerrors.New("cert-file and key-file go together")
+	}
+	var err error
+	if p.clientAuth, err = parseClientAuth(t.property(name, "client-auth")); err != nil {
+		return nil, err
+	}
+	if p.minVersion, err = parseTlsVersion(t.property(name, "min-version")); err != nil {
+		return nil, err
+	}
+	if p.ciphers, err = parseCipherSuites(t.property(name, "cipher-suites")); err != nil {
+		return nil, err
+	}
+	if caFile := t.resolve(t.property(name, "ca-file")); caFile != "" {
+		data, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, xerrors.Errorf("read CA bundle: %w", err)
+		}
+		p.pool = x509.NewCertPool()
+		if !p.pool.AppendCertsFromPEM(data) {
+			return nil, xerrors.Errorf("no certificate in CA bundle %s", caFile)
+		}
+	}
+	if p.clientAuth >= tls.VerifyClientCertIfGiven && p.pool == nil {
+		return nil, xerrors.New("client-auth verifying client certificates needs a ca-file")
+	}
+	return p, nil
+}
+
+func (t *implTlsProfiles) reloadLoop(stopCh <-chan struct{}) {
+	defer t.wg.Done()
+	ticker := time.NewTicker(t.ReloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			for _, p := range t.profiles {
+				if p.certFile == "" {
+					continue
+				}
+				if err := t.load(p); err != nil {
+					t.Log.Warn("TlsCertificateReloadFailed", zap.String("profile", p.name), zap.String("file", p.certFile), zap.Error(err))
+				}
+			}
+		case <-stopCh:
+			return
+		}
+	}
+}
+
+// load reads the certificate and key of p again when either file changed.
+func (t *implTlsProfiles) load(p *tlsProfile) error {
+	certInfo, err := os.Stat(p.certFile)
+	if err != nil {
+		return xerrors.Errorf("stat certificate: %w", err)
+	}
+	keyInfo, err := os.Stat(p.keyFile)
+	if err != nil {
+		return xerrors.Errorf("stat key: %w", err)
+	}
+	p.mu.RLock()
+	unchanged := p.cert != nil && certInfo.ModTime().Equal(p.certMod) && keyInfo.ModTime().Equal(p.keyMod)
+	p.mu.RUnlock()
+	if unchanged {
+		return nil
+	}
+	cert, err := tls.LoadX509KeyPair(p.certFile, p.keyFile)
+	if err != nil {
+		return xerrors.Errorf("load certificate: %w", err)
+	}
+	if cert.Leaf == nil {
+		if cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+			return xerrors.Errorf("parse certificate: %w", err)
+		}
+	}
+	p.mu.Lock()
+	p.cert, p.certMod, p.keyMod = &cert, certInfo.ModTime(), keyInfo.ModTime()
+	p.mu.Unlock()
+	tlsCertificateExpiry.WithLabelValues(p.name).Set(float64(cert.Leaf.NotAfter.Unix()))
+	t.Log.Info("TlsCertificateLoaded",
+		zap.String("profile", p.name),
+		zap.String("subject", cert.Leaf.Subject.String()),
+		zap.Time("notAfter", cert.Leaf.NotAfter))
+	return nil
+}
+
+func (t *implTlsProfiles) profile(name string) (*tlsProfile, error) {
+	p, ok := t.profiles[name]
+	if !ok {
+		return nil, xerrors.Errorf("unknown TLS profile '%s', expected one of tls.profiles", name)
+	}
+	return p, nil
+}
+
+func (t *implTlsProfiles) ServerConfig(name string) (*tls.Config, error) {
+	p, err := t.profile(name)
+	if err != nil {
+		return nil, err
+	}
+	if p.certFile == "" {
+		return nil, xerrors.Errorf("TLS profile '%s' has no certificate for a server", name)
+	}
+	return &tls.Config{
+		MinVersion:   p.minVersion,
+		CipherSuites: p.ciphers,
+		ClientAuth:   p.clientAuth,
+		ClientCAs:    p.pool,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return p.certificate(), nil
+		},
+	}, nil
+}
+
+func (t *implTlsProfiles) ClientConfig(name string) (*tls.Config, error) {
+	p, err := t.profile(name)
+	if err != nil {
+		return nil, err
+	}
+	cfg := &tls.Config{
+		MinVersion:   p.minVersion,
+		CipherSuites: p.ciphers,
+		RootCAs:      p.pool,
+		ServerName:   p.serverName,
+	}
+	if p.certFile != "" {
+		cfg.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return p.certificate(), nil
+		}
+	}
+	return cfg, nil
+}
+
+func (p *tlsProfile) certificate() *tls.Certificate {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.cert
+}
+
+func (t *implTlsProfiles) BeanName() string {
+	return "tls-profiles"
+}
+
+func (t *implTlsProfiles) GetStats(cb func(name, value string) bool) error {
+	for _, name := range t.sortedNames() {
+		if cert := t.profiles[name].certificate(); cert != nil {
+			cb(name+".subject", cert.Leaf.Subject.String())
+			cb(name+".not-after", cert.Leaf.NotAfter.UTC().Format(time.RFC3339))
+		}
+	}
+	return nil
+}
+
+func (t *implTlsProfiles) HealthWarnings() []string {
+	now := time.Now()
+	var warnings []string
+	for _, name := range t.sortedNames() {
+		cert := t.profiles[name].certificate()
+		if cert == nil {
+			continue
+		}
+		notAfter := cert.Leaf.NotAfter
+		switch {
+		case !now.Before(notAfter):
+			warnings = append(warnings, fmt.Sprintf("TLS profile '%s': certificate expired at %s", name, notAfter.UTC().Format(time.RFC3339)))
+		case notAfter.Sub(now) < t.ExpiryWarning:
+			warnings = append(warnings, fmt.Sprintf("TLS profile '%s': certificate expires at %s", name, notAfter.UTC().Format(time.RFC3339)))
+		}
+	}
+	return warnings
+}
+
+func (t *implTlsProfiles) sortedNames() []string {
+	names := make([]string, 0, len(t.profiles))
+	for name := range t.profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func parseClientAuth(s string) (tls.ClientAuthType, error) {
+	switch s {
+	case "", "none":
+		return tls.NoClientCert, nil
+	case "request":
+		return tls.RequestClientCert, nil
+	case "require":
+		return tls.RequireAnyClientCert, nil
+	case "verify-if-given":
+		return tls.VerifyClientCertIfGiven, nil
+	case "require-and-verify":
+		return tls.RequireAndVerifyClientCert, nil
+	}
+	return 0, xerrors.Errorf("unknown client-auth '%s', expected none, request, require, verify-if-given or require-and-verify", s)
+}
+
+func parseTlsVersion(s string) (uint16, error) {
+	switch s {
+	case "", "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	}
+	return 0, xerrors.Errorf("unsupported min-version '%s', expected 1.2 or 1.3", s)
+}
+
+// parseCipherSuites maps comma separated IANA names to the secure suites of crypto/tls.
+func parseCipherSuites(s string) ([]uint16, error) {
+	var ids []uint16
+	for _, name := range commaList(s) {
+		id, ok := uint16(0), false
+		for _, suite := range tls.CipherSuites() {
+			if suite.Name == name {
+				id, ok = suite.ID, true
+				break
+			}
+		}
+		if !ok {
+			return nil, xerrors.Errorf("unknown or insecure cipher suite '%s'", name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
@@ -0,0 +1,289 @@
+package servion
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.arpabet.com/glue"
+	"go.uber.org/zap"
+)
+
+// writeTestPair signs a certificate for dnsName valid until notAfter and writes it with its key as PEM files.
+func writeTestPair(t *testing.T, ca *testCA, certFile, keyFile, dnsName string, notAfter time.Time) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: dnsName},
+		DNSNames:     []string{dnsName},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     notAfter,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDer}), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeTestCA(t *testing.T, ca *testCA, file string) {
+	t.Helper()
+	if err := os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.cert.Raw}), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newTestTlsProfiles(t *testing.T, props glue.Properties, names ...string) (*implTlsProfiles, error) {
+	t.Helper()
+	p := &implTlsProfiles{
+		Log:           zap.NewNop(),
+		Properties:    props,
+		Names:         names,
+		ExpiryWarning: 14 * 24 * time.Hour,
+	}
+	if err := p.PostConstruct(); err != nil {
+		return nil, err
+	}
+	t.Cleanup(func() { p.Destroy() })
+	return p, nil
+}
+
+func TestTlsProfiles_MutualHandshake(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	writeTestCA(t, ca, filepath.Join(dir, "ca.crt"))
+	writeTestPair(t, ca, filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key"), "localhost", time.Now().Add(time.Hour))
+	writeTestPair(t, ca, filepath.Join(dir, "client.crt"), filepath.Join(dir, "client.key"), "billing", time.Now().Add(time.Hour))
+
+	props := glue.NewProperties()
+	props.Set("tls.server.cert-file", filepath.Join(dir, "server.crt"))
+	props.Set("tls.server.key-file", filepath.Join(dir, "server.key"))
+	props.Set("tls.server.ca-file", filepath.Join(dir, "ca.crt"))
+	props.Set("tls.server.client-auth", "require-and-verify")
+	props.Set("tls.server.min-version", "1.3")
+	props.Set("tls.client.cert-file", filepath.Join(dir, "client.crt"))
+	props.Set("tls.client.key-file", filepath.Join(dir, "client.key"))
+	props.Set("tls.client.ca-file", filepath.Join(dir, "ca.crt"))
+	props.Set("tls.client.server-name", "localhost")
+	profiles, err := newTestTlsProfiles(t, props, "server", "client")
+	if err != nil {
+		t.Fatalf("PostConstruct: %v", err)
+	}
+
+	serverCfg, err := profiles.ServerConfig("server")
+	if err != nil {
+		t.Fatalf("ServerConfig: %v", err)
+	}
+	if serverCfg.MinVersion != tls.VersionTLS13 || serverCfg.ClientAuth != tls.RequireAndVerifyClientCert {
+		t.Errorf("server config = min %x auth %v", serverCfg.MinVersion, serverCfg.ClientAuth)
+	}
+	clientCfg, err := profiles.ClientConfig("client")
+	if err != nil {
+		t.Fatalf("ClientConfig: %v", err)
+	}
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", serverCfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	peer := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			peer <- err.Error()
+			return
+		}
+		defer conn.Close()
+		tc := conn.(*tls.Conn)
+		if err := tc.Handshake(); err != nil {
+			peer <- err.Error()
+			return
+		}
+		chains := tc.ConnectionState().VerifiedChains
+		if len(chains) == 0 {
+			peer <- "no verified chain"
+			return
+		}
+		peer <- chains[0][0].Subject.CommonName
+	}()
+
+	conn, err := tls.Dial("tcp", ln.Addr().String(), clientCfg)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	defer conn.Close()
+	if got := <-peer; got != "billing" {
+		t.Errorf("server saw client %q, want billing", got)
+	}
+}
+
+func TestTlsProfiles_Reload(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	writeTestPair(t, ca, certFile, keyFile, "old.example.com", time.Now().Add(time.Hour))
+
+	props := glue.NewProperties()
+	props.Set("tls.web.cert-file", certFile)
+	props.Set("tls.web.key-file", keyFile)
+	profiles, err := newTestTlsProfiles(t, props, "web")
+	if err != nil {
+		t.Fatalf("PostConstruct: %v", err)
+	}
+	cfg, _ := profiles.ServerConfig("web")
+	first, _ := cfg.GetCertificate(nil)
+	if first.Leaf.Subject.CommonName != "old.example.com" {
+		t.Fatalf("loaded %q", first.Leaf.Subject.CommonName)
+	}
+
+	notAfter := time.Now().Add(48 * time.Hour).Truncate(time.Second)
+	writeTestPair(t, ca, certFile, keyFile, "new.example.com", notAfter)
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(certFile, later, later)
+	os.Chtimes(keyFile, later, later)
+	p := profiles.profiles["web"]
+	if err := profiles.load(p); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	// configurations handed out before read the new certificate
+	second, _ := cfg.GetCertificate(nil)
+	if second.Leaf.Subject.CommonName != "new.example.com" {
+		t.Errorf("after reload %q, want new.example.com", second.Leaf.Subject.CommonName)
+	}
+	if got := testutil.ToFloat64(tlsCertificateExpiry.WithLabelValues("web")); got != float64(notAfter.Unix()) {
+		t.Errorf("expiry gauge = %v, want %v", got, notAfter.Unix())
+	}
+
+	// a broken pair keeps the certificate loaded before
+	os.WriteFile(keyFile, []byte("garbage"), 0600)
+	evenLater := later.Add(time.Minute)
+	os.Chtimes(keyFile, evenLater, evenLater)
+	if err := profiles.load(p); err == nil {
+		t.Error("expected error for a broken key")
+	}
+	if third, _ := cfg.GetCertificate(nil); third != second {
+		t.Error("broken pair replaced the certificate")
+	}
+}
+
+func TestTlsProfiles_HealthWarnings(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	writeTestPair(t, ca, filepath.Join(dir, "soon.crt"), filepath.Join(dir, "soon.key"), "soon", time.Now().Add(24*time.Hour))
+	writeTestPair(t, ca, filepath.Join(dir, "later.crt"), filepath.Join(dir, "later.key"), "later", time.Now().Add(90*24*time.Hour))
+
+	props := glue.NewProperties()
+	for _, name := range []string{"soon", "later"} {
+		props.Set("tls."+name+".cert-file", filepath.Join(dir, name+".crt"))
+		props.Set("tls."+name+".key-file", filepath.Join(dir, name+".key"))
+	}
+	profiles, err := newTestTlsProfiles(t, props, "soon", "later")
+	if err != nil {
+		t.Fatalf("PostConstruct: %v", err)
+	}
+	warnings := profiles.HealthWarnings()
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "'soon'") {
+		t.Errorf("warnings = %v, want one of profile soon", warnings)
+	}
+
+	stats := map[string]string{}
+	profiles.GetStats(func(name, value string) bool {
+		stats[name] = value
+		return true
+	})
+	if stats["later.subject"] != "CN=later" || stats["soon.not-after"] == "" {
+		t.Errorf("stats = %v", stats)
+	}
+
+	profiles.ExpiryWarning = 0
+	if warnings := profiles.HealthWarnings(); len(warnings) != 0 {
+		t.Errorf("warnings without a period = %v", warnings)
+	}
+}
+
+func TestTlsProfiles_Lookup(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	writeTestCA(t, ca, filepath.Join(dir, "ca.crt"))
+	props := glue.NewProperties()
+	props.Set("tls.upstream.ca-file", filepath.Join(dir, "ca.crt"))
+	props.Set("tls.upstream.cipher-suites", "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256, TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256")
+	profiles, err := newTestTlsProfiles(t, props, "upstream")
+	if err != nil {
+		t.Fatalf("PostConstruct: %v", err)
+	}
+
+	cfg, err := profiles.ClientConfig("upstream")
+	if err != nil {
+		t.Fatalf("ClientConfig: %v", err)
+	}
+	if cfg.RootCAs == nil || cfg.GetClientCertificate != nil || len(cfg.CipherSuites) != 2 {
+		t.Errorf("client config = %+v", cfg)
+	}
+	if _, err := profiles.ServerConfig("upstream"); err == nil {
+		t.Error("expected error for a server profile without a certificate")
+	}
+	if _, err := profiles.ClientConfig("missing"); err == nil {
+		t.Error("expected error for an unknown profile")
+	}
+}
+
+func TestTlsProfiles_InvalidConfig(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	writeTestCA(t, ca, filepath.Join(dir, "ca.crt"))
+	writeTestPair(t, ca, filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key"), "localhost", time.Now().Add(time.Hour))
+
+	cases := map[string]map[string]string{
+		"cert without key":    {"cert-file": filepath.Join(dir, "tls.crt")},
+		"missing cert":        {"cert-file": filepath.Join(dir, "none.crt"), "key-file": filepath.Join(dir, "tls.key")},
+		"missing CA":          {"ca-file": filepath.Join(dir, "none.crt")},
+		"CA without certs":    {"ca-file": filepath.Join(dir, "tls.key")},
+		"unknown client-auth": {"client-auth": "always"},
+		"verify without CA":   {"client-auth": "require-and-verify"},
+		"old min-version":     {"min-version": "1.0"},
+		"insecure cipher":     {"cipher-suites": "TLS_RSA_WITH_RC4_128_SHA"},
+		"dash cipher":         {"cipher-suites": "-"},
+	}
+	for name, settings := range cases {
+		t.Run(name, func(t *testing.T) {
+			props := glue.NewProperties()
+			for k, v := range settings {
+				props.Set("tls.bad."+k, v)
+			}
+			if _, err := newTestTlsProfiles(t, props, "bad"); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+
+	if _, err := newTestTlsProfiles(t, glue.NewProperties(), "dup", "dup"); err == nil {
+		t.Error("expected error for a duplicate profile")
+	}
+}
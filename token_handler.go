@@ -0,0 +1,264 @@
+/*
+ * Copyright (c) 2026 Karagatan LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package servion
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+	"golang.org/x/xerrors"
+)
+
+// maxTokenRequestSize caps the form of a token request.
+const maxTokenRequestSize = 64 << 10
+
+type implTokenHandler struct {
+	Log     *zap.Logger `inject:"optional"`
+	Runtime Runtime     `inject:"optional"`
+
+	HandlerPattern string `value:"token.pattern,default=/oauth/token"`
+
+	// Client file in the format of the API key file, relative paths resolve against the home directory
+	ClientsFile string `value:"token.clients-file,default="`
+
+	// Client lines in the format of the API key file, instead of a file
+	Clients []string `value:"token.clients,default="`
+
+	// How often the client file is checked for changes
+	ReloadInterval time.Duration `value:"token.reload-interval,default=10s"`
+
+	// Signing key, exactly one of them: the jwt.secret of the verifiers, or the private half of jwt.public-key or a JWKS key
+	Secret         string `value:"token.secret,default="`
+	PrivateKey     string `value:"token.private-key,default="`
+	PrivateKeyFile string `value:"token.private-key-file,default="`
+
+	// Signing algorithm, default by key type; key ID, default the RFC 7638 thumbprint
+	Alg string `value:"token.alg,default="`
+	Kid string `value:"token.kid,default="`
+
+	Issuer   string        `value:"token.issuer,default="`
+	Audience string        `value:"token.audience,default="`
+	Lifetime time.Duration `value:"token.lifetime,default=1h"`
+
+	RolesClaim  string `value:"token.roles-claim,default=roles"`
+	ScopesClaim string `value:"token.scopes-claim,default=scope"`
+
+	clients *implApiKeyProvider
+	signer  *jwtSigner
+}
+
+/*
+TokenHandler creates an HttpHandler serving the OAuth 2.0 client_credentials
+grant (RFC 6749 section 4.4), so a service issues machine tokens to its own
+consumers. Clients authenticate with HTTP Basic or the client_id and
+client_secret form fields:
+
+	curl -u billing-job:$SECRET -d grant_type=client_credentials -d scope=invoices.rw \
+	    https://api.example.com/oauth/token
+
+Clients are listed like API keys, the SHA-256 of the secret, the client ID,
+roles, allowed scopes, expiry and flags, in token.clients-file (reloaded on
+change) or token.clients; "servion apikey --subject billing-job" generates a
+secret and its line. A request for no scope gets all allowed scopes, one for a
+scope the client lacks is refused with invalid_scope. The access token is a JWT
+with iss, sub and client_id (the client ID), aud, iat, exp, jti, the roles and
+the scopes, signed with the key JwtAuthProvider verifies. exp never outlives the
+client credentials. No refresh token is issued, as the grant does not need one:
+clients request a new token with their credentials.
+
+Configuration properties:
+
+	token.pattern          – URL pattern (default "/oauth/token")
+	token.clients-file     – client file, relative to the home directory
+	token.clients          – client lines separated by ';', instead of a file
+	token.reload-interval  – how often the client file is checked for changes (default 10s)
+	token.secret           – HMAC secret, the jwt.secret of the verifiers
+	token.private-key      – private key as PEM or base64 PKCS#8 DER
+	token.private-key-file – private key file, relative to the home directory
+	token.alg              – signing algorithm (default HS256, ES256/384/512 by curve, RS256 or EdDSA)
+	token.kid              – key ID (default the RFC 7638 thumbprint of the key)
+	token.issuer           – iss claim
+	token.audience         – aud claim
+	token.lifetime         – token lifetime (default 1h)
+	token.roles-claim      – claim name of the roles (default "roles")
+	token.scopes-claim     – claim name of the scopes (default "scope")
+*/
+func TokenHandler() HttpHandler {
+	return &implTokenHandler{}
+}
+
+func (t *implTokenHandler) PostConstruct() error {
+	if t.Log == nil {
+		t.Log = zap.NewNop()
+	}
+	if t.Lifetime <= 0 {
+		return xerrors.Errorf("token: token.lifetime must be positive, got %s", t.Lifetime)
+	}
+
+	keyFile := t.PrivateKeyFile
+	if keyFile != "" && !filepath.IsAbs(keyFile) && t.Runtime != nil {
+		keyFile = filepath.Join(t.Runtime.HomeDir(), keyFile)
+	}
+	signer, err := newJwtSigner(t.Secret, t.PrivateKey, keyFile, t.Alg, t.Kid)
+	if err != nil {
+		return xerrors.Errorf("token: token.secret, token.private-key, token.private-key-file: %w", err)
+	}
+	t.signer = signer
+
+	var lines []string
+	for _, line := range t.Clients {
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+	switch {
+	case t.ClientsFile != "" && len(lines) > 0:
+		return xerrors.New("token: token.clients-file and token.clients are mutually exclusive")
+	case t.ClientsFile != "":
+		t.clients = &implApiKeyProvider{Log: t.Log, Runtime: t.Runtime, File: t.ClientsFile, ReloadInterval: t.ReloadInterval}
+		if err := t.clients.PostConstruct(); err != nil {
+			return xerrors.Errorf("token: %w", err)
+		}
+	case len(lines) > 0:
+		keys, err := parseApiKeys([]byte(strings.Join(lines, "\n")))
+		if err != nil {
+			return xerrors.Errorf("token: token.clients:%w", err)
+		}
+		t.clients = &implApiKeyProvider{Log: t.Log, keys: keys}
+	default:
+		return xerrors.New("token: one of token.clients-file or token.clients must be configured")
+	}
+	return nil
+}
+
+func (t *implTokenHandler) Destroy() error {
+	if t.clients != nil {
+		return t.clients.Destroy()
+	}
+	return nil
+}
+
+func (t *implTokenHandler) Pattern() string {
+	return t.HandlerPattern
+}
+
+func (t *implTokenHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxTokenRequestSize)
+	if err := r.ParseForm(); err != nil {
+		writeTokenError(w, http.StatusBadRequest, "invalid_request", "invalid form")
+		return
+	}
+	if r.PostForm.Get("grant_type") != "client_credentials" {
+		writeTokenError(w, http.StatusBadRequest, "unsupported_grant_type", "only client_credentials is supported")
+		return
+	}
+
+	id, secret, basic := r.BasicAuth()
+	if basic {
+		if r.PostForm.Has("client_secret") {
+			writeTokenError(w, http.StatusBadRequest, "invalid_request", "more than one client authentication method")
+			return
+		}
+		// RFC 6749 section 2.3.1 form-encodes both before Basic
+		id, _ = url.QueryUnescape(id)
+		secret, _ = url.QueryUnescape(secret)
+	} else {
+		id, secret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	client, err := t.clients.Authenticate(secret)
+	if err == nil && client.Subject != id {
+		err = ErrUnauthorized
+	}
+	if err != nil || id == "" {
+		t.Log.Debug("TokenClientRejected", zap.String("client", id), zap.Error(err))
+		w.Header().Set("WWW-Authenticate", `Basic realm="token"`)
+		writeTokenError(w, http.StatusUnauthorized, "invalid_client", "client authentication failed")
+		return
+	}
+
+	scopes := client.Scopes
+	if requested := strings.Fields(r.PostForm.Get("scope")); len(requested) > 0 {
+		for _, s := range requested {
+			if !containsString(client.Scopes, s) {
+				writeTokenError(w, http.StatusBadRequest, "invalid_scope", "scope '"+s+"' is not allowed")
+				return
+			}
+		}
+		scopes = requested
+	}
+
+	now := time.Now()
+	exp := now.Add(t.Lifetime)
+	if v := client.Attributes["expires"]; v != "" {
+		if expires, err := time.Parse(time.RFC3339, v); err == nil && expires.Before(exp) {
+			exp = expires
+		}
+	}
+	claims := jwt.MapClaims{
+		"sub":       client.Subject,
+		"client_id": client.Subject,
+		"iat":       now.Unix(),
+		"exp":       exp.Unix(),
+		"jti":       randomToken(),
+	}
+	if t.Issuer != "" {
+		claims["iss"] = t.Issuer
+	}
+	if t.Audience != "" {
+		claims["aud"] = t.Audience
+	}
+	if len(client.Roles) > 0 {
+		claims[t.RolesClaim] = client.Roles
+	}
+	if len(scopes) > 0 {
+		claims[t.ScopesClaim] = strings.Join(scopes, " ")
+	}
+	token, err := t.signer.sign(claims)
+	if err != nil {
+		t.Log.Error("TokenSign", zap.String("client", client.Subject), zap.Error(err))
+		writeTokenError(w, http.StatusInternalServerError, "server_error", "")
+		return
+	}
+	t.Log.Info("TokenIssued", zap.String("client", client.Subject), zap.Strings("scopes", scopes))
+
+	resp := map[string]interface{}{
+		"access_token": token,
+		"token_type":   "Bearer",
+		"expires_in":   int64(exp.Sub(now).Seconds()),
+	}
+	if len(scopes) > 0 {
+		resp["scope"] = strings.Join(scopes, " ")
+	}
+	writeTokenResponse(w, http.StatusOK, resp)
+}
+
+// writeTokenError writes an error response of RFC 6749 section 5.2.
+func writeTokenError(w http.ResponseWriter, status int, code, description string) {
+	resp := map[string]interface{}{"error": code}
+	if description != "" {
+		resp["error_description"] = description
+	}
+	writeTokenResponse(w, status, resp)
+}
+
+func writeTokenResponse(w http.ResponseWriter, status int, resp map[string]interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,171 @@
+package servion
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTokenHandler(t *testing.T, configure func(h *implTokenHandler)) *implTokenHandler {
+	t.Helper()
+	h := &implTokenHandler{
+		HandlerPattern: "/oauth/token",
+		Lifetime:       time.Hour,
+		RolesClaim:     "roles",
+		ScopesClaim:    "scope",
+	}
+	configure(h)
+	if err := h.PostConstruct(); err != nil {
+		t.Fatalf("PostConstruct: %v", err)
+	}
+	t.Cleanup(func() { h.Destroy() })
+	return h
+}
+
+func requestToken(h http.Handler, form url.Values, id, secret string) (*httptest.ResponseRecorder, map[string]interface{}) {
+	r := httptest.NewRequest(http.MethodPost, "/oauth/token", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if id != "" {
+		r.SetBasicAuth(url.QueryEscape(id), url.QueryEscape(secret))
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+	var body map[string]interface{}
+	json.Unmarshal(rec.Body.Bytes(), &body)
+	return rec, body
+}
+
+func TestTokenHandler_ClientCredentials(t *testing.T) {
+	priv, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	privDER, _ := x509.MarshalPKCS8PrivateKey(priv)
+	pubDER, _ := x509.MarshalPKIXPublicKey(&priv.PublicKey)
+
+	h := newTokenHandler(t, func(h *implTokenHandler) {
+		h.PrivateKey = base64.StdEncoding.EncodeToString(privDER)
+		h.Issuer = "https://api.example.com"
+		h.Audience = "billing"
+		h.Clients = []string{
+			hashToken("s3cret:+/") + " billing-job service invoices.read,invoices.write -",
+			hashToken("other") + " blocked - - - disabled",
+		}
+	})
+	verifier := &implJwtAuthProvider{
+		PublicKeyB64: base64.StdEncoding.EncodeToString(pubDER),
+		Issuer:       "https://api.example.com",
+		Audience:     "billing",
+		RolesClaim:   "roles",
+		ScopesClaim:  "scope",
+	}
+	if err := verifier.PostConstruct(); err != nil {
+		t.Fatal(err)
+	}
+
+	form := url.Values{"grant_type": {"client_credentials"}, "scope": {"invoices.read"}}
+	rec, body := requestToken(h, form, "billing-job", "s3cret:+/")
+	if rec.Code != http.StatusOK || rec.Header().Get("Cache-Control") != "no-store" {
+		t.Fatalf("expected 200, got %d %s", rec.Code, rec.Body)
+	}
+	if body["token_type"] != "Bearer" || body["scope"] != "invoices.read" || body["expires_in"].(float64) != 3600 {
+		t.Errorf("unexpected response %v", body)
+	}
+	info, err := verifier.Authenticate(body["access_token"].(string))
+	if err != nil {
+		t.Fatalf("issued token does not verify: %v", err)
+	}
+	if info.Subject != "billing-job" || strings.Join(info.Roles, ",") != "service" || strings.Join(info.Scopes, ",") != "invoices.read" {
+		t.Errorf("unexpected identity %+v", info)
+	}
+
+	// no scope requested gets all allowed scopes, with client_secret_post
+	rec, body = requestToken(h, url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {"billing-job"},
+		"client_secret": {"s3cret:+/"},
+	}, "", "")
+	if rec.Code != http.StatusOK || body["scope"] != "invoices.read invoices.write" {
+		t.Errorf("expected all scopes, got %d %v", rec.Code, body)
+	}
+}
+
+func TestTokenHandler_Errors(t *testing.T) {
+	h := newTokenHandler(t, func(h *implTokenHandler) {
+		h.Secret = "signing-secret"
+		h.Clients = []string{
+			hashToken("s3cret") + " billing-job - invoices.read -",
+			hashToken("old") + " old-job - - " + time.Now().Add(-time.Hour).UTC().Format(time.RFC3339),
+		}
+	})
+	grant := url.Values{"grant_type": {"client_credentials"}}
+
+	cases := []struct {
+		name   string
+		form   url.Values
+		id     string
+		secret string
+		status int
+		code   string
+	}{
+		{"wrong secret", grant, "billing-job", "wrong", http.StatusUnauthorized, "invalid_client"},
+		{"secret of another client", grant, "other-job", "s3cret", http.StatusUnauthorized, "invalid_client"},
+		{"expired client", grant, "old-job", "old", http.StatusUnauthorized, "invalid_client"},
+		{"no credentials", grant, "", "", http.StatusUnauthorized, "invalid_client"},
+		{"scope not allowed", url.Values{"grant_type": {"client_credentials"}, "scope": {"invoices.write"}}, "billing-job", "s3cret", http.StatusBadRequest, "invalid_scope"},
+		{"password grant", url.Values{"grant_type": {"password"}}, "billing-job", "s3cret", http.StatusBadRequest, "unsupported_grant_type"},
+		{"two methods", url.Values{"grant_type": {"client_credentials"}, "client_secret": {"s3cret"}}, "billing-job", "s3cret", http.StatusBadRequest, "invalid_request"},
+	}
+	for _, c := range cases {
+		rec, body := requestToken(h, c.form, c.id, c.secret)
+		if rec.Code != c.status || body["error"] != c.code {
+			t.Errorf("%s: got %d %v, want %d %s", c.name, rec.Code, body, c.status, c.code)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/oauth/token", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET: expected 405, got %d", rec.Code)
+	}
+}
+
+func TestTokenHandler_ExpiryCappedByClient(t *testing.T) {
+	expires := time.Now().Add(10 * time.Minute).Truncate(time.Second)
+	path := filepath.Join(t.TempDir(), "clients")
+	writeApiKeys(t, path, hashToken("s3cret")+" job - - "+expires.UTC().Format(time.RFC3339))
+	h := newTokenHandler(t, func(h *implTokenHandler) {
+		h.Secret = "signing-secret"
+		h.ClientsFile = path
+	})
+
+	rec, body := requestToken(h, url.Values{"grant_type": {"client_credentials"}}, "job", "s3cret")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d %s", rec.Code, rec.Body)
+	}
+	if got := body["expires_in"].(float64); got > 600 || got < 590 {
+		t.Errorf("expires_in = %v, want the remaining client lifetime", got)
+	}
+}
+
+func TestTokenHandler_PostConstruct(t *testing.T) {
+	client := hashToken("s3cret") + " job - - -"
+	for name, h := range map[string]*implTokenHandler{
+		"no key":        {Lifetime: time.Hour, Clients: []string{client}},
+		"no clients":    {Lifetime: time.Hour, Secret: "x"},
+		"both clients":  {Lifetime: time.Hour, Secret: "x", Clients: []string{client}, ClientsFile: "clients"},
+		"bad client":    {Lifetime: time.Hour, Secret: "x", Clients: []string{"nothash job - - -"}},
+		"zero lifetime": {Secret: "x", Clients: []string{client}},
+	} {
+		if err := h.PostConstruct(); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+/*
+ * Copyright (c) 2026 Karagatan LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package servion
+
+import (
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+	"golang.org/x/xerrors"
+)
+
+type implUserAuthProvider struct {
+	Log     *zap.Logger `inject:"optional"`
+	Runtime Runtime     `inject:"optional"`
+	Users   UserStore   `inject:""`
+
+	Secret         string `value:"user.secret,default="`
+	PrivateKey     string `value:"user.private-key,default="`
+	PrivateKeyFile string `value:"user.private-key-file,default="`
+	Alg            string `value:"user.alg,default="`
+	Kid            string `value:"user.kid,default="`
+
+	Issuer   string `value:"user.issuer,default="`
+	Audience string `value:"user.audience,default="`
+
+	parser *jwt.Parser
+	key    interface{}
+}
+
+/*
+UserAuthProvider creates an Authenticator of the tokens LoginHandler issues, so
+local users log in through AuthMiddleware like any bearer token. It reads the
+same user.* key, issuer and audience properties as the handler. Beyond the
+signature and expiry of a token it checks the account in the UserStore on every
+request: tokens of deleted or disabled users, and tokens issued before the last
+password change, are refused at once, and roles are those of the account now,
+not those at login.
+*/
+func UserAuthProvider() Authenticator {
+	return &implUserAuthProvider{}
+}
+
+func (t *implUserAuthProvider) PostConstruct() error {
+	if t.Log == nil {
+		t.Log = zap.NewNop()
+	}
+	signer, err := newUserTokenSigner(t.Runtime, t.Secret, t.PrivateKey, t.PrivateKeyFile, t.Alg, t.Kid)
+	if err != nil {
+		return err
+	}
+	t.key = signer.verificationKey()
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{signer.method.Alg()}),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	}
+	if t.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(t.Issuer))
+	}
+	if t.Audience != "" {
+		opts = append(opts, jwt.WithAudience(t.Audience))
+	}
+	t.parser = jwt.NewParser(opts...)
+	return nil
+}
+
+func (t *implUserAuthProvider) Authenticate(tokenStr string) (AuthInfo, error) {
+	claims := jwt.MapClaims{}
+	_, err := t.parser.ParseWithClaims(tokenStr, claims, func(*jwt.Token) (interface{}, error) {
+		return t.key, nil
+	})
+	if err != nil {
+		return AuthInfo{}, xerrors.Errorf("%v: %w", err, ErrUnauthorized)
+	}
+	if !containsString(claimStringSlice(claims, "amr"), "pwd") {
+		return AuthInfo{}, xerrors.Errorf("not a login token: %w", ErrUnauthorized)
+	}
+	username := claimString(claims, "sub")
+	iat, _ := claims.GetIssuedAt()
+	if username == "" || iat == nil {
+		return AuthInfo{}, xerrors.Errorf("login token without sub or iat: %w", ErrUnauthorized)
+	}
+
+	user, err := t.Users.Load(username)
+	if err != nil {
+		t.Log.Error("UserStore", zap.String("user", username), zap.Error(err))
+		return AuthInfo{}, xerrors.Errorf("user store: %v: %w", err, ErrServiceUnavailable)
+	}
+	switch {
+	case user == nil:
+		return AuthInfo{}, xerrors.Errorf("user '%s' is unknown: %w", username, ErrUnauthorized)
+	case user.Disabled:
+		return AuthInfo{}, xerrors.Errorf("user '%s' is disabled: %w", username, ErrUnauthorized)
+	case iat.Before(user.PasswordChanged.Truncate(time.Second)):
+		return AuthInfo{}, xerrors.Errorf("token of '%s' predates the password change: %w", username, ErrUnauthorized)
+	}
+
+	info := AuthInfo{
+		HashedToken: hashToken(tokenStr),
+		Subject:     user.Username,
+		Roles:       user.Roles,
+		Attributes:  map[string]string{"auth": "password"},
+	}
+	if exp, err := claims.GetExpirationTime(); err == nil && exp != nil {
+		info.Attributes["expires"] = exp.UTC().Format(time.RFC3339)
+	}
+	return info, nil
+}
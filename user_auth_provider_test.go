@@ -0,0 +1,85 @@
+package servion
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestUserAuthProvider(t *testing.T) {
+	users := newTestUserStore(t, newTestUser(t, "alice", "correct horse", "admin"))
+	h := newTestLoginHandler(t, users, func(h *implLoginHandler) { h.Issuer = "tool" })
+	p := &implUserAuthProvider{Users: users, Secret: "login-secret", Issuer: "tool"}
+	if err := p.PostConstruct(); err != nil {
+		t.Fatal(err)
+	}
+
+	user, err := h.login("alice", "correct horse", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := h.signer.sign(jwt.MapClaims{
+		"sub": user.Username, "iss": "tool", "amr": []string{"pwd"}, "roles": []string{"stale"},
+		"iat": time.Now().Unix(), "exp": time.Now().Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := p.Authenticate(token)
+	if err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	if info.Subject != "alice" || len(info.Roles) != 1 || info.Roles[0] != "admin" || info.Attributes["auth"] != "password" {
+		t.Errorf("expected the roles of the account, got %+v", info)
+	}
+
+	// role changes apply at once
+	u, _ := users.Load("alice")
+	u.Roles = []string{"viewer"}
+	users.Store(u)
+	if info, _ := p.Authenticate(token); info.Roles[0] != "viewer" {
+		t.Errorf("Roles = %v, want [viewer]", info.Roles)
+	}
+
+	// a password reset refuses older tokens
+	u.PasswordChanged = time.Now().Add(time.Minute)
+	users.Store(u)
+	if _, err := p.Authenticate(token); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("token before the password change: %v", err)
+	}
+
+	u.PasswordChanged = time.Now().Add(-time.Minute)
+	u.Disabled = true
+	users.Store(u)
+	if _, err := p.Authenticate(token); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("disabled user: %v", err)
+	}
+
+	users.Delete("alice")
+	if _, err := p.Authenticate(token); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("deleted user: %v", err)
+	}
+}
+
+func TestUserAuthProvider_RefusesOtherTokens(t *testing.T) {
+	users := newTestUserStore(t, newTestUser(t, "alice", "correct horse"))
+	p := &implUserAuthProvider{Users: users, Secret: "login-secret"}
+	if err := p.PostConstruct(); err != nil {
+		t.Fatal(err)
+	}
+	exp := time.Now().Add(time.Hour).Unix()
+	for name, token := range map[string]string{
+		"no amr":       makeHmacToken(t, "login-secret", jwt.MapClaims{"sub": "alice", "iat": time.Now().Unix(), "exp": exp}),
+		"wrong secret": makeHmacToken(t, "other", jwt.MapClaims{"sub": "alice", "amr": []string{"pwd"}, "iat": time.Now().Unix(), "exp": exp}),
+		"no exp":       makeHmacToken(t, "login-secret", jwt.MapClaims{"sub": "alice", "amr": []string{"pwd"}, "iat": time.Now().Unix()}),
+		"no iat":       makeHmacToken(t, "login-secret", jwt.MapClaims{"sub": "alice", "amr": []string{"pwd"}, "exp": exp}),
+		"garbage":      "not-a-token",
+	} {
+		if _, err := p.Authenticate(token); !errors.Is(err, ErrUnauthorized) {
+			t.Errorf("%s: expected ErrUnauthorized, got %v", name, err)
+		}
+	}
+}
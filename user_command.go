@@ -0,0 +1,308 @@
+/*
+ * Copyright (c) 2026 Karagatan LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package servion
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.arpabet.com/cligo"
+	"golang.org/x/term"
+	"golang.org/x/xerrors"
+)
+
+// minPasswordLength is the shortest password the user commands accept.
+const minPasswordLength = 8
+
+type implUserGroup struct {
+	Parent cligo.CliGroup `cli:"group=cli"`
+}
+
+/*
+UserCommands creates the "user" command group managing the accounts of
+FileUserStore. Passwords are prompted for on a terminal, or read as the first
+line of stdin otherwise:
+
+	app user add --name alice --roles admin
+	echo "$PASSWORD" | app user passwd --name alice
+	app user unlock --name alice
+	app user list
+	app user remove --name alice
+
+Add them to the beans of cligo.Main with append(beans, servion.UserCommands()...).
+*/
+func UserCommands() []interface{} {
+	return []interface{}{
+		&implUserGroup{},
+		&implUserAddCommand{in: os.Stdin, out: os.Stdout},
+		&implUserPasswdCommand{in: os.Stdin, out: os.Stdout},
+		&implUserUnlockCommand{out: os.Stdout},
+		&implUserListCommand{out: os.Stdout},
+		&implUserRemoveCommand{out: os.Stdout},
+	}
+}
+
+func (t *implUserGroup) Group() string {
+	return "user"
+}
+
+func (t *implUserGroup) Help() (string, string) {
+	return "Manages local user accounts.",
+		`These commands edit the user file (user.file) read by FileUserStore; running servers apply the changes at the next login or request.`
+}
+
+type implUserAddCommand struct {
+	Parent    cligo.CliGroup `cli:"group=user"`
+	HomeDir   string         `cli:"option=home,default=.,help=home directory of application"`
+	File      string         `cli:"option=file,default=users.json,help=user file, relative to the home directory"`
+	Name      string         `cli:"option=name,default=,help=user name"`
+	Roles     string         `cli:"option=roles,default=,help=comma separated roles"`
+	Algorithm string         `cli:"option=hash,default=argon2id,help=password hash, argon2id or bcrypt"`
+
+	in  io.Reader
+	out io.Writer
+}
+
+func (t *implUserAddCommand) Command() string {
+	return "add"
+}
+
+func (t *implUserAddCommand) Help() (string, string) {
+	return "Adds a user.", `This command adds a user with a password read from the terminal or stdin. It refuses to replace an existing user, use passwd to reset a password.`
+}
+
+func (t *implUserAddCommand) Run(ctx context.Context) error {
+	store := userStoreAt(t.HomeDir, t.File)
+	if !validUsername.MatchString(t.Name) {
+		return xerrors.New("--name is required and may contain letters, digits and . _ @ + - only")
+	}
+	existing, err := store.Load(t.Name)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return xerrors.Errorf("user '%s' exists, use passwd to reset the password", t.Name)
+	}
+	password, err := readNewPassword(t.in, t.out)
+	if err != nil {
+		return err
+	}
+	hash, err := hashPassword(password, t.Algorithm)
+	if err != nil {
+		return err
+	}
+	user := &UserAccount{
+		Username:        t.Name,
+		PasswordHash:    hash,
+		Roles:           apiKeyList(t.Roles),
+		PasswordChanged: time.Now(),
+	}
+	if err := store.Store(user); err != nil {
+		return err
+	}
+	fmt.Fprintf(t.out, "added user %s\n", t.Name)
+	return nil
+}
+
+type implUserPasswdCommand struct {
+	Parent    cligo.CliGroup `cli:"group=user"`
+	HomeDir   string         `cli:"option=home,default=.,help=home directory of application"`
+	File      string         `cli:"option=file,default=users.json,help=user file, relative to the home directory"`
+	Name      string         `cli:"option=name,default=,help=user name"`
+	Algorithm string         `cli:"option=hash,default=argon2id,help=password hash, argon2id or bcrypt"`
+
+	in  io.Reader
+	out io.Writer
+}
+
+func (t *implUserPasswdCommand) Command() string {
+	return "passwd"
+}
+
+func (t *implUserPasswdCommand) Help() (string, string) {
+	return "Resets the password of a user.", `This command sets a new password and unlocks the user. Tokens issued before are refused by UserAuthProvider.`
+}
+
+func (t *implUserPasswdCommand) Run(ctx context.Context) error {
+	store := userStoreAt(t.HomeDir, t.File)
+	user, err := loadExistingUser(store, t.Name)
+	if err != nil {
+		return err
+	}
+	password, err := readNewPassword(t.in, t.out)
+	if err != nil {
+		return err
+	}
+	if user.PasswordHash, err = hashPassword(password, t.Algorithm); err != nil {
+		return err
+	}
+	user.PasswordChanged = time.Now()
+	user.FailedLogins, user.LockedUntil = 0, time.Time{}
+	if err := store.Store(user); err != nil {
+		return err
+	}
+	fmt.Fprintf(t.out, "password of %s changed\n", t.Name)
+	return nil
+}
+
+type implUserUnlockCommand struct {
+	Parent  cligo.CliGroup `cli:"group=user"`
+	HomeDir string         `cli:"option=home,default=.,help=home directory of application"`
+	File    string         `cli:"option=file,default=users.json,help=user file, relative to the home directory"`
+	Name    string         `cli:"option=name,default=,help=user name"`
+
+	out io.Writer
+}
+
+func (t *implUserUnlockCommand) Command() string {
+	return "unlock"
+}
+
+func (t *implUserUnlockCommand) Help() (string, string) {
+	return "Unlocks a user.", `This command clears the failed logins and the lockout of a user.`
+}
+
+func (t *implUserUnlockCommand) Run(ctx context.Context) error {
+	store := userStoreAt(t.HomeDir, t.File)
+	user, err := loadExistingUser(store, t.Name)
+	if err != nil {
+		return err
+	}
+	user.FailedLogins, user.LockedUntil = 0, time.Time{}
+	if err := store.Store(user); err != nil {
+		return err
+	}
+	fmt.Fprintf(t.out, "unlocked user %s\n", t.Name)
+	return nil
+}
+
+type implUserListCommand struct {
+	Parent  cligo.CliGroup `cli:"group=user"`
+	HomeDir string         `cli:"option=home,default=.,help=home directory of application"`
+	File    string         `cli:"option=file,default=users.json,help=user file, relative to the home directory"`
+
+	out io.Writer
+}
+
+func (t *implUserListCommand) Command() string {
+	return "list"
+}
+
+func (t *implUserListCommand) Help() (string, string) {
+	return "Lists users.", `This command prints the users with their roles and state.`
+}
+
+func (t *implUserListCommand) Run(ctx context.Context) error {
+	users, err := userStoreAt(t.HomeDir, t.File).List()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, u := range users {
+		roles := strings.Join(u.Roles, ",")
+		if roles == "" {
+			roles = "-"
+		}
+		state := "active"
+		switch {
+		case u.Disabled:
+			state = "disabled"
+		case now.Before(u.LockedUntil):
+			state = "locked until " + u.LockedUntil.UTC().Format(time.RFC3339)
+		}
+		fmt.Fprintf(t.out, "%s %s %s\n", u.Username, roles, state)
+	}
+	return nil
+}
+
+type implUserRemoveCommand struct {
+	Parent  cligo.CliGroup `cli:"group=user"`
+	HomeDir string         `cli:"option=home,default=.,help=home directory of application"`
+	File    string         `cli:"option=file,default=users.json,help=user file, relative to the home directory"`
+	Name    string         `cli:"option=name,default=,help=user name"`
+
+	out io.Writer
+}
+
+func (t *implUserRemoveCommand) Command() string {
+	return "remove"
+}
+
+func (t *implUserRemoveCommand) Help() (string, string) {
+	return "Removes a user.", `This command deletes a user; its tokens are refused by UserAuthProvider from then on.`
+}
+
+func (t *implUserRemoveCommand) Run(ctx context.Context) error {
+	store := userStoreAt(t.HomeDir, t.File)
+	if _, err := loadExistingUser(store, t.Name); err != nil {
+		return err
+	}
+	if err := store.Delete(t.Name); err != nil {
+		return err
+	}
+	fmt.Fprintf(t.out, "removed user %s\n", t.Name)
+	return nil
+}
+
+func userStoreAt(home, file string) *implFileUserStore {
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(home, file)
+	}
+	return newFileUserStore(file)
+}
+
+func loadExistingUser(store UserStore, name string) (*UserAccount, error) {
+	if name == "" {
+		return nil, xerrors.New("--name is required")
+	}
+	user, err := store.Load(name)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, xerrors.Errorf("unknown user '%s'", name)
+	}
+	return user, nil
+}
+
+// readNewPassword prompts twice for a password on a terminal, or reads the first line of in otherwise.
+func readNewPassword(in io.Reader, out io.Writer) (string, error) {
+	var password string
+	if f, ok := in.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		fmt.Fprint(out, "Password: ")
+		first, err := term.ReadPassword(int(f.Fd()))
+		fmt.Fprintln(out)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprint(out, "Repeat password: ")
+		second, err := term.ReadPassword(int(f.Fd()))
+		fmt.Fprintln(out)
+		if err != nil {
+			return "", err
+		}
+		if string(first) != string(second) {
+			return "", xerrors.New("passwords do not match")
+		}
+		password = string(first)
+	} else {
+		line, err := bufio.NewReader(in).ReadString('\n')
+		if err != nil && err != io.EOF {
+			return "", err
+		}
+		password = strings.TrimRight(line, "\r\n")
+	}
+	if len(password) < minPasswordLength {
+		return "", xerrors.Errorf("password must have at least %d characters", minPasswordLength)
+	}
+	return password, nil
+}
@@ -0,0 +1,75 @@
+package servion
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUserCommands(t *testing.T) {
+	home := t.TempDir()
+	var out bytes.Buffer
+
+	add := &implUserAddCommand{HomeDir: home, File: "users.json", Name: "alice", Roles: "admin,ops", Algorithm: PasswordHashArgon2id,
+		in: strings.NewReader("correct horse\n"), out: &out}
+	if err := add.Run(context.Background()); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	add.in = strings.NewReader("another one\n")
+	if err := add.Run(context.Background()); err == nil {
+		t.Error("add of an existing user must fail")
+	}
+
+	store := newFileUserStore(filepath.Join(home, "users.json"))
+	u, _ := store.Load("alice")
+	if u == nil || strings.Join(u.Roles, ",") != "admin,ops" {
+		t.Fatalf("added user %+v", u)
+	}
+	if ok, _, _ := verifyPassword(u.PasswordHash, "correct horse", ""); !ok {
+		t.Error("password not stored")
+	}
+
+	u.FailedLogins, u.LockedUntil = 2, time.Now().Add(time.Hour)
+	store.Store(u)
+	out.Reset()
+	list := &implUserListCommand{HomeDir: home, File: "users.json", out: &out}
+	list.Run(context.Background())
+	if !strings.HasPrefix(out.String(), "alice admin,ops locked until ") {
+		t.Errorf("list: %q", out.String())
+	}
+
+	unlock := &implUserUnlockCommand{HomeDir: home, File: "users.json", Name: "alice", out: &out}
+	if err := unlock.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if u, _ := store.Load("alice"); u.FailedLogins != 0 || !u.LockedUntil.IsZero() {
+		t.Errorf("not unlocked: %+v", u)
+	}
+
+	before := u.PasswordChanged
+	passwd := &implUserPasswdCommand{HomeDir: home, File: "users.json", Name: "alice", Algorithm: PasswordHashBcrypt,
+		in: strings.NewReader("new password\n"), out: &out}
+	if err := passwd.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	u, _ = store.Load("alice")
+	if ok, _, _ := verifyPassword(u.PasswordHash, "new password", ""); !ok || !u.PasswordChanged.After(before) {
+		t.Errorf("password not reset: %+v", u)
+	}
+
+	passwd.in = strings.NewReader("short\n")
+	if err := passwd.Run(context.Background()); err == nil {
+		t.Error("expected an error for a short password")
+	}
+
+	remove := &implUserRemoveCommand{HomeDir: home, File: "users.json", Name: "alice", out: &out}
+	if err := remove.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := remove.Run(context.Background()); err == nil {
+		t.Error("remove of an unknown user must fail")
+	}
+}
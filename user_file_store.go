@@ -0,0 +1,205 @@
+/*
+ * Copyright (c) 2026 Karagatan LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package servion
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+// maxUserFileSize caps the user file read on every change.
+const maxUserFileSize = 16 << 20
+
+// validUsername keeps user names printable and safe in logs, tokens and the user file.
+var validUsername = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._@+-]{0,127}$`)
+
+type implFileUserStore struct {
+	Runtime Runtime `inject:"optional"`
+
+	// User file, relative paths resolve against the home directory
+	File string `value:"user.file,default=users.json"`
+
+	path    string
+	mu      sync.Mutex
+	users   map[string]*UserAccount
+	fileMod time.Time
+	size    int64
+}
+
+/*
+FileUserStore creates a UserStore keeping all accounts in one JSON file under
+the home directory, readable by the owner only. Every write replaces the file
+atomically, and a file changed by another process, such as the "user"
+commands, is read again before the next access, so accounts added or reset from
+the command line apply to a running server at once.
+
+Configuration properties:
+
+	user.file  – user file, relative to the home directory (default "users.json")
+*/
+func FileUserStore() UserStore {
+	return &implFileUserStore{}
+}
+
+// newFileUserStore opens the user file at path, for the "user" commands.
+func newFileUserStore(path string) *implFileUserStore {
+	return &implFileUserStore{path: path}
+}
+
+func (t *implFileUserStore) PostConstruct() error {
+	t.path = t.File
+	if !filepath.IsAbs(t.path) && t.Runtime != nil {
+		t.path = filepath.Join(t.Runtime.HomeDir(), t.path)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := t.reload(); err != nil {
+		return xerrors.Errorf("user: %w", err)
+	}
+	return nil
+}
+
+func (t *implFileUserStore) Load(username string) (*UserAccount, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := t.reload(); err != nil {
+		return nil, err
+	}
+	u, ok := t.users[username]
+	if !ok {
+		return nil, nil
+	}
+	return copyUser(u), nil
+}
+
+func (t *implFileUserStore) Store(u *UserAccount) error {
+	if !validUsername.MatchString(u.Username) {
+		return xerrors.Errorf("invalid user name '%s'", u.Username)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := t.reload(); err != nil {
+		return err
+	}
+	users := make(map[string]*UserAccount, len(t.users)+1)
+	for k, v := range t.users {
+		users[k] = v
+	}
+	users[u.Username] = copyUser(u)
+	return t.write(users)
+}
+
+func (t *implFileUserStore) Delete(username string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := t.reload(); err != nil {
+		return err
+	}
+	if _, ok := t.users[username]; !ok {
+		return nil
+	}
+	users := make(map[string]*UserAccount, len(t.users))
+	for k, v := range t.users {
+		if k != username {
+			users[k] = v
+		}
+	}
+	return t.write(users)
+}
+
+func (t *implFileUserStore) List() ([]*UserAccount, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := t.reload(); err != nil {
+		return nil, err
+	}
+	list := make([]*UserAccount, 0, len(t.users))
+	for _, u := range t.users {
+		list = append(list, copyUser(u))
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Username < list[j].Username })
+	return list, nil
+}
+
+// reload reads the user file when it changed; a missing file is an empty store. Callers hold mu.
+func (t *implFileUserStore) reload() error {
+	fi, err := os.Stat(t.path)
+	if os.IsNotExist(err) {
+		t.users, t.fileMod, t.size = map[string]*UserAccount{}, time.Time{}, 0
+		return nil
+	}
+	if err != nil {
+		return xerrors.Errorf("stat user file: %w", err)
+	}
+	if t.users != nil && fi.ModTime().Equal(t.fileMod) && fi.Size() == t.size {
+		return nil
+	}
+	if fi.Size() > maxUserFileSize {
+		return xerrors.Errorf("user file %s exceeds %d bytes", t.path, maxUserFileSize)
+	}
+	data, err := os.ReadFile(t.path)
+	if err != nil {
+		return xerrors.Errorf("read user file: %w", err)
+	}
+	var list []*UserAccount
+	if err := json.Unmarshal(data, &list); err != nil {
+		return xerrors.Errorf("user file %s: %w", t.path, err)
+	}
+	users := make(map[string]*UserAccount, len(list))
+	for _, u := range list {
+		users[u.Username] = u
+	}
+	t.users, t.fileMod, t.size = users, fi.ModTime(), fi.Size()
+	return nil
+}
+
+// write replaces the user file with users. Callers hold mu.
+func (t *implFileUserStore) write(users map[string]*UserAccount) error {
+	list := make([]*UserAccount, 0, len(users))
+	for _, u := range users {
+		list = append(list, u)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Username < list[j].Username })
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(t.path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return xerrors.Errorf("store user: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, ".users-*")
+	if err != nil {
+		return xerrors.Errorf("store user: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return xerrors.Errorf("store user: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return xerrors.Errorf("store user: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), t.path); err != nil {
+		return xerrors.Errorf("store user: %w", err)
+	}
+	// the next access reads the file back, taking its new modification time
+	t.users = nil
+	return nil
+}
+
+func copyUser(u *UserAccount) *UserAccount {
+	c := *u
+	c.Roles = append([]string(nil), u.Roles...)
+	return &c
+}
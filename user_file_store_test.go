@@ -0,0 +1,62 @@
+package servion
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileUserStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.json")
+	s := &implFileUserStore{File: path}
+	if err := s.PostConstruct(); err != nil {
+		t.Fatal(err)
+	}
+	if u, err := s.Load("alice"); u != nil || err != nil {
+		t.Fatalf("empty store: %v, %v", u, err)
+	}
+
+	changed := time.Now().Truncate(time.Second)
+	alice := &UserAccount{Username: "alice", PasswordHash: "h1", Roles: []string{"admin"}, PasswordChanged: changed}
+	if err := s.Store(alice); err != nil {
+		t.Fatal(err)
+	}
+	alice.Roles[0] = "mutated"
+	if err := s.Store(&UserAccount{Username: "bob@example.com", PasswordHash: "h2"}); err != nil {
+		t.Fatal(err)
+	}
+
+	u, err := s.Load("alice")
+	if err != nil || u == nil || u.Roles[0] != "admin" || !u.PasswordChanged.Equal(changed) {
+		t.Fatalf("Load = %+v, %v", u, err)
+	}
+	if fi, _ := os.Stat(path); fi.Mode().Perm() != 0600 {
+		t.Errorf("user file mode %v, want 0600", fi.Mode().Perm())
+	}
+
+	// another process, e.g. the user commands, writes the file
+	other := newFileUserStore(path)
+	if err := other.Store(&UserAccount{Username: "carol", PasswordHash: "h3"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := other.Delete("alice"); err != nil {
+		t.Fatal(err)
+	}
+	list, err := s.List()
+	if err != nil || len(list) != 2 || list[0].Username != "bob@example.com" || list[1].Username != "carol" {
+		t.Fatalf("List = %v, %v", list, err)
+	}
+
+	if err := s.Store(&UserAccount{Username: "bad name"}); err == nil {
+		t.Error("expected an error for an invalid user name")
+	}
+	if err := s.Delete("nobody"); err != nil {
+		t.Errorf("Delete of an unknown user: %v", err)
+	}
+
+	os.WriteFile(path, []byte("not json"), 0600)
+	if _, err := s.Load("carol"); err == nil {
+		t.Error("expected an error for a corrupt file")
+	}
+}
@@ -0,0 +1,112 @@
+/*
+ * Copyright (c) 2026 Karagatan LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package servion
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/xerrors"
+)
+
+const (
+	PasswordHashArgon2id = "argon2id"
+	PasswordHashBcrypt   = "bcrypt"
+)
+
+// argon2id parameters of the OWASP password storage recommendation: 19 MiB, 2 passes, 1 lane.
+const (
+	argon2Memory  = 19 * 1024
+	argon2Time    = 2
+	argon2Threads = 1
+	argon2SaltLen = 16
+	argon2KeyLen  = 32
+	bcryptCost    = 12
+)
+
+/*
+hashPassword hashes password with algorithm, argon2id or bcrypt, in the PHC
+string format, e.g. "$argon2id$v=19$m=19456,t=2,p=1$<salt>$<hash>"; bcrypt
+limits passwords to 72 bytes.
+*/
+func hashPassword(password, algorithm string) (string, error) {
+	switch algorithm {
+	case PasswordHashArgon2id, "":
+		salt := make([]byte, argon2SaltLen)
+		if _, err := rand.Read(salt); err != nil {
+			return "", err
+		}
+		key := argon2.IDKey([]byte(password), salt, argon2Time, argon2Memory, argon2Threads, argon2KeyLen)
+		return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, argon2Memory, argon2Time, argon2Threads,
+			base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+	case PasswordHashBcrypt:
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), bcryptCost)
+		if err != nil {
+			return "", xerrors.Errorf("bcrypt: %w", err)
+		}
+		return string(hash), nil
+	}
+	return "", xerrors.Errorf("unknown password hash '%s', expected argon2id or bcrypt", algorithm)
+}
+
+/*
+verifyPassword reports whether password matches hash, an argon2id or bcrypt
+hash. rehash tells that the hash uses another algorithm than algorithm or
+weaker parameters, so it should be replaced after a successful login.
+*/
+func verifyPassword(hash, password, algorithm string) (ok, rehash bool, err error) {
+	if algorithm == "" {
+		algorithm = PasswordHashArgon2id
+	}
+	switch {
+	case strings.HasPrefix(hash, "$argon2id$"):
+		var (
+			version, passes, threads int
+			memory                   uint32
+		)
+		parts := strings.Split(hash, "$")
+		if len(parts) != 6 {
+			return false, false, xerrors.New("malformed argon2id hash")
+		}
+		if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+			return false, false, xerrors.Errorf("unsupported argon2id version '%s'", parts[2])
+		}
+		if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &passes, &threads); err != nil || passes < 1 || threads < 1 || threads > 255 || memory > 1<<20 {
+			return false, false, xerrors.Errorf("malformed argon2id parameters '%s'", parts[3])
+		}
+		salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+		if err != nil {
+			return false, false, xerrors.New("malformed argon2id salt")
+		}
+		want, err := base64.RawStdEncoding.DecodeString(parts[5])
+		if err != nil || len(want) == 0 {
+			return false, false, xerrors.New("malformed argon2id hash")
+		}
+		got := argon2.IDKey([]byte(password), salt, uint32(passes), memory, uint8(threads), uint32(len(want)))
+		ok = subtle.ConstantTimeCompare(got, want) == 1
+		rehash = algorithm != PasswordHashArgon2id || memory < argon2Memory || passes < argon2Time
+		return ok, rehash, nil
+
+	case strings.HasPrefix(hash, "$2a$"), strings.HasPrefix(hash, "$2b$"), strings.HasPrefix(hash, "$2y$"):
+		err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+		switch {
+		case err == nil:
+		case errors.Is(err, bcrypt.ErrMismatchedHashAndPassword):
+			return false, false, nil
+		default:
+			return false, false, xerrors.Errorf("bcrypt: %w", err)
+		}
+		cost, _ := bcrypt.Cost([]byte(hash))
+		return true, algorithm != PasswordHashBcrypt || cost < bcryptCost, nil
+	}
+	return false, false, xerrors.New("unknown password hash format")
+}
@@ -0,0 +1,69 @@
+package servion
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestPasswordHash_Argon2id(t *testing.T) {
+	hash, err := hashPassword("correct horse", PasswordHashArgon2id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=19456,t=2,p=1$") {
+		t.Errorf("unexpected hash format %s", hash)
+	}
+	if other, _ := hashPassword("correct horse", PasswordHashArgon2id); other == hash {
+		t.Error("hashes of the same password must differ by salt")
+	}
+
+	ok, rehash, err := verifyPassword(hash, "correct horse", PasswordHashArgon2id)
+	if !ok || rehash || err != nil {
+		t.Errorf("verify = %v, %v, %v", ok, rehash, err)
+	}
+	if ok, _, _ := verifyPassword(hash, "wrong horse", PasswordHashArgon2id); ok {
+		t.Error("wrong password accepted")
+	}
+	if _, rehash, _ := verifyPassword(hash, "correct horse", PasswordHashBcrypt); !rehash {
+		t.Error("expected a rehash when bcrypt is configured")
+	}
+}
+
+func TestPasswordHash_Bcrypt(t *testing.T) {
+	hash, err := hashPassword("correct horse", PasswordHashBcrypt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ok, rehash, err := verifyPassword(hash, "correct horse", PasswordHashBcrypt)
+	if !ok || rehash || err != nil {
+		t.Errorf("verify = %v, %v, %v", ok, rehash, err)
+	}
+	if ok, _, err := verifyPassword(hash, "wrong horse", PasswordHashBcrypt); ok || err != nil {
+		t.Errorf("wrong password: %v, %v", ok, err)
+	}
+
+	// hashes of other tools with a lower cost are upgraded
+	weak, _ := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	if ok, rehash, _ := verifyPassword(string(weak), "correct horse", PasswordHashArgon2id); !ok || !rehash {
+		t.Errorf("weak bcrypt hash: ok=%v rehash=%v", ok, rehash)
+	}
+}
+
+func TestPasswordHash_Malformed(t *testing.T) {
+	for _, hash := range []string{
+		"",
+		"plaintext",
+		"$argon2id$v=19$m=19456,t=2,p=1$salt",
+		"$argon2id$v=18$m=19456,t=2,p=1$c2FsdA$aGFzaA",
+		"$argon2id$v=19$m=99999999,t=2,p=1$c2FsdA$aGFzaA",
+	} {
+		if ok, _, err := verifyPassword(hash, "x", ""); ok || err == nil {
+			t.Errorf("%q: expected an error, got ok=%v", hash, ok)
+		}
+	}
+	if _, err := hashPassword("x", "md5"); err == nil {
+		t.Error("expected an error for an unknown algorithm")
+	}
+}
@@ -14,6 +14,7 @@ import (
 	"os"
 	"os/signal"
 	"runtime/debug"
+	"strconv"
 	"strings"
 	"syscall"
 
@@ -51,6 +52,35 @@ func ParsePrefixList(str string) []string {
 	return list
 }
 
+// ParseByteSize parses a size in bytes with an optional KB, MB or GB suffix (1024-based).
+func ParseByteSize(s string) (int64, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	multiplier := int64(1)
+	for _, unit := range []struct {
+		suffix string
+		size   int64
+	}{
+		{"GB", 1 << 30},
+		{"MB", 1 << 20},
+		{"KB", 1 << 10},
+		{"B", 1},
+	} {
+		if strings.HasSuffix(s, unit.suffix) {
+			s = strings.TrimSpace(strings.TrimSuffix(s, unit.suffix))
+			multiplier = unit.size
+			break
+		}
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 {
+		return 0, xerrors.Errorf("size must not be negative, got %d", n)
+	}
+	return n * multiplier, nil
+}
+
 const (
 	acceptEncoding  = "Accept-Encoding"
 	contentEncoding = "Content-Encoding"
@@ -147,3 +147,28 @@ func TestGzipWriter_NoHeaderOnError(t *testing.T) {
 		t.Error("should not set gzip Content-Encoding on non-200 status")
 	}
 }
+
+func TestParseByteSize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+	}{
+		{"0", 0},
+		{"512", 512},
+		{"512B", 512},
+		{"64KB", 64 << 10},
+		{" 10mb ", 10 << 20},
+		{"2GB", 2 << 30},
+	}
+	for _, tt := range tests {
+		got, err := ParseByteSize(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseByteSize(%q) = %d, %v, want %d", tt.in, got, err, tt.want)
+		}
+	}
+	for _, bad := range []string{"", "MB", "1.5MB", "-1"} {
+		if _, err := ParseByteSize(bad); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
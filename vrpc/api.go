@@ -17,6 +17,7 @@ package servionvrpc
 import (
 	"reflect"
 
+	"go.arpabet.com/servion"
 	"go.arpabet.com/value"
 	"go.arpabet.com/value-rpc/valueclient"
 	"go.arpabet.com/value-rpc/valuerpc"
@@ -81,6 +82,24 @@ type Authenticator interface {
 	Authenticate(conn valuerpc.MsgConn, credential value.Value) (principal string, err error)
 }
 
+var AuthInfoAuthenticatorClass = reflect.TypeOf((*AuthInfoAuthenticator)(nil)).Elem()
+
+/*
+AuthInfoAuthenticator is an Authenticator that also knows the roles, scopes and
+attributes of the principal, e.g. from the claims of a bearer token. When the
+Authenticator bean implements it, ValueServer calls AuthenticateInfo in place of
+Authenticate and binds the full servion.AuthInfo to the connection, which
+Authorize and AuthFromContext hand to rules and handlers. The connection
+principal is then "<subject>#<digest>"; read the subject with AuthFromContext.
+*/
+type AuthInfoAuthenticator interface {
+	Authenticator
+
+	// AuthenticateInfo validates credential and returns the identity of the
+	// connection, nil for anonymous, or a non-nil error to reject it.
+	AuthenticateInfo(conn valuerpc.MsgConn, credential value.Value) (*servion.AuthInfo, error)
+}
+
 // ValueClientClass is the reflect.Type of valueclient.Client, produced by
 // ValueClientFactory.
 var ValueClientClass = reflect.TypeOf((*valueclient.Client)(nil)).Elem()
@@ -0,0 +1,114 @@
+/*
+ * Copyright (c) 2026 Karagatan LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package servionvrpc
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+
+	"go.arpabet.com/servion"
+	"go.arpabet.com/value"
+	"go.arpabet.com/value-rpc/valuerpc"
+)
+
+// principalSeparator splits the subject from the identity digest in the
+// connection principal of an AuthInfoAuthenticator.
+const principalSeparator = "#"
+
+/*
+connAuthInfos maps the connection principal bound by an authInfoBinder to the
+servion.AuthInfo it stands for. vRPC handlers only see the principal of their
+connection, so the identity travels in it: the principal is the subject followed
+by a keyed digest of the whole AuthInfo, which differs per server and per set of
+roles, scopes and attributes, and is never a bare subject.
+*/
+var connAuthInfos sync.Map
+
+/*
+authInfoBinder is the handshake hook of an AuthInfoAuthenticator bean on one
+ValueServer. A reconnect with the same credential yields the same principal, so
+session resumption keeps working; the entries live until the server shuts down.
+*/
+type authInfoBinder struct {
+	authenticator AuthInfoAuthenticator
+	key           []byte
+
+	mu         sync.Mutex
+	principals map[string]struct{}
+}
+
+func newAuthInfoBinder(a AuthInfoAuthenticator) (*authInfoBinder, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return &authInfoBinder{
+		authenticator: a,
+		key:           key,
+		principals:    make(map[string]struct{}),
+	}, nil
+}
+
+func (t *authInfoBinder) authenticate(conn valuerpc.MsgConn, credential value.Value) (string, error) {
+	info, err := t.authenticator.AuthenticateInfo(conn, credential)
+	if err != nil || info == nil || info.Subject == "" {
+		return "", err
+	}
+	principal, err := t.principal(info)
+	if err != nil {
+		return "", err
+	}
+	t.mu.Lock()
+	t.principals[principal] = struct{}{}
+	t.mu.Unlock()
+	connAuthInfos.Store(principal, *info)
+	return principal, nil
+}
+
+func (t *authInfoBinder) principal(info *servion.AuthInfo) (string, error) {
+	data, err := json.Marshal(info)
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, t.key)
+	mac.Write(data)
+	return info.Subject + principalSeparator + hex.EncodeToString(mac.Sum(nil)[:16]), nil
+}
+
+// release forgets the identities bound by this server.
+func (t *authInfoBinder) release() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for principal := range t.principals {
+		connAuthInfos.Delete(principal)
+	}
+	t.principals = make(map[string]struct{})
+}
+
+/*
+AuthFromContext returns the identity of a vRPC call: the servion.AuthInfo of the
+context when set, else the AuthInfo the AuthInfoAuthenticator returned for the
+connection, else an AuthInfo holding only the principal. It reports false for
+anonymous connections.
+*/
+func AuthFromContext(ctx context.Context) (servion.AuthInfo, bool) {
+	if info, ok := servion.AuthFromContext(ctx); ok {
+		return info, true
+	}
+	principal := valuerpc.PrincipalFromContext(ctx)
+	if principal == "" {
+		return servion.AuthInfo{}, false
+	}
+	if info, ok := connAuthInfos.Load(principal); ok {
+		return info.(servion.AuthInfo), true
+	}
+	return servion.AuthInfo{Subject: principal}, true
+}
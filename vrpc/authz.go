@@ -0,0 +1,44 @@
+/*
+ * Copyright (c) 2026 Karagatan LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package servionvrpc
+
+import (
+	"context"
+
+	"go.arpabet.com/servion"
+	"go.arpabet.com/value"
+)
+
+/*
+Authorize wraps a vRPC function handler with the servion.Authorizer, the vRPC
+counterpart of servion.AuthzMiddleware. The call is checked against rules
+targeting "vrpc:<name>" before fn runs; the identity is that of AuthFromContext,
+so rules on roles and scopes need an AuthInfoAuthenticator bean.
+
+	func (t *adminService) RegisterFunctions(srv valueserver.Server) error {
+		return srv.AddFunction("admin.reset", valuerpc.Any, valuerpc.String,
+			servionvrpc.Authorize(t.Authorizer, "admin.reset", t.reset))
+	}
+
+Denied calls fail with an error wrapping servion.ErrForbidden, or
+servion.ErrUnauthorized for anonymous connections when the rule needs an identity.
+*/
+func Authorize(authorizer servion.Authorizer, name string, fn func(ctx context.Context, args value.Value) (value.Value, error)) func(ctx context.Context, args value.Value) (value.Value, error) {
+	return func(ctx context.Context, args value.Value) (value.Value, error) {
+		var info *servion.AuthInfo
+		if auth, ok := AuthFromContext(ctx); ok {
+			info = &auth
+		}
+		err := authorizer.Authorize(ctx, servion.AccessRequest{
+			Transport: servion.TransportVRPC,
+			Resource:  name,
+		}, info)
+		if err != nil {
+			return nil, err
+		}
+		return fn(ctx, args)
+	}
+}
@@ -0,0 +1,187 @@
+/*
+ * Copyright (c) 2026 Karagatan LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package servionvrpc_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.arpabet.com/servion"
+	servionvrpc "go.arpabet.com/servion/vrpc"
+	"go.arpabet.com/value"
+	"go.arpabet.com/value-rpc/valueclient"
+	"go.arpabet.com/value-rpc/valuerpc"
+	"go.arpabet.com/value-rpc/valueserver"
+	"golang.org/x/xerrors"
+)
+
+// adminOnly permits function calls of subjects holding the admin role.
+type adminOnly struct {
+	seen servion.AccessRequest
+}
+
+func (t *adminOnly) Authorize(_ context.Context, req servion.AccessRequest, info *servion.AuthInfo) error {
+	t.seen = req
+	if info == nil {
+		return xerrors.Errorf("anonymous: %w", servion.ErrUnauthorized)
+	}
+	for _, role := range info.Roles {
+		if role == "admin" {
+			return nil
+		}
+	}
+	return xerrors.Errorf("%s: %w", info.Subject, servion.ErrForbidden)
+}
+
+func TestAuthorize(t *testing.T) {
+	authorizer := &adminOnly{}
+	called := false
+	fn := servionvrpc.Authorize(authorizer, "admin.reset", func(context.Context, value.Value) (value.Value, error) {
+		called = true
+		return value.Utf8("ok"), nil
+	})
+
+	if _, err := fn(context.Background(), nil); !errors.Is(err, servion.ErrUnauthorized) {
+		t.Fatalf("anonymous: error = %v, want ErrUnauthorized", err)
+	}
+	user := servion.ContextWithAuth(context.Background(), servion.AuthInfo{Subject: "bob"})
+	if _, err := fn(user, nil); !errors.Is(err, servion.ErrForbidden) {
+		t.Fatalf("user: error = %v, want ErrForbidden", err)
+	}
+	if called {
+		t.Fatal("handler must not run for denied calls")
+	}
+
+	admin := servion.ContextWithAuth(context.Background(), servion.AuthInfo{Subject: "root", Roles: []string{"admin"}})
+	if _, err := fn(admin, nil); err != nil {
+		t.Fatalf("admin: %v", err)
+	}
+	if !called {
+		t.Fatal("handler did not run")
+	}
+	if authorizer.seen.Transport != servion.TransportVRPC || authorizer.seen.Resource != "admin.reset" {
+		t.Fatalf("access request = %+v", authorizer.seen)
+	}
+}
+
+// roleService authenticates tokens to subjects with roles and exposes admin.reset
+// to admins only.
+type roleService struct{}
+
+func (t *roleService) RegisterFunctions(srv valueserver.Server) error {
+	err := srv.AddFunction("whoami", valuerpc.Any, valuerpc.String, func(ctx context.Context, _ value.Value) (value.Value, error) {
+		auth, _ := servionvrpc.AuthFromContext(ctx)
+		return value.Utf8(auth.Subject), nil
+	})
+	if err != nil {
+		return err
+	}
+	return srv.AddFunction("admin.reset", valuerpc.Any, valuerpc.String,
+		servionvrpc.Authorize(&adminOnly{}, "admin.reset", func(context.Context, value.Value) (value.Value, error) {
+			return value.Utf8("reset"), nil
+		}))
+}
+
+func (t *roleService) Authenticate(conn valuerpc.MsgConn, cred value.Value) (string, error) {
+	info, err := t.AuthenticateInfo(conn, cred)
+	if err != nil {
+		return "", err
+	}
+	return info.Subject, nil
+}
+
+func (t *roleService) AuthenticateInfo(_ valuerpc.MsgConn, cred value.Value) (*servion.AuthInfo, error) {
+	if cred == nil || cred.Kind() != value.STRING {
+		return nil, xerrors.New("missing credential")
+	}
+	switch cred.(value.String).Utf8() {
+	case "root-token":
+		return &servion.AuthInfo{Subject: "root", Roles: []string{"admin"}}, nil
+	case "bob-token":
+		return &servion.AuthInfo{Subject: "bob", Roles: []string{"user"}}, nil
+	case "alice-admin-token":
+		return &servion.AuthInfo{Subject: "alice", Roles: []string{"admin"}}, nil
+	case "alice-user-token":
+		return &servion.AuthInfo{Subject: "alice", Roles: []string{"user"}}, nil
+	}
+	return nil, xerrors.New("invalid token")
+}
+
+func TestAuthorize_AuthInfoAuthenticator(t *testing.T) {
+	addr, teardown := startServer(t,
+		servionvrpc.ValueServerScanner("value-server", &roleService{}),
+	)
+	defer teardown()
+
+	call := func(token string) (value.Value, error) {
+		client := valueclient.NewClient(addr, "")
+		client.SetCredential(value.Utf8(token))
+		client.SetTimeout(500)
+		if err := client.Connect(); err != nil {
+			return nil, err
+		}
+		defer client.Close()
+		return client.CallFunction(context.Background(), "admin.reset", nil)
+	}
+
+	// the admin role of the token reaches the role rule
+	resp, err := call("root-token")
+	if err != nil {
+		t.Fatalf("admin: %v", err)
+	}
+	if resp.String() != "reset" {
+		t.Fatalf("admin: response = %q", resp.String())
+	}
+
+	if _, err := call("bob-token"); err == nil {
+		t.Fatal("expected the call of a non-admin to be forbidden")
+	}
+}
+
+func TestAuthorize_AuthInfoPerConnection(t *testing.T) {
+	addr, teardown := startServer(t,
+		servionvrpc.ValueServerScanner("value-server", &roleService{}),
+	)
+	defer teardown()
+
+	connect := func(token string) valueclient.Client {
+		client := valueclient.NewClient(addr, "")
+		client.SetCredential(value.Utf8(token))
+		client.SetTimeout(500)
+		if err := client.Connect(); err != nil {
+			t.Fatalf("connect %s: %v", token, err)
+		}
+		return client
+	}
+
+	// both connections authenticate alice, with different roles
+	admin := connect("alice-admin-token")
+	defer admin.Close()
+	user := connect("alice-user-token")
+	defer user.Close()
+
+	for _, client := range []valueclient.Client{admin, user} {
+		resp, err := client.CallFunction(context.Background(), "whoami", nil)
+		if err != nil {
+			t.Fatalf("whoami: %v", err)
+		}
+		if resp.String() != "alice" {
+			t.Fatalf("whoami = %q, want alice", resp.String())
+		}
+	}
+
+	// the later login of alice does not change the roles of the first connection
+	if _, err := admin.CallFunction(context.Background(), "admin.reset", nil); err != nil {
+		t.Fatalf("admin connection: %v", err)
+	}
+	if _, err := user.CallFunction(context.Background(), "admin.reset", nil); err == nil {
+		t.Fatal("expected the user connection of alice to be forbidden")
+	}
+	if _, err := admin.CallFunction(context.Background(), "admin.reset", nil); err != nil {
+		t.Fatalf("admin connection after user login: %v", err)
+	}
+}
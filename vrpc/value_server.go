@@ -30,7 +30,8 @@ type implValueServer struct {
 
 	beanName string
 
-	srv valueserver.Server
+	srv      valueserver.Server
+	authInfo *authInfoBinder
 
 	alive        atomic.Bool
 	shutdownOnce sync.Once
@@ -103,7 +104,13 @@ func (t *implValueServer) Bind() (err error) {
 		srv.SetConnectAuthorizer(t.Authorizer.AuthorizeConnect)
 	}
 
-	if t.Authenticator != nil {
+	if ia, ok := t.Authenticator.(AuthInfoAuthenticator); ok {
+		if t.authInfo, err = newAuthInfoBinder(ia); err != nil {
+			srv.Close()
+			return err
+		}
+		srv.SetAuthenticator(t.authInfo.authenticate)
+	} else if t.Authenticator != nil {
 		srv.SetAuthenticator(t.Authenticator.Authenticate)
 	}
 
@@ -172,6 +179,9 @@ func (t *implValueServer) Shutdown() (err error) {
 		if t.srv != nil {
 			err = t.srv.Close()
 		}
+		if t.authInfo != nil {
+			t.authInfo.release()
+		}
 	})
 
 	return